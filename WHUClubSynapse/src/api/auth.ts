@@ -25,18 +25,19 @@ const getIsUsingMockAPI = () => {
 // 1. 用户登录
 export const login = async (
   data: LoginRequest,
-): Promise<{ data: User; token: string }> => {
+): Promise<{ data: User; token: string; refreshToken?: string }> => {
   if (getIsUsingMockAPI()) {
     return await mockAuth.mockLogin(data)
   }
   
   const response = await request.post('/auth/login', data)
-  // 后端直接返回用户对象，token在Header中
+  // 后端返回用户对象及refresh_token，access token在Header中
   const token = response.headers.authorization?.replace('Bearer ', '') || ''
   
   return {
     data: response.data,
-    token: token
+    token: token,
+    refreshToken: response.data?.refresh_token,
   }
 }
 
@@ -114,54 +115,51 @@ export const getCurrentUser = async (): Promise<User> => {
   const id=useAuthStore().currentUserId
   return await getUserById(id as number)
 }
-// TODO:用户退出登录
+// 用户退出登录，同时吊销当前access token与refresh token
 export const logout = async (): Promise<ApiResponse<null>> => {
-  return getIsUsingMockAPI() ? await mockAuth.mockLogout() : await request.post('/auth/logout')
+  return getIsUsingMockAPI()
+    ? await mockAuth.mockLogout()
+    : await request.post('/auth/logout', { refresh_token: localStorage.getItem('refresh_token') || '' })
 }
 
-// TODO:刷新token
-export const refreshToken = async (): Promise<{ token: string }> => {
-  return Promise.resolve({
-    token: 'mock_jwt_token_' + Date.now(),
+// 刷新token，旧refresh token使用后即失效
+export const refreshToken = async (): Promise<{ token: string; refreshToken: string }> => {
+  const response = await request.post('/auth/refresh', {
+    refresh_token: localStorage.getItem('refresh_token') || '',
   })
+
+  return {
+    token: response.headers.authorization?.replace('Bearer ', '') || '',
+    refreshToken: response.data?.refresh_token || '',
+  }
 }
 
-// TODO:修改密码
+// 修改密码，成功后需重新登录
 export const changePassword = async (data: {
   oldPassword: string
   newPassword: string
-}): Promise<{ data: ApiResponse<null> }> => {
-  return Promise.resolve({
-    data: {
-      code: 200,
-      message: '密码修改成功',
-      data: null,
-    },
+}): Promise<void> => {
+  await request.put('/api/user/password', {
+    old_password: data.oldPassword,
+    new_password: data.newPassword,
   })
 }
 
-// TODO:忘记密码
-export const forgotPassword = async (email: string): Promise<{ data: ApiResponse<null> }> => {
-  return Promise.resolve({
-    data: {
-      code: 200,
-      message: '密码重置邮件已发送',
-      data: null,
-    },
-  })
+// 忘记密码，向邮箱发送找回密码验证码
+export const forgotPassword = async (email: string): Promise<void> => {
+  await request.post('/auth/forgot_password', { email })
 }
 
-// TODO:重置密码
+// 使用找回密码验证码重置密码
 export const resetPassword = async (data: {
-  token: string
+  email: string
+  vrfcode: string
   newPassword: string
-}): Promise<{ data: ApiResponse<null> }> => {
-  return Promise.resolve({
-    data: {
-      code: 200,
-      message: '密码重置成功',
-      data: null,
-    },
+}): Promise<void> => {
+  await request.post('/auth/reset_password', {
+    email: data.email,
+    vrfcode: data.vrfcode,
+    new_password: data.newPassword,
   })
 }
 
@@ -6,6 +6,7 @@ import * as mockClub from './mock/club'
 import { mockGetClubPosts, mockGetClubPostDetail, mockGetClubPostReplies, mockCreateClubPost, mockReplyClubPost, mockGetClubJoinApplications } from './mock/club'
 import { getUserById } from './auth'
 import { config } from '@/config'
+import { resolveFileUrl } from '@/utils/fileUrl'
 
 // 获取动态配置
 const getIsUsingMockAPI = () => {
@@ -62,7 +63,7 @@ export const getClubList = async (
         club.logo_url = `${config.apiBaseUrl}/pub/club_logos/default.jpg?t=${timestamp}`
       }
       else{
-        club.logo_url = `${config.apiBaseUrl}/` + club.logo_url+ `?t=${timestamp}`
+        club.logo_url = resolveFileUrl(club.logo_url)+ `?t=${timestamp}`
       }
       return club
     }),
@@ -102,7 +103,7 @@ export const getClubDetail = async (id: string, post_num: number = 5): Promise<C
     response.data.logo_url = `${config.apiBaseUrl}/pub/club_logos/default.jpg?t=${timestamp}`
   } 
    else{
-        response.data.logo_url = `${config.apiBaseUrl}/` + response.data.logo_url+ `?t=${timestamp}`
+        response.data.logo_url = resolveFileUrl(response.data.logo_url)+ `?t=${timestamp}`
       }
       if(response.data.leader_id){
         const user=await getUserById(response.data.leader_id)
@@ -228,7 +229,7 @@ export const getFavoriteClubs = async (
         club.logo_url = `${config.apiBaseUrl}/pub/club_logos/default.jpg?t=${timestamp}`
       }
        else{
-        club.logo_url = `${config.apiBaseUrl}/` + club.logo_url+ `?t=${timestamp}`
+        club.logo_url = resolveFileUrl(club.logo_url)+ `?t=${timestamp}`
       }
       return club
     }),
@@ -329,7 +330,7 @@ export const getJoinedClubs = async (
         club.logo_url = `${config.apiBaseUrl}/pub/club_logos/default.jpg?t=${timestamp}`
         }
          else{
-        club.logo_url = `${config.apiBaseUrl}/` + club.logo_url+ `?t=${new Date().getTime()}`
+        club.logo_url = resolveFileUrl(club.logo_url)+ `?t=${new Date().getTime()}`
       }
         return club
         }), 
@@ -446,7 +447,9 @@ export const getClubPostDetail = async (postId: string, contentUrl: string) => {
     const res = await mockClub.mockGetClubPostDetail(postId, contentUrl)
     return res
   }
-  const res = await request.get('/' + contentUrl, {
+  // 对象存储为S3时content_url为完整地址，本地存储时为相对于后端的路径
+  const url = /^https?:\/\//.test(contentUrl) ? contentUrl : '/' + contentUrl
+  const res = await request.get(url, {
     headers: {
       'Content-Type': '',
     },
@@ -459,7 +462,13 @@ export const getClubPostComments = async (postId: string, page: number, pageSize
   if (getIsUsingMockAPI()) {
     return await mockClub.mockGetClubPostReplies(postId, page, pageSize)
   }
-  const response = await request.get(`/api/club/post/comments/${postId}`)
+  // 后端按楼层分页，每条顶层评论在replies中携带其全部回复
+  const response = await request.get(`/api/club/post/comments/${postId}`, {
+    params: {
+      offset: (page - 1) * pageSize,
+      comment_num: pageSize,
+    },
+  })
   if (response.data == null) {
     return {
       list: [],
@@ -480,8 +489,9 @@ export const getClubPostComments = async (postId: string, page: number, pageSize
     })
   )
   return {
-    list: updatedList.slice((page - 1) * pageSize, page * pageSize),
-    total: response.data.length,
+    list: updatedList,
+    // 接口不返回总数，本页已满时多计一页以便翻页
+    total: (page - 1) * pageSize + list.length + (list.length === pageSize ? pageSize : 0),
     page: page,
     pageSize: pageSize,
   }
@@ -523,6 +533,7 @@ export const replyClubPost = async (data: {
   post_id: number
   user_id: number
   content: string
+  parent_comment_id?: number
 }): Promise<{ data: ApiResponse<null> }> => {
   if (getIsUsingMockAPI()) {
     return await mockClub.mockReplyClubPost(data)
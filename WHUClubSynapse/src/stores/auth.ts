@@ -6,6 +6,7 @@ import { getUserIdFromToken, getUserRoleFromToken, isTokenExpired, getUserInfoFr
 import type { User, LoginRequest, RegisterRequest, UserPreferences } from '@/types'
 import { config } from '@/config'
 import { prepareUserForBackend } from '@/utils/userExtension'
+import { resolveFileUrl } from '@/utils/fileUrl'
 
 export const useAuthStore = defineStore('auth', () => {
   // 状态
@@ -33,6 +34,9 @@ export const useAuthStore = defineStore('auth', () => {
       console.log(user.value)
       token.value = response.token
       localStorage.setItem('token', response.token)
+      if (response.refreshToken) {
+        localStorage.setItem('refresh_token', response.refreshToken)
+      }
 
       // 登录成功后获取完整的用户信息
       await fetchUserInfo()
@@ -75,7 +79,7 @@ export const useAuthStore = defineStore('auth', () => {
 
       }
       else{
-         userInfo.avatar_url=`${config.apiBaseUrl}/`+userInfo.avatar_url
+         userInfo.avatar_url=resolveFileUrl(userInfo.avatar_url)
       }
 
       user.value = userInfo
@@ -103,6 +107,8 @@ export const useAuthStore = defineStore('auth', () => {
   // 退出登录
   const logout = async () => {
     try {
+      await authApi.logout().catch(() => undefined)
+      localStorage.removeItem('refresh_token')
       login({username:'guest',password:'123456a'})
      isGuest.value=true
     } catch (error) {
@@ -154,7 +160,7 @@ export const useAuthStore = defineStore('auth', () => {
           user.avatar_url = `${config.apiBaseUrl}/pub/user_avatars/default.jpg`
         }
         else{
-          user.avatar_url=`${config.apiBaseUrl}/`+user.avatar_url
+          user.avatar_url=resolveFileUrl(user.avatar_url)
         }
         return user
       })
@@ -4,6 +4,7 @@ export interface User {
   username: string
   email: string
   avatar_url?: string
+  avatar_variants?: Record<string, string> // 各尺寸头像地址，键为边长（64/256/512）
   role: string
   last_active?: string
   extension?: string // 🆕 扩展信息JSON字符串
@@ -48,6 +49,7 @@ export interface UserExtension {
 export interface UpdateUserRequest {
   user_id?: number
   username?: string
+  /** @deprecated 更新用户信息时不再支持，请使用 changePassword，需校验原密码 */
   password?: string
   email?: string
   extension?: string
@@ -76,6 +78,7 @@ export interface Club {
   club_name: string
   desc: string
   logo_url: string
+  logo_variants?: Record<string, string> // 各尺寸logo地址，键为边长（64/256/512）
   category: number
   created_at: string
   member_count: number
@@ -199,6 +202,9 @@ export interface ClubPost {
   author_id?: number
   content_url?: string
   is_pinned?: boolean // 是否置顶
+  reactions?: Record<string, number> // 各表情回应数
+  reacted?: boolean
+  my_reaction?: string
 
   content?: string
   authorName?: string
@@ -259,8 +265,15 @@ export interface ClubPostComment {
   user_id: number
   authorName?: string
   authorAvatar?: string
+  parent_comment_id?: number
   content: string
+  status?: 'normal' | 'deleted' | 'removed'
   created_at: string
+  updated_at?: string
+  replies?: ClubPostComment[]
+  reactions?: Record<string, number>
+  reacted?: boolean
+  my_reaction?: string
 }
 
 // 社团成员类型
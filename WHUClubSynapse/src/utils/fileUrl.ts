@@ -0,0 +1,13 @@
+import { config } from '@/config'
+
+/**
+ * 将后端返回的文件地址转换为可访问的地址
+ * 对象存储返回的绝对地址原样使用；本地存储的地址为/pub/...，早期数据为pub/...，均拼接到API地址之后
+ */
+export function resolveFileUrl(url: string): string {
+  if (/^https?:\/\//i.test(url)) {
+    return url
+  }
+
+  return url.startsWith('/') ? `${config.apiBaseUrl}${url}` : `${config.apiBaseUrl}/${url}`
+}
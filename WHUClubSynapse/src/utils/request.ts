@@ -34,26 +34,68 @@ request.interceptors.request.use(
   },
 )
 
+// access token过期后用refresh token换取新token，并发请求共享同一次刷新
+let refreshing: Promise<string> | null = null
+
+const refreshAccessToken = (): Promise<string> => {
+  if (!refreshing) {
+    refreshing = axios
+      .post(`${config.apiBaseUrl}/auth/refresh`, {
+        refresh_token: localStorage.getItem('refresh_token') || '',
+      })
+      .then((response) => {
+        const token = response.headers.authorization?.replace('Bearer ', '') || ''
+        localStorage.setItem('token', token)
+        localStorage.setItem('refresh_token', response.data?.data?.refresh_token || '')
+        return token
+      })
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  return refreshing
+}
+
 // 响应拦截器
 request.interceptors.response.use(
   (response: AxiosResponse) => {
-    // 根据接口文档，后端接口返回格式不统一：
-    // - 登录接口：直接返回用户对象，token在Header中
-    // - 注册接口：返回 { id, username }
-    // - ping接口：返回文本 "pong"
-    // - 用户信息接口：直接返回用户对象
-    // - 验证码接口：返回文本消息
-    // - 用户列表接口：返回用户数组
-    
-    // 直接返回响应，让各个API函数自己处理数据格式
+    // 后端统一返回 { code, message, data }，拆包后各API函数仍直接读取 response.data
+    // 流式接口（SSE、CSV导出、LLM转发）不使用该格式，原样返回
+    const body = response.data as ApiResponse | undefined
+    if (body && typeof body === 'object' && typeof body.code === 'number' && 'data' in body) {
+      if (body.code !== 0 && body.message) {
+        ElMessage.warning(body.message)
+      }
+      response.data = body.data ?? body.message
+    }
+
     return response
   },
-  (error) => {
+  async (error) => {
+    const original = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined
+    if (
+      error.response?.status === 401 &&
+      original &&
+      !original._retried &&
+      !original.url?.startsWith('/auth/') &&
+      localStorage.getItem('refresh_token')
+    ) {
+      original._retried = true
+      try {
+        const token = await refreshAccessToken()
+        original.headers.Authorization = `Bearer ${token}`
+        return request(original)
+      } catch {
+        localStorage.removeItem('refresh_token')
+      }
+    }
+
     console.error('响应错误:', error)
     // console.log(error.response.status)
     // 统一错误处理
     if (error.response) {
-      const { status, data } = error.response
+      const { status } = error.response
+      const data = error.response.data?.message ?? error.response.data
 
       switch (status) {
         case 400:
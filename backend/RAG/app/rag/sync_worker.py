@@ -12,6 +12,13 @@ from app.rag.mcp_rag_service import retriever
 
 SHUTDOWN_REQUESTED = False
 
+# 消息的op字段，缺省时按upsert处理
+OP_UPSERT = "upsert"
+OP_DELETE = "delete"
+VISIBILITY_PUBLIC = "public"
+# 按ID探测已有文本块时每批的ID数量
+CHUNK_ID_PROBE_SIZE = 64
+
 text_splitter = RecursiveCharacterTextSplitter(
     chunk_size=settings.CHUNK_SIZE,
     chunk_overlap=settings.CHUNK_OVERLAP,
@@ -42,16 +49,40 @@ def sanitize_metadata_value(value):
     return value
 
 
+def chunk_id(source_id_base: str, index: int) -> str:
+    """来源第index个文本块的ID"""
+    return f"dynamic::{source_id_base}::chunk::{index}"
+
+
+def find_chunk_ids(source_id_base: str) -> List[str]:
+    """
+    按ID找出来源现有的全部文本块。早期写入的文本块没有source_id元信息，无法按元信息删除；
+    同一来源的块ID从0开始连续编号，逐批探测直到某批未全部命中
+    """
+    found = []
+    start = 0
+    while True:
+        probe_ids = [chunk_id(source_id_base, i) for i in range(start, start + CHUNK_ID_PROBE_SIZE)]
+        existing = chroma_collection.get(ids=probe_ids, include=[])["ids"]
+        found.extend(existing)
+        if len(existing) < len(probe_ids):
+            return found
+        start += CHUNK_ID_PROBE_SIZE
+
+
 # 批量消息处理
 def process_messages_batch(messages: List[Tuple[str, Dict[str, str]]]):
     """
     一次性处理一批消息，对大文档进行切分，然后统一处理。
+    op为delete或元信息中visibility不是public的消息会移除该来源的全部文本块；
+    同一来源在一批中出现多次时以最后一条为准。
     """
     first_msg_id = messages[0][0]
     last_msg_id = messages[-1][0]
     logger.debug(f"Parsing batch of {len(messages)} messages from {first_msg_id} to {last_msg_id}.")
 
-    batch_ids, batch_embeddings, batch_metadatas, batch_documents = [], [], [], []
+    # source_id -> (chunks, metadata)，值为None表示移除
+    latest: Dict[str, object] = {}
     processed_msg_ids = []
 
     for msg_id, msg_data in messages:
@@ -59,53 +90,82 @@ def process_messages_batch(messages: List[Tuple[str, Dict[str, str]]]):
             # 直接以字符串形式获取 source_id 和 content，使用 .get() 保证安全
             source_id_base = msg_data.get('source_id')
             content = msg_data.get('content')
+            op = msg_data.get('op') or OP_UPSERT
 
-            # 2. 检查必要字段是否存在且不为空
-            if not source_id_base or not content:
-                raise ValueError("Message is missing 'source_id' or 'content', or they are empty.")
-
-            source_id = "dynamic::" + source_id_base
+            if not source_id_base:
+                raise ValueError("Message is missing 'source_id'.")
 
-            chunks = text_splitter.split_text(content)
-            logger.debug(f"Document {source_id_base} was split into {len(chunks)} chunks.")
+            if op not in (OP_UPSERT, OP_DELETE):
+                raise ValueError(f"Unknown op '{op}'.")
 
             # 安全地处理 metadata
             metadata_str = msg_data.get('metadata', '')
             raw_metadata = json.loads(metadata_str) if metadata_str.strip() else {}
             sanitized_metadata = {key: sanitize_metadata_value(value) for key, value in raw_metadata.items()}
 
+            # 非公开内容不进入知识库，避免回答中泄露仅成员可见的帖子
+            if op == OP_DELETE or sanitized_metadata.get('visibility', VISIBILITY_PUBLIC) != VISIBILITY_PUBLIC:
+                latest[source_id_base] = None
+                processed_msg_ids.append(msg_id)
+                logger.debug(f"Document {source_id_base} will be removed.")
+                continue
+
+            # 2. 检查必要字段是否存在且不为空
+            if not content:
+                raise ValueError("Message is missing 'content', or it is empty.")
+
+            chunks = text_splitter.split_text(content)
+            logger.debug(f"Document {source_id_base} was split into {len(chunks)} chunks.")
+
             if not sanitized_metadata:
                 sanitized_metadata['source'] = source_id_base
+            # source_id 用于移除或更新时定位该来源的全部文本块
+            sanitized_metadata['source_id'] = source_id_base
 
-            # 为每个切分出的文本块准备数据
-            for i, chunk_content in enumerate(chunks):
-                chunk_id = f"{source_id}::chunk::{i}"
-                batch_ids.append(chunk_id)
-                batch_documents.append(chunk_content)
-                batch_metadatas.append(sanitized_metadata)
-
+            latest[source_id_base] = (chunks, sanitized_metadata)
             processed_msg_ids.append(msg_id)
 
         except Exception as e:
             logger.error(f"Failed to parse or process message. Error: {e}", extra={"msg_id": msg_id})
 
-    if not batch_documents:
+    if not latest:
         # 如果所有消息都解析失败，也要返回ID以便ACK，防止毒丸消息
         return [msg_id for msg_id, _ in messages] if messages else []
 
+    batch_ids, batch_metadatas, batch_documents = [], [], []
+    for source_id_base, entry in latest.items():
+        if entry is None:
+            continue
+
+        chunks, metadata = entry
+        # 为每个切分出的文本块准备数据
+        for i, chunk_content in enumerate(chunks):
+            batch_ids.append(chunk_id(source_id_base, i))
+            batch_documents.append(chunk_content)
+            batch_metadatas.append(metadata)
+
     try:
-        # 批量向量化所有切分出的文本块
-        batch_embeddings = retriever.get_embeddings(batch_documents)
-
-        # 批量写入向量数据库
-        chroma_collection.upsert(
-            ids=batch_ids,
-            embeddings=batch_embeddings,
-            metadatas=batch_metadatas,
-            documents=batch_documents
-        )
+        # 先移除旧的文本块，内容变短后多出的旧块也随之清除
+        chroma_collection.delete(where={"source_id": {"$in": list(latest.keys())}})
+        # 没有source_id元信息的早期文本块按ID删除
+        stale_ids = [cid for source_id_base in latest for cid in find_chunk_ids(source_id_base)]
+        if stale_ids:
+            chroma_collection.delete(ids=stale_ids)
+
+        if batch_documents:
+            # 批量向量化所有切分出的文本块
+            batch_embeddings = retriever.get_embeddings(batch_documents)
+
+            # 批量写入向量数据库
+            chroma_collection.upsert(
+                ids=batch_ids,
+                embeddings=batch_embeddings,
+                metadatas=batch_metadatas,
+                documents=batch_documents
+            )
         logger.info(
-            f"Successfully processed and upserted {len(batch_ids)} chunks from {len(processed_msg_ids)} original messages.")
+            f"Successfully processed {len(processed_msg_ids)} messages: "
+            f"upserted {len(batch_ids)} chunks, removed {sum(1 for e in latest.values() if e is None)} sources.")
         return processed_msg_ids
 
     except Exception as e:
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/baseconfig"
 	"whuclubsynapse-server/internal/base_server/esimpl"
 	"whuclubsynapse-server/internal/base_server/grpcimpl"
@@ -15,6 +17,7 @@ import (
 	"whuclubsynapse-server/internal/base_server/redisimpl"
 	"whuclubsynapse-server/internal/base_server/repo"
 	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/base_server/storage"
 	"whuclubsynapse-server/internal/shared/config"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 	"whuclubsynapse-server/internal/shared/jwtutil"
@@ -58,22 +61,29 @@ func main() {
 
 	app.Use(crs, routeLogger)
 
-	app.HandleDir("/pub/post_files/", service.POST_FILE_DIR)
-	app.HandleDir("/pub/club_logos/", handler.CLUB_LOGO_DIR)
-	app.HandleDir("/pub/user_avatars/", handler.USR_AVATAR_DIR)
-
 	rootApp := mvc.New(app.Party("/"))
 
 	config := LoadConfig("../../config/basic_config.json")
+	ConfigureRemoteAddr(app, config)
+
+	blobStore := CreateBlobStore(config)
+	// S3模式下迁移前上传的文件仍在本地目录中
+	app.HandleDir("/pub/", config.BlobLocalRoot)
 
 	logger := CreateLogger(config)
 	database := ConnectDatabase(config)
 	jwtFactory := CreateJwtFactory(config, logger)
+	checkinJwtFactory := CreateCheckinJwtFactory(config, logger)
 
 	redisService := redisimpl.NewRedisClientService(config, logger)
-	if err := esimpl.InitElasticsearch(); err != nil {
+	searchClient, err := esimpl.NewSearchClientService(config, logger)
+	if err != nil {
 		panic(err)
 	}
+	// ES不可用时不阻止启动，搜索接口返回服务不可用
+	if err := searchClient.EnsureIndices(); err != nil {
+		logger.Error("初始化ES索引失败", "error", err)
+	}
 
 	userRepo := repo.CreateUserRepo(database, logger)
 	categoryRepo := repo.CreateCategoryRepo(database, logger)
@@ -82,14 +92,64 @@ func main() {
 	joinClubAppliRepo := repo.CreateJoinClubAppliRepo(database, logger)
 	clubMemberRepo := repo.CreateClubMemberRepo(database, logger)
 	clubPostRepo := repo.CreateClubPostRepo(database, logger)
+	createPostAppliRepo := repo.CreateCreatePostAppliRepo(database, logger)
 	updateClubInfoAppliRepo := repo.CreateUpdateClubInfoAppliRepo(database, logger)
 	clubFavoriteRepo := repo.CreateClubFavoriteRepo(database, logger)
 	postCommentRepo := repo.CreatePostCommentRepo(database, logger)
+	postRevisionRepo := repo.CreatePostRevisionRepo(database, logger)
+	postAttachmentRepo := repo.CreatePostAttachmentRepo(database, logger)
+	reactionRepo := repo.CreateReactionRepo(database, logger)
+	leaderTransferRepo := repo.CreateLeaderTransferRepo(database, logger)
+	notificationRepo := repo.CreateNotificationRepo(database, logger)
+	clubEventRepo := repo.CreateClubEventRepo(database, logger)
+	eventRsvpRepo := repo.CreateEventRsvpRepo(database, logger)
+	eventAttendanceRepo := repo.CreateEventAttendanceRepo(database, logger)
+	searchOutboxRepo := repo.CreateSearchOutboxRepo(database, logger)
+	ragOutboxRepo := repo.CreateRagOutboxRepo(database, logger)
+	llmUsageLogRepo := repo.CreateLlmUsageLogRepo(database, logger)
 
 	txCoordinator := repo.NewTransactionCoordinator(database)
 
 	userService := service.NewUserService(userRepo)
 	mailvrfService := grpcimpl.NewMailvrfClientService(config, logger)
+	notificationService := service.NewNotificationService(notificationRepo, logger)
+	searchService := service.NewSearchService(
+		clubRepo,
+		clubPostRepo,
+		searchOutboxRepo,
+
+		searchClient,
+		blobStore,
+
+		txCoordinator,
+
+		logger,
+	)
+	ragSyncService := service.NewRagSyncService(
+		clubRepo,
+		clubPostRepo,
+		ragOutboxRepo,
+
+		redisService,
+		blobStore,
+
+		txCoordinator,
+
+		logger,
+	)
+	authService := service.NewAuthService(
+		jwtFactory,
+		time.Duration(config.JwtRefreshExpirationTime)*time.Hour,
+		service.LoginLockoutPolicy{
+			FailWindow: time.Duration(config.LoginFailWindow) * time.Second,
+			Threshold:  config.LoginLockThreshold,
+			LockBase:   time.Duration(config.LoginLockBase) * time.Second,
+			LockMax:    time.Duration(config.LoginLockMax) * time.Second,
+		},
+		redisService,
+		userRepo,
+		logger,
+	)
 	clubService := service.NewClubService(
 		userRepo,
 		clubRepo,
@@ -100,22 +160,108 @@ func main() {
 		categoryRepo,
 		updateClubInfoAppliRepo,
 		clubFavoriteRepo,
+		leaderTransferRepo,
+
+		notificationService,
+		authService,
+		searchService,
+		ragSyncService,
 
 		txCoordinator,
 
 		logger,
 	)
+	attachmentService := service.NewAttachmentService(
+		postAttachmentRepo,
+		clubPostRepo,
+
+		blobStore,
+
+		txCoordinator,
+
+		service.AttachmentPolicy{
+			MaxSize: config.PostAttachmentMaxSize << 20,
+			MaxNum:  config.PostAttachmentMaxNum,
+		},
+
+		logger,
+	)
+	imageService := service.NewImageService(blobStore, logger)
 	postService := service.CreatePostService(
 		clubPostRepo,
+		createPostAppliRepo,
 		postCommentRepo,
+		postRevisionRepo,
+		clubRepo,
+		clubMemberRepo,
+
+		blobStore,
+		notificationService,
+		attachmentService,
+		searchService,
+		ragSyncService,
+
 		txCoordinator,
+
 		logger,
 	)
 
+	reactionService := service.NewReactionService(
+		reactionRepo,
+		clubPostRepo,
+		postCommentRepo,
+
+		redisService,
+
+		txCoordinator,
+
+		logger,
+	)
+
+	eventService := service.NewEventService(
+		clubEventRepo,
+		eventRsvpRepo,
+		eventAttendanceRepo,
+		clubMemberRepo,
+
+		notificationService,
+
+		txCoordinator,
+
+		logger,
+	)
+
+	llmUsageService := service.NewLlmUsageService(
+		llmUsageLogRepo,
+
+		redisService,
+
+		map[string]service.LlmQuota{
+			dbstruct.ROLE_USER: {
+				DailyRequests: config.LlmQuotaUserRequests,
+				DailyTokens:   config.LlmQuotaUserTokens,
+			},
+			dbstruct.ROLE_PUBLISHER: {
+				DailyRequests: config.LlmQuotaPublisherRequests,
+				DailyTokens:   config.LlmQuotaPublisherTokens,
+			},
+			dbstruct.ROLE_ADMIN: {
+				DailyRequests: config.LlmQuotaAdminRequests,
+				DailyTokens:   config.LlmQuotaAdminTokens,
+			},
+		},
+
+		logger,
+	)
+
+	clubRoleGuard := handler.NewClubRoleGuard(clubService, postService, logger)
+	rateLimiter := handler.NewRateLimiter(redisService, logger)
+
 	rootApp.Register(
 		config,
 
 		jwtFactory,
+		checkinJwtFactory,
 		logger,
 		database,
 
@@ -124,10 +270,36 @@ func main() {
 		mailvrfService,
 		clubService,
 		postService,
+		notificationService,
+		eventService,
+		authService,
+		reactionService,
+		attachmentService,
+		imageService,
+		searchService,
+		ragSyncService,
+		llmUsageService,
 	)
 
-	InitAuthHandler(rootApp)
-	InitApiHandler(rootApp, jwtFactory, logger, config)
+	go reactionService.RunFlusher(
+		context.Background(),
+		time.Duration(config.ReactionFlushInterval)*time.Second,
+	)
+	go postService.RunScheduler(
+		context.Background(),
+		time.Duration(config.PostScheduleInterval)*time.Second,
+	)
+	go searchService.RunSyncWorker(
+		context.Background(),
+		time.Duration(config.SearchSyncInterval)*time.Second,
+	)
+	go ragSyncService.RunRelay(
+		context.Background(),
+		time.Duration(config.RagRelayInterval)*time.Second,
+	)
+
+	InitAuthHandler(rootApp, config, rateLimiter)
+	InitApiHandler(rootApp, jwtFactory, authService, logger, config, clubRoleGuard, rateLimiter)
 
 	app.Listen(":" + config.ServerPort)
 }
@@ -141,6 +313,27 @@ func LoadConfig(relPath string) *baseconfig.Config {
 	return &cfg
 }
 
+// ConfigureRemoteAddr 配置从代理请求头中取客户端IP，未配置请求头时ctx.RemoteAddr()为连接的对端地址
+func ConfigureRemoteAddr(app *iris.Application, cfg *baseconfig.Config) {
+	if len(cfg.RemoteAddrHeaders) == 0 {
+		return
+	}
+
+	app.Configure(iris.WithRemoteAddrHeader(cfg.RemoteAddrHeaders...))
+	for _, r := range cfg.TrustedProxyRanges {
+		app.Configure(iris.WithRemoteAddrPrivateSubnet(r.Start, r.End))
+	}
+}
+
+func CreateBlobStore(cfg *baseconfig.Config) storage.BlobStore {
+	store, err := storage.NewBlobStore(cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return store
+}
+
 func CreateLogger(cfg *baseconfig.Config) *slog.Logger {
 	return logger.CreateLogger(nil)
 }
@@ -159,45 +352,89 @@ func ConnectDatabase(cfg *baseconfig.Config) *gorm.DB {
 
 func CreateJwtFactory(cfg *baseconfig.Config, lgr *slog.Logger) *jwtutil.CliamsFactory[model.UserClaims] {
 	return jwtutil.NewClaimsFactory[model.UserClaims](
-		time.Duration(cfg.JwtExpirationTime)*time.Hour,
+		time.Duration(cfg.JwtAccessExpirationTime)*time.Minute,
 		cfg.JwtSecretKey,
 		lgr,
 	)
 }
 
-func InitAuthHandler(parent *mvc.Application) {
+func CreateCheckinJwtFactory(cfg *baseconfig.Config, lgr *slog.Logger) *jwtutil.CliamsFactory[model.CheckinClaims] {
+	return jwtutil.NewClaimsFactory[model.CheckinClaims](
+		time.Duration(cfg.CheckinExpirationTime)*time.Minute,
+		cfg.CheckinSecretKey,
+		lgr,
+	)
+}
+
+func InitAuthHandler(
+	parent *mvc.Application,
+	cfg *baseconfig.Config,
+	limiter *handler.RateLimiter,
+) {
 	authController := parent.Party("/auth")
+
+	authController.Router.Use(
+		limiter.Limit("auth_ip",
+			cfg.RateLimitAuthIpLimit,
+			time.Duration(cfg.RateLimitAuthIpWindow)*time.Second,
+			handler.RateKeyByClientIP,
+		),
+		limiter.Limit("auth_id",
+			cfg.RateLimitAuthIdLimit,
+			time.Duration(cfg.RateLimitAuthIdWindow)*time.Second,
+			handler.RateKeyByJSONField("username", "email"),
+		),
+	)
+
 	authController.Handle(new(handler.AuthHandler))
 }
 func InitApiHandler(
 	parent *mvc.Application,
 	jwtFct *jwtutil.CliamsFactory[model.UserClaims],
+	authService service.AuthService,
 	lgr *slog.Logger,
 	cfg *baseconfig.Config,
+	guard *handler.ClubRoleGuard,
+	limiter *handler.RateLimiter,
 ) {
 	apiApp := parent.Party("/api")
 
 	apiApp.Router.Use(func(ctx iris.Context) {
 		authHeader := ctx.GetHeader("Authorization")
+
+		// 通知推送连接由EventSource发起，无法携带请求头，改用一次性凭证认证
+		if ticket := ctx.URLParam("ticket"); authHeader == "" && ticket != "" &&
+			ctx.Path() == "/api/notification/stream" {
+			userClaims, err := authService.ConsumeStreamTicket(ticket)
+			if err != nil {
+				handler.WriteServiceError(ctx, err, apperr.ErrUnauthorized)
+				return
+			}
+
+			ctx.Values().Set("user_claims_user_id", userClaims.UserId)
+			ctx.Values().Set("user_claims_user_role", userClaims.Role)
+
+			ctx.Next()
+			return
+		}
+
 		if authHeader == "" {
-			ctx.StopWithText(
-				iris.StatusUnauthorized,
-				"Authorization请求体缺失",
+			handler.WriteError(ctx,
+				apperr.ErrUnauthorized.WithMessage("Authorization请求体缺失"),
 			)
 			return
 		}
 
 		const kBearerPrefix = "Bearer "
 		claims := strings.TrimPrefix(authHeader, kBearerPrefix)
-		userClaims, err := jwtFct.ParseToken(claims)
+		userClaims, err := authService.VerifyAccessToken(claims)
 		if err != nil {
-			lgr.Info("claims反序列化错误",
+			lgr.Info("access token校验失败",
 				"error", err, "authHeader", authHeader,
 			)
 
-			ctx.StopWithText(
-				iris.StatusUnauthorized,
-				"无效的Authorization请求头",
+			handler.WriteServiceError(ctx, err,
+				apperr.ErrUnauthorized.WithMessage("无效的Authorization请求头"),
 			)
 			return
 		}
@@ -208,10 +445,12 @@ func InitApiHandler(
 		ctx.Next()
 	})
 
-	handler.InitTransHandler(apiApp, cfg)
+	handler.InitTransHandler(apiApp, cfg, limiter, lgr)
 
 	InitUserHandler(apiApp)
-	InitClubHandler(apiApp, jwtFct, lgr)
+	InitNotificationHandler(apiApp)
+	InitSearchHandler(apiApp)
+	InitClubHandler(apiApp, jwtFct, lgr, guard)
 }
 
 func InitUserHandler(parent *mvc.Application) {
@@ -219,43 +458,34 @@ func InitUserHandler(parent *mvc.Application) {
 	userApp.Handle(new(handler.UserHandler))
 }
 
+func InitNotificationHandler(parent *mvc.Application) {
+	notificationApp := parent.Party("/notification")
+	notificationApp.Handle(new(handler.NotificationHandler))
+}
+
+func InitSearchHandler(parent *mvc.Application) {
+	searchApp := parent.Party("/search")
+	searchApp.Handle(new(handler.SearchHandler))
+}
+
 func InitClubHandler(
 	parent *mvc.Application,
 	jwtFct *jwtutil.CliamsFactory[model.UserClaims],
 	lgr *slog.Logger,
+	guard *handler.ClubRoleGuard,
 ) {
 	clubApp := parent.Party("/club")
 	clubApp.Handle(new(handler.ClubHandler))
 
-	InitClubPubHandler(clubApp, jwtFct, lgr)
+	InitClubPubHandler(clubApp, guard)
 	InitClubAdminHandler(clubApp, jwtFct, lgr)
-	InitPostHandler(clubApp)
+	InitPostHandler(clubApp, guard)
+	InitEventHandler(clubApp, guard)
 }
 
-func InitClubPubHandler(
-	parent *mvc.Application,
-	jwtFct *jwtutil.CliamsFactory[model.UserClaims],
-	lgr *slog.Logger,
-) {
+func InitClubPubHandler(parent *mvc.Application, guard *handler.ClubRoleGuard) {
 	pubApp := parent.Party("/pub")
-
-	pubApp.Router.Use(func(ctx iris.Context) {
-		userRole := ctx.Values().GetString("user_claims_user_role")
-		if userRole == "" {
-			ctx.StopWithStatus(iris.StatusBadRequest)
-			return
-		}
-
-		if userRole != dbstruct.ROLE_PUBLISHER &&
-			userRole != dbstruct.ROLE_ADMIN {
-			ctx.StopWithStatus(iris.StatusForbidden)
-			return
-		}
-
-		ctx.Next()
-	})
-
-	pubApp.Handle(new(handler.ClubPubHandler))
+	pubApp.Handle(&handler.ClubPubHandler{RoleGuard: guard})
 }
 
 func InitClubAdminHandler(
@@ -268,12 +498,12 @@ func InitClubAdminHandler(
 	adminApp.Router.Use(func(ctx iris.Context) {
 		userRole := ctx.Values().GetString("user_claims_user_role")
 		if userRole == "" {
-			ctx.StopWithStatus(iris.StatusBadRequest)
+			handler.WriteError(ctx, apperr.ErrBadRequest)
 			return
 		}
 
 		if userRole != dbstruct.ROLE_ADMIN {
-			ctx.StopWithStatus(iris.StatusForbidden)
+			handler.WriteError(ctx, apperr.ErrForbidden)
 			return
 		}
 
@@ -283,31 +513,20 @@ func InitClubAdminHandler(
 	adminApp.Handle(new(handler.ClubAdminHandler))
 }
 
-func InitPostHandler(parent *mvc.Application) {
+func InitPostHandler(parent *mvc.Application, guard *handler.ClubRoleGuard) {
 	postApp := parent.Party("/post")
 
-	postApp.Handle(new(handler.PostHandler))
+	postApp.Handle(&handler.PostHandler{RoleGuard: guard})
 
-	InitPostPubHandler(postApp)
+	InitPostPubHandler(postApp, guard)
 }
 
-func InitPostPubHandler(parent *mvc.Application) {
+func InitPostPubHandler(parent *mvc.Application, guard *handler.ClubRoleGuard) {
 	pubApp := parent.Party("/pub")
+	pubApp.Handle(&handler.PostPubHandler{RoleGuard: guard})
+}
 
-	pubApp.Router.Use(func(ctx iris.Context) {
-		userRole := ctx.Values().GetString("user_claims_user_role")
-		if userRole == "" {
-			ctx.StopWithStatus(iris.StatusBadRequest)
-			return
-		}
-
-		if userRole != dbstruct.ROLE_PUBLISHER &&
-			userRole != dbstruct.ROLE_ADMIN {
-			ctx.StopWithStatus(iris.StatusForbidden)
-		}
-
-		ctx.Next()
-	})
-
-	pubApp.Handle(new(handler.PostPubHandler))
+func InitEventHandler(parent *mvc.Application, guard *handler.ClubRoleGuard) {
+	eventApp := parent.Party("/{id:int}/events")
+	eventApp.Handle(&handler.EventHandler{RoleGuard: guard})
 }
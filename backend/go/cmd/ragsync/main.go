@@ -0,0 +1,365 @@
+// ragsync 将数据库中已有的社团与公开帖子全量推送至rag_sync_stream，用于初始化RAG知识库。
+//
+// 每推送一批后把进度写入检查点文件，中断后重新运行会从上次的位置继续；-reset从头开始。
+// 正文读取失败的帖子ID记入检查点，下次运行时先重试这些帖子。
+// 指定-dry-run时不连接Redis，把将要推送的内容按local_synced_data.jsonl的格式写入文件，
+// 此时不读写检查点。
+//
+// 默认配置路径与服务端一致，需在cmd/base_server目录下运行：
+//
+//	go run ../ragsync -dry-run synced.jsonl
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"whuclubsynapse-server/internal/base_server/baseconfig"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/config"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+	"whuclubsynapse-server/internal/shared/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	kTargetClubs = "clubs"
+	kTargetPosts = "posts"
+	kTargetAll   = "all"
+)
+
+// sCheckpoint 已推送的最大社团ID与帖子ID，以及此前读取正文失败、待重试的帖子ID
+type sCheckpoint struct {
+	ClubAfter   uint   `json:"club_after"`
+	PostAfter   uint   `json:"post_after"`
+	PostPending []uint `json:"post_pending,omitempty"`
+}
+
+// sSink 消息的去向：Redis流或dry-run文件
+type sSink interface {
+	Send(msgs []*redisimpl.RagMessage) error
+}
+
+type sStreamSink struct {
+	redisService redisimpl.RedisClientService
+}
+
+func (s *sStreamSink) Send(msgs []*redisimpl.RagMessage) error {
+	_, err := s.redisService.AddRagMessages(msgs)
+	return err
+}
+
+type sJsonlSink struct {
+	writer *bufio.Writer
+}
+
+// Send 每行与RAG侧存储的条目对应：id为带dynamic::前缀的来源ID，document为正文
+func (s *sJsonlSink) Send(msgs []*redisimpl.RagMessage) error {
+	for _, msg := range msgs {
+		line, err := json.Marshal(map[string]any{
+			"id":       "dynamic::" + msg.SourceId,
+			"op":       msg.Op,
+			"document": msg.Content,
+			"metadata": msg.Metadata,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return s.writer.Flush()
+}
+
+type sBackfiller struct {
+	clubRepo     repo.ClubRepo
+	clubPostRepo repo.ClubPostRepo
+	blobStore    storage.BlobStore
+
+	sink      sSink
+	batchSize int
+
+	// checkpointPath为空时不保存进度
+	checkpointPath string
+	checkpoint     sCheckpoint
+
+	logger *slog.Logger
+}
+
+func main() {
+	cfgPath := flag.String("config", "../../config/basic_config.json", "配置文件路径")
+	target := flag.String("target", kTargetAll, "推送的内容：clubs、posts或all")
+	batchSize := flag.Int("batch", 100, "每批推送的消息数")
+	checkpointPath := flag.String("checkpoint", "ragsync_checkpoint.json", "检查点文件路径")
+	reset := flag.Bool("reset", false, "忽略已有检查点，从头推送")
+	dryRun := flag.String("dry-run", "", "不推送，将消息写入指定的JSONL文件")
+	flag.Parse()
+
+	switch *target {
+	case kTargetClubs, kTargetPosts, kTargetAll:
+	default:
+		fmt.Fprintln(os.Stderr, "未知的推送内容：", *target)
+		os.Exit(2)
+	}
+	if *batchSize <= 0 {
+		fmt.Fprintln(os.Stderr, "batch需大于0")
+		os.Exit(2)
+	}
+
+	var cfg baseconfig.Config
+	if err := config.LoadConfig(&cfg, *cfgPath); err != nil {
+		panic(err.Error())
+	}
+
+	lgr := logger.CreateLogger(nil)
+
+	database, err := gorm.Open(postgres.Open(cfg.DatabaseDsn), &gorm.Config{})
+	if err != nil {
+		panic(err.Error())
+	}
+
+	blobStore, err := storage.NewBlobStore(&cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	b := &sBackfiller{
+		clubRepo:     repo.CreateClubRepo(database, lgr),
+		clubPostRepo: repo.CreateClubPostRepo(database, lgr),
+		blobStore:    blobStore,
+
+		batchSize: *batchSize,
+
+		logger: lgr,
+	}
+
+	if *dryRun != "" {
+		file, err := os.Create(*dryRun)
+		if err != nil {
+			panic(err.Error())
+		}
+		defer file.Close()
+
+		b.sink = &sJsonlSink{writer: bufio.NewWriter(file)}
+	} else {
+		b.sink = &sStreamSink{redisService: redisimpl.NewRedisClientService(&cfg, lgr)}
+		b.checkpointPath = *checkpointPath
+
+		if !*reset {
+			if err := b.sLoadCheckpoint(); err != nil {
+				lgr.Error("读取检查点失败", "error", err, "path", b.checkpointPath)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *target != kTargetPosts {
+		if err := b.sBackfillClubs(); err != nil {
+			lgr.Error("推送社团信息失败", "error", err, "club_after", b.checkpoint.ClubAfter)
+			os.Exit(1)
+		}
+	}
+
+	if *target != kTargetClubs {
+		if err := b.sBackfillPosts(); err != nil {
+			lgr.Error("推送帖子失败", "error", err,
+				"post_after", b.checkpoint.PostAfter, "post_pending", b.checkpoint.PostPending,
+			)
+			os.Exit(1)
+		}
+	}
+
+	lgr.Info("推送完成", "club_after", b.checkpoint.ClubAfter, "post_after", b.checkpoint.PostAfter)
+}
+
+func (b *sBackfiller) sBackfillClubs() error {
+	count := 0
+	for {
+		clubs, err := b.clubRepo.ScanClubs(b.checkpoint.ClubAfter, b.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(clubs) == 0 {
+			b.logger.Info("社团信息推送完成", "count", count)
+			return nil
+		}
+
+		msgs := make([]*redisimpl.RagMessage, 0, len(clubs))
+		for _, club := range clubs {
+			msgs = append(msgs, redisimpl.NewClubRagMessage(club))
+		}
+
+		if err := b.sink.Send(msgs); err != nil {
+			return err
+		}
+
+		count += len(msgs)
+		b.checkpoint.ClubAfter = clubs[len(clubs)-1].ClubId
+		if err := b.sSaveCheckpoint(); err != nil {
+			return err
+		}
+	}
+}
+
+// sBackfillPosts 只推送已发布的公开帖子；正文读取失败的帖子记入检查点待下次重试，不阻塞其余帖子
+func (b *sBackfiller) sBackfillPosts() error {
+	count, err := b.sRetryPendingPosts()
+	if err != nil {
+		return err
+	}
+
+	for {
+		posts, err := b.clubPostRepo.ScanSearchablePosts(b.checkpoint.PostAfter, b.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		sent, failed, err := b.sSendPosts(posts)
+		if err != nil {
+			return err
+		}
+
+		count += sent
+		b.checkpoint.PostAfter = posts[len(posts)-1].PostId
+		b.checkpoint.PostPending = append(b.checkpoint.PostPending, failed...)
+		if err := b.sSaveCheckpoint(); err != nil {
+			return err
+		}
+	}
+
+	if len(b.checkpoint.PostPending) > 0 {
+		b.logger.Warn("部分帖子内容读取失败，已记入检查点，下次运行时重试",
+			"count", count, "pending", b.checkpoint.PostPending,
+		)
+		return nil
+	}
+
+	b.logger.Info("帖子推送完成", "count", count)
+	return nil
+}
+
+// sRetryPendingPosts 重试上次读取失败的帖子；已删除或不再公开的帖子不再重试
+func (b *sBackfiller) sRetryPendingPosts() (int, error) {
+	if len(b.checkpoint.PostPending) == 0 {
+		return 0, nil
+	}
+
+	b.logger.Info("重试上次失败的帖子", "pending", b.checkpoint.PostPending)
+
+	count := 0
+	var pending []uint
+	for start := 0; start < len(b.checkpoint.PostPending); start += b.batchSize {
+		ids := b.checkpoint.PostPending[start:min(start+b.batchSize, len(b.checkpoint.PostPending))]
+
+		posts, err := b.clubPostRepo.GetSearchablePosts(ids)
+		if err != nil {
+			return count, err
+		}
+
+		sent, failed, err := b.sSendPosts(posts)
+		if err != nil {
+			return count, err
+		}
+
+		count += sent
+		pending = append(pending, failed...)
+	}
+
+	b.checkpoint.PostPending = pending
+	return count, b.sSaveCheckpoint()
+}
+
+// sSendPosts 推送一批帖子，返回推送数与正文读取失败的帖子ID
+func (b *sBackfiller) sSendPosts(posts []*dbstruct.ClubPost) (int, []uint, error) {
+	var failed []uint
+	msgs := make([]*redisimpl.RagMessage, 0, len(posts))
+	for _, post := range posts {
+		content, err := b.sGetContent(post.ContentUrl)
+		if err != nil || len(content) == 0 {
+			b.logger.Warn("读取帖子内容失败，稍后重试",
+				"error", err, "post_id", post.PostId, "path", post.ContentUrl,
+			)
+			failed = append(failed, post.PostId)
+			continue
+		}
+
+		msgs = append(msgs, redisimpl.NewPostRagMessage(post, content))
+	}
+
+	if len(msgs) > 0 {
+		if err := b.sink.Send(msgs); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return len(msgs), failed, nil
+}
+
+func (b *sBackfiller) sGetContent(contentUrl string) ([]byte, error) {
+	key, ok := b.blobStore.KeyOf(contentUrl)
+	if !ok {
+		return nil, errors.New("无效的帖子内容地址：" + contentUrl)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return b.blobStore.Get(ctxTmt, key)
+}
+
+// sLoadCheckpoint 检查点文件不存在时从头开始
+func (b *sBackfiller) sLoadCheckpoint() error {
+	data, err := os.ReadFile(b.checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &b.checkpoint); err != nil {
+		return err
+	}
+
+	b.logger.Info("从检查点继续",
+		"club_after", b.checkpoint.ClubAfter, "post_after", b.checkpoint.PostAfter,
+	)
+
+	return nil
+}
+
+// sSaveCheckpoint 先写临时文件再改名，中途退出时检查点不会损坏
+func (b *sBackfiller) sSaveCheckpoint() error {
+	if b.checkpointPath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(b.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := b.checkpointPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, b.checkpointPath)
+}
@@ -0,0 +1,218 @@
+// reindex 从数据库全量重建搜索索引。
+//
+// 每个别名先写入新建的索引，写完后原子切换别名，期间搜索仍读取旧索引；
+// 重建开始后产生的索引同步事件会被重新置为待处理，由服务端的同步任务补上。
+//
+// 默认配置路径与服务端一致，需在cmd/base_server目录下运行：
+//
+//	go run ../reindex -index all
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"whuclubsynapse-server/internal/base_server/baseconfig"
+	"whuclubsynapse-server/internal/base_server/esimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/config"
+	"whuclubsynapse-server/internal/shared/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	kBatchSize = 500
+	// 重放起点提前的余量，覆盖开始前已提交但尚未被同步任务处理的事件
+	kRequeueMargin = time.Minute
+)
+
+type sReindexer struct {
+	clubRepo     repo.ClubRepo
+	clubPostRepo repo.ClubPostRepo
+
+	searchClient esimpl.SearchClientService
+	blobStore    storage.BlobStore
+
+	logger *slog.Logger
+}
+
+func main() {
+	cfgPath := flag.String("config", "../../config/basic_config.json", "配置文件路径")
+	target := flag.String("index", "all", "重建的索引：clubs、posts或all")
+	keepOld := flag.Bool("keep-old", false, "切换别名后保留旧索引")
+	flag.Parse()
+
+	var aliases []string
+	switch *target {
+	case esimpl.CLUB_INDEX, esimpl.POST_INDEX:
+		aliases = []string{*target}
+	case "all":
+		aliases = []string{esimpl.CLUB_INDEX, esimpl.POST_INDEX}
+	default:
+		fmt.Fprintln(os.Stderr, "未知的索引：", *target)
+		os.Exit(2)
+	}
+
+	var cfg baseconfig.Config
+	if err := config.LoadConfig(&cfg, *cfgPath); err != nil {
+		panic(err.Error())
+	}
+
+	lgr := logger.CreateLogger(nil)
+
+	database, err := gorm.Open(postgres.Open(cfg.DatabaseDsn), &gorm.Config{})
+	if err != nil {
+		panic(err.Error())
+	}
+
+	blobStore, err := storage.NewBlobStore(&cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	searchClient, err := esimpl.NewSearchClientService(&cfg, lgr)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	r := &sReindexer{
+		clubRepo:     repo.CreateClubRepo(database, lgr),
+		clubPostRepo: repo.CreateClubPostRepo(database, lgr),
+
+		searchClient: searchClient,
+		blobStore:    blobStore,
+
+		logger: lgr,
+	}
+
+	start := time.Now().Add(-kRequeueMargin)
+
+	for _, alias := range aliases {
+		if err := r.sRebuild(alias, *keepOld); err != nil {
+			lgr.Error("重建索引失败", "error", err, "alias", alias)
+			os.Exit(1)
+		}
+	}
+
+	requeued, err := repo.CreateSearchOutboxRepo(database, lgr).RequeueSince(start)
+	if err != nil {
+		lgr.Error("重放索引同步事件失败", "error", err)
+		os.Exit(1)
+	}
+
+	lgr.Info("重建索引完成", "requeued", requeued)
+}
+
+func (r *sReindexer) sRebuild(alias string, keepOld bool) error {
+	index, err := r.searchClient.CreateIndex(alias)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("开始写入新索引", "alias", alias, "index", index)
+
+	var count int
+	if alias == esimpl.CLUB_INDEX {
+		count, err = r.sLoadClubs(index)
+	} else {
+		count, err = r.sLoadPosts(index)
+	}
+	if err != nil {
+		// 未切换别名的新索引不会被读取，直接删除
+		if err := r.searchClient.DeleteIndices([]string{index}); err != nil {
+			r.logger.Error("删除未完成的索引失败", "error", err, "index", index)
+		}
+		return err
+	}
+
+	oldIndices, err := r.searchClient.SwitchAlias(alias, index)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("已切换别名", "alias", alias, "index", index, "count", count, "old", oldIndices)
+
+	if keepOld || len(oldIndices) == 0 {
+		return nil
+	}
+
+	return r.searchClient.DeleteIndices(oldIndices)
+}
+
+func (r *sReindexer) sLoadClubs(index string) (int, error) {
+	var afterId uint
+	count := 0
+	for {
+		clubs, err := r.clubRepo.ScanClubs(afterId, kBatchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(clubs) == 0 {
+			return count, nil
+		}
+
+		docs := make([]*esimpl.ClubDoc, 0, len(clubs))
+		for _, club := range clubs {
+			docs = append(docs, esimpl.NewClubDoc(club))
+		}
+
+		if err := r.searchClient.BulkIndexClubs(index, docs); err != nil {
+			return count, err
+		}
+
+		afterId = clubs[len(clubs)-1].ClubId
+		count += len(docs)
+	}
+}
+
+func (r *sReindexer) sLoadPosts(index string) (int, error) {
+	var afterId uint
+	count := 0
+	for {
+		posts, err := r.clubPostRepo.ScanSearchablePosts(afterId, kBatchSize)
+		if err != nil {
+			return count, err
+		}
+		if len(posts) == 0 {
+			return count, nil
+		}
+
+		docs := make([]*esimpl.PostDoc, 0, len(posts))
+		for _, post := range posts {
+			content, err := r.sGetContent(post.ContentUrl)
+			if err != nil {
+				// 单个帖子正文缺失不影响整体重建，只索引标题
+				r.logger.Warn("读取帖子内容失败", "error", err, "post_id", post.PostId)
+			}
+
+			docs = append(docs, esimpl.NewPostDoc(post, string(content)))
+		}
+
+		if err := r.searchClient.BulkIndexPosts(index, docs); err != nil {
+			return count, err
+		}
+
+		afterId = posts[len(posts)-1].PostId
+		count += len(docs)
+	}
+}
+
+func (r *sReindexer) sGetContent(contentUrl string) ([]byte, error) {
+	key, ok := r.blobStore.KeyOf(contentUrl)
+	if !ok {
+		return nil, errors.New("无效的帖子内容地址：" + contentUrl)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.blobStore.Get(ctxTmt, key)
+}
@@ -0,0 +1,188 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// Error 带有稳定错误码的领域错误，Code供前端判断，Status为对应HTTP状态码
+type Error struct {
+	Code    int
+	Status  int
+	Message string
+
+	cause error
+}
+
+func New(code, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.cause }
+
+// Is 按错误码比较，使 errors.Is(err, ErrXxx) 对Wrap/WithMessage后的副本同样成立
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap 附带底层错误，便于日志排查，不影响返回给前端的错误码与提示
+func (e *Error) Wrap(cause error) *Error {
+	cp := *e
+	cp.cause = cause
+	return &cp
+}
+
+// WithMessage 替换默认提示，用于通用错误码需要更具体说明的场景
+func (e *Error) WithMessage(message string) *Error {
+	cp := *e
+	cp.Message = message
+	return &cp
+}
+
+// From 将任意错误转为*Error，无法识别的错误视为内部错误
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound.Wrap(err)
+	}
+
+	return ErrInternal.Wrap(err)
+}
+
+// Or 已是领域错误或记录不存在时按其本身返回，否则使用fallback
+func Or(err error, fallback *Error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound.Wrap(err)
+	}
+
+	return fallback.Wrap(err)
+}
+
+const (
+	CODE_OK = 0
+)
+
+// 通用错误，错误码为HTTP状态码*100
+var (
+	ErrBadRequest         = New(40000, http.StatusBadRequest, "请求参数错误")
+	ErrUnauthorized       = New(40100, http.StatusUnauthorized, "未登录或登录已过期")
+	ErrForbidden          = New(40300, http.StatusForbidden, "权限不足")
+	ErrNotFound           = New(40400, http.StatusNotFound, "资源不存在")
+	ErrConflict           = New(40900, http.StatusConflict, "资源状态冲突")
+	ErrTooManyRequests    = New(42900, http.StatusTooManyRequests, "请求过于频繁")
+	ErrInternal           = New(50000, http.StatusInternalServerError, "服务器内部错误")
+	ErrServiceUnavailable = New(50300, http.StatusServiceUnavailable, "服务不可用")
+)
+
+// 用户相关 1xxx
+var (
+	ErrUserNotFound     = New(1001, http.StatusNotFound, "用户不存在")
+	ErrWrongCredentials = New(1002, http.StatusBadRequest, "用户名或密码错误")
+	ErrWrongVrfcode     = New(1003, http.StatusUnauthorized, "验证码错误")
+	ErrVrfcodeSent      = New(1004, http.StatusAccepted, "验证码已发送，请勿重复申请")
+	ErrUserExists       = New(1005, http.StatusConflict, "用户名或邮箱已被注册")
+	ErrTokenRevoked     = New(1006, http.StatusUnauthorized, "登录状态已失效，请重新登录")
+	ErrRefreshInvalid   = New(1007, http.StatusUnauthorized, "refresh token无效或已过期")
+	ErrLoginLocked      = New(1008, http.StatusTooManyRequests, "登录失败次数过多，请稍后再试")
+)
+
+// 社团相关 2xxx
+var (
+	ErrClubNotFound       = New(2001, http.StatusNotFound, "社团不存在")
+	ErrAlreadyMember      = New(2002, http.StatusConflict, "已是该社团成员")
+	ErrNotClubMember      = New(2003, http.StatusForbidden, "用户不是该社团成员")
+	ErrClubRoleDenied     = New(2004, http.StatusForbidden, "社团角色权限不足")
+	ErrInvalidClubRole    = New(2005, http.StatusBadRequest, "未知的社团角色")
+	ErrAlreadyApplied     = New(2006, http.StatusConflict, "已有待处理的申请")
+	ErrAppliNotFound      = New(2007, http.StatusNotFound, "申请不存在")
+	ErrAppliNotPending    = New(2008, http.StatusConflict, "申请已被处理")
+	ErrLeaderRoleLocked   = New(2009, http.StatusConflict, "负责人角色只能通过负责人移交变更")
+	ErrTransferPending    = New(2010, http.StatusConflict, "社团已有正在处理的负责人移交")
+	ErrTransferNotFound   = New(2011, http.StatusNotFound, "负责人移交不存在")
+	ErrTransferNotTarget  = New(2012, http.StatusForbidden, "用户不是被提名者")
+	ErrTransferStale      = New(2013, http.StatusConflict, "社团负责人已变更，移交失效")
+	ErrLeaderCannotQuit   = New(2014, http.StatusConflict, "社团负责人无法直接退出社团")
+	ErrTransferNotPending = New(2015, http.StatusConflict, "负责人移交已被处理")
+)
+
+// 帖子相关 3xxx
+var (
+	ErrPostNotFound       = New(3001, http.StatusNotFound, "帖子不存在")
+	ErrCommentNotFound    = New(3002, http.StatusNotFound, "评论不存在")
+	ErrPostNotAuthor      = New(3003, http.StatusForbidden, "仅作者可编辑帖子")
+	ErrRevisionNotFound   = New(3004, http.StatusNotFound, "帖子版本不存在")
+	ErrPostDiffTooLarge   = New(3005, http.StatusUnprocessableEntity, "帖子内容过长，无法比较差异")
+	ErrPostUnchanged      = New(3006, http.StatusBadRequest, "帖子内容未修改")
+	ErrCommentNotAuthor   = New(3007, http.StatusForbidden, "仅作者可修改评论")
+	ErrCommentDeleted     = New(3008, http.StatusConflict, "评论已被删除")
+	ErrInvalidReaction    = New(3009, http.StatusBadRequest, "不支持的表情回应")
+	ErrPostNotDraft       = New(3010, http.StatusConflict, "帖子已发布")
+	ErrInvalidPublishTime = New(3011, http.StatusBadRequest, "定时发布时间需晚于当前时间")
+	ErrPostNotPublished   = New(3012, http.StatusConflict, "帖子尚未发布，请通过草稿编辑")
+	ErrPostPendingReview  = New(3013, http.StatusConflict, "帖子正在审核中")
+	ErrAttachmentTooLarge = New(3014, http.StatusRequestEntityTooLarge, "附件大小超出限制")
+	ErrAttachmentType     = New(3015, http.StatusUnsupportedMediaType, "不支持的附件类型")
+	ErrAttachmentLimit    = New(3016, http.StatusConflict, "帖子附件数量已达上限")
+	ErrAttachmentNotFound = New(3017, http.StatusNotFound, "附件不存在")
+)
+
+// 活动相关 4xxx
+var (
+	ErrEventNotFound       = New(4001, http.StatusNotFound, "活动不存在")
+	ErrEventInvalid        = New(4002, http.StatusBadRequest, "活动信息无效")
+	ErrEventCancelled      = New(4003, http.StatusConflict, "活动已取消")
+	ErrEventStarted        = New(4004, http.StatusConflict, "活动已开始")
+	ErrEventCapacityLow    = New(4005, http.StatusConflict, "人数上限不能低于已报名人数")
+	ErrAlreadyRsvped       = New(4006, http.StatusConflict, "已报名该活动")
+	ErrNotRsvped           = New(4007, http.StatusConflict, "未报名该活动")
+	ErrCheckinNotOpen      = New(4008, http.StatusConflict, "活动当前不在签到时段")
+	ErrAlreadyCheckedIn    = New(4009, http.StatusConflict, "已签到")
+	ErrCheckinTokenInvalid = New(4010, http.StatusForbidden, "签到凭证无效或已过期")
+)
+
+// 通知相关 5xxx
+var (
+	ErrNotificationNotFound = New(5001, http.StatusNotFound, "通知不存在")
+)
+
+// 图片上传相关 6xxx
+var (
+	ErrInvalidImage  = New(6001, http.StatusUnsupportedMediaType, "文件不是有效的图片")
+	ErrImageTooLarge = New(6002, http.StatusRequestEntityTooLarge, "图片大小超出限制")
+)
+
+// LLM/RAG转发相关 7xxx
+var (
+	ErrProxyRouteDenied    = New(7001, http.StatusNotFound, "不支持的转发接口")
+	ErrProxyBodyTooLarge   = New(7002, http.StatusRequestEntityTooLarge, "请求体大小超出限制")
+	ErrUpstreamUnavailable = New(7003, http.StatusBadGateway, "上游服务暂不可用")
+	ErrUpstreamTimeout     = New(7004, http.StatusGatewayTimeout, "上游服务响应超时")
+	ErrLlmQuotaExceeded    = New(7005, http.StatusTooManyRequests, "今日AI调用额度已用完")
+)
@@ -0,0 +1,109 @@
+package apperr
+
+import "strings"
+
+const (
+	LANG_ZH = "zh"
+	LANG_EN = "en"
+)
+
+// 英文提示，默认中文提示即Error.Message；新增错误码时需同步补充
+var enMessages = map[int]string{
+	CODE_OK: "OK",
+
+	40000: "Bad request",
+	40100: "Not logged in or session expired",
+	40300: "Permission denied",
+	40400: "Resource not found",
+	40900: "Resource state conflict",
+	42900: "Too many requests",
+	50000: "Internal server error",
+	50300: "Service unavailable",
+
+	1001: "User not found",
+	1002: "Wrong username or password",
+	1003: "Wrong verification code",
+	1004: "Verification code already sent, please do not request again",
+	1005: "Username or email already registered",
+	1006: "Session has been revoked, please log in again",
+	1007: "Refresh token is invalid or expired",
+	1008: "Too many failed login attempts, please try again later",
+
+	2001: "Club not found",
+	2002: "Already a member of the club",
+	2003: "Not a member of the club",
+	2004: "Insufficient club role",
+	2005: "Unknown club role",
+	2006: "An application is already pending",
+	2007: "Application not found",
+	2008: "Application has already been processed",
+	2009: "Leader role can only change via leadership transfer",
+	2010: "A leadership transfer is already pending",
+	2011: "Leadership transfer not found",
+	2012: "User is not the nominee",
+	2013: "Club leader has changed, transfer is no longer valid",
+	2014: "Club leader cannot quit directly",
+	2015: "Leadership transfer has already been processed",
+
+	3001: "Post not found",
+	3002: "Comment not found",
+	3003: "Only the author can edit this post",
+	3004: "Post revision not found",
+	3005: "Post content is too long to compare",
+	3006: "Post content is unchanged",
+	3007: "Only the author can modify this comment",
+	3008: "Comment has been deleted",
+	3009: "Unsupported reaction",
+	3010: "Post has already been published",
+	3011: "Scheduled publish time must be in the future",
+	3012: "Post has not been published yet",
+	3013: "Post is under review",
+	3014: "Attachment exceeds the size limit",
+	3015: "Unsupported attachment type",
+	3016: "Post has reached the attachment limit",
+	3017: "Attachment not found",
+
+	4001: "Event not found",
+	4002: "Invalid event info",
+	4003: "Event has been cancelled",
+	4004: "Event has already started",
+	4005: "Capacity cannot be lower than current sign-ups",
+	4006: "Already signed up for the event",
+	4007: "Not signed up for the event",
+	4008: "Check-in is not open",
+	4009: "Already checked in",
+	4010: "Check-in token is invalid or expired",
+
+	5001: "Notification not found",
+
+	6001: "File is not a valid image",
+	6002: "Image exceeds the size limit",
+
+	7001: "Unsupported forwarding route",
+	7002: "Request body exceeds the size limit",
+	7003: "Upstream service is unavailable",
+	7004: "Upstream service timed out",
+	7005: "Daily AI usage quota exceeded",
+}
+
+// ParseLang 从Accept-Language中取首选语言，未识别时返回中文
+func ParseLang(acceptLanguage string) string {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(acceptLanguage)), LANG_EN) {
+		return LANG_EN
+	}
+	return LANG_ZH
+}
+
+// Localize 返回指定语言下的提示
+func (e *Error) Localize(lang string) string {
+	return LocalizeCode(e.Code, e.Message, lang)
+}
+
+func LocalizeCode(code int, zhMessage, lang string) string {
+	if lang == LANG_EN {
+		if msg, ok := enMessages[code]; ok {
+			return msg
+		}
+	}
+	return zhMessage
+}
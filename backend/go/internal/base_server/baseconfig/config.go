@@ -21,9 +21,90 @@ type Config struct {
 	GrpcMinConn     int    `mapstructure:"grpc_min_conn"`
 	GrpcIdleTimeout int    `mapstructure:"grpc_idle_timeout"`
 
-	JwtExpirationTime uint64 `mapstructure:"jwt_expiration_time"`
-	JwtSecretKey      string `mapstructure:"jwt_secret_key"`
+	JwtAccessExpirationTime  uint64 `mapstructure:"jwt_access_expiration_time"`
+	JwtRefreshExpirationTime uint64 `mapstructure:"jwt_refresh_expiration_time"`
+	JwtSecretKey             string `mapstructure:"jwt_secret_key"`
+
+	CheckinExpirationTime uint64 `mapstructure:"checkin_expiration_time"`
+	CheckinSecretKey      string `mapstructure:"checkin_secret_key"`
+
+	// 限流窗口单位均为秒，同一窗口内最多放行Limit次请求
+	RateLimitAuthIpLimit   int    `mapstructure:"rate_limit_auth_ip_limit"`
+	RateLimitAuthIpWindow  uint64 `mapstructure:"rate_limit_auth_ip_window"`
+	RateLimitAuthIdLimit   int    `mapstructure:"rate_limit_auth_id_limit"`
+	RateLimitAuthIdWindow  uint64 `mapstructure:"rate_limit_auth_id_window"`
+	RateLimitLlmUserLimit  int    `mapstructure:"rate_limit_llm_user_limit"`
+	RateLimitLlmUserWindow uint64 `mapstructure:"rate_limit_llm_user_window"`
+
+	// LLM每日调用额度，按用户角色区分；Tokens为响应的token数，0表示不限
+	LlmQuotaUserRequests      int64 `mapstructure:"llm_quota_user_requests"`
+	LlmQuotaUserTokens        int64 `mapstructure:"llm_quota_user_tokens"`
+	LlmQuotaPublisherRequests int64 `mapstructure:"llm_quota_publisher_requests"`
+	LlmQuotaPublisherTokens   int64 `mapstructure:"llm_quota_publisher_tokens"`
+	LlmQuotaAdminRequests     int64 `mapstructure:"llm_quota_admin_requests"`
+	LlmQuotaAdminTokens       int64 `mapstructure:"llm_quota_admin_tokens"`
+
+	// 登录失败达到阈值后锁定，锁定时长从Base起每多失败一次翻倍，不超过Max；单位均为秒
+	LoginFailWindow    uint64 `mapstructure:"login_fail_window"`
+	LoginLockThreshold int64  `mapstructure:"login_lock_threshold"`
+	LoginLockBase      uint64 `mapstructure:"login_lock_base"`
+	LoginLockMax       uint64 `mapstructure:"login_lock_max"`
+
+	// 部署在反向代理之后时从这些请求头中取客户端IP，如X-Forwarded-For，供限流与登录锁定使用；
+	// 为空时使用连接的对端地址。服务可被直接访问时不应配置，否则客户端可伪造请求头
+	RemoteAddrHeaders []string `mapstructure:"remote_addr_headers"`
+	// 取客户端IP时跳过的代理地址段，私有网段已默认跳过，代理使用公网地址时需在此列出
+	TrustedProxyRanges []IPRange `mapstructure:"trusted_proxy_ranges"`
+
+	// 回应计数从Redis合并到数据库的周期，单位为秒
+	ReactionFlushInterval uint64 `mapstructure:"reaction_flush_interval"`
+	// 定时帖子的发布检查周期，单位为秒
+	PostScheduleInterval uint64 `mapstructure:"post_schedule_interval"`
+	// 单个帖子附件的大小上限（MB，不超过64）与数量上限
+	PostAttachmentMaxSize int64 `mapstructure:"post_attachment_max_size"`
+	PostAttachmentMaxNum  int   `mapstructure:"post_attachment_max_num"`
+
+	// 上传文件的存储后端，local或s3，未配置时为local
+	BlobBackend string `mapstructure:"blob_backend"`
+	// local后端的存储目录；对象公开地址为BlobPublicBase加对象键
+	BlobLocalRoot   string `mapstructure:"blob_local_root"`
+	BlobPublicBase  string `mapstructure:"blob_public_base"`
+	BlobS3Endpoint  string `mapstructure:"blob_s3_endpoint"`
+	BlobS3Region    string `mapstructure:"blob_s3_region"`
+	BlobS3Bucket    string `mapstructure:"blob_s3_bucket"`
+	BlobS3AccessKey string `mapstructure:"blob_s3_access_key"`
+	BlobS3SecretKey string `mapstructure:"blob_s3_secret_key"`
+	BlobS3PathStyle bool   `mapstructure:"blob_s3_path_style"`
+
+	// 搜索使用的ES节点地址，未配置时连接本机默认端口
+	EsAddrs    []string `mapstructure:"es_addrs"`
+	EsUsername string   `mapstructure:"es_username"`
+	EsPassword string   `mapstructure:"es_password"`
+	// 索引同步事件的处理周期，单位为秒
+	SearchSyncInterval uint64 `mapstructure:"search_sync_interval"`
 
 	LlmAddr string `mapstructure:"llm_addr"`
 	RagAddr string `mapstructure:"rag_addr"`
+	// 转发至RAG服务时附带的Authorization
+	RagApiKey string `mapstructure:"rag_api_key"`
+	// 允许转发的上游接口，未列出的接口一律拒绝
+	LlmRoutes []ProxyRoute `mapstructure:"llm_routes"`
+	RagRoutes []ProxyRoute `mapstructure:"rag_routes"`
+	// RAG同步事件推送至rag_sync_stream的周期，单位为秒
+	RagRelayInterval uint64 `mapstructure:"rag_relay_interval"`
+}
+
+// ProxyRoute 转发接口配置。Timeout单位为秒，流式接口的超时覆盖整个响应过程；
+// MaxBodySize单位为KB；二者未配置时使用默认值
+// IPRange 包含首尾的IP地址段
+type IPRange struct {
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+}
+
+type ProxyRoute struct {
+	Path        string   `mapstructure:"path"`
+	Methods     []string `mapstructure:"methods"`
+	Timeout     uint64   `mapstructure:"timeout"`
+	MaxBodySize int64    `mapstructure:"max_body_size"`
 }
@@ -1,7 +0,0 @@
-// package dto
-
-// type ProcCreatePostRequest struct {
-// 	CreatePostAppliId int    `json:"post_id"`
-// 	Result            string `json:"result"`
-// 	Reason            string `json:"reason"`
-// }
@@ -1,20 +1,25 @@
 package dto
 
 type ClubBasic struct {
-	ClubId       int      `json:"club_id"`
-	ClubName     string   `json:"club_name"`
-	LeaderId     int      `json:"leader_id"`
-	Category     int      `json:"category"`
-	Tags         []string `json:"tags"`
-	LogoUrl      string   `json:"logo_url"`
-	Desc         string   `json:"desc"`
-	Requirements string   `json:"requirements"`
-	CreatedAt    string   `json:"created_at"`
-	MemberCount  int      `json:"member_count"`
+	ClubId   int      `json:"club_id"`
+	ClubName string   `json:"club_name"`
+	LeaderId int      `json:"leader_id"`
+	Category int      `json:"category"`
+	Tags     []string `json:"tags"`
+	LogoUrl  string   `json:"logo_url"`
+	// LogoVariants 各尺寸logo地址，键为边长（64/256/512），旧logo为空
+	LogoVariants map[string]string `json:"logo_variants,omitempty"`
+	Desc         string            `json:"desc"`
+	Requirements string            `json:"requirements"`
+	CreatedAt    string            `json:"created_at"`
+	MemberCount  int               `json:"member_count"`
+
+	RequirePostReview bool `json:"require_post_review"`
 }
 
 type ClubDetail struct {
 	ClubBasic
 	Members []*ClubMember    `json:"members"`
 	Posts   []*ClubPostBasic `json:"posts"`
+	Events  []*ClubEvent     `json:"events"`
 }
@@ -0,0 +1,60 @@
+package dto
+
+type ClubEvent struct {
+	EventId     int    `json:"event_id"`
+	ClubId      int    `json:"club_id"`
+	CreatorId   int    `json:"creator_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	StartAt     string `json:"start_at"`
+	EndAt       string `json:"end_at"`
+	Capacity    int    `json:"capacity"`
+	GoingCount  int    `json:"going_count"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// EditEventRequest 创建与编辑活动共用，时间格式为 2006-01-02 15:04:05
+type EditEventRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	StartAt     string `json:"start_at"`
+	EndAt       string `json:"end_at"`
+	Capacity    int    `json:"capacity"`
+}
+
+type EventRsvp struct {
+	UserId    int    `json:"user_id"`
+	Status    string `json:"status"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type RsvpResponse struct {
+	EventId int    `json:"event_id"`
+	Status  string `json:"status"`
+}
+
+type CheckinTokenResponse struct {
+	Token    string `json:"token"`
+	ExpireAt string `json:"expire_at"`
+}
+
+type CheckinRequest struct {
+	Token string `json:"token"`
+}
+
+type EventAttendance struct {
+	UserId      int    `json:"user_id"`
+	CheckedInAt string `json:"checked_in_at"`
+}
+
+type AttendanceStat struct {
+	UserId         int     `json:"user_id"`
+	RoleInClub     string  `json:"role_in_club"`
+	AttendedNum    int     `json:"attended_num"`
+	HeldNum        int     `json:"held_num"`
+	AttendanceRate float64 `json:"attendance_rate"`
+	LastCheckedIn  string  `json:"last_checked_in"`
+}
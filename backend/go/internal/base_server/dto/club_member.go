@@ -8,3 +8,8 @@ type ClubMember struct {
 	JoinedAt   string `json:"joined_at"`
 	LastActive string `json:"last_active"`
 }
+
+type ChangeMemberRoleRequest struct {
+	UserId int    `json:"user_id"`
+	Role   string `json:"role"`
+}
@@ -9,6 +9,14 @@ type ClubPostBasic struct {
 	CommentCount int    `json:"comment_count"`
 	CreatedAt    string `json:"created_at"`
 	ContentUrl   string `json:"content_url"`
+
+	Reactions  map[string]int64 `json:"reactions"`
+	Reacted    bool             `json:"reacted"`
+	MyReaction string           `json:"my_reaction"`
+}
+
+type ReactRequest struct {
+	Kind string `json:"kind"`
 }
 
 type CreatePostRequest struct {
@@ -16,3 +24,65 @@ type CreatePostRequest struct {
 	Title   string `json:"title"`
 	Content string `json:"content"`
 }
+
+type EditPostRequest struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+type PostRevision struct {
+	Version     int    `json:"version"`
+	Title       string `json:"title"`
+	ContentUrl  string `json:"content_url"`
+	PublishedAt string `json:"published_at"`
+	ArchivedAt  string `json:"archived_at"`
+}
+
+type PostRevisionList struct {
+	PostId         int             `json:"post_id"`
+	CurrentVersion int             `json:"current_version"`
+	Revisions      []*PostRevision `json:"revisions"`
+}
+
+type PostDiffLine struct {
+	Op      string `json:"op"`
+	Content string `json:"content"`
+}
+
+type PostDiffResponse struct {
+	PostId      int             `json:"post_id"`
+	FromVersion int             `json:"from_version"`
+	ToVersion   int             `json:"to_version"`
+	FromTitle   string          `json:"from_title"`
+	ToTitle     string          `json:"to_title"`
+	Lines       []*PostDiffLine `json:"lines"`
+}
+
+// SaveDraftRequest PublishAt为空时保存为草稿，否则在该时间定时发布
+type SaveDraftRequest struct {
+	ClubId    int    `json:"club_id"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	PublishAt string `json:"publish_at"`
+}
+
+type PostDraft struct {
+	PostId    int    `json:"post_id"`
+	ClubId    int    `json:"club_id"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Status    string `json:"status"`
+	PublishAt string `json:"publish_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type PostAttachment struct {
+	AttachmentId int    `json:"attachment_id"`
+	PostId       int    `json:"post_id"`
+	FileName     string `json:"file_name"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url,omitempty"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	CreatedAt    string `json:"created_at"`
+}
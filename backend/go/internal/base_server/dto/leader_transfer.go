@@ -0,0 +1,19 @@
+package dto
+
+type NominateLeaderRequest struct {
+	UserId int `json:"user_id"`
+}
+
+type ProcLeaderTransferRequest struct {
+	TransferId int    `json:"transfer_id"`
+	Result     string `json:"result"`
+}
+
+type LeaderTransferResponse struct {
+	TransferId int    `json:"transfer_id"`
+	ClubId     int    `json:"club_id"`
+	FromUserId int    `json:"from_user_id"`
+	ToUserId   int    `json:"to_user_id"`
+	Status     string `json:"status"`
+	CreatedAt  string `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+// LlmUsageResponse 当天的用量与额度，额度为0表示不限
+type LlmUsageResponse struct {
+	Date          string `json:"date"`
+	Role          string `json:"role"`
+	Requests      int64  `json:"requests"`
+	Bytes         int64  `json:"bytes"`
+	Tokens        int64  `json:"tokens"`
+	RequestsQuota int64  `json:"requests_quota"`
+	TokensQuota   int64  `json:"tokens_quota"`
+}
+
+// LlmUsageSummary Requests只含上游已响应的调用，被拒绝与失败的调用分别计数
+type LlmUsageSummary struct {
+	UserId        uint  `json:"user_id,omitempty"`
+	Requests      int64 `json:"requests"`
+	Rejected      int64 `json:"rejected"`
+	Failed        int64 `json:"failed"`
+	ResponseBytes int64 `json:"response_bytes"`
+	Tokens        int64 `json:"tokens"`
+}
+
+// LlmUsageReport Users与Total为整个时间段的汇总，不受分页影响
+type LlmUsageReport struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Users   int64             `json:"users"`
+	Total   LlmUsageSummary   `json:"total"`
+	Entries []LlmUsageSummary `json:"entries"`
+}
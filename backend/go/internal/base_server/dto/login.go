@@ -6,10 +6,27 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	UserId     int    `json:"user_id"`
-	Username   string `json:"username"`
-	Email      string `json:"email"`
-	AvatarUrl  string `json:"avatar_url"`
-	Role       string `json:"role"`
-	LastActive string `json:"last_active"`
+	UserId         int               `json:"user_id"`
+	Username       string            `json:"username"`
+	Email          string            `json:"email"`
+	AvatarUrl      string            `json:"avatar_url"`
+	AvatarVariants map[string]string `json:"avatar_variants,omitempty"`
+	Role           string            `json:"role"`
+	LastActive     string            `json:"last_active"`
+
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshResponse struct {
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
@@ -3,3 +3,14 @@ package dto
 type MailvrfRequest struct {
 	Email string `json:"email"`
 }
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email"`
+	Vrfcode     string `json:"vrfcode"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
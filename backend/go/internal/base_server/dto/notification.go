@@ -0,0 +1,19 @@
+package dto
+
+type Notification struct {
+	NotificationId uint   `json:"notification_id"`
+	Type           string `json:"type"`
+	Title          string `json:"title"`
+	Content        string `json:"content"`
+	RefId          uint   `json:"ref_id"`
+	IsRead         bool   `json:"is_read"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type UnreadNumResponse struct {
+	UnreadNum int64 `json:"unread_num"`
+}
+
+type StreamTicketResponse struct {
+	Ticket string `json:"ticket"`
+}
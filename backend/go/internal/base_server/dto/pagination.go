@@ -0,0 +1,8 @@
+package dto
+
+// CursorPage 游标分页响应，下一页请求时将next_cursor原样作为cursor参数传回
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
@@ -1,15 +1,31 @@
 package dto
 
 type PostComment struct {
-	CommentId int    `json:"comment_id"`
-	UserId    int    `json:"user_id"`
-	PostId    int    `json:"post_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
+	CommentId       int            `json:"comment_id"`
+	UserId          int            `json:"user_id"`
+	PostId          int            `json:"post_id"`
+	ParentCommentId int            `json:"parent_comment_id"`
+	Content         string         `json:"content"`
+	Status          string         `json:"status"`
+	CreatedAt       string         `json:"created_at"`
+	UpdatedAt       string         `json:"updated_at"`
+	Replies         []*PostComment `json:"replies,omitempty"`
+
+	Reactions  map[string]int64 `json:"reactions"`
+	Reacted    bool             `json:"reacted"`
+	MyReaction string           `json:"my_reaction"`
 }
 
 type CreateCommentRequest struct {
-	UserId  int    `json:"user_id"`
-	PostId  int    `json:"post_id"`
+	PostId          int    `json:"post_id"`
+	ParentCommentId int    `json:"parent_comment_id"`
+	Content         string `json:"content"`
+}
+
+type EditCommentRequest struct {
 	Content string `json:"content"`
 }
+
+type RemoveCommentRequest struct {
+	Reason string `json:"reason"`
+}
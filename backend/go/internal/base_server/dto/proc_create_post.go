@@ -0,0 +1,25 @@
+package dto
+
+type ProcCreatePostRequest struct {
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+}
+
+type CreatePostAppli struct {
+	PostAppliId    int    `json:"post_appli_id"`
+	PostId         int    `json:"post_id"`
+	ClubId         int    `json:"club_id"`
+	ApplicantId    int    `json:"applicant_id"`
+	Kind           string `json:"kind"` // create为发帖申请，edit为改帖申请
+	Title          string `json:"title"`
+	Content        string `json:"content,omitempty"`
+	ContentUrl     string `json:"content_url,omitempty"` // 改帖申请的新版本内容地址
+	Status         string `json:"status"`
+	AppliedAt      string `json:"applied_at"`
+	ReviewedAt     string `json:"reviewed_at,omitempty"`
+	RejectedReason string `json:"rejected_reason,omitempty"`
+}
+
+type PostReviewPolicyRequest struct {
+	RequireReview bool `json:"require_review"`
+}
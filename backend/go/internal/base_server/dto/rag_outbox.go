@@ -0,0 +1,20 @@
+package dto
+
+type RagOutboxEntry struct {
+	OutboxId      uint   `json:"outbox_id"`
+	Entity        string `json:"entity"`
+	EntityId      uint   `json:"entity_id"`
+	Op            string `json:"op"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	LastError     string `json:"last_error"`
+	MessageId     string `json:"message_id"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// RagOutboxResponse Counts为各状态的事件总数，不受分页影响
+type RagOutboxResponse struct {
+	Counts  map[string]int64 `json:"counts"`
+	Entries []RagOutboxEntry `json:"entries"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// Response 统一响应体，code为0表示成功，非0时为apperr中定义的错误码
+type Response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data"`
+}
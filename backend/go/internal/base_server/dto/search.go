@@ -0,0 +1,33 @@
+package dto
+
+// SearchResult 搜索结果分页，Page从1开始
+type SearchResult[T any] struct {
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Items    []T   `json:"items"`
+}
+
+// Highlight 字段名到高亮片段，片段已做HTML转义，命中词以<em>包裹；无关键词时为空
+type ClubSearchHit struct {
+	ClubId      int      `json:"club_id"`
+	ClubName    string   `json:"club_name"`
+	Category    int      `json:"category"`
+	Tags        []string `json:"tags"`
+	LogoUrl     string   `json:"logo_url"`
+	Desc        string   `json:"desc"`
+	MemberCount int      `json:"member_count"`
+	CreatedAt   string   `json:"created_at"`
+
+	Highlight map[string][]string `json:"highlight,omitempty"`
+}
+
+type PostSearchHit struct {
+	PostId    int    `json:"post_id"`
+	ClubId    int    `json:"club_id"`
+	AuthorId  int    `json:"author_id"`
+	Title     string `json:"title"`
+	CreatedAt string `json:"created_at"`
+
+	Highlight map[string][]string `json:"highlight,omitempty"`
+}
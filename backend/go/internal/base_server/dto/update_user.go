@@ -3,7 +3,7 @@ package dto
 type UpdateUserRequest struct {
 	UserId    int    `json:"user_id"`
 	Username  string `json:"username"`
-	Password  string `json:"password"`
+	Password  string `json:"password"` // 不再支持，非空时拒绝请求；修改密码请使用/api/user/password
 	Email     string `json:"email"`
 	Extension string `json:"extension"` // 扩展字段，可以存储额外信息
 }
@@ -5,139 +5,521 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strings"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"whuclubsynapse-server/internal/base_server/baseconfig"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"github.com/elastic/go-elasticsearch/v9"
-	"github.com/elastic/go-elasticsearch/v9/esapi" // 确保导入这个包
+	"github.com/elastic/go-elasticsearch/v9/esapi"
 )
 
-var esClient *elasticsearch.Client
+// 读写均通过别名进行，实际索引名为 别名_时间戳，重建索引时切换别名指向
+const (
+	CLUB_INDEX = "clubs"
+	POST_INDEX = "posts"
 
-type Club dbstruct.Club
+	// 中文字段建索引时细粒度分词，检索时粗粒度分词，需安装IK分词插件
+	kIndexAnalyzer  = "ik_max_word"
+	kSearchAnalyzer = "ik_smart"
+)
+
+// ClubDoc 社团索引文档
+type ClubDoc struct {
+	ClubId       uint      `json:"club_id"`
+	Name         string    `json:"name"`
+	Desc         string    `json:"desc"`
+	Requirements string    `json:"requirements"`
+	CategoryId   uint      `json:"category_id"`
+	Tags         []string  `json:"tags"`
+	MemberCount  int       `json:"member_count"`
+	LogoUrl      string    `json:"logo_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PostDoc 帖子索引文档，只收录已发布的公开帖子
+type PostDoc struct {
+	PostId    uint      `json:"post_id"`
+	ClubId    uint      `json:"club_id"`
+	UserId    uint      `json:"user_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SearchClientService interface {
+	// EnsureIndices 创建缺失的社团与帖子索引及其别名，已存在的索引不做修改
+	EnsureIndices() error
+
+	// CreateIndex 按别名对应的映射创建一个新的版本化索引，返回索引名
+	CreateIndex(alias string) (string, error)
+	// BulkIndexClubs 直接写入指定索引而非别名，用于重建索引
+	BulkIndexClubs(index string, docs []*ClubDoc) error
+	BulkIndexPosts(index string, docs []*PostDoc) error
+	// SwitchAlias 将别名原子地切换到index，返回原先别名指向的索引；
+	// 与别名同名的旧版实体索引在切换时一并删除
+	SwitchAlias(alias, index string) ([]string, error)
+	DeleteIndices(indices []string) error
+
+	IndexClub(doc *ClubDoc) error
+	// DeleteClub 删除社团文档及其全部帖子文档，文档不存在时不报错
+	DeleteClub(clubId uint) error
+	IndexPost(doc *PostDoc) error
+	// DeletePost 文档不存在时不报错
+	DeletePost(postId uint) error
+
+	SearchClubs(query *ClubQuery) (*SearchResult[ClubDoc], error)
+	SearchPosts(query *PostQuery) (*SearchResult[PostDoc], error)
+}
 
-// 初始化ES客户端
-func InitElasticsearch() error {
-	cfg := elasticsearch.Config{
-		Addresses: []string{"http://localhost:9200"},
+type sSearchClientService struct {
+	client *elasticsearch.Client
+
+	logger *slog.Logger
+}
+
+func NewSearchClientService(
+	cfg *baseconfig.Config,
+	logger *slog.Logger,
+) (SearchClientService, error) {
+	addrs := cfg.EsAddrs
+	if len(addrs) == 0 {
+		addrs = []string{"http://localhost:9200"}
 	}
-	client, err := elasticsearch.NewClient(cfg)
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: addrs,
+		Username:  cfg.EsUsername,
+		Password:  cfg.EsPassword,
+	})
 	if err != nil {
-		return fmt.Errorf("创建ES客户端失败: %s", err)
+		return nil, fmt.Errorf("创建ES客户端失败：%w", err)
 	}
-	esClient = client
-	return nil
+
+	return &sSearchClientService{
+		client: client,
+		logger: logger,
+	}, nil
 }
 
-// 创建Club索引 - 严格使用文档中的函数
-func CreateClubIndex() {
-	// 1. 定义映射JSON（如文档示例）
-	mapping := `{
-		"mappings": {
-			"properties": {
-				"name": {"type": "text", "analyzer": "ik_max_word"},
-				"desc": {"type": "text", "analyzer": "ik_max_word"},
-				"tags": {"type": "keyword"}
-			}
+func sTextField() map[string]any {
+	return map[string]any{
+		"type":            "text",
+		"analyzer":        kIndexAnalyzer,
+		"search_analyzer": kSearchAnalyzer,
+	}
+}
+
+func sClubMapping() map[string]any {
+	return map[string]any{
+		"mappings": map[string]any{
+			"properties": map[string]any{
+				"club_id":      map[string]any{"type": "long"},
+				"name":         sTextField(),
+				"desc":         sTextField(),
+				"requirements": sTextField(),
+				"category_id":  map[string]any{"type": "integer"},
+				"tags":         map[string]any{"type": "keyword"},
+				"member_count": map[string]any{"type": "integer"},
+				"logo_url":     map[string]any{"type": "keyword", "index": false},
+				"created_at":   map[string]any{"type": "date"},
+			},
+		},
+	}
+}
+
+func sPostMapping() map[string]any {
+	return map[string]any{
+		"mappings": map[string]any{
+			"properties": map[string]any{
+				"post_id":    map[string]any{"type": "long"},
+				"club_id":    map[string]any{"type": "long"},
+				"user_id":    map[string]any{"type": "long"},
+				"title":      sTextField(),
+				"content":    sTextField(),
+				"created_at": map[string]any{"type": "date"},
+				"updated_at": map[string]any{"type": "date"},
+			},
+		},
+	}
+}
+
+func sMappingOf(alias string) (map[string]any, error) {
+	switch alias {
+	case CLUB_INDEX:
+		return sClubMapping(), nil
+	case POST_INDEX:
+		return sPostMapping(), nil
+	default:
+		return nil, fmt.Errorf("未知的索引：%s", alias)
+	}
+}
+
+func (s *sSearchClientService) EnsureIndices() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, alias := range []string{CLUB_INDEX, POST_INDEX} {
+		// 别名或同名的旧版实体索引存在时均返回200
+		res, err := esapi.IndicesExistsRequest{Index: []string{alias}}.Do(ctx, s.client)
+		if err != nil {
+			return err
 		}
-	}`
+		res.Body.Close()
 
-	// 2. 创建索引请求 - 完全按照文档中的调用方式
-	req := esapi.IndicesCreateRequest{
-		Index: "clubs",
-		Body:  strings.NewReader(mapping),
+		if res.StatusCode == http.StatusOK {
+			continue
+		}
+
+		index, err := s.CreateIndex(alias)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.SwitchAlias(alias, index); err != nil {
+			return err
+		}
+
+		s.logger.Info("已创建ES索引", "alias", alias, "index", index)
 	}
 
-	// 3. 执行请求
-	res, err := req.Do(context.Background(), esClient)
+	return nil
+}
+
+func (s *sSearchClientService) CreateIndex(alias string) (string, error) {
+	mapping, err := sMappingOf(alias)
 	if err != nil {
-		log.Fatalf("索引创建失败: %s", err)
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(mapping)
+	if err != nil {
+		return "", err
+	}
+
+	index := alias + "_" + time.Now().UTC().Format("20060102150405")
+	res, err := esapi.IndicesCreateRequest{
+		Index: index,
+		Body:  bytes.NewReader(body),
+	}.Do(ctx, s.client)
+	if err != nil {
+		return "", err
+	}
+
+	if err := sCheckResponse(res); err != nil {
+		return "", fmt.Errorf("创建索引%s失败：%w", index, err)
+	}
+
+	return index, nil
+}
+
+func (s *sSearchClientService) BulkIndexClubs(index string, docs []*ClubDoc) error {
+	ids := make([]uint, 0, len(docs))
+	items := make([]any, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ClubId)
+		items = append(items, doc)
+	}
+
+	return s.sBulkIndex(index, ids, items)
+}
+
+func (s *sSearchClientService) BulkIndexPosts(index string, docs []*PostDoc) error {
+	ids := make([]uint, 0, len(docs))
+	items := make([]any, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.PostId)
+		items = append(items, doc)
+	}
+
+	return s.sBulkIndex(index, ids, items)
+}
+
+type sBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Id    string          `json:"_id"`
+		Error json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (s *sSearchClientService) sBulkIndex(index string, ids []uint, docs []any) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for i, doc := range docs {
+		action := map[string]any{
+			"index": map[string]any{"_id": strconv.FormatUint(uint64(ids[i]), 10)},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return err
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	res, err := esapi.BulkRequest{
+		Index: index,
+		Body:  &buf,
+	}.Do(ctx, s.client)
+	if err != nil {
+		return err
 	}
-	defer res.Body.Close()
 
 	if res.IsError() {
-		log.Printf("ES错误响应: %s", res.String())
+		return sCheckResponse(res)
+	}
+	defer res.Body.Close()
+
+	var parsed sBulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return err
+	}
+
+	if !parsed.Errors {
+		return nil
+	}
+
+	// 只报告第一条失败，其余失败原因通常相同
+	for _, item := range parsed.Items {
+		for _, result := range item {
+			if len(result.Error) > 0 {
+				return fmt.Errorf("批量写入索引%s失败：文档%s %s", index, result.Id, result.Error)
+			}
+		}
+	}
+
+	return fmt.Errorf("批量写入索引%s失败", index)
+}
+
+func (s *sSearchClientService) SwitchAlias(alias, index string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := esapi.IndicesGetAliasRequest{Name: []string{alias}}.Do(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	// 响应为 索引名 -> 别名信息，别名不存在时为404
+	var current map[string]json.RawMessage
+	if res.StatusCode == http.StatusOK {
+		err = json.NewDecoder(res.Body).Decode(&current)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		log.Println("Club索引创建成功")
+		res.Body.Close()
+	}
+
+	actions := []any{
+		map[string]any{"add": map[string]any{"index": index, "alias": alias}},
+	}
+
+	var oldIndices []string
+	for oldIndex := range current {
+		if oldIndex == index {
+			continue
+		}
+		oldIndices = append(oldIndices, oldIndex)
+		actions = append(actions, map[string]any{
+			"remove": map[string]any{"index": oldIndex, "alias": alias},
+		})
+	}
+
+	if len(current) == 0 {
+		res, err := esapi.IndicesExistsRequest{Index: []string{alias}}.Do(ctx, s.client)
+		if err != nil {
+			return nil, err
+		}
+		res.Body.Close()
+
+		// 旧版本直接以别名为名建立的索引，别名无法与之同名，须在同一操作中删除
+		if res.StatusCode == http.StatusOK {
+			actions = append(actions, map[string]any{
+				"remove_index": map[string]any{"index": alias},
+			})
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{"actions": actions})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = esapi.IndicesUpdateAliasesRequest{Body: bytes.NewReader(body)}.Do(ctx, s.client)
+	if err != nil {
+		return nil, err
 	}
+
+	if err := sCheckResponse(res); err != nil {
+		return nil, fmt.Errorf("切换别名%s失败：%w", alias, err)
+	}
+
+	return oldIndices, nil
 }
 
-// 添加Club文档 - 严格使用文档中的函数
-func AddClubToIndex(club Club) error {
-	// 序列化文档
-	data, err := json.Marshal(club)
+func (s *sSearchClientService) DeleteIndices(indices []string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := esapi.IndicesDeleteRequest{Index: indices}.Do(ctx, s.client)
 	if err != nil {
 		return err
 	}
 
-	// 创建索引请求 - 按照文档中的API调用
-	req := esapi.IndexRequest{
-		Index: "clubs",
-		Body:  bytes.NewReader(data),
+	return sCheckResponse(res)
+}
+
+func (s *sSearchClientService) IndexClub(doc *ClubDoc) error {
+	return s.sIndex(CLUB_INDEX, doc.ClubId, doc)
+}
+
+func (s *sSearchClientService) DeleteClub(clubId uint) error {
+	if err := s.sDelete(CLUB_INDEX, clubId); err != nil {
+		return err
 	}
 
-	// 执行请求
-	res, err := req.Do(context.Background(), esClient)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{
+			"term": map[string]any{"club_id": clubId},
+		},
+	})
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return fmt.Errorf("文档添加失败: %s", res.String())
+	// 帖子文档可能正被并发更新，版本冲突时跳过即可
+	res, err := esapi.DeleteByQueryRequest{
+		Index:     []string{POST_INDEX},
+		Body:      bytes.NewReader(body),
+		Conflicts: "proceed",
+	}.Do(ctx, s.client)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return sCheckResponse(res)
 }
 
-// 搜索Clubs - 严格使用文档中的函数
-func SearchClubs(query string) ([]Club, error) {
-	// 构建查询
-	searchQuery := `{
-		"query": {
-			"multi_match": {
-				"query": "` + query + `",
-				"fields": ["name", "desc"]
-			}
-		}
-	}`
+func (s *sSearchClientService) IndexPost(doc *PostDoc) error {
+	return s.sIndex(POST_INDEX, doc.PostId, doc)
+}
+
+func (s *sSearchClientService) DeletePost(postId uint) error {
+	return s.sDelete(POST_INDEX, postId)
+}
+
+// sIndex 以数据库主键作为文档ID写入，重复写入即覆盖
+func (s *sSearchClientService) sIndex(index string, id uint, doc any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// 创建搜索请求 - 按照文档中的API调用
-	req := esapi.SearchRequest{
-		Index: []string{"clubs"},
-		Body:  strings.NewReader(searchQuery),
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
 	}
 
-	// 执行搜索
-	res, err := req.Do(context.Background(), esClient)
+	res, err := esapi.IndexRequest{
+		Index:      index,
+		DocumentID: strconv.FormatUint(uint64(id), 10),
+		Body:       bytes.NewReader(body),
+	}.Do(ctx, s.client)
 	if err != nil {
-		return nil, err
+		return err
 	}
+
+	return sCheckResponse(res)
+}
+
+func (s *sSearchClientService) sDelete(index string, id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := esapi.DeleteRequest{
+		Index:      index,
+		DocumentID: strconv.FormatUint(uint64(id), 10),
+	}.Do(ctx, s.client)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil
+	}
+
+	return sCheckResponse(res)
+}
+
+// sCheckResponse 关闭响应体，ES返回错误时附带响应内容
+func sCheckResponse(res *esapi.Response) error {
 	defer res.Body.Close()
 
-	if res.IsError() {
-		return nil, fmt.Errorf("搜索失败: %s", res.String())
+	if !res.IsError() {
+		io.Copy(io.Discard, res.Body)
+		return nil
 	}
 
-	// 解析响应
-	var r map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
-		return nil, err
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("ES请求失败：%s %s", res.Status(), msg)
+}
+
+// NewClubDoc 标签解析失败时按无标签收录
+func NewClubDoc(club *dbstruct.Club) *ClubDoc {
+	var tags []string
+	if len(club.Tags) > 0 {
+		_ = json.Unmarshal(club.Tags, &tags)
 	}
 
-	// 提取结果
-	var clubs []Club
-	hits := r["hits"].(map[string]interface{})["hits"].([]interface{})
-	for _, hit := range hits {
-		source := hit.(map[string]interface{})["_source"]
-		data, _ := json.Marshal(source)
+	return &ClubDoc{
+		ClubId:       club.ClubId,
+		Name:         club.Name,
+		Desc:         club.Description,
+		Requirements: club.Requirements,
+		CategoryId:   club.CategoryId,
+		Tags:         tags,
+		MemberCount:  club.MemberCount,
+		LogoUrl:      club.LogoUrl,
+		CreatedAt:    club.CreatedAt,
+	}
+}
 
-		var club Club
-		if err := json.Unmarshal(data, &club); err == nil {
-			clubs = append(clubs, club)
-		}
+func NewPostDoc(post *dbstruct.ClubPost, content string) *PostDoc {
+	return &PostDoc{
+		PostId:    post.PostId,
+		ClubId:    post.ClubId,
+		UserId:    post.UserId,
+		Title:     post.Title,
+		Content:   content,
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
 	}
+}
 
-	return clubs, nil
+// IsPostSearchable 只有已发布的公开帖子进入索引
+func IsPostSearchable(post *dbstruct.ClubPost) bool {
+	return post.Status == dbstruct.POST_STATUS_PUBLISHED && post.Visibility == 0
 }
@@ -0,0 +1,216 @@
+package esimpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+)
+
+const (
+	kHighlightPreTag  = "<em>"
+	kHighlightPostTag = "</em>"
+	kFragmentSize     = 120
+	kFragmentNum      = 3
+)
+
+// ClubQuery 社团检索条件，Keyword为空时只按筛选条件列出
+type ClubQuery struct {
+	Keyword    string
+	CategoryId uint
+	Tags       []string // 须同时带有全部标签
+	MinMembers *int
+	MaxMembers *int
+
+	From int
+	Size int
+}
+
+// PostQuery 帖子检索条件，ClubId为0时检索全部社团
+type PostQuery struct {
+	Keyword string
+	ClubId  uint
+
+	From int
+	Size int
+}
+
+type SearchHit[T any] struct {
+	Doc   T
+	Score float64
+	// Highlight 字段名到高亮片段，片段中的原文已做HTML转义
+	Highlight map[string][]string
+}
+
+type SearchResult[T any] struct {
+	Total int64
+	Hits  []SearchHit[T]
+}
+
+type sSearchResponse[T any] struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Score     float64             `json:"_score"`
+			Source    T                   `json:"_source"`
+			Highlight map[string][]string `json:"highlight"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (s *sSearchClientService) SearchClubs(query *ClubQuery) (*SearchResult[ClubDoc], error) {
+	var filters []any
+	if query.CategoryId > 0 {
+		filters = append(filters, map[string]any{
+			"term": map[string]any{"category_id": query.CategoryId},
+		})
+	}
+	for _, tag := range query.Tags {
+		filters = append(filters, map[string]any{
+			"term": map[string]any{"tags": tag},
+		})
+	}
+	if query.MinMembers != nil || query.MaxMembers != nil {
+		memberRange := map[string]any{}
+		if query.MinMembers != nil {
+			memberRange["gte"] = *query.MinMembers
+		}
+		if query.MaxMembers != nil {
+			memberRange["lte"] = *query.MaxMembers
+		}
+		filters = append(filters, map[string]any{
+			"range": map[string]any{"member_count": memberRange},
+		})
+	}
+
+	body := sSearchBody(
+		query.Keyword,
+		[]string{"name^3", "tags^2", "desc", "requirements"},
+		filters,
+		[]string{"name", "desc", "requirements"},
+		query.From, query.Size,
+	)
+
+	return sSearch[ClubDoc](s, CLUB_INDEX, body)
+}
+
+func (s *sSearchClientService) SearchPosts(query *PostQuery) (*SearchResult[PostDoc], error) {
+	var filters []any
+	if query.ClubId > 0 {
+		filters = append(filters, map[string]any{
+			"term": map[string]any{"club_id": query.ClubId},
+		})
+	}
+
+	body := sSearchBody(
+		query.Keyword,
+		[]string{"title^3", "content"},
+		filters,
+		[]string{"title", "content"},
+		query.From, query.Size,
+	)
+	// 正文只以高亮片段返回
+	body["_source"] = map[string]any{"excludes": []string{"content"}}
+
+	return sSearch[PostDoc](s, POST_INDEX, body)
+}
+
+// sSearchBody 查询以结构体序列化构造，用户输入只作为字段值出现，不会改变查询结构；
+// 无关键词时按创建时间倒序列出
+func sSearchBody(
+	keyword string,
+	fields []string,
+	filters []any,
+	highlightFields []string,
+	from, size int,
+) map[string]any {
+	boolQuery := map[string]any{}
+	if len(filters) > 0 {
+		boolQuery["filter"] = filters
+	}
+
+	body := map[string]any{
+		"query":            map[string]any{"bool": boolQuery},
+		"from":             from,
+		"size":             size,
+		"track_total_hits": true,
+	}
+
+	if keyword == "" {
+		body["sort"] = []any{
+			map[string]any{"created_at": map[string]any{"order": "desc"}},
+		}
+		return body
+	}
+
+	boolQuery["must"] = []any{
+		map[string]any{
+			"multi_match": map[string]any{
+				"query":  keyword,
+				"fields": fields,
+				"type":   "best_fields",
+			},
+		},
+	}
+
+	highlight := map[string]any{}
+	for _, field := range highlightFields {
+		highlight[field] = map[string]any{}
+	}
+	body["highlight"] = map[string]any{
+		"pre_tags":            []string{kHighlightPreTag},
+		"post_tags":           []string{kHighlightPostTag},
+		"encoder":             "html",
+		"fragment_size":       kFragmentSize,
+		"number_of_fragments": kFragmentNum,
+		"fields":              highlight,
+	}
+
+	return body
+}
+
+func sSearch[T any](s *sSearchClientService, index string, body map[string]any) (*SearchResult[T], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := esapi.SearchRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(data),
+	}.Do(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsError() {
+		return nil, sCheckResponse(res)
+	}
+	defer res.Body.Close()
+
+	var parsed sSearchResponse[T]
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult[T]{
+		Total: parsed.Hits.Total.Value,
+		Hits:  make([]SearchHit[T], 0, len(parsed.Hits.Hits)),
+	}
+	for _, hit := range parsed.Hits.Hits {
+		result.Hits = append(result.Hits, SearchHit[T]{
+			Doc:       hit.Source,
+			Score:     hit.Score,
+			Highlight: hit.Highlight,
+		})
+	}
+
+	return result, nil
+}
@@ -1,17 +1,17 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.36.5
 // 	protoc        v6.30.0
 // source: internal/base_server/grpcimpl/mailvrf/verification_service.proto
 
 package mailvrf
 
 import (
-	reflect "reflect"
-	sync "sync"
-
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -22,20 +22,18 @@ const (
 )
 
 type VerificationRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Email         string                 `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Purpose       string                 `protobuf:"bytes,2,opt,name=purpose,proto3" json:"purpose,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Email string `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *VerificationRequest) Reset() {
 	*x = VerificationRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *VerificationRequest) String() string {
@@ -46,7 +44,7 @@ func (*VerificationRequest) ProtoMessage() {}
 
 func (x *VerificationRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -68,22 +66,26 @@ func (x *VerificationRequest) GetEmail() string {
 	return ""
 }
 
+func (x *VerificationRequest) GetPurpose() string {
+	if x != nil {
+		return x.Purpose
+	}
+	return ""
+}
+
 type VerificationResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         int32                  `protobuf:"varint,1,opt,name=error,proto3" json:"error,omitempty"`
+	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Error int32  `protobuf:"varint,1,opt,name=error,proto3" json:"error,omitempty"`
-	Email string `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *VerificationResponse) Reset() {
 	*x = VerificationResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *VerificationResponse) String() string {
@@ -94,7 +96,7 @@ func (*VerificationResponse) ProtoMessage() {}
 
 func (x *VerificationResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -125,43 +127,45 @@ func (x *VerificationResponse) GetEmail() string {
 
 var File_internal_auth_server_grpcimpl_mailvrf_verification_service_proto protoreflect.FileDescriptor
 
-var file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc = []byte{
+var file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc = string([]byte{
 	0x0a, 0x40, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x61, 0x75, 0x74, 0x68, 0x5f,
 	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x69, 0x6d, 0x70, 0x6c, 0x2f,
 	0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x2f, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61,
 	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x12, 0x07, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x22, 0x2b, 0x0a, 0x13, 0x56,
+	0x74, 0x6f, 0x12, 0x07, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x22, 0x45, 0x0a, 0x13, 0x56,
 	0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
 	0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x22, 0x42, 0x0a, 0x14, 0x56, 0x65, 0x72, 0x69,
-	0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x32, 0x65, 0x0a, 0x13,
-	0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x12, 0x4e, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79,
-	0x43, 0x6f, 0x64, 0x65, 0x12, 0x1c, 0x2e, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x2e, 0x56,
-	0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x2e, 0x56, 0x65, 0x72,
-	0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x00, 0x42, 0x0c, 0x5a, 0x0a, 0x2e, 0x2f, 0x3b, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72,
-	0x66, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-}
+	0x09, 0x52, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x75, 0x72, 0x70,
+	0x6f, 0x73, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x75, 0x72, 0x70, 0x6f,
+	0x73, 0x65, 0x22, 0x42, 0x0a, 0x14, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x6d, 0x61, 0x69, 0x6c, 0x32, 0x65, 0x0a, 0x13, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69,
+	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4e, 0x0a,
+	0x0d, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x1c,
+	0x2e, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x6d,
+	0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x0c, 0x5a,
+	0x0a, 0x2e, 0x2f, 0x3b, 0x6d, 0x61, 0x69, 0x6c, 0x76, 0x72, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+})
 
 var (
 	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescOnce sync.Once
-	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData = file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc
+	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData []byte
 )
 
 func file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescGZIP() []byte {
 	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescOnce.Do(func() {
-		file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData)
+		file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc), len(file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc)))
 	})
 	return file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDescData
 }
 
 var file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_goTypes = []interface{}{
+var file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_goTypes = []any{
 	(*VerificationRequest)(nil),  // 0: mailvrf.VerificationRequest
 	(*VerificationResponse)(nil), // 1: mailvrf.VerificationResponse
 }
@@ -180,37 +184,11 @@ func file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_init(
 	if File_internal_auth_server_grpcimpl_mailvrf_verification_service_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerificationRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VerificationResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc), len(file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc)),
 			NumEnums:      0,
 			NumMessages:   2,
 			NumExtensions: 0,
@@ -221,7 +199,6 @@ func file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_init(
 		MessageInfos:      file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_msgTypes,
 	}.Build()
 	File_internal_auth_server_grpcimpl_mailvrf_verification_service_proto = out.File
-	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_rawDesc = nil
 	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_goTypes = nil
 	file_internal_auth_server_grpcimpl_mailvrf_verification_service_proto_depIdxs = nil
 }
@@ -14,8 +14,13 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+const (
+	MAILVRF_PURPOSE_REGISTER       = "register"
+	MAILVRF_PURPOSE_RESET_PASSWORD = "reset_password"
+)
+
 type MailvrfClientService interface {
-	RequestVerify(email string) error
+	RequestVerify(email, purpose string) error
 }
 
 type sMailvrfClientService struct {
@@ -48,7 +53,7 @@ func NewMailvrfClientService(
 	}
 }
 
-func (s *sMailvrfClientService) RequestVerify(email string) error {
+func (s *sMailvrfClientService) RequestVerify(email, purpose string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -60,7 +65,10 @@ func (s *sMailvrfClientService) RequestVerify(email string) error {
 
 	client := mailvrf.NewVerificationServiceClient(conn.ClientConn)
 
-	res, err := client.GetVerifyCode(ctx, &mailvrf.VerificationRequest{Email: email})
+	res, err := client.GetVerifyCode(ctx, &mailvrf.VerificationRequest{
+		Email:   email,
+		Purpose: purpose,
+	})
 	if err != nil {
 		slog.Error("gRPC邮件验证服务异常", "error", err)
 		return fmt.Errorf("gRPC邮件验证服务异常：%w", err)
@@ -5,12 +5,13 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/grpcimpl"
 	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/redisimpl"
 	"whuclubsynapse-server/internal/base_server/service"
-	"whuclubsynapse-server/internal/shared/jwtutil"
+	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
@@ -22,8 +23,7 @@ const (
 )
 
 type AuthHandler struct {
-	JwtFactory *jwtutil.CliamsFactory[model.UserClaims]
-
+	AuthService    service.AuthService
 	RedisService   redisimpl.RedisClientService
 	UserService    service.UserService
 	MailvrfService grpcimpl.MailvrfClientService
@@ -40,8 +40,7 @@ func (h *AuthHandler) BeforeActivation(b mvc.BeforeActivation) {
 func (h *AuthHandler) GetMyInfo(ctx iris.Context) {
 	encryptedToken := ctx.GetCookie("uuid")
 	if encryptedToken == "" {
-		ctx.StatusCode(iris.StatusOK)
-		ctx.Text("Cookie UUID获取失败")
+		WriteError(ctx, apperr.ErrUnauthorized.WithMessage("Cookie UUID获取失败"))
 		return
 	}
 
@@ -49,8 +48,7 @@ func (h *AuthHandler) GetMyInfo(ctx iris.Context) {
 		[]byte(UUID_ENCRYPT_KEY), encryptedToken,
 	)
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("Cookie UUID无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("Cookie UUID无效"))
 		return
 	}
 
@@ -64,18 +62,18 @@ func (h *AuthHandler) GetMyInfo(ctx iris.Context) {
 			"error", err, "id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusNotFound)
-		ctx.Text("用户信息获取失败")
+		WriteServiceError(ctx, err, apperr.ErrNotFound.WithMessage("用户信息获取失败"))
 		return
 	}
 
-	ctx.JSON(dto.UserInfo{
-		UserId:     user.UserId,
-		Email:      user.Email,
-		Role:       user.Role,
-		Username:   user.Username,
-		AvatarUrl:  user.AvatarUrl,
-		LastActive: user.LastActive.Format("2006-01-02 15:04:05"),
+	WriteOK(ctx, dto.UserInfo{
+		UserId:         user.UserId,
+		Email:          user.Email,
+		Role:           user.Role,
+		Username:       user.Username,
+		AvatarUrl:      user.AvatarUrl,
+		AvatarVariants: model.ParseImageVariants(user.AvatarVariants),
+		LastActive:     user.LastActive.Format("2006-01-02 15:04:05"),
 	})
 }
 
@@ -84,112 +82,188 @@ func (h *AuthHandler) PostLogin(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("Login请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	clientIp := ctx.RemoteAddr()
+
+	locked, err := h.AuthService.CheckLoginLock(reqBody.Username, clientIp)
+	if err != nil {
+		h.Logger.Error("查询登录锁定状态失败", "error", err, "username", reqBody.Username)
+	}
+	if locked > 0 {
+		WriteTooManyRequests(ctx, apperr.ErrLoginLocked, locked)
 		return
 	}
 
 	userDetail, ok := h.UserService.Login(reqBody.Username, reqBody.Password)
 	if !ok {
-		h.Logger.Info("用户名或密码错误")
+		h.Logger.Info("用户名或密码错误", "username", reqBody.Username)
+
+		lock, err := h.AuthService.RecordLoginFailure(reqBody.Username, clientIp)
+		if err != nil {
+			h.Logger.Error("记录登录失败次数失败", "error", err, "username", reqBody.Username)
+		}
+		if lock > 0 {
+			WriteTooManyRequests(ctx, apperr.ErrLoginLocked, lock)
+			return
+		}
+
+		WriteError(ctx, apperr.ErrWrongCredentials)
+		return
+	}
+
+	if err := h.AuthService.ResetLoginFailure(reqBody.Username, clientIp); err != nil {
+		h.Logger.Error("重置登录失败次数失败", "error", err, "username", reqBody.Username)
+	}
+
+	tokens, ok := h.sIssueTokens(ctx, userDetail)
+	if !ok {
+		return
+	}
+
+	resLoginConfirm := dto.LoginResponse{
+		UserId:         int(userDetail.UserId),
+		Username:       userDetail.Username,
+		Email:          userDetail.Email,
+		Role:           userDetail.Role,
+		AvatarUrl:      userDetail.AvatarUrl,
+		AvatarVariants: model.ParseImageVariants(userDetail.AvatarVariants),
+		LastActive:     userDetail.LastActive.Format("2006-01-02 15:04:05"),
+
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+
+	WriteOK(ctx, resLoginConfirm)
+}
+
+func (h *AuthHandler) PostRefresh(ctx iris.Context) {
+	var reqBody dto.RefreshRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("Refresh请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	user, err := h.AuthService.RotateRefreshToken(reqBody.RefreshToken)
+	if err != nil {
+		h.Logger.Info("刷新token失败", "error", err)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("刷新token失败"))
+		return
+	}
+
+	tokens, ok := h.sIssueTokens(ctx, user)
+	if !ok {
+		return
+	}
+
+	WriteOK(ctx, dto.RefreshResponse{
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	})
+}
+
+func (h *AuthHandler) PostLogout(ctx iris.Context) {
+	// 请求体可省略，此时仅吊销当前access token
+	var reqBody dto.LogoutRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Debug("Logout请求体为空或格式错误", "error", err)
+	}
+
+	accessToken := strings.TrimPrefix(ctx.GetHeader("Authorization"), kBearerPrefix)
+
+	if err := h.AuthService.Logout(accessToken, reqBody.RefreshToken); err != nil {
+		h.Logger.Error("注销失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户名或密码错误")
+		WriteServiceError(ctx, err, apperr.ErrServiceUnavailable.WithMessage("注销失败"))
 		return
 	}
 
-	strToken := strconv.FormatInt(int64(userDetail.UserId), 10) +
-		":" + userDetail.Role
+	ctx.RemoveCookie("uuid")
+
+	WriteOK(ctx, nil)
+}
+
+// sIssueTokens 签发access token与refresh token并写入响应头，失败时直接写入错误响应
+func (h *AuthHandler) sIssueTokens(ctx iris.Context, user *dbstruct.User) (*model.TokenPair, bool) {
+	strToken := strconv.FormatInt(int64(user.UserId), 10) +
+		":" + user.Role
 
 	uuid, err := model.Encrypt(
 		[]byte(UUID_ENCRYPT_KEY), strToken,
 	)
 	if err != nil {
 		h.Logger.Info("转uuid加密失败",
-			"error", err, "user_id", userDetail.UserId,
+			"error", err, "user_id", user.UserId,
 		)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("token生成失败")
-		return
-
+		WriteError(ctx, apperr.ErrInternal.WithMessage("token生成失败"))
+		return nil, false
 	}
 
-	token, err := h.JwtFactory.GenToken(model.UserClaims{
-		Role:   userDetail.Role,
-		UserId: int(userDetail.UserId),
-		Uuid:   uuid,
-	})
+	tokens, err := h.AuthService.IssueTokens(int(user.UserId), user.Role, uuid)
 	if err != nil {
 		h.Logger.Info("生成token失败",
-			"error", err, "user_id", userDetail.UserId,
+			"error", err, "user_id", user.UserId,
 		)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("token生成失败")
-		return
+		WriteError(ctx, apperr.ErrInternal.WithMessage("token生成失败"))
+		return nil, false
 	}
 
-	ctx.Header("Authorization", kBearerPrefix+token)
+	ctx.Header("Authorization", kBearerPrefix+tokens.AccessToken)
 	ctx.SetCookieKV(
 		"uuid", uuid,
 		iris.CookieHTTPOnly(true),
 		iris.CookieExpires(time.Hour*24),
 	)
 
-	resLoginConfirm := dto.LoginResponse{
-		UserId:     int(userDetail.UserId),
-		Username:   userDetail.Username,
-		Email:      userDetail.Email,
-		Role:       userDetail.Role,
-		AvatarUrl:  userDetail.AvatarUrl,
-		LastActive: userDetail.LastActive.Format("2006-01-02 15:04:05"),
-	}
-
-	ctx.JSON(resLoginConfirm)
+	return tokens, true
 }
 
 func (h *AuthHandler) PostSendVrfEmail(ctx iris.Context) {
 	var reqBody dto.MailvrfRequest
 	if err := ctx.ReadJSON(&reqBody); err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
 	if h.RedisService.CheckVrfcodeExisting(reqBody.Email) {
-		ctx.StatusCode(iris.StatusAccepted)
-		ctx.Text("验证码已发送，请勿重复申请")
+		WriteError(ctx, apperr.ErrVrfcodeSent)
 		return
 	}
 
-	if err := h.MailvrfService.RequestVerify(reqBody.Email); err != nil {
+	if err := h.MailvrfService.RequestVerify(
+		reqBody.Email, grpcimpl.MAILVRF_PURPOSE_REGISTER,
+	); err != nil {
 		h.Logger.Info("发送验证码失败",
 			"error", err, "email", reqBody.Email,
 		)
 
-		ctx.StatusCode(iris.StatusServiceUnavailable)
-		ctx.Text("邮箱服务不可用")
+		WriteServiceError(ctx, err, apperr.ErrServiceUnavailable.WithMessage("邮箱服务不可用"))
 		return
 	}
 
-	ctx.Text("邮件已发送至%s", reqBody.Email)
+	WriteOK(ctx, nil)
 }
 
 func (h *AuthHandler) PostRegister(ctx iris.Context) {
 	var reqBody dto.RegisterRequest
 	if err := ctx.ReadJSON(&reqBody); err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
-	if !h.RedisService.ValidateRegVrfcode(
+	if !h.RedisService.ValidateVrfcode(
+		grpcimpl.MAILVRF_PURPOSE_REGISTER,
 		reqBody.Email,
 		reqBody.Vrfcode,
 	) {
-		ctx.StatusCode(iris.StatusUnauthorized)
-		ctx.Text("验证码错误")
+		WriteError(ctx, apperr.ErrWrongVrfcode)
 		return
 	}
 
@@ -202,8 +276,7 @@ func (h *AuthHandler) PostRegister(ctx iris.Context) {
 			"error", err, "encrypted_password", reqBody.EncryptedPassword,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("密码无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("密码无效"))
 		return
 	}
 
@@ -217,15 +290,95 @@ func (h *AuthHandler) PostRegister(ctx iris.Context) {
 			"error", err, "reqBody", reqBody,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("注册失败，请检查用户名或邮箱")
+		WriteServiceError(ctx, err, apperr.ErrUserExists)
 		return
 	}
 
+	if err := h.RedisService.DeleteVrfcode(reqBody.Email); err != nil {
+		h.Logger.Error("删除注册验证码失败", "error", err, "email", reqBody.Email)
+	}
+
 	resRegConfirm := dto.RegisterResponse{
 		UserId:   newUser.UserId,
 		Username: newUser.Username,
 	}
 
-	ctx.JSON(resRegConfirm)
+	WriteOK(ctx, resRegConfirm)
+}
+
+// PostForgotPassword 请求解析成功后一律返回成功，邮箱是否注册、验证码是否已发送及发送失败均只记录日志，
+// 避免响应差异被用于探测已注册邮箱
+func (h *AuthHandler) PostForgotPassword(ctx iris.Context) {
+	var reqBody dto.MailvrfRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	h.sSendResetVrfcode(reqBody.Email)
+
+	WriteOK(ctx, nil)
+}
+
+func (h *AuthHandler) sSendResetVrfcode(email string) {
+	if _, err := h.UserService.GetUserByEmail(email); err != nil {
+		h.Logger.Info("找回密码邮箱未注册", "error", err, "email", email)
+		return
+	}
+
+	if h.RedisService.CheckVrfcodeExisting(email) {
+		h.Logger.Info("找回密码验证码已发送", "email", email)
+		return
+	}
+
+	if err := h.MailvrfService.RequestVerify(
+		email, grpcimpl.MAILVRF_PURPOSE_RESET_PASSWORD,
+	); err != nil {
+		h.Logger.Error("发送找回密码验证码失败", "error", err, "email", email)
+		return
+	}
+
+	// 未标记用途的验证码只能用于注册
+	if err := h.RedisService.TagVrfcodePurpose(
+		grpcimpl.MAILVRF_PURPOSE_RESET_PASSWORD, email,
+	); err != nil {
+		h.Logger.Error("标记找回密码验证码失败", "error", err, "email", email)
+	}
+}
+
+func (h *AuthHandler) PostResetPassword(ctx iris.Context) {
+	var reqBody dto.ResetPasswordRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	if !h.RedisService.ValidateVrfcode(
+		grpcimpl.MAILVRF_PURPOSE_RESET_PASSWORD,
+		reqBody.Email,
+		reqBody.Vrfcode,
+	) {
+		WriteError(ctx, apperr.ErrWrongVrfcode)
+		return
+	}
+
+	user, err := h.UserService.ResetPassword(reqBody.Email, reqBody.NewPassword)
+	if err != nil {
+		h.Logger.Info("重置密码失败", "error", err, "email", reqBody.Email)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("重置密码失败"))
+		return
+	}
+
+	if err := h.RedisService.DeleteVrfcode(reqBody.Email); err != nil {
+		h.Logger.Error("删除找回密码验证码失败", "error", err, "email", reqBody.Email)
+	}
+
+	if err := h.AuthService.RevokeAllSessions(int(user.UserId)); err != nil {
+		h.Logger.Error("重置密码后吊销登录状态失败",
+			"error", err, "user_id", user.UserId,
+		)
+	}
+
+	WriteOK(ctx, nil)
 }
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/model"
-	"whuclubsynapse-server/internal/base_server/redisimpl"
 	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
 	"whuclubsynapse-server/internal/shared/jwtutil"
 
 	"github.com/kataras/iris/v12"
@@ -15,8 +17,9 @@ import (
 type ClubAdminHandler struct {
 	JwtFactory *jwtutil.CliamsFactory[model.UserClaims]
 
-	ClubService  service.ClubService
-	RedisService redisimpl.RedisClientService
+	ClubService     service.ClubService
+	RagSyncService  service.RagSyncService
+	LlmUsageService service.LlmUsageService
 
 	Logger *slog.Logger
 }
@@ -27,6 +30,11 @@ func (h *ClubAdminHandler) BeforeActivation(b mvc.BeforeActivation) {
 
 	b.Handle("GET", "/create_list", "GetCreateList")
 	b.Handle("GET", "/update_list", "GetUpdateList")
+
+	b.Handle("GET", "/rag_outbox", "GetRagOutbox")
+	b.Handle("PUT", "/rag_outbox/{id:int}/retry", "PutRetryRagOutbox")
+
+	b.Handle("GET", "/llm_usage", "GetLlmUsageReport")
 }
 
 func (h *ClubAdminHandler) PutProcAppliForCreateClub(ctx iris.Context) {
@@ -34,8 +42,7 @@ func (h *ClubAdminHandler) PutProcAppliForCreateClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("ProcCreateClub请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -48,8 +55,7 @@ func (h *ClubAdminHandler) PutProcAppliForCreateClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.CreateClubAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("通过社团创建申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("通过社团创建申请失败"))
 			return
 		}
 
@@ -59,44 +65,31 @@ func (h *ClubAdminHandler) PutProcAppliForCreateClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.CreateClubAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("获取新创建的社团信息失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取新创建的社团信息失败"))
 			return
 		}
 
-		ctx.JSON(iris.Map{
+		WriteOK(ctx, iris.Map{
 			"new_club_id": newClub.ClubId,
 			"status":      "创建成功",
 		})
 
-		if err := h.RedisService.UploadClubInfo(newClub); err != nil {
-			h.Logger.Info("转发新创建的社团信息失败",
-				"error", err, "appli_id", reqBody.CreateClubAppliId,
-			)
-
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("转发新创建的社团信息失败")
-			return
-		}
-
 	case "reject":
 		if err := h.ClubService.RejectAppliForCreateClub(reqBody.CreateClubAppliId, reqBody.Reason); err != nil {
 			h.Logger.Info("拒绝社团创建申请失败",
 				"error", err, "appli_id", reqBody.CreateClubAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("拒绝社团创建申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("拒绝社团创建申请失败"))
 			return
 		}
 
-		ctx.JSON(iris.Map{
+		WriteOK(ctx, iris.Map{
 			"status": "拒绝成功",
 		})
 
 	default:
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("result参数错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("result参数错误"))
 		return
 	}
 }
@@ -106,8 +99,7 @@ func (h *ClubAdminHandler) PutProcAppliForUpdateClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("ProcCreateClub请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -118,8 +110,7 @@ func (h *ClubAdminHandler) PutProcAppliForUpdateClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.UpdateAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("通过社团更新申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("通过社团更新申请失败"))
 			return
 		}
 
@@ -129,42 +120,54 @@ func (h *ClubAdminHandler) PutProcAppliForUpdateClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.UpdateAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("拒绝社团更新申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("拒绝社团更新申请失败"))
 			return
 		}
 
 	default:
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("result参数错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("result参数错误"))
 		return
 	}
 
-	ctx.Text("通过社团更新申请成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubAdminHandler) GetUpdateList(ctx iris.Context) {
-	offset, err := ctx.URLParamInt("offset")
+	cursor, cursorMode, err := parseCursorParam(ctx)
 	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
+		return
+	}
+
+	offset, err := ctx.URLParamInt("offset")
+	if err != nil && !cursorMode {
 		h.Logger.Error("获取offset参数失败", "error", err)
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取offset参数失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取offset参数失败"))
 		return
 	}
 
 	num, err := ctx.URLParamInt("num")
 	if err != nil {
 		h.Logger.Error("获取num参数失败", "error", err)
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取num参数失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取num参数失败"))
 		return
 	}
 
-	applis, err := h.ClubService.GetUpdateList(offset, num)
+	var applis []*dbstruct.UpdateClubInfoAppli
+	var page *model.Page[*dbstruct.UpdateClubInfoAppli]
+	if cursorMode {
+		page, err = h.ClubService.GetUpdateListByCursor(cursor, num)
+		if err == nil {
+			applis = page.Items
+		}
+	} else {
+		applis, err = h.ClubService.GetUpdateList(offset, num)
+	}
 	if err != nil {
 		h.Logger.Error("获取社团更新申请列表失败", "error", err)
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("获取社团更新申请列表失败")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取社团更新申请列表失败"))
 		return
 	}
 
@@ -178,31 +181,50 @@ func (h *ClubAdminHandler) GetUpdateList(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resApplis)
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resApplis))
+		return
+	}
+
+	WriteOK(ctx, resApplis)
 }
 
 func (h *ClubAdminHandler) GetCreateList(ctx iris.Context) {
-	offset, err := ctx.URLParamInt("offset")
+	cursor, cursorMode, err := parseCursorParam(ctx)
 	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
+		return
+	}
+
+	offset, err := ctx.URLParamInt("offset")
+	if err != nil && !cursorMode {
 		h.Logger.Error("获取offset参数失败", "error", err)
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取offset参数失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取offset参数失败"))
 		return
 	}
 
 	num, err := ctx.URLParamInt("num")
 	if err != nil {
 		h.Logger.Error("获取num参数失败", "error", err)
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取num参数失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取num参数失败"))
 		return
 	}
 
-	applis, err := h.ClubService.GetCreateList(offset, num)
+	var applis []*dbstruct.CreateClubAppli
+	var page *model.Page[*dbstruct.CreateClubAppli]
+	if cursorMode {
+		page, err = h.ClubService.GetCreateListByCursor(cursor, num)
+		if err == nil {
+			applis = page.Items
+		}
+	} else {
+		applis, err = h.ClubService.GetCreateList(offset, num)
+	}
 	if err != nil {
 		h.Logger.Error("获取社团创建申请列表失败", "error", err)
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("获取社团创建申请列表失败")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取社团创建申请列表失败"))
 		return
 	}
 
@@ -223,5 +245,134 @@ func (h *ClubAdminHandler) GetCreateList(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resApplis)
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resApplis))
+		return
+	}
+
+	WriteOK(ctx, resApplis)
+}
+
+// GetRagOutbox 查看RAG同步事件，status可选pending、done、failed，为空时不限状态
+func (h *ClubAdminHandler) GetRagOutbox(ctx iris.Context) {
+	status := ctx.URLParam("status")
+	switch status {
+	case "", dbstruct.OUTBOX_STATUS_PENDING, dbstruct.OUTBOX_STATUS_DONE, dbstruct.OUTBOX_STATUS_FAILED:
+	default:
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("status参数错误"))
+		return
+	}
+
+	offset := ctx.URLParamIntDefault("offset", 0)
+	num := ctx.URLParamIntDefault("num", 20)
+	if offset < 0 || num <= 0 || num > 100 {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("分页参数错误"))
+		return
+	}
+
+	events, counts, err := h.RagSyncService.GetOutbox(status, offset, num)
+	if err != nil {
+		h.Logger.Error("获取RAG同步事件失败", "error", err)
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取RAG同步事件失败"))
+		return
+	}
+
+	resEntries := make([]dto.RagOutboxEntry, 0, len(events))
+	for _, event := range events {
+		resEntries = append(resEntries, dto.RagOutboxEntry{
+			OutboxId:      event.OutboxId,
+			Entity:        event.Entity,
+			EntityId:      event.EntityId,
+			Op:            event.Op,
+			Status:        event.Status,
+			Attempts:      event.Attempts,
+			NextAttemptAt: event.NextAttemptAt.Format("2006-01-02 15:04:05"),
+			LastError:     event.LastError,
+			MessageId:     event.MessageId,
+			CreatedAt:     event.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	WriteOK(ctx, dto.RagOutboxResponse{
+		Counts:  counts,
+		Entries: resEntries,
+	})
+}
+
+func (h *ClubAdminHandler) PutRetryRagOutbox(ctx iris.Context, id int) {
+	if err := h.RagSyncService.RetryEvent(uint(id)); err != nil {
+		h.Logger.Info("重新提交RAG同步事件失败", "error", err, "outbox_id", id)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("重新提交RAG同步事件失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+// GetLlmUsageReport 按用户汇总LLM调用用量。from与to为yyyy-MM-dd格式的日期，均包含在内，
+// 默认为截至当天的最近7天，跨度不超过92天
+func (h *ClubAdminHandler) GetLlmUsageReport(ctx iris.Context) {
+	today := time.Now().Format(time.DateOnly)
+
+	to, err := time.ParseInLocation(time.DateOnly, ctx.URLParamDefault("to", today), time.Local)
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("to参数错误"))
+		return
+	}
+
+	from := to.AddDate(0, 0, -6)
+	if str := ctx.URLParam("from"); str != "" {
+		from, err = time.ParseInLocation(time.DateOnly, str, time.Local)
+		if err != nil {
+			WriteError(ctx, apperr.ErrBadRequest.WithMessage("from参数错误"))
+			return
+		}
+	}
+
+	end := to.AddDate(0, 0, 1)
+	if from.After(to) || end.Sub(from) > 92*24*time.Hour {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("统计时间范围错误"))
+		return
+	}
+
+	offset := ctx.URLParamIntDefault("offset", 0)
+	num := ctx.URLParamIntDefault("num", 20)
+	if offset < 0 || num <= 0 || num > 100 {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("分页参数错误"))
+		return
+	}
+
+	total, summaries, err := h.LlmUsageService.GetReport(from, end, offset, num)
+	if err != nil {
+		h.Logger.Error("获取LLM用量统计失败", "error", err)
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取LLM用量统计失败"))
+		return
+	}
+
+	resEntries := make([]dto.LlmUsageSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		resEntries = append(resEntries, dto.LlmUsageSummary{
+			UserId:        summary.UserId,
+			Requests:      summary.Requests,
+			Rejected:      summary.Rejected,
+			Failed:        summary.Failed,
+			ResponseBytes: summary.ResponseBytes,
+			Tokens:        summary.Tokens,
+		})
+	}
+
+	WriteOK(ctx, dto.LlmUsageReport{
+		From:  from.Format(time.DateOnly),
+		To:    to.Format(time.DateOnly),
+		Users: total.Users,
+		Total: dto.LlmUsageSummary{
+			Requests:      total.Requests,
+			Rejected:      total.Rejected,
+			Failed:        total.Failed,
+			ResponseBytes: total.ResponseBytes,
+			Tokens:        total.Tokens,
+		},
+		Entries: resEntries,
+	})
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/service"
@@ -15,14 +16,17 @@ import (
 )
 
 const (
-	CLUB_LOGO_DIR = "pub/club_logos/"
+	// 社团logo在对象存储中的键前缀
+	CLUB_LOGO_PREFIX = "club_logos"
 )
 
 type ClubHandler struct {
 	JwtFactory *jwtutil.CliamsFactory[model.UserClaims]
 
-	ClubService service.ClubService
-	PostService service.PostService
+	ClubService     service.ClubService
+	PostService     service.PostService
+	EventService    service.EventService
+	ReactionService service.ReactionService
 
 	Logger *slog.Logger
 }
@@ -40,6 +44,7 @@ func (h *ClubHandler) BeforeActivation(b mvc.BeforeActivation) {
 	b.Handle("GET", "/my_createapplis", "GetMyCreateApplis")
 	b.Handle("GET", "/my_joinapplis", "GetMyJoinApplis")
 	b.Handle("GET", "/my_favorites", "GetMyFavorites")
+	b.Handle("GET", "/my_leader_transfers", "GetMyLeaderTransfers")
 
 	b.Handle("POST", "/{id:int}/join", "PostApplyForJoinClub")
 	b.Handle("POST", "/create", "PostApplyForCreateClub")
@@ -48,20 +53,38 @@ func (h *ClubHandler) BeforeActivation(b mvc.BeforeActivation) {
 	b.Handle("POST", "/unfavorite", "PostUnfavoriteClub")
 
 	b.Handle("POST", "/quit/{id:int}", "PostQuitClub")
+
+	b.Handle("PUT", "/proc_transfer", "PutProcLeaderTransfer")
 }
 
 func (h *ClubHandler) GetClubList(ctx iris.Context) {
 	offset := ctx.URLParamIntDefault("offset", 0)
 	num := ctx.URLParamIntDefault("num", 10)
 
-	clubs, err := h.ClubService.GetClubList(offset, num)
+	cursor, cursorMode, err := parseCursorParam(ctx)
+	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
+		return
+	}
+
+	var clubs []*dbstruct.Club
+	var page *model.Page[*dbstruct.Club]
+	if cursorMode {
+		page, err = h.ClubService.GetClubListByCursor(cursor, num)
+		if err == nil {
+			clubs = page.Items
+		}
+	} else {
+		clubs, err = h.ClubService.GetClubList(offset, num)
+	}
 	if err != nil {
 		h.Logger.Error("获取社团列表失败",
 			"error", err, "offset", offset, "num", num,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定范围社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定范围社团列表"))
 		return
 	}
 
@@ -75,25 +98,30 @@ func (h *ClubHandler) GetClubList(ctx iris.Context) {
 				"error", err, "club", club,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
 		resClubList = append(resClubList, dto.ClubBasic{
-			ClubId:      int(club.ClubId),
-			ClubName:    club.Name,
-			LeaderId:    int(club.LeaderId),
-			Desc:        club.Description,
-			LogoUrl:     club.LogoUrl,
-			Category:    int(club.CategoryId),
-			Tags:        tags,
-			CreatedAt:   club.CreatedAt.Format("2006-01-02 15:04:05"),
-			MemberCount: int(club.MemberCount),
+			ClubId:       int(club.ClubId),
+			ClubName:     club.Name,
+			LeaderId:     int(club.LeaderId),
+			Desc:         club.Description,
+			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
+			Category:     int(club.CategoryId),
+			Tags:         tags,
+			CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
+			MemberCount:  int(club.MemberCount),
 		})
 	}
 
-	ctx.JSON(resClubList)
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resClubList))
+		return
+	}
+
+	WriteOK(ctx, resClubList)
 }
 
 func (h *ClubHandler) GetClubCategories(ctx iris.Context) {
@@ -101,8 +129,7 @@ func (h *ClubHandler) GetClubCategories(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Error("获取社团分类失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取社团分类")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取社团分类"))
 		return
 	}
 
@@ -114,7 +141,7 @@ func (h *ClubHandler) GetClubCategories(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resCategories)
+	WriteOK(ctx, resCategories)
 }
 
 func (h *ClubHandler) GetClubBasicInfo(ctx iris.Context, id int) {
@@ -124,21 +151,23 @@ func (h *ClubHandler) GetClubBasicInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团信息")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团信息"))
 		return
 	}
 
-	ctx.JSON(dto.ClubBasic{
+	WriteOK(ctx, dto.ClubBasic{
 		ClubId:       int(club.ClubId),
 		ClubName:     club.Name,
 		LeaderId:     int(club.LeaderId),
 		Desc:         club.Description,
 		Requirements: club.Requirements,
 		LogoUrl:      club.LogoUrl,
+		LogoVariants: model.ParseImageVariants(club.LogoVariants),
 		Category:     int(club.CategoryId),
 		Tags:         nil,
 		CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
+
+		RequirePostReview: club.RequirePostReview,
 	})
 }
 
@@ -149,8 +178,7 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团信息")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团信息"))
 		return
 	}
 
@@ -160,8 +188,7 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团成员列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团成员列表"))
 		return
 	}
 
@@ -185,8 +212,7 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团帖子列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团帖子列表"))
 		return
 	}
 
@@ -196,8 +222,7 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团置顶帖子")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团置顶帖子"))
 		return
 	}
 
@@ -225,6 +250,24 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			CreatedAt:    pinnedPost.CreatedAt.Format(time.DateTime),
 		})
 	}
+	fillPostReactions(ctx, h.ReactionService, h.Logger, resClubPosts)
+
+	eventNum := ctx.URLParamIntDefault("event_num", 3)
+
+	clubEvents, err := h.EventService.GetUpcomingEvents(id, eventNum)
+	if err != nil {
+		h.Logger.Error("获取社团活动列表失败",
+			"error", err, "club_id", id,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团活动列表"))
+		return
+	}
+
+	var resClubEvents []*dto.ClubEvent
+	for _, event := range clubEvents {
+		resClubEvents = append(resClubEvents, toClubEventDto(event))
+	}
 
 	var tags []string
 	err = h.sTagsToArray(club.Tags, &tags)
@@ -233,8 +276,7 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			"error", err, "club", club,
 		)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("无法解析社团标签")
+		WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 		return
 	}
 
@@ -245,17 +287,21 @@ func (h *ClubHandler) GetClubInfo(ctx iris.Context, id int) {
 			LeaderId:     int(club.LeaderId),
 			Desc:         club.Description,
 			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
 			Category:     int(club.CategoryId),
 			Tags:         tags,
 			CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
 			MemberCount:  int(club.MemberCount),
 			Requirements: club.Requirements,
+
+			RequirePostReview: club.RequirePostReview,
 		},
 		Members: resClubMems,
 		Posts:   resClubPosts,
+		Events:  resClubEvents,
 	}
 
-	ctx.JSON(resClub)
+	WriteOK(ctx, resClub)
 }
 
 func (h *ClubHandler) GetClubsByCategory(ctx iris.Context, catId int) {
@@ -265,8 +311,7 @@ func (h *ClubHandler) GetClubsByCategory(ctx iris.Context, catId int) {
 			"error", err, "cat_id", catId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定分类社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定分类社团列表"))
 		return
 	}
 
@@ -280,25 +325,25 @@ func (h *ClubHandler) GetClubsByCategory(ctx iris.Context, catId int) {
 				"error", err, "club", club,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
 		resClubsCat = append(resClubsCat, dto.ClubBasic{
-			ClubId:      int(club.ClubId),
-			ClubName:    club.Name,
-			LeaderId:    int(club.LeaderId),
-			Desc:        club.Description,
-			LogoUrl:     club.LogoUrl,
-			Category:    int(club.CategoryId),
-			Tags:        tags,
-			CreatedAt:   club.CreatedAt.Format("2006-01-02 15:04:05"),
-			MemberCount: int(club.MemberCount),
+			ClubId:       int(club.ClubId),
+			ClubName:     club.Name,
+			LeaderId:     int(club.LeaderId),
+			Desc:         club.Description,
+			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
+			Category:     int(club.CategoryId),
+			Tags:         tags,
+			CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
+			MemberCount:  int(club.MemberCount),
 		})
 	}
 
-	ctx.JSON(resClubsCat)
+	WriteOK(ctx, resClubsCat)
 }
 
 func (h *ClubHandler) GetLatestClubs(ctx iris.Context) {
@@ -308,8 +353,7 @@ func (h *ClubHandler) GetLatestClubs(ctx iris.Context) {
 			"error", err,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取最新社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取最新社团列表"))
 		return
 	}
 
@@ -323,25 +367,25 @@ func (h *ClubHandler) GetLatestClubs(ctx iris.Context) {
 				"error", err, "club", club,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
 		resClubs = append(resClubs, dto.ClubBasic{
-			ClubId:      int(club.ClubId),
-			ClubName:    club.Name,
-			LeaderId:    int(club.LeaderId),
-			Desc:        club.Description,
-			LogoUrl:     club.LogoUrl,
-			Category:    int(club.CategoryId),
-			Tags:        tags,
-			CreatedAt:   club.CreatedAt.Format("2006-01-02 15:04:05"),
-			MemberCount: int(club.MemberCount),
+			ClubId:       int(club.ClubId),
+			ClubName:     club.Name,
+			LeaderId:     int(club.LeaderId),
+			Desc:         club.Description,
+			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
+			Category:     int(club.CategoryId),
+			Tags:         tags,
+			CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
+			MemberCount:  int(club.MemberCount),
 		})
 	}
 
-	ctx.JSON(resClubs)
+	WriteOK(ctx, resClubs)
 }
 
 func (h *ClubHandler) GetClubNum(ctx iris.Context) {
@@ -349,12 +393,11 @@ func (h *ClubHandler) GetClubNum(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Error("获取社团总数失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取社团总数失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("获取社团总数失败"))
 		return
 	}
 
-	ctx.JSON(map[string]any{
+	WriteOK(ctx, map[string]any{
 		"club_num": cnt,
 	})
 }
@@ -364,7 +407,7 @@ func (h *ClubHandler) GetMyClubs(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Error("无法获取用户ID", "error", err)
 
-		ctx.StopWithStatus(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
@@ -374,8 +417,7 @@ func (h *ClubHandler) GetMyClubs(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取用户社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取用户社团列表"))
 		return
 	}
 
@@ -389,32 +431,31 @@ func (h *ClubHandler) GetMyClubs(ctx iris.Context) {
 				"error", err, "club", club,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
 		resClubs = append(resClubs, dto.ClubBasic{
-			ClubId:      int(club.ClubId),
-			ClubName:    club.Name,
-			LeaderId:    int(club.LeaderId),
-			Desc:        club.Description,
-			LogoUrl:     club.LogoUrl,
-			Category:    int(club.CategoryId),
-			Tags:        tags,
-			CreatedAt:   club.CreatedAt.Format("2006-01-02 15:04:05"),
-			MemberCount: int(club.MemberCount),
+			ClubId:       int(club.ClubId),
+			ClubName:     club.Name,
+			LeaderId:     int(club.LeaderId),
+			Desc:         club.Description,
+			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
+			Category:     int(club.CategoryId),
+			Tags:         tags,
+			CreatedAt:    club.CreatedAt.Format("2006-01-02 15:04:05"),
+			MemberCount:  int(club.MemberCount),
 		})
 	}
 
-	ctx.JSON(resClubs)
+	WriteOK(ctx, resClubs)
 }
 
 func (h *ClubHandler) GetMyCreateApplis(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取用户ID失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取用户ID失败"))
 		return
 	}
 
@@ -425,8 +466,7 @@ func (h *ClubHandler) GetMyCreateApplis(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取用户创建社团申请列表失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("获取用户创建社团申请列表失败"))
 		return
 	}
 
@@ -439,8 +479,7 @@ func (h *ClubHandler) GetMyCreateApplis(ctx iris.Context) {
 				"error", err, "appli", appli,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析创建社团申请")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析创建社团申请"))
 			return
 		}
 
@@ -451,8 +490,7 @@ func (h *ClubHandler) GetMyCreateApplis(ctx iris.Context) {
 				"error", err, "club", proposal,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
@@ -473,14 +511,13 @@ func (h *ClubHandler) GetMyCreateApplis(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resApplis)
+	WriteOK(ctx, resApplis)
 }
 
 func (h *ClubHandler) GetMyJoinApplis(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取用户ID失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取用户ID失败"))
 		return
 	}
 
@@ -491,8 +528,7 @@ func (h *ClubHandler) GetMyJoinApplis(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取用户加入社团申请列表失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("获取用户加入社团申请列表失败"))
 		return
 	}
 
@@ -510,14 +546,13 @@ func (h *ClubHandler) GetMyJoinApplis(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resApplis)
+	WriteOK(ctx, resApplis)
 }
 
 func (h *ClubHandler) GetMyFavorites(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("获取用户ID失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取用户ID失败"))
 		return
 	}
 
@@ -527,8 +562,7 @@ func (h *ClubHandler) GetMyFavorites(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取收藏社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取收藏社团列表"))
 		return
 	}
 
@@ -541,25 +575,25 @@ func (h *ClubHandler) GetMyFavorites(ctx iris.Context) {
 				"error", err, "club", club,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法解析社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法解析社团标签"))
 			return
 		}
 
 		resClubList = append(resClubList, dto.ClubBasic{
-			ClubId:      int(club.ClubId),
-			ClubName:    club.Name,
-			LeaderId:    int(club.LeaderId),
-			Category:    int(club.CategoryId),
-			Tags:        tags,
-			CreatedAt:   club.CreatedAt.Format(time.DateTime),
-			Desc:        club.Description,
-			LogoUrl:     club.LogoUrl,
-			MemberCount: int(club.MemberCount),
+			ClubId:       int(club.ClubId),
+			ClubName:     club.Name,
+			LeaderId:     int(club.LeaderId),
+			Category:     int(club.CategoryId),
+			Tags:         tags,
+			CreatedAt:    club.CreatedAt.Format(time.DateTime),
+			Desc:         club.Description,
+			LogoUrl:      club.LogoUrl,
+			LogoVariants: model.ParseImageVariants(club.LogoVariants),
+			MemberCount:  int(club.MemberCount),
 		})
 	}
 
-	ctx.JSON(resClubList)
+	WriteOK(ctx, resClubList)
 }
 
 func (h *ClubHandler) PostApplyForJoinClub(ctx iris.Context, id int) {
@@ -567,8 +601,7 @@ func (h *ClubHandler) PostApplyForJoinClub(ctx iris.Context, id int) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Error("请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -576,7 +609,7 @@ func (h *ClubHandler) PostApplyForJoinClub(ctx iris.Context, id int) {
 	if err != nil {
 		h.Logger.Error("无法获取用户ID", "error", err)
 
-		ctx.StopWithStatus(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
@@ -588,12 +621,11 @@ func (h *ClubHandler) PostApplyForJoinClub(ctx iris.Context, id int) {
 			"error", err, "user_id", userId, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法申请加入社团")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法申请加入社团"))
 		return
 	}
 
-	ctx.Text("加入社团申请成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubHandler) PostApplyForCreateClub(ctx iris.Context) {
@@ -603,8 +635,7 @@ func (h *ClubHandler) PostApplyForCreateClub(ctx iris.Context) {
 			"error", err,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取用户ID")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("无法获取用户ID"))
 		return
 	}
 
@@ -612,8 +643,7 @@ func (h *ClubHandler) PostApplyForCreateClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Error("请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -625,8 +655,7 @@ func (h *ClubHandler) PostApplyForCreateClub(ctx iris.Context) {
 				"error", err, "tags", reqBody.Tags,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法反序列化社团标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法反序列化社团标签"))
 			return
 		}
 	}
@@ -644,12 +673,11 @@ func (h *ClubHandler) PostApplyForCreateClub(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法申请创建社团")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法申请创建社团"))
 		return
 	}
 
-	ctx.Text("创建社团申请成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubHandler) PostFavoriteClub(ctx iris.Context) {
@@ -657,8 +685,7 @@ func (h *ClubHandler) PostFavoriteClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("收藏社团请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -666,20 +693,18 @@ func (h *ClubHandler) PostFavoriteClub(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Info("获取用户ID失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID获取失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
 		return
 	}
 
 	if err := h.ClubService.FavouriteClub(userId, reqBody.ClubId); err != nil {
 		h.Logger.Info("收藏社团失败", "error", err)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("收藏社团失败")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("收藏社团失败"))
 		return
 	}
 
-	ctx.Text("收藏社团成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubHandler) PostUnfavoriteClub(ctx iris.Context) {
@@ -687,8 +712,7 @@ func (h *ClubHandler) PostUnfavoriteClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("取消收藏社团请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -696,20 +720,18 @@ func (h *ClubHandler) PostUnfavoriteClub(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Info("获取用户ID失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID获取失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
 		return
 	}
 
 	if err := h.ClubService.UnfavouriteClub(userId, reqBody.ClubId); err != nil {
 		h.Logger.Info("取消收藏社团失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("取消收藏社团失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("取消收藏社团失败"))
 		return
 	}
 
-	ctx.Text("取消收藏社团成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubHandler) sTagsToArray(raw []byte, v *[]string) error {
@@ -743,8 +765,7 @@ func (h *ClubHandler) PostQuitClub(ctx iris.Context, id int) {
 	if err != nil {
 		h.Logger.Error("获取用户ID失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID无效"))
 		return
 	}
 
@@ -754,8 +775,7 @@ func (h *ClubHandler) PostQuitClub(ctx iris.Context, id int) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取用户社团列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取用户社团列表"))
 		return
 	}
 
@@ -766,8 +786,7 @@ func (h *ClubHandler) PostQuitClub(ctx iris.Context, id int) {
 			h.Logger.Error("用户是社团的负责人，无法退出",
 				"user_id", userId, "club_id", id,
 			)
-			ctx.StatusCode(iris.StatusBadRequest)
-			ctx.Text("用户是社团的负责人，无法退出")
+			WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户是社团的负责人，无法退出"))
 			return
 		}
 
@@ -780,8 +799,7 @@ func (h *ClubHandler) PostQuitClub(ctx iris.Context, id int) {
 		h.Logger.Error("用户不可以退出该社团",
 			"user_id", userId, "club_id", id,
 		)
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户不可以退出该社团")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户不可以退出该社团"))
 		return
 	}
 
@@ -790,10 +808,86 @@ func (h *ClubHandler) PostQuitClub(ctx iris.Context, id int) {
 			"error", err, "club_id", id, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法退出社团")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法退出社团"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *ClubHandler) GetMyLeaderTransfers(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取用户ID失败"))
+		return
+	}
+
+	transfers, err := h.ClubService.GetLeaderTransfersForUser(userId)
+	if err != nil {
+		h.Logger.Error("获取负责人移交列表失败",
+			"error", err, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取负责人移交列表"))
+		return
+	}
+
+	var resTransfers []*dto.LeaderTransferResponse
+	for _, transfer := range transfers {
+		resTransfers = append(resTransfers, &dto.LeaderTransferResponse{
+			TransferId: int(transfer.TransferId),
+			ClubId:     int(transfer.ClubId),
+			FromUserId: int(transfer.FromUserId),
+			ToUserId:   int(transfer.ToUserId),
+			Status:     transfer.Status,
+			CreatedAt:  transfer.CreatedAt.Format(time.DateTime),
+		})
+	}
+
+	WriteOK(ctx, resTransfers)
+}
+
+func (h *ClubHandler) PutProcLeaderTransfer(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("获取用户ID失败"))
+		return
+	}
+
+	var reqBody dto.ProcLeaderTransferRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("ProcLeaderTransfer请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
-	ctx.Text("退出社团成功")
+	switch reqBody.Result {
+	case "accept":
+		if err := h.ClubService.AcceptLeaderTransfer(reqBody.TransferId, userId); err != nil {
+			h.Logger.Info("接受负责人移交失败",
+				"error", err, "transfer_id", reqBody.TransferId,
+			)
+
+			WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("接受负责人移交失败"))
+			return
+		}
+
+		WriteOK(ctx, nil)
+
+	case "reject":
+		if err := h.ClubService.RejectLeaderTransfer(reqBody.TransferId, userId); err != nil {
+			h.Logger.Info("拒绝负责人移交失败",
+				"error", err, "transfer_id", reqBody.TransferId,
+			)
+
+			WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("拒绝负责人移交失败"))
+			return
+		}
+
+		WriteOK(ctx, nil)
+
+	default:
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("result参数错误"))
+	}
 }
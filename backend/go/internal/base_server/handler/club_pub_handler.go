@@ -2,11 +2,8 @@ package handler
 
 import (
 	"encoding/json"
-	"io"
 	"log/slog"
-	"os"
-	"path/filepath"
-	"strconv"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/service"
@@ -21,20 +18,29 @@ import (
 type ClubPubHandler struct {
 	JwtFactory *jwtutil.CliamsFactory[model.UserClaims]
 
-	ClubService service.ClubService
+	ClubService  service.ClubService
+	ImageService service.ImageService
+	RoleGuard    *ClubRoleGuard
 
 	Logger *slog.Logger
 }
 
 func (h *ClubPubHandler) BeforeActivation(b mvc.BeforeActivation) {
-	b.Handle("GET", "/join_applis/{id:int}", "GetJoinApplisForClub")
+	officerOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_OFFICER, ClubIdFromParam("id"))
+	viceLeaderOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, ClubIdFromParam("id"))
+	leaderOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_LEADER, ClubIdFromParam("id"))
+
+	b.Handle("GET", "/join_applis/{id:int}", "GetJoinApplisForClub", officerOnly)
 	b.Handle("GET", "/my_update_applis", "GetMyUpdateApplis")
 
-	b.Handle("POST", "/update/{id:int}", "PostApplyForUpdateClubInfo")
-	b.Handle("POST", "/update_logo/{id:int}", "PostUploadLogo")
-	b.Handle("POST", "/assemble/{id:int}", "PostAssembleClub")
+	b.Handle("POST", "/update/{id:int}", "PostApplyForUpdateClubInfo", leaderOnly)
+	b.Handle("POST", "/update_logo/{id:int}", "PostUploadLogo", viceLeaderOnly)
+	b.Handle("POST", "/assemble/{id:int}", "PostAssembleClub", leaderOnly)
 
 	b.Handle("PUT", "/proc_join", "PutProcAppliForJoinClub")
+	b.Handle("PUT", "/member_role/{id:int}", "PutChangeMemberRole", leaderOnly)
+	b.Handle("PUT", "/post_review/{id:int}", "PutPostReviewPolicy", leaderOnly)
+	b.Handle("POST", "/transfer/{id:int}", "PostNominateLeader", leaderOnly)
 }
 
 func (h *ClubPubHandler) PostApplyForUpdateClubInfo(ctx iris.Context, id int) {
@@ -42,8 +48,7 @@ func (h *ClubPubHandler) PostApplyForUpdateClubInfo(ctx iris.Context, id int) {
 	if err != nil {
 		h.Logger.Error("获取用户ID失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID无效"))
 		return
 	}
 
@@ -51,8 +56,7 @@ func (h *ClubPubHandler) PostApplyForUpdateClubInfo(ctx iris.Context, id int) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Error("请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
@@ -63,8 +67,7 @@ func (h *ClubPubHandler) PostApplyForUpdateClubInfo(ctx iris.Context, id int) {
 		if err != nil {
 			h.Logger.Error("序列化标签失败", "error", err)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法序列化标签")
+			WriteError(ctx, apperr.ErrInternal.WithMessage("无法序列化标签"))
 			return
 		}
 		jsonbData = datatypes.JSON(data)
@@ -85,12 +88,11 @@ func (h *ClubPubHandler) PostApplyForUpdateClubInfo(ctx iris.Context, id int) {
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法申请更新社团信息")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法申请更新社团信息"))
 		return
 	}
 
-	ctx.Text("更新社团信息申请成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubPubHandler) PutProcAppliForJoinClub(ctx iris.Context) {
@@ -98,8 +100,21 @@ func (h *ClubPubHandler) PutProcAppliForJoinClub(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("ProcCreateClub请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	appli, err := h.ClubService.GetJoinAppli(reqBody.JoinAppliId)
+	if err != nil {
+		h.Logger.Info("获取社团加入申请失败",
+			"error", err, "appli_id", reqBody.JoinAppliId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("社团加入申请不存在"))
+		return
+	}
+
+	if !h.RoleGuard.Authorize(ctx, int(appli.ClubId), dbstruct.ROLE_CLUB_OFFICER) {
 		return
 	}
 
@@ -110,8 +125,7 @@ func (h *ClubPubHandler) PutProcAppliForJoinClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.JoinAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("通过社团加入申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("通过社团加入申请失败"))
 			return
 		}
 
@@ -121,18 +135,16 @@ func (h *ClubPubHandler) PutProcAppliForJoinClub(ctx iris.Context) {
 				"error", err, "appli_id", reqBody.JoinAppliId,
 			)
 
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("拒绝社团加入申请失败")
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("拒绝社团加入申请失败"))
 			return
 		}
 
 	default:
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("result参数错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("result参数错误"))
 		return
 	}
 
-	ctx.Text("通过社团更新申请成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubPubHandler) GetJoinApplisForClub(ctx iris.Context, id int) {
@@ -141,8 +153,7 @@ func (h *ClubPubHandler) GetJoinApplisForClub(ctx iris.Context, id int) {
 	if err != nil {
 		h.Logger.Error("获取社团加入申请列表失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取社团加入申请列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取社团加入申请列表"))
 		return
 	}
 
@@ -160,99 +171,43 @@ func (h *ClubPubHandler) GetJoinApplisForClub(ctx iris.Context, id int) {
 		})
 	}
 
-	ctx.JSON(resApplisList)
+	WriteOK(ctx, resApplisList)
 }
 
 func (h *ClubPubHandler) PostUploadLogo(ctx iris.Context, id int) {
-	userRole := ctx.Values().GetString("user_claims_user_role")
-	if userRole == "" {
-		ctx.StatusCode(iris.StatusForbidden)
-		return
-	}
-
-	userId, err := ctx.Values().GetInt("user_claims_user_id")
-	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		return
-	}
-
-	clubs, err := h.ClubService.GetClubListByUserId(userId)
-	isLeader := false
-	for _, club := range clubs {
-		if int(club.ClubId) != id {
-			continue
-		}
-
-		if club.LeaderId == uint(userId) {
-			isLeader = true
-			break
-		}
-	}
-
-	if !isLeader {
-		ctx.StatusCode(iris.StatusForbidden)
-		return
-	}
-
 	file, _, err := ctx.FormFile("logo")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("未成功获取上传的logo文件")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("未成功获取上传的logo文件"))
 		return
 	}
 
 	defer file.Close()
 
-	if err := os.MkdirAll(CLUB_LOGO_DIR, os.ModePerm); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("目录创建失败")
-		return
-	}
-
-	filePath := filepath.Join(CLUB_LOGO_DIR, "_"+strconv.Itoa(id))
-	dst, err := os.Create(filePath)
+	variants, err := h.ImageService.StoreSquareVariants(ctx.Request().Context(), CLUB_LOGO_PREFIX, file)
 	if err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("文件创建失败")
-		return
-	}
-	defer dst.Close()
+		h.Logger.Info("保存社团logo失败", "error", err, "club_id", id)
 
-	if _, err := io.Copy(dst, file); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("文件保存失败")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("文件保存失败"))
 		return
 	}
 
-	if err := h.ClubService.UpdateClubLogo(id, filePath); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("更新数据库Logo URL失败")
+	if err := h.ClubService.UpdateClubLogo(id, variants); err != nil {
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("更新数据库Logo URL失败"))
 		return
 	}
 
-	ctx.JSON(iris.Map{"status": "文件上传成功", "path": filePath})
+	WriteOK(ctx, iris.Map{"status": "文件上传成功", "path": variants.Default(), "variants": variants})
 }
 
 func (h *ClubPubHandler) PostAssembleClub(ctx iris.Context, id int) {
-	userRole := ctx.Values().GetString("user_claims_user_role")
-	if userRole == "" ||
-		(userRole != dbstruct.ROLE_ADMIN &&
-			userRole != dbstruct.ROLE_PUBLISHER) {
-		h.Logger.Error("用户权限错误", "role", userRole)
-
-		ctx.StatusCode(iris.StatusForbidden)
-		return
-	}
-
 	if err := h.ClubService.DissambleClub(id); err != nil {
 		h.Logger.Error("解散社团失败", "error", err, "club_id", id)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("无法解散社团")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法解散社团"))
 		return
 	}
 
-	ctx.Text("社团解散成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *ClubPubHandler) GetMyUpdateApplis(ctx iris.Context) {
@@ -260,8 +215,7 @@ func (h *ClubPubHandler) GetMyUpdateApplis(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Error("获取用户ID失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID无效"))
 		return
 	}
 
@@ -269,8 +223,7 @@ func (h *ClubPubHandler) GetMyUpdateApplis(ctx iris.Context) {
 	if err != nil {
 		h.Logger.Error("获取社团更新申请列表失败", "error", err)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("无法获取社团更新申请列表")
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法获取社团更新申请列表"))
 		return
 	}
 
@@ -284,5 +237,79 @@ func (h *ClubPubHandler) GetMyUpdateApplis(ctx iris.Context) {
 		})
 	}
 
-	ctx.JSON(resApplis)
+	WriteOK(ctx, resApplis)
+}
+
+func (h *ClubPubHandler) PutChangeMemberRole(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		h.Logger.Error("获取用户ID失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID无效"))
+		return
+	}
+
+	var reqBody dto.ChangeMemberRoleRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("ChangeMemberRole请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	if err := h.ClubService.ChangeMemberRole(
+		id, userId, reqBody.UserId, reqBody.Role,
+	); err != nil {
+		h.Logger.Info("修改社团成员角色失败",
+			"error", err, "club_id", id,
+			"target_id", reqBody.UserId, "role", reqBody.Role,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法修改社团成员角色"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *ClubPubHandler) PutPostReviewPolicy(ctx iris.Context, id int) {
+	var reqBody dto.PostReviewPolicyRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("设置发帖审核请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	if err := h.ClubService.SetPostReviewPolicy(id, reqBody.RequireReview); err != nil {
+		h.Logger.Error("设置发帖审核失败",
+			"error", err, "club_id", id, "require_review", reqBody.RequireReview,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法设置发帖审核"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *ClubPubHandler) PostNominateLeader(ctx iris.Context, id int) {
+	var reqBody dto.NominateLeaderRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("NominateLeader请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	if err := h.ClubService.NominateLeader(id, reqBody.UserId); err != nil {
+		h.Logger.Info("提名社团负责人失败",
+			"error", err, "club_id", id, "nominee_id", reqBody.UserId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法提名社团负责人"))
+		return
+	}
+
+	WriteOK(ctx, nil)
 }
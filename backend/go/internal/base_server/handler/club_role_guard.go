@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"github.com/kataras/iris/v12"
+	"gorm.io/gorm"
+)
+
+const (
+	kCtxClubId     = "user_club_club_id"
+	kCtxRoleInClub = "user_club_role_in_club"
+)
+
+// ClubIdResolver 从请求中解析出路由所指向的社团ID
+type ClubIdResolver func(ctx iris.Context) (int, error)
+
+type ClubRoleGuard struct {
+	ClubService service.ClubService
+	PostService service.PostService
+
+	Logger *slog.Logger
+}
+
+func NewClubRoleGuard(
+	clubService service.ClubService,
+	postService service.PostService,
+	logger *slog.Logger,
+) *ClubRoleGuard {
+	return &ClubRoleGuard{
+		ClubService: clubService,
+		PostService: postService,
+		Logger:      logger,
+	}
+}
+
+// Require 生成按社团角色鉴权的中间件，调用者在对应社团中的角色不低于minRole才放行；
+// 路由指向的帖子、评论等不存在时返回对应的404错误
+func (g *ClubRoleGuard) Require(minRole string, resolver ClubIdResolver) iris.Handler {
+	return func(ctx iris.Context) {
+		clubId, err := resolver(ctx)
+		if err != nil {
+			g.Logger.Info("解析路由社团ID失败", "error", err, "path", ctx.Path())
+
+			WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法确定目标社团"))
+			return
+		}
+
+		if !g.Authorize(ctx, clubId, minRole) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Authorize 校验调用者在clubId社团中的角色，失败时直接写入响应并返回false
+func (g *ClubRoleGuard) Authorize(ctx iris.Context, clubId int, minRole string) bool {
+	userRole := ctx.Values().GetString("user_claims_user_role")
+	if userRole == "" {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return false
+	}
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return false
+	}
+
+	// 系统管理员视同社团负责人
+	if userRole == dbstruct.ROLE_ADMIN {
+		ctx.Values().Set(kCtxClubId, clubId)
+		ctx.Values().Set(kCtxRoleInClub, dbstruct.ROLE_CLUB_LEADER)
+		return true
+	}
+
+	member, err := g.ClubService.GetMemberInClub(clubId, userId)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			g.Logger.Error("查询社团成员角色失败",
+				"error", err, "user_id", userId, "club_id", clubId,
+			)
+
+			WriteError(ctx, apperr.ErrInternal)
+			return false
+		}
+
+		WriteError(ctx, apperr.ErrNotClubMember)
+		return false
+	}
+
+	if !model.HasClubPermission(member.RoleInClub, minRole) {
+		g.Logger.Info("社团角色权限不足",
+			"user_id", userId, "club_id", clubId,
+			"role_in_club", member.RoleInClub, "required", minRole,
+		)
+
+		WriteError(ctx, apperr.ErrClubRoleDenied)
+		return false
+	}
+
+	ctx.Values().Set(kCtxClubId, clubId)
+	ctx.Values().Set(kCtxRoleInClub, member.RoleInClub)
+
+	return true
+}
+
+// ClubIdFromParam 路由参数即为社团ID
+func ClubIdFromParam(name string) ClubIdResolver {
+	return func(ctx iris.Context) (int, error) {
+		return ctx.Params().GetInt(name)
+	}
+}
+
+// ClubIdFromPostParam 路由参数为帖子ID，取帖子所属社团
+func (g *ClubRoleGuard) ClubIdFromPostParam(name string) ClubIdResolver {
+	return func(ctx iris.Context) (int, error) {
+		postId, err := ctx.Params().GetInt(name)
+		if err != nil {
+			return 0, err
+		}
+
+		post, err := g.PostService.GetPostById(postId)
+		if err != nil {
+			return 0, err
+		}
+
+		return int(post.ClubId), nil
+	}
+}
+
+// ClubIdFromCommentParam 路由参数为评论ID，取评论所在帖子的所属社团
+func (g *ClubRoleGuard) ClubIdFromCommentParam(name string) ClubIdResolver {
+	return func(ctx iris.Context) (int, error) {
+		commentId, err := ctx.Params().GetInt(name)
+		if err != nil {
+			return 0, err
+		}
+
+		comment, err := g.PostService.GetCommentById(commentId)
+		if err != nil {
+			return 0, err
+		}
+
+		post, err := g.PostService.GetPostById(int(comment.PostId))
+		if err != nil {
+			return 0, err
+		}
+
+		return int(post.ClubId), nil
+	}
+}
+
+// ClubIdFromPostAppliParam 从路径参数中的发帖申请ID解析所属社团
+func (g *ClubRoleGuard) ClubIdFromPostAppliParam(name string) ClubIdResolver {
+	return func(ctx iris.Context) (int, error) {
+		appliId, err := ctx.Params().GetInt(name)
+		if err != nil {
+			return 0, err
+		}
+
+		appli, err := g.PostService.GetCreatePostAppli(appliId)
+		if err != nil {
+			return 0, err
+		}
+
+		return int(appli.ClubId), nil
+	}
+}
@@ -0,0 +1,499 @@
+package handler
+
+import (
+	"encoding/csv"
+	"log/slog"
+	"strconv"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/dto"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+	"whuclubsynapse-server/internal/shared/jwtutil"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+// EventHandler 挂载于 /api/club/{id}/events，路由参数id为社团ID
+type EventHandler struct {
+	CheckinJwtFactory *jwtutil.CliamsFactory[model.CheckinClaims]
+
+	EventService service.EventService
+	RoleGuard    *ClubRoleGuard
+
+	Logger *slog.Logger
+}
+
+func (h *EventHandler) BeforeActivation(b mvc.BeforeActivation) {
+	memberOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_MEMBER, ClubIdFromParam("id"))
+	officerOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_OFFICER, ClubIdFromParam("id"))
+	viceLeaderOnly := h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, ClubIdFromParam("id"))
+
+	b.Handle("GET", "/", "GetEventList")
+	b.Handle("GET", "/{eventId:int}", "GetEventInfo")
+	b.Handle("GET", "/{eventId:int}/rsvps", "GetEventRsvps", officerOnly)
+	b.Handle("GET", "/{eventId:int}/attendances", "GetEventAttendances", officerOnly)
+	b.Handle("GET", "/attendance_stats", "GetAttendanceStats", officerOnly)
+	b.Handle("GET", "/{eventId:int}/checkin_token", "GetCheckinToken", viceLeaderOnly)
+
+	b.Handle("POST", "/", "PostCreateEvent", viceLeaderOnly)
+	b.Handle("PUT", "/{eventId:int}", "PutUpdateEvent", viceLeaderOnly)
+	b.Handle("POST", "/{eventId:int}/cancel", "PostCancelEvent", viceLeaderOnly)
+
+	b.Handle("POST", "/{eventId:int}/rsvp", "PostRsvpEvent", memberOnly)
+	b.Handle("DELETE", "/{eventId:int}/rsvp", "DeleteRsvpEvent", memberOnly)
+	b.Handle("POST", "/{eventId:int}/checkin", "PostCheckin", memberOnly)
+}
+
+func toClubEventDto(event *dbstruct.ClubEvent) *dto.ClubEvent {
+	return &dto.ClubEvent{
+		EventId:     int(event.EventId),
+		ClubId:      int(event.ClubId),
+		CreatorId:   int(event.CreatorId),
+		Title:       event.Title,
+		Description: event.Description,
+		Location:    event.Location,
+		StartAt:     event.StartAt.Format(time.DateTime),
+		EndAt:       event.EndAt.Format(time.DateTime),
+		Capacity:    event.Capacity,
+		GoingCount:  event.GoingCount,
+		Status:      event.Status,
+		CreatedAt:   event.CreatedAt.Format(time.DateTime),
+	}
+}
+
+func (h *EventHandler) sParseEventRequest(reqBody *dto.EditEventRequest) (*dbstruct.ClubEvent, error) {
+	startAt, err := time.ParseInLocation(time.DateTime, reqBody.StartAt, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	endAt, err := time.ParseInLocation(time.DateTime, reqBody.EndAt, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dbstruct.ClubEvent{
+		Title:       reqBody.Title,
+		Description: reqBody.Description,
+		Location:    reqBody.Location,
+		StartAt:     startAt,
+		EndAt:       endAt,
+		Capacity:    reqBody.Capacity,
+	}, nil
+}
+
+func (h *EventHandler) GetEventList(ctx iris.Context) {
+	clubId, err := ctx.Params().GetInt("id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("社团ID无效"))
+		return
+	}
+
+	offset := ctx.URLParamIntDefault("offset", 0)
+	num := ctx.URLParamIntDefault("num", 10)
+
+	events, err := h.EventService.GetEventList(clubId, offset, num)
+	if err != nil {
+		h.Logger.Error("获取社团活动列表失败",
+			"error", err, "club_id", clubId, "offset", offset, "num", num,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取社团活动列表"))
+		return
+	}
+
+	resEvents := make([]*dto.ClubEvent, 0, len(events))
+	for _, event := range events {
+		resEvents = append(resEvents, toClubEventDto(event))
+	}
+
+	WriteOK(ctx, resEvents)
+}
+
+func (h *EventHandler) GetEventInfo(ctx iris.Context) {
+	clubId, err := ctx.Params().GetInt("id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("社团ID无效"))
+		return
+	}
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	event, err := h.EventService.GetEvent(clubId, eventId)
+	if err != nil {
+		h.Logger.Info("获取活动信息失败",
+			"error", err, "club_id", clubId, "event_id", eventId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrNotFound.WithMessage("活动不存在"))
+		return
+	}
+
+	WriteOK(ctx, toClubEventDto(event))
+}
+
+func (h *EventHandler) GetEventRsvps(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	rsvps, err := h.EventService.GetEventRsvps(clubId, eventId)
+	if err != nil {
+		h.Logger.Info("获取活动报名列表失败",
+			"error", err, "club_id", clubId, "event_id", eventId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取活动报名列表"))
+		return
+	}
+
+	resRsvps := make([]*dto.EventRsvp, 0, len(rsvps))
+	for _, rsvp := range rsvps {
+		resRsvps = append(resRsvps, &dto.EventRsvp{
+			UserId:    int(rsvp.UserId),
+			Status:    rsvp.Status,
+			UpdatedAt: rsvp.UpdatedAt.Format(time.DateTime),
+		})
+	}
+
+	WriteOK(ctx, resRsvps)
+}
+
+func (h *EventHandler) PostCreateEvent(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	var reqBody dto.EditEventRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("CreateEvent请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	event, err := h.sParseEventRequest(&reqBody)
+	if err != nil {
+		h.Logger.Info("活动时间格式错误", "error", err, "req", reqBody)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动时间格式错误"))
+		return
+	}
+
+	event.ClubId = uint(clubId)
+	event.CreatorId = uint(userId)
+
+	if err := h.EventService.CreateEvent(event); err != nil {
+		h.Logger.Info("创建活动失败",
+			"error", err, "club_id", clubId, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("创建活动失败"))
+		return
+	}
+
+	WriteOK(ctx, toClubEventDto(event))
+}
+
+func (h *EventHandler) PutUpdateEvent(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	var reqBody dto.EditEventRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("UpdateEvent请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	newInfo, err := h.sParseEventRequest(&reqBody)
+	if err != nil {
+		h.Logger.Info("活动时间格式错误", "error", err, "req", reqBody)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动时间格式错误"))
+		return
+	}
+
+	if err := h.EventService.UpdateEvent(clubId, eventId, *newInfo); err != nil {
+		h.sWriteEventError(ctx, err, "编辑活动失败", clubId, eventId)
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *EventHandler) PostCancelEvent(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	if err := h.EventService.CancelEvent(clubId, eventId); err != nil {
+		h.sWriteEventError(ctx, err, "取消活动失败", clubId, eventId)
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *EventHandler) PostRsvpEvent(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	status, err := h.EventService.RsvpEvent(clubId, eventId, userId)
+	if err != nil {
+		h.sWriteEventError(ctx, err, "报名活动失败", clubId, eventId)
+		return
+	}
+
+	WriteOK(ctx, dto.RsvpResponse{
+		EventId: eventId,
+		Status:  status,
+	})
+}
+
+func (h *EventHandler) DeleteRsvpEvent(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	if err := h.EventService.CancelRsvp(clubId, eventId, userId); err != nil {
+		h.sWriteEventError(ctx, err, "取消报名失败", clubId, eventId)
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+// GetCheckinToken 签发短期签到凭证，前端据此生成二维码并在过期前刷新
+func (h *EventHandler) GetCheckinToken(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	if _, err := h.EventService.CheckCheckinOpen(clubId, eventId); err != nil {
+		h.sWriteEventError(ctx, err, "活动当前不可签到", clubId, eventId)
+		return
+	}
+
+	token, err := h.CheckinJwtFactory.GenToken(model.CheckinClaims{
+		Purpose:  model.CLAIMS_PURPOSE_CHECKIN,
+		EventId:  eventId,
+		ClubId:   clubId,
+		IssuerId: userId,
+	})
+	if err != nil {
+		h.Logger.Error("生成签到凭证失败",
+			"error", err, "club_id", clubId, "event_id", eventId,
+		)
+
+		WriteError(ctx, apperr.ErrInternal.WithMessage("生成签到凭证失败"))
+		return
+	}
+
+	WriteOK(ctx, dto.CheckinTokenResponse{
+		Token:    token,
+		ExpireAt: time.Now().Add(h.CheckinJwtFactory.ExpirationTime).Format(time.DateTime),
+	})
+}
+
+func (h *EventHandler) PostCheckin(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	var reqBody dto.CheckinRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("Checkin请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	claims, err := h.CheckinJwtFactory.ParseToken(reqBody.Token)
+	if err != nil {
+		h.Logger.Info("签到凭证解析失败",
+			"error", err, "user_id", userId, "event_id", eventId,
+		)
+
+		WriteError(ctx, apperr.ErrForbidden.WithMessage("签到凭证无效或已过期"))
+		return
+	}
+
+	if claims.Purpose != model.CLAIMS_PURPOSE_CHECKIN ||
+		claims.EventId != eventId || claims.ClubId != clubId {
+		h.Logger.Info("签到凭证与活动不匹配",
+			"claims", claims, "user_id", userId, "event_id", eventId,
+		)
+
+		WriteError(ctx, apperr.ErrForbidden.WithMessage("签到凭证无效或已过期"))
+		return
+	}
+
+	if err := h.EventService.CheckIn(clubId, eventId, userId); err != nil {
+		h.sWriteEventError(ctx, err, "签到失败", clubId, eventId)
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *EventHandler) GetEventAttendances(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	eventId, err := ctx.Params().GetInt("eventId")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("活动ID无效"))
+		return
+	}
+
+	attendances, err := h.EventService.GetEventAttendances(clubId, eventId)
+	if err != nil {
+		h.sWriteEventError(ctx, err, "无法获取活动签到列表", clubId, eventId)
+		return
+	}
+
+	resAttendances := make([]*dto.EventAttendance, 0, len(attendances))
+	for _, attendance := range attendances {
+		resAttendances = append(resAttendances, &dto.EventAttendance{
+			UserId:      int(attendance.UserId),
+			CheckedInAt: attendance.CheckedInAt.Format(time.DateTime),
+		})
+	}
+
+	WriteOK(ctx, resAttendances)
+}
+
+// GetAttendanceStats 成员出勤统计，format=csv时以附件形式导出
+func (h *EventHandler) GetAttendanceStats(ctx iris.Context) {
+	clubId := ctx.Values().GetIntDefault(kCtxClubId, 0)
+
+	stats, heldNum, err := h.EventService.GetAttendanceStats(clubId)
+	if err != nil {
+		h.Logger.Error("获取出勤统计失败", "error", err, "club_id", clubId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法获取出勤统计"))
+		return
+	}
+
+	resStats := make([]*dto.AttendanceStat, 0, len(stats))
+	for _, stat := range stats {
+		resStat := &dto.AttendanceStat{
+			UserId:      int(stat.UserId),
+			RoleInClub:  stat.RoleInClub,
+			AttendedNum: stat.AttendedNum,
+			HeldNum:     int(heldNum),
+		}
+		if heldNum > 0 {
+			resStat.AttendanceRate = float64(stat.AttendedNum) / float64(heldNum)
+		}
+		if stat.LastCheckedIn != nil {
+			resStat.LastCheckedIn = stat.LastCheckedIn.Format(time.DateTime)
+		}
+
+		resStats = append(resStats, resStat)
+	}
+
+	if ctx.URLParamDefault("format", "json") != "csv" {
+		WriteOK(ctx, resStats)
+		return
+	}
+
+	ctx.ContentType("text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition",
+		"attachment; filename=attendance_club_"+strconv.Itoa(clubId)+".csv",
+	)
+
+	writer := csv.NewWriter(ctx.ResponseWriter())
+	_ = writer.Write([]string{
+		"user_id", "role_in_club", "attended_num", "held_num", "attendance_rate", "last_checked_in",
+	})
+	for _, stat := range resStats {
+		_ = writer.Write([]string{
+			strconv.Itoa(stat.UserId),
+			stat.RoleInClub,
+			strconv.Itoa(stat.AttendedNum),
+			strconv.Itoa(stat.HeldNum),
+			strconv.FormatFloat(stat.AttendanceRate, 'f', 4, 64),
+			stat.LastCheckedIn,
+		})
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		h.Logger.Error("导出出勤统计失败", "error", err, "club_id", clubId)
+	}
+}
+
+func (h *EventHandler) sWriteEventError(ctx iris.Context, err error, msg string, clubId, eventId int) {
+	h.Logger.Info(msg,
+		"error", err, "club_id", clubId, "event_id", eventId,
+	)
+
+	WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage(msg))
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/dto"
+	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+const (
+	kSSEHeartbeatInterval = 30 * time.Second
+)
+
+type NotificationHandler struct {
+	NotificationService service.NotificationService
+	AuthService         service.AuthService
+
+	Logger *slog.Logger
+}
+
+func (h *NotificationHandler) BeforeActivation(b mvc.BeforeActivation) {
+	b.Handle("GET", "/list", "GetNotificationList")
+	b.Handle("GET", "/unread_num", "GetUnreadNum")
+	b.Handle("GET", "/stream", "GetNotificationStream")
+	b.Handle("POST", "/stream_ticket", "PostStreamTicket")
+
+	b.Handle("PUT", "/read/{id:int}", "PutMarkRead")
+	b.Handle("PUT", "/read_all", "PutMarkAllRead")
+}
+
+func toNotificationDto(n *dbstruct.Notification) dto.Notification {
+	return dto.Notification{
+		NotificationId: n.NotificationId,
+		Type:           n.Type,
+		Title:          n.Title,
+		Content:        n.Content,
+		RefId:          n.RefId,
+		IsRead:         n.IsRead,
+		CreatedAt:      n.CreatedAt.Format(time.DateTime),
+	}
+}
+
+func (h *NotificationHandler) GetNotificationList(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	offset := ctx.URLParamIntDefault("offset", 0)
+	num := ctx.URLParamIntDefault("num", 10)
+	unreadOnly := ctx.URLParamBoolDefault("unread_only", false)
+
+	notifications, err := h.NotificationService.
+		GetNotifications(userId, offset, num, unreadOnly)
+	if err != nil {
+		h.Logger.Error("获取通知列表失败",
+			"error", err, "user_id", userId, "offset", offset, "num", num,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取通知列表失败"))
+		return
+	}
+
+	resList := make([]dto.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		resList = append(resList, toNotificationDto(n))
+	}
+
+	WriteOK(ctx, resList)
+}
+
+func (h *NotificationHandler) GetUnreadNum(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	unreadNum, err := h.NotificationService.GetUnreadNum(userId)
+	if err != nil {
+		h.Logger.Error("获取未读通知数失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取未读通知数失败"))
+		return
+	}
+
+	WriteOK(ctx, dto.UnreadNumResponse{UnreadNum: unreadNum})
+}
+
+func (h *NotificationHandler) PutMarkRead(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	if err := h.NotificationService.MarkRead(userId, id); err != nil {
+		h.Logger.Error("标记通知已读失败",
+			"error", err, "user_id", userId, "notification_id", id,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("标记通知已读失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *NotificationHandler) PutMarkAllRead(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	if err := h.NotificationService.MarkAllRead(userId); err != nil {
+		h.Logger.Error("标记全部通知已读失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("标记全部通知已读失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+// PostStreamTicket EventSource无法携带Authorization请求头，
+// 前端先以此接口换取一次性凭证，再以/stream?ticket=<凭证>建立连接
+func (h *NotificationHandler) PostStreamTicket(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+	userRole := ctx.Values().GetString("user_claims_user_role")
+
+	ticket, err := h.AuthService.IssueStreamTicket(userId, userRole)
+	if err != nil {
+		h.Logger.Error("签发推送连接凭证失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("签发推送连接凭证失败"))
+		return
+	}
+
+	WriteOK(ctx, dto.StreamTicketResponse{Ticket: ticket})
+}
+
+// GetNotificationStream 以SSE方式向客户端实时推送新通知
+func (h *NotificationHandler) GetNotificationStream(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	flusher, ok := ctx.ResponseWriter().Flusher()
+	if !ok {
+		WriteError(ctx, apperr.ErrInternal.WithMessage("当前连接不支持流式推送"))
+		return
+	}
+
+	ch, cancel := h.NotificationService.Subscribe(uint(userId))
+	defer cancel()
+
+	ctx.ContentType("text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.StatusCode(iris.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(kSSEHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	reqCtx := ctx.Request().Context()
+	for {
+		select {
+		case <-reqCtx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.ResponseWriter(), ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case n, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(toNotificationDto(n))
+			if err != nil {
+				h.Logger.Error("序列化通知失败",
+					"error", err, "notification_id", n.NotificationId,
+				)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(ctx.ResponseWriter(),
+				"id: %d\nevent: notification\ndata: %s\n\n",
+				n.NotificationId, data,
+			); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
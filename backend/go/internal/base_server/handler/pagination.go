@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"whuclubsynapse-server/internal/base_server/dto"
+	"whuclubsynapse-server/internal/base_server/model"
+
+	"github.com/kataras/iris/v12"
+)
+
+// parseCursorParam 请求带有cursor参数时启用游标分页，首页传空串即可；
+// 不带cursor参数时沿用offset分页
+func parseCursorParam(ctx iris.Context) (*model.Cursor, bool, error) {
+	if !ctx.URLParamExists("cursor") {
+		return nil, false, nil
+	}
+
+	token := ctx.URLParam("cursor")
+	if token == "" {
+		return nil, true, nil
+	}
+
+	cursor, err := model.DecodeCursor(token)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return cursor, true, nil
+}
+
+func toCursorPage[T, R any](page *model.Page[T], items []R) dto.CursorPage[R] {
+	if items == nil {
+		items = []R{}
+	}
+
+	return dto.CursorPage[R]{
+		Items:      items,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+}
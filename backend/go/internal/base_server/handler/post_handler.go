@@ -5,8 +5,10 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
-	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/service"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
@@ -14,9 +16,14 @@ import (
 	"github.com/kataras/iris/v12/mvc"
 )
 
+// 附件上传请求体的上限，单个附件的大小由AttachmentService按配置校验
+const kAttachmentBodyMaxSize = 64 << 20
+
 type PostHandler struct {
-	PostService  service.PostService
-	RedisService redisimpl.RedisClientService
+	PostService       service.PostService
+	ReactionService   service.ReactionService
+	AttachmentService service.AttachmentService
+	RoleGuard         *ClubRoleGuard
 
 	Logger *slog.Logger
 }
@@ -28,6 +35,27 @@ func (h *PostHandler) BeforeActivation(b mvc.BeforeActivation) {
 
 	b.Handle("POST", "/create", "PostCreatePost")
 	b.Handle("POST", "/comment", "PostCreatePostComment")
+	b.Handle("PUT", "/comment/{id:int}", "PutEditComment")
+	b.Handle("DELETE", "/comment/{id:int}", "DeleteComment")
+
+	b.Handle("PUT", "/edit/{id:int}", "PutEditPost")
+	b.Handle("DELETE", "/delete/{id:int}", "DeletePost")
+	b.Handle("GET", "/revisions/{id:int}", "GetPostRevisions")
+	b.Handle("GET", "/diff/{id:int}", "GetPostDiff")
+
+	b.Handle("GET", "/drafts", "GetDrafts")
+	b.Handle("POST", "/draft", "PostSaveDraft")
+	b.Handle("PUT", "/draft/{id:int}", "PutUpdateDraft")
+	b.Handle("PUT", "/draft/publish/{id:int}", "PutPublishDraft")
+	b.Handle("GET", "/my_applis", "GetMyPostApplis")
+
+	b.Handle("POST", "/attachments/{id:int}", "PostUploadAttachment",
+		iris.LimitRequestBodySize(kAttachmentBodyMaxSize))
+	b.Handle("GET", "/attachments/{id:int}", "GetAttachments")
+	b.Handle("DELETE", "/attachment/{id:int}", "DeleteAttachment")
+
+	b.Handle("PUT", "/react/{target:string}/{id:int}", "PutReact")
+	b.Handle("DELETE", "/react/{target:string}/{id:int}", "DeleteReact")
 }
 
 func (h *PostHandler) GetPostList(ctx iris.Context, id int) {
@@ -36,7 +64,7 @@ func (h *PostHandler) GetPostList(ctx iris.Context, id int) {
 
 	userRole := ctx.Values().GetString("user_claims_user_role")
 	if userRole == "" {
-		ctx.StopWithStatus(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
@@ -50,15 +78,32 @@ func (h *PostHandler) GetPostList(ctx iris.Context, id int) {
 		visibility = 2
 	}
 
-	clubPosts, err := h.PostService.
-		GetPostList(id, offset, postNum, visibility)
+	cursor, cursorMode, err := parseCursorParam(ctx)
+	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
+		return
+	}
+
+	var clubPosts []*dbstruct.ClubPost
+	var page *model.Page[*dbstruct.ClubPost]
+	if cursorMode {
+		page, err = h.PostService.
+			GetPostListByCursor(id, cursor, postNum, visibility)
+		if err == nil {
+			clubPosts = page.Items
+		}
+	} else {
+		clubPosts, err = h.PostService.
+			GetPostList(id, offset, postNum, visibility)
+	}
 	if err != nil {
 		h.Logger.Error("获取社团帖子列表失败",
 			"error", err, "club_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定社团帖子列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定社团帖子列表"))
 		return
 	}
 
@@ -76,18 +121,24 @@ func (h *PostHandler) GetPostList(ctx iris.Context, id int) {
 		})
 	}
 
-	ctx.JSON(resClubPosts)
+	fillPostReactions(ctx, h.ReactionService, h.Logger, resClubPosts)
+
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resClubPosts))
+		return
+	}
+
+	WriteOK(ctx, resClubPosts)
 }
 
 func (h *PostHandler) GetPinnedPost(ctx iris.Context, id int) {
 	pinnedPost, err := h.PostService.GetPinnedPost(id)
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取置顶帖")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取置顶帖"))
 		return
 	}
 
-	ctx.JSON(dto.ClubPostBasic{
+	resPost := &dto.ClubPostBasic{
 		PostId:       int(pinnedPost.PostId),
 		ClubId:       int(pinnedPost.ClubId),
 		Title:        pinnedPost.Title,
@@ -95,44 +146,66 @@ func (h *PostHandler) GetPinnedPost(ctx iris.Context, id int) {
 		AuthorId:     int(pinnedPost.UserId),
 		CommentCount: int(pinnedPost.CommentCount),
 		CreatedAt:    pinnedPost.CreatedAt.Format("2006-01-02 15:04:05"),
-	})
+	}
+	if pinnedPost.PostId != 0 {
+		fillPostReactions(ctx, h.ReactionService, h.Logger, []*dto.ClubPostBasic{resPost})
+	}
+
+	WriteOK(ctx, resPost)
 }
 
 func (h *PostHandler) GetPostComments(ctx iris.Context, id int) {
 	userRole := ctx.Values().GetString("user_claims_user_role")
 	if userRole == "" {
-		ctx.StopWithStatus(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
+		return
+	}
+
+	commentNum := ctx.URLParamIntDefault("comment_num", 20)
+	offset := ctx.URLParamIntDefault("offset", 0)
+
+	cursor, cursorMode, err := parseCursorParam(ctx)
+	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
 		return
 	}
 
-	comments, err := h.PostService.GetPostComments(id)
+	var roots, replies []*dbstruct.ClubPostComment
+	var page *model.Page[*dbstruct.ClubPostComment]
+	if cursorMode {
+		page, replies, err = h.PostService.
+			GetPostCommentsByCursor(id, cursor, commentNum)
+		if err == nil {
+			roots = page.Items
+		}
+	} else {
+		roots, replies, err = h.PostService.
+			GetPostComments(id, offset, commentNum)
+	}
 	if err != nil {
 		h.Logger.Error("获取帖子评论失败", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定帖子评论")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定帖子评论"))
 		return
 	}
 
-	var resComments []*dto.PostComment
-	for _, comment := range comments {
-		resComments = append(resComments, &dto.PostComment{
-			CommentId: int(comment.CommentId),
-			PostId:    int(comment.PostId),
-			UserId:    int(comment.UserId),
-			Content:   comment.Content,
-			CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+	resComments := sBuildCommentTree(roots, replies)
+	fillCommentReactions(ctx, h.ReactionService, h.Logger, resComments)
+
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resComments))
+		return
 	}
 
-	ctx.JSON(resComments)
+	WriteOK(ctx, resComments)
 }
 
 func (h *PostHandler) PostCreatePost(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID获取失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
 		return
 	}
 
@@ -146,8 +219,7 @@ func (h *PostHandler) PostCreatePost(ctx iris.Context) {
 			"error", err,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("转化社团ID失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("转化社团ID失败"))
 		return
 	}
 
@@ -168,45 +240,619 @@ func (h *PostHandler) PostCreatePost(ctx iris.Context) {
 			"error", err,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("创建帖子失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("创建帖子失败"))
 		return
 	}
 
-	if err := h.RedisService.UploadPostInfo(&newPost); err != nil {
-		h.Logger.Error("上传帖子信息失败",
-			"error", err, "post_id", newPost.PostId,
-			"path", newPost.ContentUrl,
-		)
-	}
-
-	ctx.Text("创建帖子成功")
+	WriteOK(ctx, iris.Map{"post_id": newPost.PostId, "status": newPost.Status})
 }
 
 func (h *PostHandler) PostCreatePostComment(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
 	var newComment dto.CreateCommentRequest
 	if err := ctx.ReadJSON(&newComment); err != nil {
 		h.Logger.Error("解析请求失败",
 			"error", err,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("解析请求失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	if strings.TrimSpace(newComment.Content) == "" {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("评论内容不能为空"))
 		return
 	}
 
-	if err := h.PostService.CreatePostComment(dbstruct.ClubPostComment{
+	comment := dbstruct.ClubPostComment{
 		Content: newComment.Content,
 		PostId:  uint(newComment.PostId),
-		UserId:  uint(newComment.UserId),
-	}); err != nil {
+		UserId:  uint(userId),
+	}
+	if newComment.ParentCommentId > 0 {
+		parentId := uint(newComment.ParentCommentId)
+		comment.ParentCommentId = &parentId
+	}
+
+	if err := h.PostService.CreatePostComment(&comment); err != nil {
 		h.Logger.Error("创建帖子评论失败",
-			"error", err,
+			"error", err, "post_id", newComment.PostId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("创建帖子评论失败"))
+		return
+	}
+
+	WriteOK(ctx, sToCommentDto(&comment))
+}
+
+func (h *PostHandler) PutEditComment(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.EditCommentRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("解析请求失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	if strings.TrimSpace(reqBody.Content) == "" {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("评论内容不能为空"))
+		return
+	}
+
+	if err := h.PostService.EditComment(id, userId, reqBody.Content); err != nil {
+		h.Logger.Error("编辑评论失败",
+			"error", err, "comment_id", id, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("编辑评论失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostHandler) DeleteComment(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	if err := h.PostService.DeleteComment(id, userId); err != nil {
+		h.Logger.Error("删除评论失败",
+			"error", err, "comment_id", id, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("创建帖子评论失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("删除评论失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostHandler) PutEditPost(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.EditPostRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("解析请求失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	post, appli, err := h.PostService.EditPost(id, userId, reqBody.Title, reqBody.Content)
+	if err != nil {
+		h.Logger.Error("编辑帖子失败",
+			"error", err, "post_id", id, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("编辑帖子失败"))
+		return
+	}
+
+	// 修改需经审核时返回改帖申请，帖子仍展示原内容
+	if appli != nil {
+		WriteOK(ctx, toCreatePostAppliDto(appli))
+		return
+	}
+
+	resPost := &dto.ClubPostBasic{
+		PostId:       int(post.PostId),
+		ClubId:       int(post.ClubId),
+		Title:        post.Title,
+		IsPinned:     post.IsPinned,
+		ContentUrl:   post.ContentUrl,
+		AuthorId:     int(post.UserId),
+		CommentCount: int(post.CommentCount),
+		CreatedAt:    post.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	fillPostReactions(ctx, h.ReactionService, h.Logger, []*dto.ClubPostBasic{resPost})
+
+	WriteOK(ctx, resPost)
+}
+
+func (h *PostHandler) DeletePost(ctx iris.Context, id int) {
+	if !h.sAuthorizeAuthorOrLeader(ctx, id) {
+		return
+	}
+
+	if err := h.PostService.DeletePost(id); err != nil {
+		h.Logger.Error("删除帖子失败",
+			"error", err, "post_id", id,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("删除帖子失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostHandler) GetPostRevisions(ctx iris.Context, id int) {
+	if !h.sAuthorizeAuthorOrLeader(ctx, id) {
+		return
+	}
+
+	revisions, err := h.PostService.GetPostRevisions(id)
+	if err != nil {
+		h.Logger.Error("获取帖子历史版本失败",
+			"error", err, "post_id", id,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取帖子历史版本"))
+		return
+	}
+
+	resRevisions := make([]*dto.PostRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		resRevisions = append(resRevisions, &dto.PostRevision{
+			Version:     revision.Version,
+			Title:       revision.Title,
+			ContentUrl:  revision.ContentUrl,
+			PublishedAt: revision.PublishedAt.Format(time.DateTime),
+			ArchivedAt:  revision.CreatedAt.Format(time.DateTime),
+		})
+	}
+
+	WriteOK(ctx, dto.PostRevisionList{
+		PostId:         id,
+		CurrentVersion: len(revisions) + 1,
+		Revisions:      resRevisions,
+	})
+}
+
+func (h *PostHandler) GetPostDiff(ctx iris.Context, id int) {
+	if !h.sAuthorizeAuthorOrLeader(ctx, id) {
+		return
+	}
+
+	from := ctx.URLParamIntDefault("from", 0)
+	to := ctx.URLParamIntDefault("to", 0)
+
+	diff, err := h.PostService.DiffPostRevisions(id, from, to)
+	if err != nil {
+		h.Logger.Error("比较帖子版本失败",
+			"error", err, "post_id", id, "from", from, "to", to,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法比较帖子版本"))
+		return
+	}
+
+	resLines := make([]*dto.PostDiffLine, 0, len(diff.Lines))
+	for _, line := range diff.Lines {
+		resLines = append(resLines, &dto.PostDiffLine{
+			Op:      line.Op,
+			Content: line.Content,
+		})
+	}
+
+	WriteOK(ctx, dto.PostDiffResponse{
+		PostId:      int(diff.PostId),
+		FromVersion: diff.FromVersion,
+		ToVersion:   diff.ToVersion,
+		FromTitle:   diff.FromTitle,
+		ToTitle:     diff.ToTitle,
+		Lines:       resLines,
+	})
+}
+
+func (h *PostHandler) GetDrafts(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	drafts, err := h.PostService.GetDrafts(userId)
+	if err != nil {
+		h.Logger.Error("获取草稿列表失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取草稿列表"))
+		return
+	}
+
+	resDrafts := make([]*dto.PostDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		resDrafts = append(resDrafts, sToDraftDto(draft))
+	}
+
+	WriteOK(ctx, resDrafts)
+}
+
+func (h *PostHandler) PostSaveDraft(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.SaveDraftRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("解析请求失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	publishAt, err := sParsePublishAt(reqBody.PublishAt)
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("定时发布时间格式错误"))
+		return
+	}
+
+	draft := dbstruct.ClubPost{
+		UserId:       uint(userId),
+		ClubId:       uint(reqBody.ClubId),
+		Title:        reqBody.Title,
+		DraftContent: reqBody.Content,
+		PublishAt:    publishAt,
 	}
 
-	ctx.Text("创建帖子评论成功")
+	if err := h.PostService.SaveDraft(&draft); err != nil {
+		h.Logger.Error("保存草稿失败",
+			"error", err, "user_id", userId, "club_id", reqBody.ClubId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("保存草稿失败"))
+		return
+	}
+
+	WriteOK(ctx, sToDraftDto(&draft))
+}
+
+func (h *PostHandler) PutUpdateDraft(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.SaveDraftRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("解析请求失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	publishAt, err := sParsePublishAt(reqBody.PublishAt)
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("定时发布时间格式错误"))
+		return
+	}
+
+	draft, err := h.PostService.UpdateDraft(id, userId, reqBody.Title, reqBody.Content, publishAt)
+	if err != nil {
+		h.Logger.Error("更新草稿失败",
+			"error", err, "post_id", id, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("更新草稿失败"))
+		return
+	}
+
+	WriteOK(ctx, sToDraftDto(draft))
+}
+
+func (h *PostHandler) PutPublishDraft(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	post, err := h.PostService.PublishDraft(id, userId)
+	if err != nil {
+		h.Logger.Error("发布草稿失败",
+			"error", err, "post_id", id, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("发布草稿失败"))
+		return
+	}
+
+	if post.Status == dbstruct.POST_STATUS_PENDING {
+		WriteOK(ctx, sToDraftDto(post))
+		return
+	}
+
+	WriteOK(ctx, dto.ClubPostBasic{
+		PostId:     int(post.PostId),
+		ClubId:     int(post.ClubId),
+		Title:      post.Title,
+		IsPinned:   post.IsPinned,
+		ContentUrl: post.ContentUrl,
+		AuthorId:   int(post.UserId),
+		CreatedAt:  post.CreatedAt.Format("2006-01-02 15:04:05"),
+		Reactions:  map[string]int64{},
+	})
+}
+
+func (h *PostHandler) PutReact(ctx iris.Context, target string, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.ReactRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("解析请求失败", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("解析请求失败"))
+		return
+	}
+
+	if err := h.ReactionService.React(userId, target, id, reqBody.Kind); err != nil {
+		h.Logger.Error("添加回应失败",
+			"error", err, "target", target, "target_id", id, "kind", reqBody.Kind,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("添加回应失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostHandler) DeleteReact(ctx iris.Context, target string, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	if err := h.ReactionService.Unreact(userId, target, id); err != nil {
+		h.Logger.Error("取消回应失败",
+			"error", err, "target", target, "target_id", id,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("取消回应失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+// sAuthorizeAuthorOrLeader 帖子作者本人或所属社团负责人才放行，失败时已写入响应
+func (h *PostHandler) sAuthorizeAuthorOrLeader(ctx iris.Context, postId int) bool {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return false
+	}
+
+	post, err := h.PostService.GetPostById(postId)
+	if err != nil {
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定帖子"))
+		return false
+	}
+
+	if int(post.UserId) == userId {
+		return true
+	}
+
+	return h.RoleGuard.Authorize(ctx, int(post.ClubId), dbstruct.ROLE_CLUB_LEADER)
+}
+
+func sToCommentDto(comment *dbstruct.ClubPostComment) *dto.PostComment {
+	res := &dto.PostComment{
+		CommentId: int(comment.CommentId),
+		PostId:    int(comment.PostId),
+		UserId:    int(comment.UserId),
+		Content:   comment.Content,
+		Status:    comment.Status,
+		CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: comment.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+
+	if comment.ParentCommentId != nil {
+		res.ParentCommentId = int(*comment.ParentCommentId)
+	}
+
+	// 已删除的评论仅保留占位以维持楼层结构
+	if comment.Status != dbstruct.COMMENT_STATUS_NORMAL {
+		res.Content = ""
+	}
+
+	return res
+}
+
+// sBuildCommentTree 将回复挂到各自的父评论下，replies需按发表时间升序
+func sBuildCommentTree(roots, replies []*dbstruct.ClubPostComment) []*dto.PostComment {
+	nodes := make(map[int]*dto.PostComment, len(roots)+len(replies))
+
+	resComments := make([]*dto.PostComment, 0, len(roots))
+	for _, root := range roots {
+		node := sToCommentDto(root)
+		nodes[node.CommentId] = node
+		resComments = append(resComments, node)
+	}
+
+	for _, reply := range replies {
+		node := sToCommentDto(reply)
+		nodes[node.CommentId] = node
+
+		parent, ok := nodes[node.ParentCommentId]
+		if !ok && reply.RootCommentId != nil {
+			parent, ok = nodes[int(*reply.RootCommentId)]
+		}
+		if ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	return resComments
+}
+
+func (h *PostHandler) GetMyPostApplis(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	applis, err := h.PostService.GetPostApplisByUserId(userId)
+	if err != nil {
+		h.Logger.Error("获取发帖申请列表失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法获取发帖申请列表"))
+		return
+	}
+
+	resApplis := make([]*dto.CreatePostAppli, 0, len(applis))
+	for _, appli := range applis {
+		resApplis = append(resApplis, toCreatePostAppliDto(appli))
+	}
+
+	WriteOK(ctx, resApplis)
+}
+
+func sToDraftDto(draft *dbstruct.ClubPost) *dto.PostDraft {
+	res := &dto.PostDraft{
+		PostId:    int(draft.PostId),
+		ClubId:    int(draft.ClubId),
+		Title:     draft.Title,
+		Content:   draft.DraftContent,
+		Status:    draft.Status,
+		UpdatedAt: draft.UpdatedAt.Format(time.DateTime),
+	}
+
+	if draft.PublishAt != nil {
+		res.PublishAt = draft.PublishAt.Format(time.DateTime)
+	}
+
+	return res
+}
+
+// sParsePublishAt 空串表示不定时发布
+func sParsePublishAt(str string) (*time.Time, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	publishAt, err := time.ParseInLocation(time.DateTime, str, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	return &publishAt, nil
+}
+
+// PostUploadAttachment 上传帖子附件，正文中可用原始文件名或 attachment:<附件ID> 引用，
+// 发布或编辑帖子时链接会被改写为附件地址
+func (h *PostHandler) PostUploadAttachment(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	file, header, err := ctx.FormFile("file")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("未成功获取上传的附件"))
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.AttachmentService.UploadAttachment(id, userId, header.Filename, file)
+	if err != nil {
+		h.Logger.Error("上传帖子附件失败",
+			"error", err, "post_id", id, "user_id", userId, "file_name", header.Filename,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("上传附件失败"))
+		return
+	}
+
+	WriteOK(ctx, sToAttachmentDto(attachment))
+}
+
+func (h *PostHandler) GetAttachments(ctx iris.Context, id int) {
+	userId, _ := ctx.Values().GetInt("user_claims_user_id")
+
+	attachments, err := h.AttachmentService.GetAttachments(id, userId)
+	if err != nil {
+		h.Logger.Error("获取帖子附件失败", "error", err, "post_id", id)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法获取帖子附件"))
+		return
+	}
+
+	resAttachments := make([]*dto.PostAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		resAttachments = append(resAttachments, sToAttachmentDto(attachment))
+	}
+
+	WriteOK(ctx, resAttachments)
+}
+
+func (h *PostHandler) DeleteAttachment(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	if err := h.AttachmentService.DeleteAttachment(id, userId); err != nil {
+		h.Logger.Error("删除帖子附件失败",
+			"error", err, "attachment_id", id, "user_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("删除附件失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func sToAttachmentDto(attachment *dbstruct.PostAttachment) *dto.PostAttachment {
+	return &dto.PostAttachment{
+		AttachmentId: int(attachment.AttachmentId),
+		PostId:       int(attachment.PostId),
+		FileName:     attachment.FileName,
+		Url:          attachment.FileUrl,
+		ThumbnailUrl: attachment.ThumbUrl,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		CreatedAt:    attachment.CreatedAt.Format(time.DateTime),
+	}
 }
@@ -2,6 +2,10 @@ package handler
 
 import (
 	"log/slog"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/service"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
@@ -11,36 +15,42 @@ import (
 
 type PostPubHandler struct {
 	PostService service.PostService
-	Logger      *slog.Logger
+	RoleGuard   *ClubRoleGuard
+
+	Logger *slog.Logger
 }
 
 func (h *PostPubHandler) BeforeActivation(b mvc.BeforeActivation) {
-	//b.Handle("PUT", "/proc_create", "PutProcAppliForCreatePost")
-
-	b.Handle("PUT", "/ban/{id:int}", "PutBanPost")
-	b.Handle("PUT", "/pin/{id:int}", "PutPinPost")
+	b.Handle("GET", "/applis/{id:int}", "GetPostApplisForClub",
+		h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, ClubIdFromParam("id")))
+	b.Handle("PUT", "/proc_create/{id:int}", "PutProcAppliForCreatePost",
+		h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, h.RoleGuard.ClubIdFromPostAppliParam("id")))
+
+	b.Handle("PUT", "/ban/{id:int}", "PutBanPost",
+		h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, h.RoleGuard.ClubIdFromPostParam("id")))
+	b.Handle("PUT", "/pin/{id:int}", "PutPinPost",
+		h.RoleGuard.Require(dbstruct.ROLE_CLUB_OFFICER, h.RoleGuard.ClubIdFromPostParam("id")))
+	b.Handle("PUT", "/remove_comment/{id:int}", "PutRemoveComment",
+		h.RoleGuard.Require(dbstruct.ROLE_CLUB_VICE_LEADER, h.RoleGuard.ClubIdFromCommentParam("id")))
 }
 
 func (h *PostPubHandler) PutBanPost(ctx iris.Context, id int) {
-	userRole := ctx.Values().GetString("user_claims_user_role")
-	if userRole != dbstruct.ROLE_PUBLISHER &&
-		userRole != dbstruct.ROLE_ADMIN {
-		ctx.StatusCode(iris.StatusForbidden)
-		return
+	banRole := ctx.Values().GetString(kCtxRoleInClub)
+	if ctx.Values().GetString("user_claims_user_role") == dbstruct.ROLE_ADMIN {
+		banRole = dbstruct.ROLE_ADMIN
 	}
 
-	err := h.PostService.BanPost(userRole, id)
+	err := h.PostService.BanPost(banRole, id)
 	if err != nil {
 		h.Logger.Error("封禁帖子失败",
 			"error", err, "post_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法封禁指定帖子")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法封禁指定帖子"))
 		return
 	}
 
-	ctx.Text("封禁帖子成功")
+	WriteOK(ctx, nil)
 }
 
 func (h *PostPubHandler) PutPinPost(ctx iris.Context, id int) {
@@ -50,65 +60,136 @@ func (h *PostPubHandler) PutPinPost(ctx iris.Context, id int) {
 			"error", err, "post_id", id,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法置顶指定帖子")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法置顶指定帖子"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostPubHandler) PutRemoveComment(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.RemoveCommentRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil || strings.TrimSpace(reqBody.Reason) == "" {
+		h.Logger.Info("删除评论请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("需填写删除理由"))
+		return
+	}
+
+	if err := h.PostService.RemoveComment(id, userId, reqBody.Reason); err != nil {
+		h.Logger.Error("删除违规评论失败",
+			"error", err, "comment_id", id, "moderator_id", userId,
+		)
+
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法删除指定评论"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *PostPubHandler) GetPostApplisForClub(ctx iris.Context, id int) {
+	applis, err := h.PostService.GetPendingPostApplis(id)
+	if err != nil {
+		h.Logger.Error("获取待审核帖子列表失败", "error", err, "club_id", id)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("无法获取待审核帖子列表"))
 		return
 	}
 
-	ctx.Text("置顶帖子成功")
+	resApplis := make([]*dto.CreatePostAppli, 0, len(applis))
+	for _, appli := range applis {
+		resAppli := toCreatePostAppliDto(appli)
+		resAppli.Content = appli.Post.DraftContent
+
+		resApplis = append(resApplis, resAppli)
+	}
+
+	WriteOK(ctx, resApplis)
 }
 
-// func (h *PostPubHandler) PutProcAppliForCreatePost(ctx iris.Context) {
-// 	var reqBody dto.ProcCreatePostRequest
-// 	if err := ctx.ReadJSON(&reqBody); err != nil {
-// 		h.Logger.Info("ProcCreatePost请求格式错误", "error", err)
-
-// 		ctx.StatusCode(iris.StatusBadRequest)
-// 		ctx.Text("请求格式错误")
-// 		return
-// 	}
-
-// 	switch reqBody.Result {
-// 	case "approve":
-// 		oriAppli, curPost, err := h.PostService.
-// 			ApproveAppliForCreatePost(reqBody.CreatePostAppliId)
-// 		if err != nil {
-// 			h.Logger.Info("通过帖子创建申请失败",
-// 				"error", err, "appli_id", reqBody.CreatePostAppliId,
-// 			)
-
-// 			ctx.StatusCode(iris.StatusInternalServerError)
-// 			ctx.Text("通过帖子创建申请失败")
-// 			return
-// 		}
-
-// 		if err := h.PostService.TransferPost(oriAppli, curPost); err != nil {
-// 			h.Logger.Info("转移临时帖子失败",
-// 				"error", err, "draft_url", oriAppli.DraftContentUrl,
-// 			)
-
-// 			ctx.StatusCode(iris.StatusInternalServerError)
-// 			ctx.Text("帖子通过失败，无法转移")
-// 			return
-// 		}
-
-// 		ctx.Text("通过帖子创建申请成功")
-// 		return
-
-// 	case "reject":
-// 		if err := h.PostService.RejectAppliForCreatePost(reqBody.CreatePostAppliId, reqBody.Reason); err != nil {
-// 			h.Logger.Info("拒绝帖子创建申请失败",
-// 				"error", err, "appli_id", reqBody.CreatePostAppliId,
-// 			)
-
-// 			ctx.StatusCode(iris.StatusInternalServerError)
-// 			ctx.Text("拒绝帖子创建申请失败")
-// 			return
-// 		}
-
-// 	default:
-// 		ctx.StatusCode(iris.StatusBadRequest)
-// 		ctx.Text("result参数错误")
-// 		return
-// 	}
-// }
+// PutProcAppliForCreatePost 审核发帖申请，路由参数为申请ID；拒绝时必须填写理由
+func (h *PostPubHandler) PutProcAppliForCreatePost(ctx iris.Context, id int) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
+		return
+	}
+
+	var reqBody dto.ProcCreatePostRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("ProcCreatePost请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	switch reqBody.Result {
+	case "approve":
+		post, err := h.PostService.ApproveAppliForCreatePost(id, userId)
+		if err != nil {
+			h.Logger.Error("通过发帖申请失败",
+				"error", err, "appli_id", id, "reviewer_id", userId,
+			)
+
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("通过发帖申请失败"))
+			return
+		}
+
+		WriteOK(ctx, iris.Map{"post_id": post.PostId})
+		return
+
+	case "reject":
+		if strings.TrimSpace(reqBody.Reason) == "" {
+			WriteError(ctx, apperr.ErrBadRequest.WithMessage("需填写拒绝理由"))
+			return
+		}
+
+		if err := h.PostService.RejectAppliForCreatePost(id, userId, reqBody.Reason); err != nil {
+			h.Logger.Error("拒绝发帖申请失败",
+				"error", err, "appli_id", id, "reviewer_id", userId,
+			)
+
+			WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("拒绝发帖申请失败"))
+			return
+		}
+
+	default:
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("result参数错误"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func toCreatePostAppliDto(appli *dbstruct.CreatePostAppli) *dto.CreatePostAppli {
+	resAppli := &dto.CreatePostAppli{
+		PostAppliId:    int(appli.PostAppliId),
+		PostId:         int(appli.PostId),
+		ClubId:         int(appli.ClubId),
+		ApplicantId:    int(appli.ApplicantId),
+		Kind:           appli.Kind,
+		Title:          appli.Post.Title,
+		Status:         appli.Status,
+		AppliedAt:      appli.AppliedAt.Format(time.DateTime),
+		RejectedReason: appli.RejectedReason,
+	}
+
+	// 改帖申请展示待审核的新版本
+	if appli.Kind == dbstruct.POST_APPLI_EDIT {
+		resAppli.Title = appli.Title
+		resAppli.ContentUrl = appli.ContentUrl
+	}
+
+	if !appli.ReviewedAt.IsZero() {
+		resAppli.ReviewedAt = appli.ReviewedAt.Format(time.DateTime)
+	}
+
+	return resAppli
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	kRateLimitPeekBodySize = 64 << 10
+)
+
+// RateKeyFunc 从请求中提取限流维度的标识，返回空串时该请求不计入此维度
+type RateKeyFunc func(ctx iris.Context) string
+
+type RateLimiter struct {
+	RedisService redisimpl.RedisClientService
+
+	Logger *slog.Logger
+}
+
+func NewRateLimiter(
+	redisService redisimpl.RedisClientService,
+	logger *slog.Logger,
+) *RateLimiter {
+	return &RateLimiter{
+		RedisService: redisService,
+		Logger:       logger,
+	}
+}
+
+// Limit 生成滑动窗口限流中间件，同一标识在window内最多放行limit次；
+// Redis不可用时放行并记录日志，避免限流组件故障导致整体不可用
+func (l *RateLimiter) Limit(name string, limit int, window time.Duration, keyFn RateKeyFunc) iris.Handler {
+	return func(ctx iris.Context) {
+		if limit <= 0 {
+			ctx.Next()
+			return
+		}
+
+		key := keyFn(ctx)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		allowed, retryAfter, err := l.RedisService.SlidingWindowAllow(
+			name+":"+key, limit, window,
+		)
+		if err != nil {
+			l.Logger.Error("限流检查失败", "error", err, "rule", name)
+
+			ctx.Next()
+			return
+		}
+
+		if !allowed {
+			l.Logger.Info("请求触发限流",
+				"rule", name, "key", key, "path", ctx.Path(), "retry_after", retryAfter,
+			)
+
+			WriteTooManyRequests(ctx, apperr.ErrTooManyRequests, retryAfter)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// WriteTooManyRequests 写入429响应并通过Retry-After告知客户端等待秒数
+func WriteTooManyRequests(ctx iris.Context, e *apperr.Error, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(max(seconds, 1)))
+
+	WriteError(ctx, e)
+}
+
+// RateKeyByClientIP 部署在反向代理之后时需配置remote_addr_headers，否则所有请求共用代理的地址
+func RateKeyByClientIP(ctx iris.Context) string {
+	return "ip:" + ctx.RemoteAddr()
+}
+
+func RateKeyByUserId(ctx iris.Context) string {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		return ""
+	}
+
+	return "user:" + strconv.Itoa(userId)
+}
+
+// RateKeyByJSONField 按JSON请求体中首个非空的字段限流，读取后回填请求体供后续handler使用
+func RateKeyByJSONField(fields ...string) RateKeyFunc {
+	return func(ctx iris.Context) string {
+		req := ctx.Request()
+		if req.Body == nil || !strings.HasPrefix(ctx.GetContentTypeRequested(), "application/json") {
+			return ""
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, kRateLimitPeekBodySize))
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		if err != nil {
+			return ""
+		}
+
+		var values map[string]any
+		if err := json.Unmarshal(body, &values); err != nil {
+			return ""
+		}
+
+		for _, field := range fields {
+			if str, ok := values[field].(string); ok && str != "" {
+				return field + ":" + strings.ToLower(strings.TrimSpace(str))
+			}
+		}
+
+		return ""
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"log/slog"
+	"whuclubsynapse-server/internal/base_server/dto"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"github.com/kataras/iris/v12"
+)
+
+// fillPostReactions 为帖子列表填充回应计数及调用者的回应，查询失败时保持为空并记录日志
+func fillPostReactions(
+	ctx iris.Context,
+	reactionService service.ReactionService,
+	logger *slog.Logger,
+	posts []*dto.ClubPostBasic,
+) {
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, uint(post.PostId))
+	}
+
+	summaries := sGetReactionSummaries(ctx, reactionService, logger, dbstruct.REACTION_TARGET_POST, ids)
+	for _, post := range posts {
+		post.Reactions, post.MyReaction = sUnpackSummary(summaries[uint(post.PostId)])
+		post.Reacted = post.MyReaction != ""
+	}
+}
+
+// fillCommentReactions 为评论树中的全部评论填充回应信息
+func fillCommentReactions(
+	ctx iris.Context,
+	reactionService service.ReactionService,
+	logger *slog.Logger,
+	comments []*dto.PostComment,
+) {
+	var flat []*dto.PostComment
+	var walk func(nodes []*dto.PostComment)
+	walk = func(nodes []*dto.PostComment) {
+		for _, node := range nodes {
+			flat = append(flat, node)
+			walk(node.Replies)
+		}
+	}
+	walk(comments)
+
+	ids := make([]uint, 0, len(flat))
+	for _, comment := range flat {
+		ids = append(ids, uint(comment.CommentId))
+	}
+
+	summaries := sGetReactionSummaries(ctx, reactionService, logger, dbstruct.REACTION_TARGET_COMMENT, ids)
+	for _, comment := range flat {
+		comment.Reactions, comment.MyReaction = sUnpackSummary(summaries[uint(comment.CommentId)])
+		comment.Reacted = comment.MyReaction != ""
+	}
+}
+
+func sGetReactionSummaries(
+	ctx iris.Context,
+	reactionService service.ReactionService,
+	logger *slog.Logger,
+	targetType string,
+	ids []uint,
+) map[uint]*model.ReactionSummary {
+	userId, _ := ctx.Values().GetInt("user_claims_user_id")
+
+	summaries, err := reactionService.GetSummaries(userId, targetType, ids)
+	if err != nil {
+		logger.Error("获取回应信息失败", "error", err, "target_type", targetType)
+		return nil
+	}
+
+	return summaries
+}
+
+func sUnpackSummary(summary *model.ReactionSummary) (map[string]int64, string) {
+	if summary == nil {
+		return map[string]int64{}, ""
+	}
+
+	return summary.Counts, summary.MyReaction
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/dto"
+
+	"github.com/kataras/iris/v12"
+)
+
+func WriteOK(ctx iris.Context, data any) {
+	lang := apperr.ParseLang(ctx.GetHeader("Accept-Language"))
+
+	ctx.StatusCode(iris.StatusOK)
+	ctx.JSON(dto.Response{
+		Code:    apperr.CODE_OK,
+		Message: apperr.LocalizeCode(apperr.CODE_OK, "成功", lang),
+		Data:    data,
+	})
+}
+
+// WriteError 写入错误响应并终止后续中间件
+func WriteError(ctx iris.Context, e *apperr.Error) {
+	lang := apperr.ParseLang(ctx.GetHeader("Accept-Language"))
+
+	ctx.StopWithJSON(e.Status, dto.Response{
+		Code:    e.Code,
+		Message: e.Localize(lang),
+		Data:    nil,
+	})
+}
+
+// WriteServiceError service返回领域错误时按其错误码响应，其余错误使用fallback
+func WriteServiceError(ctx iris.Context, err error, fallback *apperr.Error) {
+	WriteError(ctx, apperr.Or(err, fallback))
+}
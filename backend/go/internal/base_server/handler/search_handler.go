@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/dto"
+	"whuclubsynapse-server/internal/base_server/esimpl"
+	"whuclubsynapse-server/internal/base_server/service"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/mvc"
+)
+
+const (
+	SEARCH_TYPE_CLUB = "club"
+	SEARCH_TYPE_POST = "post"
+
+	kSearchKeywordMaxLen = 100
+	kSearchPageSizeMax   = 50
+	kSearchTagsMax       = 10
+)
+
+type SearchHandler struct {
+	SearchService service.SearchService
+
+	Logger *slog.Logger
+}
+
+func (h *SearchHandler) BeforeActivation(b mvc.BeforeActivation) {
+	b.Handle("GET", "/", "GetSearch")
+}
+
+// GetSearch 按type检索社团（默认）或帖子，q为空时按创建时间倒序列出满足筛选条件的结果
+func (h *SearchHandler) GetSearch(ctx iris.Context) {
+	keyword := strings.TrimSpace(ctx.URLParam("q"))
+	if len([]rune(keyword)) > kSearchKeywordMaxLen {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("搜索关键词过长"))
+		return
+	}
+
+	page := ctx.URLParamIntDefault("page", 1)
+	pageSize := ctx.URLParamIntDefault("page_size", 10)
+	if page < 1 || pageSize < 1 || pageSize > kSearchPageSizeMax {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("分页参数无效"))
+		return
+	}
+	from := (page - 1) * pageSize
+
+	switch searchType := ctx.URLParamDefault("type", SEARCH_TYPE_CLUB); searchType {
+	case SEARCH_TYPE_CLUB:
+		h.sSearchClubs(ctx, keyword, from, page, pageSize)
+	case SEARCH_TYPE_POST:
+		h.sSearchPosts(ctx, keyword, from, page, pageSize)
+	default:
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("未知的搜索类型："+searchType))
+	}
+}
+
+func (h *SearchHandler) sSearchClubs(ctx iris.Context, keyword string, from, page, pageSize int) {
+	query := &esimpl.ClubQuery{
+		Keyword: keyword,
+		From:    from,
+		Size:    pageSize,
+	}
+
+	if category := ctx.URLParamIntDefault("category", 0); category > 0 {
+		query.CategoryId = uint(category)
+	}
+
+	for _, tag := range strings.Split(ctx.URLParam("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+	if len(query.Tags) > kSearchTagsMax {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("筛选标签过多"))
+		return
+	}
+
+	var err error
+	if query.MinMembers, err = sOptionalIntParam(ctx, "min_members"); err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("min_members参数无效"))
+		return
+	}
+	if query.MaxMembers, err = sOptionalIntParam(ctx, "max_members"); err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("max_members参数无效"))
+		return
+	}
+
+	result, err := h.SearchService.SearchClubs(query)
+	if err != nil {
+		h.Logger.Error("搜索社团失败", "error", err, "keyword", keyword)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("搜索社团失败"))
+		return
+	}
+
+	items := make([]dto.ClubSearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		items = append(items, dto.ClubSearchHit{
+			ClubId:      int(hit.Doc.ClubId),
+			ClubName:    hit.Doc.Name,
+			Category:    int(hit.Doc.CategoryId),
+			Tags:        hit.Doc.Tags,
+			LogoUrl:     hit.Doc.LogoUrl,
+			Desc:        hit.Doc.Desc,
+			MemberCount: hit.Doc.MemberCount,
+			CreatedAt:   hit.Doc.CreatedAt.Format(time.DateTime),
+			Highlight:   hit.Highlight,
+		})
+	}
+
+	WriteOK(ctx, dto.SearchResult[dto.ClubSearchHit]{
+		Total:    result.Total,
+		Page:     page,
+		PageSize: pageSize,
+		Items:    items,
+	})
+}
+
+func (h *SearchHandler) sSearchPosts(ctx iris.Context, keyword string, from, page, pageSize int) {
+	query := &esimpl.PostQuery{
+		Keyword: keyword,
+		From:    from,
+		Size:    pageSize,
+	}
+
+	if clubId := ctx.URLParamIntDefault("club_id", 0); clubId > 0 {
+		query.ClubId = uint(clubId)
+	}
+
+	result, err := h.SearchService.SearchPosts(query)
+	if err != nil {
+		h.Logger.Error("搜索帖子失败", "error", err, "keyword", keyword)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("搜索帖子失败"))
+		return
+	}
+
+	items := make([]dto.PostSearchHit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		items = append(items, dto.PostSearchHit{
+			PostId:    int(hit.Doc.PostId),
+			ClubId:    int(hit.Doc.ClubId),
+			AuthorId:  int(hit.Doc.UserId),
+			Title:     hit.Doc.Title,
+			CreatedAt: hit.Doc.CreatedAt.Format(time.DateTime),
+			Highlight: hit.Highlight,
+		})
+	}
+
+	WriteOK(ctx, dto.SearchResult[dto.PostSearchHit]{
+		Total:    result.Total,
+		Page:     page,
+		PageSize: pageSize,
+		Items:    items,
+	})
+}
+
+// sOptionalIntParam 参数缺失时返回nil
+func sOptionalIntParam(ctx iris.Context, name string) (*int, error) {
+	raw := ctx.URLParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return nil, apperr.ErrBadRequest
+	}
+
+	return &value, nil
+}
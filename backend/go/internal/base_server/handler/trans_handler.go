@@ -1,333 +1,171 @@
 package handler
 
 import (
-	"bufio"
-	"bytes"
-	"io"
+	"errors"
 	"log/slog"
-	"net/http"
-	"net/url"
-	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/baseconfig"
+	"whuclubsynapse-server/internal/base_server/proxy"
+	"whuclubsynapse-server/internal/base_server/service"
+	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
 )
 
-func InitTransHandler(parent *mvc.Application, cfg *baseconfig.Config) {
+// kUsageRouteMaxLen 与llm_usage_logs.route字段长度一致
+const kUsageRouteMaxLen = 100
+
+func InitTransHandler(
+	parent *mvc.Application,
+	cfg *baseconfig.Config,
+	limiter *RateLimiter,
+	lgr *slog.Logger,
+) {
 	transApp := parent.Party("/trans")
 
+	llmProxy, err := proxy.NewProxy("llm", cfg.LlmAddr,
+		proxy.RoutesFromConfig(cfg.LlmRoutes), nil, lgr,
+	)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	ragProxy, err := proxy.NewProxy("rag", cfg.RagAddr,
+		proxy.RoutesFromConfig(cfg.RagRoutes),
+		map[string]string{"Authorization": cfg.RagApiKey},
+		lgr,
+	)
+	if err != nil {
+		panic(err.Error())
+	}
+
 	handler := &TransHandler{
-		LlmAddr: cfg.LlmAddr,
-		RagAddr: cfg.RagAddr,
+		LlmProxy: llmProxy,
+		RagProxy: ragProxy,
+
+		LlmLimit: limiter.Limit("llm_user",
+			cfg.RateLimitLlmUserLimit,
+			time.Duration(cfg.RateLimitLlmUserWindow)*time.Second,
+			RateKeyByUserId,
+		),
 	}
 
 	transApp.Handle(handler)
 }
 
+// TransHandler 将前端请求转发至LLM与RAG服务，只放行配置中列出的接口
 type TransHandler struct {
-	LlmAddr string
-	RagAddr string
+	LlmProxy *proxy.Proxy
+	RagProxy *proxy.Proxy
+
+	// LlmLimit LLM调用开销较大，按用户单独限流
+	LlmLimit iris.Handler
+
+	LlmUsageService service.LlmUsageService
 
 	Logger *slog.Logger
 }
 
 func (h *TransHandler) BeforeActivation(b mvc.BeforeActivation) {
-	b.Handle("GET", "/llm/{route:string}", "GetTransLlm")
-	b.Handle("GET", "/rag/{route:string}", "GetTransRag")
+	// 部分上游接口含多级路径，如generate/activity_post
+	b.Handle("GET", "/llm/{route:path}", "GetTransLlm", h.LlmLimit)
+	b.Handle("GET", "/rag/{route:path}", "GetTransRag")
 
-	b.Handle("POST", "/llm/{route:string}", "PostTransLlm")
-	b.Handle("POST", "/rag/{route:string}", "PostTransRag")
+	b.Handle("POST", "/llm/{route:path}", "PostTransLlm", h.LlmLimit)
+	b.Handle("POST", "/rag/{route:path}", "PostTransRag")
 }
 
 func (h *TransHandler) GetTransLlm(ctx iris.Context, route string) {
-	req := ctx.Request().Clone(ctx.Request().Context())
-
-	req.URL, _ = url.Parse(h.LlmAddr + "/" + route)
-	req.RequestURI = ""
-
-	req.Host = strings.TrimPrefix(h.LlmAddr, "https://")
-	req.Header.Set("Host", req.Host)
-
-	h.Logger.Info("LLM请求", "url", req.URL.String())
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		h.Logger.Error("LLM服务器请求失败", "error", err)
-
-		ctx.StatusCode(iris.StatusServiceUnavailable)
-		ctx.Text("LLM服务器暂不可用")
-		return
-	}
-	defer res.Body.Close()
-
-	for key, values := range res.Header {
-		for _, value := range values {
-			ctx.ResponseWriter().Header().Add(key, value)
-		}
-	}
-	// 在处理完请求后，强制重新设置 CORS 头部
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Origin", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Headers", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Max-Age", "86400")
-
-	ctx.StatusCode(res.StatusCode)
-	io.Copy(ctx.ResponseWriter(), res.Body)
+	h.sForward(ctx, h.LlmProxy, route)
 }
 
+// PostTransLlm 调用计入用户当日的LLM额度，并写入调用记录
 func (h *TransHandler) PostTransLlm(ctx iris.Context, route string) {
-	bodyBytes, err := io.ReadAll(ctx.Request().Body)
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		h.Logger.Error("读取原始请求体失败", "error", err)
-
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：读取请求体失败")
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
+	userRole := ctx.Values().GetString("user_claims_user_role")
 
-	targetURL := h.LlmAddr + "/" + route
-	parsedURL, err := url.Parse(targetURL)
-	if err != nil {
-		h.Logger.Error("解析LLM目标URL失败", "error", err)
-
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：LLM目标URL解析失败")
-		return
+	start := time.Now()
+	usageLog := &dbstruct.LlmUsageLog{
+		UserId:       uint(userId),
+		Role:         userRole,
+		Route:        sUsageRoute(h.LlmProxy, ctx.Method(), route),
+		RequestBytes: max(ctx.Request().ContentLength, 0),
+		CreatedAt:    start,
 	}
 
-	req, err := http.NewRequest(ctx.Request().Method, parsedURL.String(), io.NopCloser(bytes.NewBuffer(bodyBytes)))
-	if err != nil {
-		h.Logger.Error("创建LLM请求失败", "error", err)
+	if err := h.LlmUsageService.Acquire(userId, userRole, start); err != nil {
+		usageLog.Outcome = dbstruct.LLM_USAGE_REJECTED
+		usageLog.StatusCode = iris.StatusTooManyRequests
+		h.LlmUsageService.Record(usageLog)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：创建LLM请求失败")
+		// 额度在次日零点恢复
+		year, month, day := start.Date()
+		WriteTooManyRequests(ctx, apperr.From(err),
+			time.Date(year, month, day+1, 0, 0, 0, 0, start.Location()).Sub(start),
+		)
 		return
 	}
 
-	req.RequestURI = ""
-
-	req.Host = strings.TrimPrefix(h.LlmAddr, "https://")
-	req.Header.Set("Host", req.Host)
-
-	for key, values := range ctx.Request().Header {
-		if strings.EqualFold(key, "Host") || strings.EqualFold(key, "Connection") || strings.EqualFold(key, "Content-Length") {
-			continue
-		}
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
-	}
-
-	h.Logger.Info("LLM请求", "url", req.URL.String())
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		h.Logger.Error("无法访问LLM服务器", "error", err)
-
-		ctx.StatusCode(iris.StatusServiceUnavailable)
-		ctx.Text("LLM服务器暂不可用")
-		return
-	}
-	defer res.Body.Close()
+	meter := proxy.NewMeter(ctx.ResponseWriter())
+	err = h.LlmProxy.Forward(meter, ctx.Request(), route)
+	h.sWriteForwardError(ctx, err)
 
-	for key, values := range res.Header {
-		for _, value := range values {
-			ctx.ResponseWriter().Header().Add(key, value)
-		}
+	usageLog.Outcome = dbstruct.LLM_USAGE_OK
+	if meter.Status() == 0 {
+		usageLog.Outcome = dbstruct.LLM_USAGE_FAILED
 	}
-	// 在处理完请求后，强制重新设置 CORS 头部
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Origin", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Headers", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Max-Age", "86400")
-
-	ctx.StatusCode(res.StatusCode)
+	usageLog.StatusCode = meter.Status()
+	usageLog.ResponseBytes = meter.Bytes()
+	usageLog.Tokens = meter.Tokens()
+	usageLog.DurationMs = time.Since(start).Milliseconds()
 
-	if !strings.Contains(strings.ToLower(res.Header.Get("Content-Type")), "text/event-stream") {
-		h.Logger.Warn("上游响应Content-Type不是text/event-stream，进行普通拷贝", "content-type", res.Header.Get("Content-Type"))
-		_, copyErr := io.Copy(ctx.ResponseWriter(), res.Body)
-		if copyErr != nil {
-			h.Logger.Error("普通转发中错误", "error", copyErr)
-		}
-		ctx.ResponseWriter().Flush()
-		h.Logger.Debug("普通转发结束")
-		return
-	}
+	h.LlmUsageService.Record(usageLog)
+}
 
-	// 尝试获取 Flusher 接口
-	flusher, ok := ctx.ResponseWriter().(http.Flusher)
-	if !ok {
-		h.Logger.Error("ResponseWriter不支持Flush，无法进行SSE转发")
-		http.Error(ctx.ResponseWriter(), "Streaming unsupported!", http.StatusInternalServerError)
-		return
+// sUsageRoute 调用记录使用白名单中的接口路径；未放行的请求路径由用户输入，截断到字段长度
+func sUsageRoute(p *proxy.Proxy, method, route string) string {
+	if matched, ok := p.Match(method, route); ok {
+		return matched.Path
 	}
 
-	scanner := bufio.NewScanner(res.Body)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		// 将读取到的行直接写入到下游客户端，并添加换行符以保持SSE格式
-		if _, writeErr := ctx.ResponseWriter().Write(append(line, '\n')); writeErr != nil {
-			h.Logger.Error("SSE转发写入下游客户端错误", "error", writeErr)
-			// 如果写入下游客户端失败，通常表示客户端断开，此时应退出循环
-			break
-		}
-		flusher.Flush() // 每次写入一行后立即刷新，确保数据实时发送
+	if runes := []rune(route); len(runes) > kUsageRouteMaxLen {
+		return string(runes[:kUsageRouteMaxLen])
 	}
 
-	// 检查Scanner是否在读取过程中遇到了错误（除了io.EOF）
-	if err := scanner.Err(); err != nil {
-		h.Logger.Error("从上游SSE读取数据时发生错误", "error", err)
-	} else {
-		// 如果没有错误，且循环结束，意味着上游SSE流已优雅关闭或数据已传输完毕
-		h.Logger.Debug("上游SSE流已优雅结束或数据传输完毕")
-	}
-	h.Logger.Debug("SSE 转发结束")
+	return route
 }
 
 func (h *TransHandler) GetTransRag(ctx iris.Context, route string) {
-	req := ctx.Request().Clone(ctx.Request().Context())
-
-	req.URL, _ = url.Parse(h.RagAddr + "/" + route)
-	req.RequestURI = ""
-
-	req.Host = strings.TrimPrefix(h.RagAddr, "http://")
-	req.Header.Set("Host", req.Host)
-	req.Header.Set("Authorization", "super_plus_api_key")
-
-	h.Logger.Info("RAG请求", "url", req.URL.String())
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		h.Logger.Error("RAG服务器请求失败", "error", err)
-
-		ctx.StatusCode(iris.StatusServiceUnavailable)
-		ctx.Text("RAG服务器暂不可用")
-		return
-	}
-	defer res.Body.Close()
-
-	for key, values := range res.Header {
-		for _, value := range values {
-			ctx.ResponseWriter().Header().Add(key, value)
-		}
-	}
-	// 在处理完请求后，强制重新设置 CORS 头部
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Origin", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Headers", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Max-Age", "86400")
-
-	ctx.StatusCode(res.StatusCode)
-	io.Copy(ctx.ResponseWriter(), res.Body)
+	h.sForward(ctx, h.RagProxy, route)
 }
 
 func (h *TransHandler) PostTransRag(ctx iris.Context, route string) {
-	bodyBytes, err := io.ReadAll(ctx.Request().Body)
-	if err != nil {
-		h.Logger.Error("读取原始请求体失败", "error", err)
-
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：读取请求体失败")
-		return
-	}
-
-	targetURL := h.RagAddr + "/" + route
-	parsedURL, err := url.Parse(targetURL)
-	if err != nil {
-		h.Logger.Error("解析RAG目标URL失败", "error", err)
-
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：RAG目标URL解析失败")
-		return
-	}
-
-	req, err := http.NewRequest(ctx.Request().Method, parsedURL.String(), io.NopCloser(bytes.NewBuffer(bodyBytes)))
-	if err != nil {
-		h.Logger.Error("创建RAG请求失败", "error", err)
-
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("内部错误：创建RAG请求失败")
-		return
-	}
-
-	req.RequestURI = ""
-
-	req.Host = strings.TrimPrefix(h.RagAddr, "http://")
-	req.Header.Set("Host", req.Host)
-	req.Header.Set("Authorization", "super_plus_api_key")
-
-	for key, values := range ctx.Request().Header {
-		if strings.EqualFold(key, "Host") || strings.EqualFold(key, "Connection") || strings.EqualFold(key, "Content-Length") {
-			continue
-		}
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
-	}
-
-	h.Logger.Info("RAG请求", "url", req.URL.String())
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		h.Logger.Error("无法访问RAG服务器", "error", err)
-
-		ctx.StatusCode(iris.StatusServiceUnavailable)
-		ctx.Text("RAG服务器暂不可用")
-		return
-	}
-	defer res.Body.Close()
-
-	for key, values := range res.Header {
-		for _, value := range values {
-			ctx.ResponseWriter().Header().Add(key, value)
-		}
-	}
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Origin", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-	ctx.ResponseWriter().Header().Set("Access-Control-Allow-Headers", "*")
-	ctx.ResponseWriter().Header().Set("Access-Control-Max-Age", "86400")
-
-	ctx.StatusCode(res.StatusCode)
-
-	if !strings.Contains(strings.ToLower(res.Header.Get("Content-Type")), "text/event-stream") {
-		h.Logger.Warn("上游响应Content-Type不是text/event-stream，进行普通拷贝", "content-type", res.Header.Get("Content-Type"))
-		_, copyErr := io.Copy(ctx.ResponseWriter(), res.Body)
-		if copyErr != nil {
-			h.Logger.Error("普通转发中错误", "error", copyErr)
-		}
-		ctx.ResponseWriter().Flush()
-		h.Logger.Debug("普通转发结束")
-		return
-	}
-
-	// 尝试获取 Flusher 接口
-	flusher, ok := ctx.ResponseWriter().(http.Flusher)
-	if !ok {
-		h.Logger.Error("ResponseWriter不支持Flush，无法进行SSE转发")
-		http.Error(ctx.ResponseWriter(), "Streaming unsupported!", http.StatusInternalServerError)
-		return
-	}
+	h.sForward(ctx, h.RagProxy, route)
+}
 
-	scanner := bufio.NewScanner(res.Body)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		// 将读取到的行直接写入到下游客户端，并添加换行符以保持SSE格式
-		if _, writeErr := ctx.ResponseWriter().Write(append(line, '\n')); writeErr != nil {
-			h.Logger.Error("SSE转发写入下游客户端错误", "error", writeErr)
-			// 如果写入下游客户端失败，通常表示客户端断开，此时应退出循环
-			break
-		}
-		flusher.Flush() // 每次写入一行后立即刷新，确保数据实时发送
-	}
+func (h *TransHandler) sForward(ctx iris.Context, p *proxy.Proxy, route string) {
+	h.sWriteForwardError(ctx, p.Forward(ctx.ResponseWriter(), ctx.Request(), route))
+}
 
-	// 检查Scanner是否在读取过程中遇到了错误（除了io.EOF）
-	if err := scanner.Err(); err != nil {
-		h.Logger.Error("从上游SSE读取数据时发生错误", "error", err)
-	} else {
-		// 如果没有错误，且循环结束，意味着上游SSE流已优雅关闭或数据已传输完毕
-		h.Logger.Debug("上游SSE流已优雅结束或数据传输完毕")
+// sWriteForwardError 上游响应开始前的失败以统一的错误格式返回；客户端已断开时不再写入
+func (h *TransHandler) sWriteForwardError(ctx iris.Context, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, proxy.ErrRouteDenied):
+		WriteError(ctx, apperr.ErrProxyRouteDenied)
+	case errors.Is(err, proxy.ErrBodyTooLarge):
+		WriteError(ctx, apperr.ErrProxyBodyTooLarge)
+	case errors.Is(err, proxy.ErrUpstreamTimeout):
+		WriteError(ctx, apperr.ErrUpstreamTimeout)
+	case errors.Is(err, proxy.ErrClientGone):
+		ctx.StopExecution()
+	default:
+		WriteError(ctx, apperr.ErrUpstreamUnavailable)
 	}
-
-	h.Logger.Debug("SSE 转发结束")
 }
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"whuclubsynapse-server/internal/base_server/proxy"
+)
+
+func TestUsageRoute(t *testing.T) {
+	p, err := proxy.NewProxy("llm", "http://127.0.0.1:1",
+		[]proxy.Route{{Path: "generate/activity_post", Methods: []string{"POST"}, Timeout: time.Second, MaxBodySize: 16}},
+		nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewProxy() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		route  string
+		want   string
+	}{
+		{"白名单接口", "POST", "/generate/activity_post/", "generate/activity_post"},
+		{"未放行的短路径", "POST", "admin", "admin"},
+		{"未放行的方法", "GET", "generate/activity_post", "generate/activity_post"},
+		{"超长路径截断", "POST", strings.Repeat("a", 300), strings.Repeat("a", kUsageRouteMaxLen)},
+		{"按字符截断", "POST", strings.Repeat("接", 150), strings.Repeat("接", kUsageRouteMaxLen)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sUsageRoute(p, tt.method, tt.route); got != tt.want {
+				t.Errorf("sUsageRoute(%q, %q) = %q, want %q", tt.method, tt.route, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,9 @@
 package handler
 
 import (
-	"io"
 	"log/slog"
-	"mime"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/base_server/dto"
 	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/service"
@@ -17,16 +12,20 @@ import (
 
 	"github.com/kataras/iris/v12"
 	"github.com/kataras/iris/v12/mvc"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	USR_AVATAR_DIR = "pub/user_avatars"
+	// 用户头像在对象存储中的键前缀
+	USR_AVATAR_PREFIX = "user_avatars"
 )
 
 type UserHandler struct {
-	JwtFactory  *jwtutil.CliamsFactory[model.UserClaims]
-	UserService service.UserService
+	JwtFactory   *jwtutil.CliamsFactory[model.UserClaims]
+	UserService  service.UserService
+	AuthService  service.AuthService
+	ImageService service.ImageService
+
+	LlmUsageService service.LlmUsageService
 
 	Logger *slog.Logger
 }
@@ -35,15 +34,16 @@ func (h *UserHandler) BeforeActivation(b mvc.BeforeActivation) {
 	b.Handle("GET", "/{id:int}", "GetUserInfo")
 	b.Handle("GET", "/list", "GetUserList")
 	b.Handle("GET", "/ping", "GetPing")
+	b.Handle("GET", "/llm_usage", "GetLlmUsage")
 
 	b.Handle("POST", "/upload_avatar", "PostUploadAvatar")
 	b.Handle("PUT", "/update", "PutUpdateUserInfo")
+	b.Handle("PUT", "/password", "PutChangePassword")
 }
 
 func (h *UserHandler) GetUserInfo(ctx iris.Context, id int) {
 	if id <= 0 {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID无效")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID无效"))
 		return
 	}
 
@@ -54,79 +54,101 @@ func (h *UserHandler) GetUserInfo(ctx iris.Context, id int) {
 			"error", err, "id", id,
 		)
 
-		ctx.StatusCode(iris.StatusNotFound)
-		ctx.Text("用户ID无效")
+		WriteServiceError(ctx, err, apperr.ErrNotFound.WithMessage("用户ID无效"))
 		return
 	}
 
 	resUserInfo := dto.UserInfo{
-		UserId:     user.UserId,
-		Email:      user.Email,
-		Role:       user.Role,
-		AvatarUrl:  user.AvatarUrl,
-		Username:   user.Username,
-		LastActive: user.LastActive.Format("2006-01-02 15:04:05"),
-		Extension:  user.Extension,
+		UserId:         user.UserId,
+		Email:          user.Email,
+		Role:           user.Role,
+		AvatarUrl:      user.AvatarUrl,
+		AvatarVariants: model.ParseImageVariants(user.AvatarVariants),
+		Username:       user.Username,
+		LastActive:     user.LastActive.Format("2006-01-02 15:04:05"),
+		Extension:      user.Extension,
 	}
 
-	ctx.JSON(resUserInfo)
+	WriteOK(ctx, resUserInfo)
 }
 
 func (h *UserHandler) GetUserList(ctx iris.Context) {
 	userRole := ctx.Values().GetString("user_claims_user_role")
 	if userRole == "" {
-		ctx.StopWithStatus(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
 	if userRole != dbstruct.ROLE_ADMIN {
-		ctx.StopWithStatus(iris.StatusForbidden)
+		WriteError(ctx, apperr.ErrForbidden)
 		return
 	}
 
 	offset := ctx.URLParamIntDefault("offset", 0)
 	num := ctx.URLParamIntDefault("num", 10)
 
-	userList, err := h.UserService.GetUserList(offset, num)
+	cursor, cursorMode, err := parseCursorParam(ctx)
+	if err != nil {
+		h.Logger.Info("cursor参数无效", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("cursor参数无效"))
+		return
+	}
+
+	var userList []*dbstruct.User
+	var page *model.Page[*dbstruct.User]
+	if cursorMode {
+		page, err = h.UserService.GetUserListByCursor(cursor, num)
+		if err == nil {
+			userList = page.Items
+		}
+	} else {
+		userList, err = h.UserService.GetUserList(offset, num)
+	}
 	if err != nil {
 		h.Logger.Info("获取用户列表失败",
 			"error", err, "offset", offset, "num", num,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("无法获取指定范围的用户列表")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("无法获取指定范围的用户列表"))
 		return
 	}
 
 	var resUserList []dto.UserInfo
 	for _, userModel := range userList {
 		resUserList = append(resUserList, dto.UserInfo{
-			UserId:     userModel.UserId,
-			Email:      userModel.Email,
-			LastActive: userModel.LastActive.Format("2006-01-02 15:04:05"),
-			AvatarUrl:  userModel.AvatarUrl,
-			Role:       userModel.Role,
-			Username:   userModel.Username,
-			Extension:  userModel.Extension,
+			UserId:         userModel.UserId,
+			Email:          userModel.Email,
+			LastActive:     userModel.LastActive.Format("2006-01-02 15:04:05"),
+			AvatarUrl:      userModel.AvatarUrl,
+			AvatarVariants: model.ParseImageVariants(userModel.AvatarVariants),
+			Role:           userModel.Role,
+			Username:       userModel.Username,
+			Extension:      userModel.Extension,
 
 			CreatedAt: userModel.CreatedAt.Format("2006-01-02 15:04:05"),
 			UpdatedAt: userModel.UpdatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
 
-	ctx.JSON(resUserList)
+	if cursorMode {
+		WriteOK(ctx, toCursorPage(page, resUserList))
+		return
+	}
+
+	WriteOK(ctx, resUserList)
 }
 
 func (h *UserHandler) GetPing(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
 	userRole := ctx.Values().GetString("user_claims_user_role")
 	if userRole == "" {
-		ctx.StatusCode(iris.StatusForbidden)
+		WriteError(ctx, apperr.ErrForbidden)
 		return
 	}
 
@@ -135,100 +157,77 @@ func (h *UserHandler) GetPing(ctx iris.Context) {
 			"error", err, "user_id", userId,
 		)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("更新用户状态失败")
+		WriteServiceError(ctx, err, apperr.ErrBadRequest.WithMessage("更新用户状态失败"))
 		return
 	}
 
-	ctx.Text("pong")
+	WriteOK(ctx, "pong")
 }
 
-func (h *UserHandler) PostUploadAvatar(ctx iris.Context) {
+// GetLlmUsage 查看当天的LLM调用用量与额度
+func (h *UserHandler) GetLlmUsage(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		return
-	}
-
-	file, header, err := ctx.FormFile("avatar")
-	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("未成功获取上传的logo文件")
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
-	defer file.Close()
-
-	var detectedContentType string
-
-	if clientContentType := header.Header.Get("Content-Type"); clientContentType != "" {
-		detectedContentType = clientContentType
-	} else {
-		fileHeaderBytes := make([]byte, 512)
-		_, readErr := file.Read(fileHeaderBytes)
-		if readErr != nil && readErr != io.EOF {
-			ctx.StatusCode(iris.StatusBadRequest)
-			ctx.Text("无法读取文件头部以检测类型。")
-			return
-		}
+	userRole := ctx.Values().GetString("user_claims_user_role")
 
-		_, seekErr := file.Seek(0, 0)
-		if seekErr != nil {
-			ctx.StatusCode(iris.StatusInternalServerError)
-			ctx.Text("无法重置文件读取位置。")
-			return
-		}
+	usage, quota, err := h.LlmUsageService.GetUsage(userId, userRole)
+	if err != nil {
+		h.Logger.Error("获取LLM用量失败", "error", err, "user_id", userId)
 
-		detectedContentType = http.DetectContentType(fileHeaderBytes)
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("获取LLM用量失败"))
+		return
 	}
 
-	var fileExtension string
-
-	extensions, err := mime.ExtensionsByType(detectedContentType)
-	if err == nil && len(extensions) > 0 {
-		fileExtension = extensions[0]
-		if !strings.HasPrefix(fileExtension, ".") {
-			fileExtension = "." + fileExtension
-		}
-	} else {
-		fileExtension = ".bin"
-	}
+	WriteOK(ctx, dto.LlmUsageResponse{
+		Date:          time.Now().Format(time.DateOnly),
+		Role:          userRole,
+		Requests:      usage.Requests,
+		Bytes:         usage.Bytes,
+		Tokens:        usage.Tokens,
+		RequestsQuota: quota.DailyRequests,
+		TokensQuota:   quota.DailyTokens,
+	})
+}
 
-	if err := os.MkdirAll(USR_AVATAR_DIR, os.ModePerm); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("目录创建失败")
+func (h *UserHandler) PostUploadAvatar(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest)
 		return
 	}
 
-	filePath := filepath.Join(USR_AVATAR_DIR, "avatar_"+strconv.Itoa(userId)+fileExtension)
-	dst, err := os.Create(filePath)
+	file, _, err := ctx.FormFile("avatar")
 	if err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("文件创建失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("未成功获取上传的头像文件"))
 		return
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("文件保存失败")
+	defer file.Close()
+
+	variants, err := h.ImageService.StoreSquareVariants(ctx.Request().Context(), USR_AVATAR_PREFIX, file)
+	if err != nil {
+		h.Logger.Info("保存头像失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("文件保存失败"))
 		return
 	}
 
-	if err := h.UserService.UpdateAvatar(userId, filePath); err != nil {
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("数据库更新失败")
+	if err := h.UserService.UpdateAvatar(userId, variants); err != nil {
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("数据库更新失败"))
 		return
 	}
 
-	ctx.JSON(iris.Map{"status": "文件上传成功", "path": filePath})
+	WriteOK(ctx, iris.Map{"status": "文件上传成功", "path": variants.Default(), "variants": variants})
 }
 
 func (h *UserHandler) PutUpdateUserInfo(ctx iris.Context) {
 	userId, err := ctx.Values().GetInt("user_claims_user_id")
 	if err != nil {
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("用户ID获取失败")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
 		return
 	}
 
@@ -236,45 +235,61 @@ func (h *UserHandler) PutUpdateUserInfo(ctx iris.Context) {
 	if err := ctx.ReadJSON(&reqBody); err != nil {
 		h.Logger.Info("UpdateUser请求格式错误", "error", err)
 
-		ctx.StatusCode(iris.StatusBadRequest)
-		ctx.Text("请求格式错误")
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
 		return
 	}
 
-	hashedPwd := ""
+	// 修改密码需校验原密码并注销其他会话，统一走PutChangePassword
 	if reqBody.Password != "" {
-		hashedBytes, err := bcrypt.GenerateFromPassword(
-			[]byte(reqBody.Password),
-			bcrypt.DefaultCost,
-		)
-		if err != nil {
-			h.Logger.Info("bcrypt加密密码失败",
-				"error", err, "encrypted_password", reqBody.Password,
-			)
-
-			ctx.StatusCode(iris.StatusBadRequest)
-			ctx.Text("密码无效")
-			return
-		}
-
-		hashedPwd = string(hashedBytes)
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请使用修改密码接口/api/user/password"))
+		return
 	}
 
 	if err := h.UserService.UpdateUser(&dbstruct.User{
-		UserId:       uint(userId),
-		Username:     reqBody.Username,
-		PasswordHash: hashedPwd,
-		Email:        reqBody.Email,
-		Extension:    reqBody.Extension,
+		UserId:    uint(userId),
+		Username:  reqBody.Username,
+		Email:     reqBody.Email,
+		Extension: reqBody.Extension,
 	}); err != nil {
 		h.Logger.Info("更新用户信息失败",
 			"error", err, "user_id", reqBody.UserId,
 		)
 
-		ctx.StatusCode(iris.StatusInternalServerError)
-		ctx.Text("更新用户信息失败")
+		WriteError(ctx, apperr.ErrInternal.WithMessage("更新用户信息失败"))
+		return
+	}
+
+	WriteOK(ctx, nil)
+}
+
+func (h *UserHandler) PutChangePassword(ctx iris.Context) {
+	userId, err := ctx.Values().GetInt("user_claims_user_id")
+	if err != nil {
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("用户ID获取失败"))
 		return
 	}
 
-	ctx.Text("更新用户信息成功")
+	var reqBody dto.ChangePasswordRequest
+	if err := ctx.ReadJSON(&reqBody); err != nil {
+		h.Logger.Info("ChangePassword请求格式错误", "error", err)
+
+		WriteError(ctx, apperr.ErrBadRequest.WithMessage("请求格式错误"))
+		return
+	}
+
+	if err := h.UserService.ChangePassword(
+		userId, reqBody.OldPassword, reqBody.NewPassword,
+	); err != nil {
+		h.Logger.Info("修改密码失败", "error", err, "user_id", userId)
+
+		WriteServiceError(ctx, err, apperr.ErrInternal.WithMessage("修改密码失败"))
+		return
+	}
+
+	// 修改密码后包括当前设备在内的全部登录状态失效，需重新登录
+	if err := h.AuthService.RevokeAllSessions(userId); err != nil {
+		h.Logger.Error("修改密码后吊销登录状态失败", "error", err, "user_id", userId)
+	}
+
+	WriteOK(ctx, nil)
 }
@@ -0,0 +1,25 @@
+package model
+
+import "whuclubsynapse-server/internal/shared/dbstruct"
+
+// 社团内角色等级，数值越大权限越高，未知角色为0
+var clubRoleLevels = map[string]int{
+	dbstruct.ROLE_CLUB_MEMBER:      1,
+	dbstruct.ROLE_CLUB_OFFICER:     2,
+	dbstruct.ROLE_CLUB_VICE_LEADER: 3,
+	dbstruct.ROLE_CLUB_LEADER:      4,
+}
+
+func ClubRoleLevel(role string) int {
+	return clubRoleLevels[role]
+}
+
+func IsValidClubRole(role string) bool {
+	return ClubRoleLevel(role) > 0
+}
+
+// HasClubPermission 判断role是否不低于required
+func HasClubPermission(role, required string) bool {
+	level := ClubRoleLevel(role)
+	return level > 0 && level >= ClubRoleLevel(required)
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Cursor 游标分页的定位点，按 (创建时间, 主键) 倒序翻页
+type Cursor struct {
+	CreatedAt time.Time
+	Id        uint
+}
+
+type sCursorPayload struct {
+	T int64 `json:"t"`
+	I uint  `json:"i"`
+}
+
+// Page 游标分页结果，NextCursor仅在HasMore为true时有效
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	HasMore    bool
+}
+
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(sCursorPayload{
+		T: c.CreatedAt.UnixMicro(),
+		I: c.Id,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload sCursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+
+	if payload.I == 0 {
+		return nil, errors.New("游标无效")
+	}
+
+	// created_at为不带时区的TIMESTAMP，pgx按UTC墙上时间写入参数，本地时区的时间会偏移
+	return &Cursor{
+		CreatedAt: time.UnixMicro(payload.T).UTC(),
+		Id:        payload.I,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"普通", Cursor{CreatedAt: time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC), Id: 42}},
+		{"微秒精度", Cursor{CreatedAt: time.Date(2025, 6, 1, 12, 30, 0, 123456000, time.UTC), Id: 1}},
+		{"零时间", Cursor{Id: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+
+			if got.Id != tt.cursor.Id || !got.CreatedAt.Equal(tt.cursor.CreatedAt) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestCursorTruncatesToMicroseconds(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 123456789, time.UTC), Id: 3}
+
+	got, err := DecodeCursor(EncodeCursor(c))
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+
+	// Postgres的TIMESTAMP精度为微秒，游标与之一致
+	if want := c.CreatedAt.Truncate(time.Microsecond); !got.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, want)
+	}
+}
+
+func TestDecodeCursorReturnsUTC(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*60*60)
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{"UTC时间", time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)},
+		{"东八区时间", time.Date(2025, 6, 1, 20, 30, 0, 0, shanghai)},
+		{"本地时区时间", time.Date(2025, 6, 1, 12, 30, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(Cursor{CreatedAt: tt.createdAt, Id: 1}))
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+
+			if got.CreatedAt.Location() != time.UTC {
+				t.Errorf("CreatedAt.Location() = %v, want UTC", got.CreatedAt.Location())
+			}
+			if !got.CreatedAt.Equal(tt.createdAt) {
+				t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, tt.createdAt)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"空串", ""},
+		{"非base64", "!!!"},
+		{"带填充的base64", base64.URLEncoding.EncodeToString([]byte(`{"t":1,"i":1}`))},
+		{"非JSON", encode("not json")},
+		{"缺少主键", encode(`{"t":1}`)},
+		{"主键为0", encode(`{"t":1,"i":0}`)},
+		{"主键为负", encode(`{"t":1,"i":-1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeCursor(tt.token); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want error", tt.token, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strconv"
+	"whuclubsynapse-server/internal/shared/jsonbutil"
+
+	"gorm.io/datatypes"
+)
+
+// 头像与社团logo统一处理成的正方形边长，由大到小依次缩放
+var IMAGE_VARIANT_SIZES = []int{512, 256, 64}
+
+// 兼容只读取单个地址的旧字段（avatar_url、logo_url）时使用的尺寸
+const IMAGE_DEFAULT_VARIANT = 256
+
+// ImageVariants 图片各尺寸的访问地址，键为边长
+type ImageVariants map[string]string
+
+func VariantKey(size int) string {
+	return strconv.Itoa(size)
+}
+
+// Default 返回默认尺寸的地址
+func (v ImageVariants) Default() string {
+	return v[VariantKey(IMAGE_DEFAULT_VARIANT)]
+}
+
+func (v ImageVariants) ToJsonb() (datatypes.JSON, error) {
+	return jsonbutil.ToJsonb(v)
+}
+
+// ParseImageVariants 未上传或旧数据没有多尺寸信息时返回nil
+func ParseImageVariants(data datatypes.JSON) ImageVariants {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var variants ImageVariants
+	if err := jsonbutil.FromJsonb(data, &variants); err != nil {
+		return nil
+	}
+
+	return variants
+}
@@ -13,6 +13,30 @@ type UserClaims struct {
 	UserId int
 	Role   string
 	Uuid   string
+
+	// TokenId 用于注销时单独吊销该token
+	TokenId string
+	// TokenVersion 签发时用户的token版本，角色变更等场景递增版本使旧token全部失效
+	TokenVersion int64
+}
+
+// TokenPair 登录或刷新时签发的一组凭证，ExpiresIn为access token有效秒数
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
+const (
+	CLAIMS_PURPOSE_CHECKIN = "event_checkin"
+)
+
+// CheckinClaims 活动签到二维码中携带的短期凭证
+type CheckinClaims struct {
+	Purpose  string
+	EventId  int
+	ClubId   int
+	IssuerId int
 }
 
 func Encrypt(key []byte, str string) (string, error) {
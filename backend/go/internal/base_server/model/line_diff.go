@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	DIFF_EQUAL  = "equal"
+	DIFF_INSERT = "insert"
+	DIFF_DELETE = "delete"
+)
+
+var ErrDiffTooLarge = errors.New("文本过长，无法计算差异")
+
+type DiffLine struct {
+	Op      string
+	Content string
+}
+
+// DiffLines 按行计算from到to的差异，去掉首尾相同的行后用LCS求解，
+// 剩余部分的行数乘积超过maxCells时返回ErrDiffTooLarge
+func DiffLines(from, to string, maxCells int) ([]DiffLine, error) {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > maxCells {
+		return nil, ErrDiffTooLarge
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DIFF_EQUAL, Content: line})
+	}
+
+	// lcs[i][j] 为midA[i:]与midB[j:]的最长公共子序列长度
+	n, m := len(midA), len(midB)
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case midA[i] == midB[j]:
+			lines = append(lines, DiffLine{Op: DIFF_EQUAL, Content: midA[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DIFF_DELETE, Content: midA[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DIFF_INSERT, Content: midB[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, DiffLine{Op: DIFF_DELETE, Content: midA[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, DiffLine{Op: DIFF_INSERT, Content: midB[j]})
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DIFF_EQUAL, Content: line})
+	}
+
+	return lines, nil
+}
+
+// PostDiff 帖子两个版本间的差异，帖子当前内容的版本号为历史版本数+1
+type PostDiff struct {
+	PostId      uint
+	FromVersion int
+	ToVersion   int
+	FromTitle   string
+	ToTitle     string
+	Lines       []DiffLine
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sFormatDiff 将差异格式化为" 行"、"-行"、"+行"，便于在用例中书写期望值
+func sFormatDiff(lines []DiffLine) []string {
+	res := make([]string, 0, len(lines))
+	for _, line := range lines {
+		switch line.Op {
+		case DIFF_EQUAL:
+			res = append(res, " "+line.Content)
+		case DIFF_DELETE:
+			res = append(res, "-"+line.Content)
+		case DIFF_INSERT:
+			res = append(res, "+"+line.Content)
+		}
+	}
+	return res
+}
+
+// sApplyDiff 由差异还原出两侧的文本
+func sApplyDiff(lines []DiffLine) (string, string) {
+	var from, to []string
+	for _, line := range lines {
+		if line.Op != DIFF_INSERT {
+			from = append(from, line.Content)
+		}
+		if line.Op != DIFF_DELETE {
+			to = append(to, line.Content)
+		}
+	}
+	return strings.Join(from, "\n"), strings.Join(to, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []string
+	}{
+		{"内容相同", "a\nb", "a\nb", []string{" a", " b"}},
+		{"空文本", "", "", []string{" "}},
+		{"从空文本新增", "", "a", []string{"-", "+a"}},
+		{"末尾追加", "a\nb", "a\nb\nc", []string{" a", " b", "+c"}},
+		{"开头删除", "a\nb\nc", "b\nc", []string{"-a", " b", " c"}},
+		{"中间替换", "a\nb\nc", "a\nx\nc", []string{" a", "-b", "+x", " c"}},
+		{"保留最长公共子序列", "a\nb\nc\nd", "b\nd\ne", []string{"-a", " b", "-c", " d", "+e"}},
+		{"完全不同时先删后增", "a\nb", "c\nd", []string{"-a", "-b", "+c", "+d"}},
+		{"重复行", "a\na\nb", "a\nb\nb", []string{" a", "-a", "+b", " b"}},
+		{"保留行尾换行", "a\n", "a\nb\n", []string{" a", "+b", " "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := DiffLines(tt.from, tt.to, 1000)
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+
+			if got := sFormatDiff(lines); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("DiffLines() = %q, want %q", got, tt.want)
+			}
+
+			if from, to := sApplyDiff(lines); from != tt.from || to != tt.to {
+				t.Errorf("差异还原为 (%q, %q), want (%q, %q)", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestDiffLinesMaxCells(t *testing.T) {
+	long := strings.Repeat("same\n", 500)
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		maxCells int
+		wantErr  bool
+	}{
+		// 首尾相同的行不参与LCS，不计入单元格数
+		{"长文本仅首尾相同", long + "a", long + "b", 1, false},
+		{"相同文本", long, long, 0, false},
+		{"恰好等于上限", "a\nb\nc", "x\ny\nz", 9, false},
+		{"超出上限", "a\nb\nc", "x\ny\nz", 8, true},
+		{"一侧为纯新增", "a\nc", "a\nb1\nb2\nb3\nc", 0, false},
+		{"去掉首尾后仍超出", "h\n" + long + "t", "h\n" + strings.Repeat("other\n", 500) + "t", 1000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := DiffLines(tt.from, tt.to, tt.maxCells)
+			if tt.wantErr {
+				if !errors.Is(err, ErrDiffTooLarge) {
+					t.Fatalf("DiffLines() error = %v, want ErrDiffTooLarge", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+			if from, to := sApplyDiff(lines); from != tt.from || to != tt.to {
+				t.Errorf("差异无法还原原文")
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package model
+
+// LlmUsageSummary 一段时间内的LLM调用汇总，Requests只含上游已响应的调用
+type LlmUsageSummary struct {
+	UserId        uint  // 按用户汇总时有效
+	Users         int64 // 涉及的用户数，仅总计有效
+	Requests      int64
+	Rejected      int64
+	Failed        int64
+	ResponseBytes int64
+	Tokens        int64
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// 行内链接与图片：[text](target "title") / ![alt](<target>)
+	kInlineLinkRegexp = regexp.MustCompile(`(!?\[[^\]]*\]\(\s*)(<[^>\n]*>|[^)\s]+)((?:\s+"[^"\n]*")?\s*\))`)
+	// 引用式链接定义：[id]: target "title"
+	kRefLinkRegexp = regexp.MustCompile(`(?m)^( {0,3}\[[^\]\n]+\]:[ \t]*)(<[^>\n]*>|\S+)`)
+)
+
+// RewriteMarkdownLinks 将正文中行内链接、图片及引用式链接定义的目标交给resolve改写，
+// resolve返回false时保留原目标
+func RewriteMarkdownLinks(content string, resolve func(target string) (string, bool)) string {
+	rewrite := func(re *regexp.Regexp, content string) string {
+		return re.ReplaceAllStringFunc(content, func(match string) string {
+			groups := re.FindStringSubmatch(match)
+
+			target := groups[2]
+			if strings.HasPrefix(target, "<") {
+				target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+
+			resolved, ok := resolve(target)
+			if !ok {
+				return match
+			}
+
+			suffix := ""
+			if len(groups) > 3 {
+				suffix = groups[3]
+			}
+
+			return groups[1] + resolved + suffix
+		})
+	}
+
+	return rewrite(kRefLinkRegexp, rewrite(kInlineLinkRegexp, content))
+}
@@ -0,0 +1,7 @@
+package model
+
+// ReactionSummary 单个帖子或评论的回应汇总，MyReaction为调用者的回应，未回应时为空
+type ReactionSummary struct {
+	Counts     map[string]int64
+	MyReaction string
+}
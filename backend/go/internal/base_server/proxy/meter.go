@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// 非流式响应最多保留的字节数，超出时不再解析token数
+	kMeterMaxBody = 256 << 10
+	// 识别data事件只需行首，"data: [DONE]"不超过此长度
+	kMeterLinePrefix = 16
+)
+
+// Meter 包装ResponseWriter，统计转发响应的状态码、字节数与token数。
+// 流式响应每个data事件对应一个增量片段，按事件数计为token数，结束标记[DONE]除外；
+// 其余响应读取JSON中的usage.total_tokens，读取不到时为0
+type Meter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int64
+
+	stream bool
+	events int64
+	line   []byte
+
+	body []byte
+}
+
+func NewMeter(w http.ResponseWriter) *Meter {
+	return &Meter{ResponseWriter: w}
+}
+
+func (m *Meter) WriteHeader(code int) {
+	if m.status == 0 {
+		m.status = code
+		m.stream = strings.HasPrefix(m.Header().Get("Content-Type"), "text/event-stream")
+	}
+
+	m.ResponseWriter.WriteHeader(code)
+}
+
+func (m *Meter) Write(p []byte) (int, error) {
+	if m.status == 0 {
+		m.WriteHeader(http.StatusOK)
+	}
+
+	n, err := m.ResponseWriter.Write(p)
+	m.bytes += int64(n)
+
+	if m.stream {
+		m.sScanEvents(p[:n])
+	} else if m.bytes <= kMeterMaxBody {
+		m.body = append(m.body, p[:n]...)
+	} else {
+		m.body = nil
+	}
+
+	return n, err
+}
+
+// Unwrap 供http.ResponseController找到底层的Flusher
+func (m *Meter) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
+// sScanEvents 只保留每行的开头部分，写入可能在任意位置截断一行
+func (m *Meter) sScanEvents(p []byte) {
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			m.sAppendLine(p)
+			return
+		}
+
+		m.sAppendLine(p[:i])
+		if data, ok := bytes.CutPrefix(m.line, []byte("data:")); ok &&
+			!bytes.Equal(bytes.TrimSpace(data), []byte("[DONE]")) {
+			m.events++
+		}
+
+		m.line = m.line[:0]
+		p = p[i+1:]
+	}
+}
+
+func (m *Meter) sAppendLine(p []byte) {
+	if room := kMeterLinePrefix - len(m.line); room > 0 {
+		m.line = append(m.line, p[:min(room, len(p))]...)
+	}
+}
+
+// Status 尚未写入响应时为0
+func (m *Meter) Status() int {
+	return m.status
+}
+
+func (m *Meter) Bytes() int64 {
+	return m.bytes
+}
+
+func (m *Meter) Tokens() int64 {
+	if m.stream {
+		return m.events
+	}
+
+	if len(m.body) == 0 {
+		return 0
+	}
+
+	var res struct {
+		Usage struct {
+			TotalTokens int64 `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(m.body, &res); err != nil {
+		return 0
+	}
+
+	return res.Usage.TotalTokens
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMeterTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		writes      []string
+		wantTokens  int64
+	}{
+		{
+			name:        "流式响应按data事件计数",
+			contentType: "text/event-stream",
+			writes:      []string{"data: {\"a\":1}\n\n", "data: {\"a\":2}\n\n", "data: [DONE]\n\n"},
+			wantTokens:  2,
+		},
+		{
+			name:        "事件在任意位置被截断",
+			contentType: "text/event-stream; charset=utf-8",
+			writes:      []string{"da", "ta: {\"a\"", ":1}\n", "\ndata: x\n\nd", "ata: [DO", "NE]\n\n"},
+			wantTokens:  2,
+		},
+		{
+			name:        "CRLF换行",
+			contentType: "text/event-stream",
+			writes:      []string{"data: x\r\n\r\ndata: [DONE]\r\n\r\n"},
+			wantTokens:  1,
+		},
+		{
+			name:        "多个事件在同一次写入中",
+			contentType: "text/event-stream",
+			writes:      []string{"data: a\n\ndata: b\n\ndata: c\n\n"},
+			wantTokens:  3,
+		},
+		{
+			name:        "超长data行",
+			contentType: "text/event-stream",
+			writes:      []string{"data: " + strings.Repeat("x", 100), strings.Repeat("y", 100) + "\n\n"},
+			wantTokens:  1,
+		},
+		{
+			name:        "非data行不计数",
+			contentType: "text/event-stream",
+			writes:      []string{"event: ping\n", ": keep-alive\n", "id: 1\n", "retry: 1000\n\n"},
+			wantTokens:  0,
+		},
+		{
+			name:        "JSON响应读取usage",
+			contentType: "application/json",
+			writes:      []string{`{"id":"x","usage":{"prompt_tokens":10,`, `"total_tokens":42}}`},
+			wantTokens:  42,
+		},
+		{
+			name:        "JSON响应没有usage",
+			contentType: "application/json",
+			writes:      []string{`{"id":"x"}`},
+			wantTokens:  0,
+		},
+		{
+			name:        "非JSON响应",
+			contentType: "text/plain",
+			writes:      []string{"data: x\n\n"},
+			wantTokens:  0,
+		},
+		{
+			name:        "响应体超出保留上限",
+			contentType: "application/json",
+			writes:      []string{`{"usage":{"total_tokens":42},"pad":"`, strings.Repeat("x", kMeterMaxBody), `"}`},
+			wantTokens:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			meter := NewMeter(rec)
+
+			meter.Header().Set("Content-Type", tt.contentType)
+			meter.WriteHeader(http.StatusOK)
+
+			var wantBytes int64
+			for _, p := range tt.writes {
+				if _, err := meter.Write([]byte(p)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				wantBytes += int64(len(p))
+			}
+
+			if got := meter.Tokens(); got != tt.wantTokens {
+				t.Errorf("Tokens() = %d, want %d", got, tt.wantTokens)
+			}
+			if got := meter.Bytes(); got != wantBytes {
+				t.Errorf("Bytes() = %d, want %d", got, wantBytes)
+			}
+			if got := rec.Body.String(); got != strings.Join(tt.writes, "") {
+				t.Errorf("客户端收到的响应与上游不一致")
+			}
+		})
+	}
+}
+
+func TestMeterStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(m *Meter)
+		wantStatus int
+	}{
+		{"未写入响应", func(m *Meter) {}, 0},
+		{"直接写入响应体", func(m *Meter) { m.Write([]byte("ok")) }, http.StatusOK},
+		{"上游错误状态码", func(m *Meter) { m.WriteHeader(http.StatusBadGateway) }, http.StatusBadGateway},
+		{"只记录首次状态码", func(m *Meter) {
+			m.WriteHeader(http.StatusTooManyRequests)
+			m.WriteHeader(http.StatusOK)
+		}, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meter := NewMeter(httptest.NewRecorder())
+			tt.write(meter)
+
+			if got := meter.Status(); got != tt.wantStatus {
+				t.Errorf("Status() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/baseconfig"
+)
+
+const (
+	kDefaultTimeout     = 30 * time.Second
+	kDefaultMaxBodySize = 64 << 10
+)
+
+var (
+	ErrRouteDenied         = errors.New("不支持的转发接口")
+	ErrBodyTooLarge        = errors.New("请求体大小超出限制")
+	ErrUpstreamUnavailable = errors.New("上游服务暂不可用")
+	ErrUpstreamTimeout     = errors.New("上游服务响应超时")
+	// ErrClientGone 客户端在上游响应前断开，无需再写入响应
+	ErrClientGone = errors.New("客户端已断开")
+)
+
+// 转发给上游的请求头白名单，用户的Authorization、Cookie等一律不转发
+var kForwardHeaders = []string{
+	"Accept",
+	"Accept-Language",
+	"Cache-Control",
+	"Content-Type",
+	"Last-Event-ID",
+	"User-Agent",
+}
+
+// 不回传给客户端的上游响应头；CORS头由服务端统一设置
+var kDroppedResponseHeaders = []string{
+	"Set-Cookie",
+	"Server",
+	"X-Powered-By",
+}
+
+// Route 允许转发的上游接口，Path不含前导斜杠
+type Route struct {
+	Path        string
+	Methods     []string
+	Timeout     time.Duration
+	MaxBodySize int64
+}
+
+// RoutesFromConfig 转换配置中的接口列表，未配置的超时与大小上限使用默认值
+func RoutesFromConfig(cfgRoutes []baseconfig.ProxyRoute) []Route {
+	routes := make([]Route, 0, len(cfgRoutes))
+	for _, cfgRoute := range cfgRoutes {
+		route := Route{
+			Path:        strings.Trim(cfgRoute.Path, "/"),
+			Timeout:     time.Duration(cfgRoute.Timeout) * time.Second,
+			MaxBodySize: cfgRoute.MaxBodySize << 10,
+		}
+		for _, method := range cfgRoute.Methods {
+			route.Methods = append(route.Methods, strings.ToUpper(method))
+		}
+		if route.Timeout <= 0 {
+			route.Timeout = kDefaultTimeout
+		}
+		if route.MaxBodySize <= 0 {
+			route.MaxBodySize = kDefaultMaxBodySize
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// Proxy 转发到单个上游服务，只放行白名单中的接口
+type Proxy struct {
+	name    string
+	routes  map[string]*Route
+	headers map[string]string
+
+	reverseProxy *httputil.ReverseProxy
+
+	logger *slog.Logger
+}
+
+type sStateKey struct{}
+
+// sForwardState 在单次转发的各个回调之间传递接口与错误
+type sForwardState struct {
+	route *Route
+	err   error
+}
+
+// NewProxy name用于日志；headers为转发时附加的请求头，如上游服务的鉴权信息
+func NewProxy(
+	name, addr string,
+	routes []Route,
+	headers map[string]string,
+	logger *slog.Logger,
+) (*Proxy, error) {
+	target, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" || target.Host == "" {
+		return nil, errors.New("无效的上游地址：" + addr)
+	}
+
+	p := &Proxy{
+		name:    name,
+		routes:  make(map[string]*Route, len(routes)),
+		headers: headers,
+		logger:  logger,
+	}
+	for i := range routes {
+		p.routes[routes[i].Path] = &routes[i]
+	}
+
+	p.reverseProxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			state := pr.In.Context().Value(sStateKey{}).(*sForwardState)
+
+			pr.Out.URL.Path = "/" + state.route.Path
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(target)
+
+			header := make(http.Header, len(kForwardHeaders)+len(p.headers))
+			for _, key := range kForwardHeaders {
+				if values := pr.In.Header.Values(key); len(values) > 0 {
+					header[key] = slices.Clone(values)
+				}
+			}
+			for key, value := range p.headers {
+				header.Set(key, value)
+			}
+			pr.Out.Header = header
+		},
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// 每次写入后立即刷新，SSE事件不会在缓冲中滞留
+		FlushInterval: -1,
+		ModifyResponse: func(res *http.Response) error {
+			for _, key := range kDroppedResponseHeaders {
+				res.Header.Del(key)
+			}
+			for key := range res.Header {
+				if strings.HasPrefix(key, "Access-Control-") {
+					res.Header.Del(key)
+				}
+			}
+			return nil
+		},
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			state := r.Context().Value(sStateKey{}).(*sForwardState)
+
+			switch {
+			case errors.Is(err, context.DeadlineExceeded):
+				state.err = ErrUpstreamTimeout
+			case errors.Is(err, context.Canceled):
+				state.err = ErrClientGone
+			default:
+				state.err = ErrUpstreamUnavailable
+			}
+
+			p.logger.Error("转发请求失败",
+				"error", err, "upstream", p.name, "route", state.route.Path,
+			)
+		},
+	}
+
+	return p, nil
+}
+
+// Match 查找允许以method访问的path接口，path首尾的斜杠忽略
+func (p *Proxy) Match(method, path string) (*Route, bool) {
+	route, ok := p.routes[strings.Trim(path, "/")]
+	if !ok || !slices.Contains(route.Methods, method) {
+		return nil, false
+	}
+
+	return route, true
+}
+
+// Forward 转发请求至上游的path接口。返回错误时尚未向客户端写入任何内容，由调用方写入错误响应；
+// 响应开始写入后客户端断开或超时只记录日志。客户端断开时上游请求随之取消
+func (p *Proxy) Forward(w http.ResponseWriter, r *http.Request, path string) error {
+	route, ok := p.Match(r.Method, path)
+	if !ok {
+		return ErrRouteDenied
+	}
+
+	if r.ContentLength > route.MaxBodySize {
+		return ErrBodyTooLarge
+	}
+
+	// 请求体较小，先完整读入再转发，超限时可以在写入上游前拒绝
+	body, err := io.ReadAll(io.LimitReader(r.Body, route.MaxBodySize+1))
+	if err != nil {
+		return ErrClientGone
+	}
+	if int64(len(body)) > route.MaxBodySize {
+		return ErrBodyTooLarge
+	}
+
+	state := &sForwardState{route: route}
+
+	ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+	defer cancel()
+
+	out := r.WithContext(context.WithValue(ctx, sStateKey{}, state))
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+
+	p.logger.Info("转发请求", "upstream", p.name, "route", route.Path, "method", r.Method)
+
+	// 响应写入中途失败时ReverseProxy以ErrAbortHandler中止处理，此时响应已无法修改
+	defer func() {
+		if rec := recover(); rec != nil {
+			if rec != http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			p.logger.Warn("转发响应中断",
+				"upstream", p.name, "route", route.Path, "error", ctx.Err(),
+			)
+		}
+	}()
+
+	p.reverseProxy.ServeHTTP(w, out)
+
+	return state.err
+}
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sNewTestProxy(t *testing.T, upstream http.HandlerFunc) *Proxy {
+	t.Helper()
+
+	server := httptest.NewServer(upstream)
+	t.Cleanup(server.Close)
+
+	p, err := NewProxy("test", server.URL,
+		[]Route{
+			{Path: "chat", Methods: []string{"POST"}, Timeout: time.Second, MaxBodySize: 16},
+			{Path: "generate/activity_post", Methods: []string{"GET", "POST"}, Timeout: time.Second, MaxBodySize: 16},
+			{Path: "slow", Methods: []string{"GET"}, Timeout: 50 * time.Millisecond, MaxBodySize: 16},
+		},
+		map[string]string{"Authorization": "upstream-key"},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewProxy() error = %v", err)
+	}
+
+	return p
+}
+
+// sEchoUpstream 返回收到的路径、鉴权头与请求体
+func sEchoUpstream(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/slow" {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Set-Cookie", "session=upstream")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	io.WriteString(w, r.URL.Path+"|"+r.Header.Get("Authorization")+"|"+r.Header.Get("Cookie")+"|"+string(body))
+}
+
+// sChunkedReader 隐藏具体类型，使请求以未知长度的分块方式发送
+type sChunkedReader struct{ io.Reader }
+
+func TestProxyForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   io.Reader
+
+		wantErr  error
+		wantBody string
+	}{
+		{
+			name:     "白名单接口",
+			method:   "POST",
+			path:     "chat",
+			body:     strings.NewReader("hello"),
+			wantBody: "/chat|upstream-key||hello",
+		},
+		{
+			name:     "多级路径与首尾斜杠",
+			method:   "GET",
+			path:     "/generate/activity_post/",
+			wantBody: "/generate/activity_post|upstream-key||",
+		},
+		{
+			name:    "不在白名单中的接口",
+			method:  "POST",
+			path:    "admin",
+			wantErr: ErrRouteDenied,
+		},
+		{
+			name:    "路径穿越",
+			method:  "POST",
+			path:    "chat/../admin",
+			wantErr: ErrRouteDenied,
+		},
+		{
+			name:    "不允许的方法",
+			method:  "GET",
+			path:    "chat",
+			wantErr: ErrRouteDenied,
+		},
+		{
+			name:     "请求体恰好达到上限",
+			method:   "POST",
+			path:     "chat",
+			body:     strings.NewReader(strings.Repeat("a", 16)),
+			wantBody: "/chat|upstream-key||" + strings.Repeat("a", 16),
+		},
+		{
+			name:    "声明长度超出上限",
+			method:  "POST",
+			path:    "chat",
+			body:    strings.NewReader(strings.Repeat("a", 17)),
+			wantErr: ErrBodyTooLarge,
+		},
+		{
+			name:    "分块请求体超出上限",
+			method:  "POST",
+			path:    "chat",
+			body:    sChunkedReader{strings.NewReader(strings.Repeat("a", 17))},
+			wantErr: ErrBodyTooLarge,
+		},
+		{
+			name:    "上游超时",
+			method:  "GET",
+			path:    "slow",
+			wantErr: ErrUpstreamTimeout,
+		},
+	}
+
+	p := sNewTestProxy(t, sEchoUpstream)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/trans/llm/"+tt.path, tt.body)
+			req.Header.Set("Authorization", "Bearer user-token")
+			req.Header.Set("Cookie", "session=user")
+			rec := httptest.NewRecorder()
+
+			err := p.Forward(rec, req, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Forward() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if rec.Body.Len() != 0 {
+					t.Errorf("出错时已写入响应：%q", rec.Body.String())
+				}
+				return
+			}
+
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("响应 = %q, want %q", got, tt.wantBody)
+			}
+			for _, key := range []string{"Set-Cookie", "Access-Control-Allow-Origin"} {
+				if got := rec.Header().Get(key); got != "" {
+					t.Errorf("响应头%s = %q, want empty", key, got)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyForwardUpstreamDown(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	addr := server.URL
+	server.Close()
+
+	p, err := NewProxy("test", addr,
+		[]Route{{Path: "chat", Methods: []string{"POST"}, Timeout: time.Second, MaxBodySize: 16}},
+		nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewProxy() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	err = p.Forward(rec, httptest.NewRequest("POST", "/api/trans/llm/chat", nil), "chat")
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("Forward() error = %v, want %v", err, ErrUpstreamUnavailable)
+	}
+}
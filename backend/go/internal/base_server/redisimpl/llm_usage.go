@@ -0,0 +1,118 @@
+package redisimpl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kLlmUsagePrefix = "llm_usage_"
+	// 计数保留到次日，跨零点的调用结束时仍能累计到开始当天
+	kLlmUsageTTL = 48 * time.Hour
+)
+
+// LlmUsage 用户单日的LLM调用用量
+type LlmUsage struct {
+	Requests int64
+	Bytes    int64
+	Tokens   int64
+}
+
+// 未超出额度时计入一次调用，额度为0表示不限；
+// 返回 {是否放行, requests, bytes, tokens}，拒绝时为计入前的用量
+var acquireLlmQuotaScript = redis.NewScript(`
+local key = KEYS[1]
+local maxRequests = tonumber(ARGV[1])
+local maxTokens = tonumber(ARGV[2])
+
+local usage = redis.call('HMGET', key, 'requests', 'bytes', 'tokens')
+local requests = tonumber(usage[1]) or 0
+local bytes = tonumber(usage[2]) or 0
+local tokens = tonumber(usage[3]) or 0
+
+if (maxRequests > 0 and requests >= maxRequests) or (maxTokens > 0 and tokens >= maxTokens) then
+	return {0, requests, bytes, tokens}
+end
+
+requests = redis.call('HINCRBY', key, 'requests', 1)
+redis.call('PEXPIRE', key, ARGV[3])
+return {1, requests, bytes, tokens}
+`)
+
+func sLlmUsageKey(day string, userId int) string {
+	return kLlmUsagePrefix + day + "_" + strconv.Itoa(userId)
+}
+
+// AcquireLlmQuota 检查用户day当天的用量，未超出额度时计入一次调用。
+// token数在调用结束后才累计，额度只约束之后的调用
+func (s *sRedisClientService) AcquireLlmQuota(
+	day string,
+	userId int,
+	maxRequests, maxTokens int64,
+) (bool, *LlmUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := acquireLlmQuotaScript.Run(ctx, s.client.Inst(),
+		[]string{sLlmUsageKey(day, userId)},
+		maxRequests, maxTokens, kLlmUsageTTL.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return false, nil, err
+	}
+
+	return res[0] == 1, &LlmUsage{Requests: res[1], Bytes: res[2], Tokens: res[3]}, nil
+}
+
+// IncrLlmUsage 累加用户day当天的用量，delta中的字段可为负数
+func (s *sRedisClientService) IncrLlmUsage(day string, userId int, delta *LlmUsage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	key := sLlmUsageKey(day, userId)
+	_, err := s.client.Inst().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if delta.Requests != 0 {
+			pipe.HIncrBy(ctx, key, "requests", delta.Requests)
+		}
+		if delta.Bytes != 0 {
+			pipe.HIncrBy(ctx, key, "bytes", delta.Bytes)
+		}
+		if delta.Tokens != 0 {
+			pipe.HIncrBy(ctx, key, "tokens", delta.Tokens)
+		}
+		pipe.Expire(ctx, key, kLlmUsageTTL)
+		return nil
+	})
+	return err
+}
+
+// GetLlmUsage 当天没有调用时各项均为0
+func (s *sRedisClientService) GetLlmUsage(day string, userId int) (*LlmUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	values, err := s.client.Inst().HMGet(ctx, sLlmUsageKey(day, userId),
+		"requests", "bytes", "tokens",
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]int64, len(values))
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		fields[i], err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &LlmUsage{Requests: fields[0], Bytes: fields[1], Tokens: fields[2]}, nil
+}
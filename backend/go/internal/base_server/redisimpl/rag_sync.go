@@ -0,0 +1,200 @@
+package redisimpl
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kRagSyncStream = "rag_sync_stream"
+)
+
+// rag_sync_stream消息的op字段（缺省时RAG侧按upsert处理）及元信息中的可见范围
+const (
+	RAG_OP_UPSERT = "upsert"
+	RAG_OP_DELETE = "delete"
+
+	RAG_VISIBILITY_PUBLIC  = "public"
+	RAG_VISIBILITY_MEMBERS = "members"
+	RAG_VISIBILITY_ADMIN   = "admin"
+)
+
+// RagMessage rag_sync_stream中的一条消息，Metadata写入时序列化为JSON字符串
+type RagMessage struct {
+	SourceId string
+	Op       string
+	Content  string
+	Metadata map[string]any
+}
+
+// RagClubSourceId RAG知识库中社团信息的来源ID
+func RagClubSourceId(clubId uint) string {
+	return "club_id::" + strconv.FormatUint(uint64(clubId), 10)
+}
+
+// RagPostSourceId RAG知识库中帖子的来源ID
+func RagPostSourceId(postId uint) string {
+	return "post_id::" + strconv.FormatUint(uint64(postId), 10)
+}
+
+// RagVisibility 帖子可见范围在RAG元信息中的取值，RAG侧只保留public的内容
+func RagVisibility(visibility int16) string {
+	switch visibility {
+	case 0:
+		return RAG_VISIBILITY_PUBLIC
+	case 1:
+		return RAG_VISIBILITY_MEMBERS
+	default:
+		return RAG_VISIBILITY_ADMIN
+	}
+}
+
+func NewClubRagMessage(clubInfo *dbstruct.Club) *RagMessage {
+	return &RagMessage{
+		SourceId: RagClubSourceId(clubInfo.ClubId),
+		Op:       RAG_OP_UPSERT,
+		Content:  clubInfo.Description,
+		Metadata: map[string]any{
+			"source_type":  "club",
+			"visibility":   RAG_VISIBILITY_PUBLIC,
+			"name":         clubInfo.Name,
+			"description":  clubInfo.Description,
+			"requirements": clubInfo.Requirements,
+			"tags":         clubInfo.Tags,
+			"category":     clubInfo.CategoryId,
+		},
+	}
+}
+
+func NewPostRagMessage(postInfo *dbstruct.ClubPost, content []byte) *RagMessage {
+	return &RagMessage{
+		SourceId: RagPostSourceId(postInfo.PostId),
+		Op:       RAG_OP_UPSERT,
+		Content:  string(content),
+		Metadata: map[string]any{
+			"source_type": "post",
+			"visibility":  RagVisibility(postInfo.Visibility),
+			"title":       postInfo.Title,
+			"club_id":     postInfo.ClubId,
+			"author_id":   postInfo.UserId,
+			"is_pinned":   postInfo.IsPinned,
+		},
+	}
+}
+
+// Values 写入流的字段
+func (m *RagMessage) Values() (map[string]any, error) {
+	metadata := m.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	metadataJson, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"source_id": m.SourceId,
+		"op":        m.Op,
+		"content":   m.Content,
+		"metadata":  string(metadataJson),
+	}, nil
+}
+
+func (s *sRedisClientService) UploadClubInfo(clubInfo *dbstruct.Club) (string, error) {
+	s.logger.Debug("上传社团信息", "club", clubInfo)
+
+	msgId, err := s.sAddRagMessage(NewClubRagMessage(clubInfo))
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("上传社团信息成功", "msg_id", msgId)
+
+	return msgId, nil
+}
+
+func (s *sRedisClientService) UploadPostInfo(postInfo *dbstruct.ClubPost, content []byte) (string, error) {
+	s.logger.Debug("上传帖子信息", "post", postInfo)
+
+	msgId, err := s.sAddRagMessage(NewPostRagMessage(postInfo, content))
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("上传帖子信息成功", "msg_id", msgId)
+
+	return msgId, nil
+}
+
+func (s *sRedisClientService) RemoveRagSource(sourceId string, metadata map[string]any) (string, error) {
+	msgId, err := s.sAddRagMessage(&RagMessage{
+		SourceId: sourceId,
+		Op:       RAG_OP_DELETE,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("移除RAG内容成功", "source_id", sourceId, "msg_id", msgId)
+
+	return msgId, nil
+}
+
+func (s *sRedisClientService) AddRagMessages(msgs []*RagMessage) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipe := s.client.Inst().Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(msgs))
+	for _, msg := range msgs {
+		values, err := msg.Values()
+		if err != nil {
+			return nil, err
+		}
+
+		cmds = append(cmds, pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: kRagSyncStream,
+			Values: values,
+		}))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("Redis操作异常", "error", err)
+		return nil, err
+	}
+
+	msgIds := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		msgIds = append(msgIds, cmd.Val())
+	}
+
+	return msgIds, nil
+}
+
+func (s *sRedisClientService) sAddRagMessage(msg *RagMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values, err := msg.Values()
+	if err != nil {
+		return "", err
+	}
+
+	msgId, err := s.client.Inst().XAdd(ctx, &redis.XAddArgs{
+		Stream: kRagSyncStream,
+		Values: values,
+	}).Result()
+	if err != nil {
+		s.logger.Error("Redis操作异常", "error", err)
+		return "", err
+	}
+
+	return msgId, nil
+}
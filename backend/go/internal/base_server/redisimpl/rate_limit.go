@@ -0,0 +1,109 @@
+package redisimpl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kRateLimitPrefix    = "rate_limit_"
+	kLoginFailurePrefix = "login_fail_"
+	kLoginLockPrefix    = "login_lock_"
+)
+
+// 滑动窗口：有序集合按请求时间（毫秒）记录窗口内的每次请求，
+// 超出上限时返回最早一次请求离开窗口前的剩余毫秒数
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+
+if redis.call('ZCARD', key) >= limit then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	return {0, tonumber(oldest[2]) + window - now}
+end
+
+redis.call('ZADD', key, now, ARGV[4])
+redis.call('PEXPIRE', key, window)
+return {1, 0}
+`)
+
+// SlidingWindowAllow 在window内最多放行limit次请求，拒绝时返回需等待的时长
+func (s *sRedisClientService) SlidingWindowAllow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 36)
+
+	res, err := slidingWindowScript.Run(ctx, s.client.Inst(),
+		[]string{kRateLimitPrefix + key},
+		now.UnixMilli(), window.Milliseconds(), limit, member,
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// RecordLoginFailure 记录一次登录失败，返回window内累计失败次数
+func (s *sRedisClientService) RecordLoginFailure(identifier string, window time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	key := kLoginFailurePrefix + identifier
+
+	var incr *redis.IntCmd
+	_, err := s.client.Inst().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (s *sRedisClientService) ResetLoginFailure(identifier string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return s.client.Inst().Del(ctx,
+		kLoginFailurePrefix+identifier,
+		kLoginLockPrefix+identifier,
+	).Err()
+}
+
+func (s *sRedisClientService) SetLoginLock(identifier string, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return s.client.Inst().Set(ctx, kLoginLockPrefix+identifier, 1, duration).Err()
+}
+
+// GetLoginLock 返回剩余锁定时长，未锁定时为0
+func (s *sRedisClientService) GetLoginLock(identifier string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ttl, err := s.client.Inst().PTTL(ctx, kLoginLockPrefix+identifier).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// 键不存在时PTTL返回负值
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
@@ -0,0 +1,99 @@
+package redisimpl
+
+import (
+	"testing"
+	"time"
+)
+
+// sWindowStep 依次发起的请求，sleep为发起前等待的时长
+type sWindowStep struct {
+	sleep time.Duration
+	want  bool
+}
+
+func TestSlidingWindowAllow(t *testing.T) {
+	s := sNewTestService(t)
+
+	tests := []struct {
+		name   string
+		limit  int
+		window time.Duration
+		steps  []sWindowStep
+	}{
+		{
+			name: "窗口内超出上限被拒绝", limit: 2, window: time.Second,
+			steps: []sWindowStep{{0, true}, {0, true}, {0, false}, {0, false}},
+		},
+		{
+			name: "最早的请求离开窗口后放行", limit: 2, window: 300 * time.Millisecond,
+			steps: []sWindowStep{{0, true}, {0, true}, {0, false}, {350 * time.Millisecond, true}},
+		},
+		{
+			name: "被拒绝的请求不计入窗口", limit: 1, window: 300 * time.Millisecond,
+			steps: []sWindowStep{{0, true}, {200 * time.Millisecond, false}, {150 * time.Millisecond, true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := sTestKey(t)
+
+			for i, step := range tt.steps {
+				time.Sleep(step.sleep)
+
+				allowed, retryAfter, err := s.SlidingWindowAllow(key, tt.limit, tt.window)
+				if err != nil {
+					t.Fatalf("第%d次请求 error = %v", i+1, err)
+				}
+				if allowed != step.want {
+					t.Fatalf("第%d次请求 allowed = %v, want %v", i+1, allowed, step.want)
+				}
+
+				if allowed && retryAfter != 0 {
+					t.Errorf("第%d次请求放行时 retryAfter = %v, want 0", i+1, retryAfter)
+				}
+				if !allowed && (retryAfter <= 0 || retryAfter > tt.window) {
+					t.Errorf("第%d次请求 retryAfter = %v, want (0, %v]", i+1, retryAfter, tt.window)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginFailureAndLock(t *testing.T) {
+	s := sNewTestService(t)
+	identifier := sTestKey(t)
+
+	for i := int64(1); i <= 3; i++ {
+		failNum, err := s.RecordLoginFailure(identifier, time.Minute)
+		if err != nil {
+			t.Fatalf("RecordLoginFailure() error = %v", err)
+		}
+		if failNum != i {
+			t.Fatalf("RecordLoginFailure() = %d, want %d", failNum, i)
+		}
+	}
+
+	if lock, err := s.GetLoginLock(identifier); err != nil || lock != 0 {
+		t.Fatalf("未锁定时 GetLoginLock() = (%v, %v), want (0, nil)", lock, err)
+	}
+
+	if err := s.SetLoginLock(identifier, time.Minute); err != nil {
+		t.Fatalf("SetLoginLock() error = %v", err)
+	}
+	if lock, err := s.GetLoginLock(identifier); err != nil || lock <= 0 || lock > time.Minute {
+		t.Fatalf("锁定后 GetLoginLock() = (%v, %v), want (0, 1m]", lock, err)
+	}
+
+	if err := s.ResetLoginFailure(identifier); err != nil {
+		t.Fatalf("ResetLoginFailure() error = %v", err)
+	}
+	if lock, err := s.GetLoginLock(identifier); err != nil || lock != 0 {
+		t.Fatalf("重置后 GetLoginLock() = (%v, %v), want (0, nil)", lock, err)
+	}
+	if failNum, err := s.RecordLoginFailure(identifier, time.Minute); err != nil || failNum != 1 {
+		t.Fatalf("重置后 RecordLoginFailure() = (%d, %v), want (1, nil)", failNum, err)
+	}
+
+	s.ResetLoginFailure(identifier)
+}
@@ -0,0 +1,121 @@
+package redisimpl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kReactionDeltaPrefix = "reaction_delta_"
+	kReactionDirtySet    = "reaction_dirty"
+)
+
+// 从对象累积的计数增量中扣除已合并写库的部分，全部归零后删除并移出待合并集合；
+// 合并期间新产生的增量保留到下次合并。ARGV为 target, kind1, delta1, kind2, delta2 ...
+var ackReactionDeltaScript = redis.NewScript(`
+for i = 2, #ARGV, 2 do
+	redis.call('HINCRBY', KEYS[1], ARGV[i], -tonumber(ARGV[i + 1]))
+end
+
+for _, v in ipairs(redis.call('HVALS', KEYS[1])) do
+	if tonumber(v) ~= 0 then
+		return 0
+	end
+end
+
+redis.call('DEL', KEYS[1])
+redis.call('SREM', KEYS[2], ARGV[1])
+return 1
+`)
+
+// IncrReactionDelta 累加回应计数增量，并将对象标记为待合并
+func (s *sRedisClientService) IncrReactionDelta(target, kind string, delta int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.client.Inst().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, kReactionDeltaPrefix+target, kind, delta)
+		pipe.SAdd(ctx, kReactionDirtySet, target)
+		return nil
+	})
+	return err
+}
+
+// GetReactionDeltas 批量读取尚未合并的计数增量，没有增量的对象不出现在结果中
+func (s *sRedisClientService) GetReactionDeltas(targets []string) (map[string]map[string]int64, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cmds := make([]*redis.MapStringStringCmd, len(targets))
+	_, err := s.client.Inst().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, target := range targets {
+			cmds[i] = pipe.HGetAll(ctx, kReactionDeltaPrefix+target)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]map[string]int64)
+	for i, cmd := range cmds {
+		deltas, err := sParseReactionDeltas(cmd.Val())
+		if err != nil {
+			return nil, err
+		}
+
+		if len(deltas) > 0 {
+			res[targets[i]] = deltas
+		}
+	}
+
+	return res, nil
+}
+
+// GetDirtyReactionTargets 随机取出至多num个待合并的对象
+func (s *sRedisClientService) GetDirtyReactionTargets(num int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return s.client.Inst().SRandMemberN(ctx, kReactionDirtySet, int64(num)).Result()
+}
+
+// AckReactionDeltas 在增量写库成功后调用，写库失败时增量留在Redis中等待下次合并
+func (s *sRedisClientService) AckReactionDeltas(target string, deltas map[string]int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := make([]any, 0, 1+2*len(deltas))
+	args = append(args, target)
+	for kind, delta := range deltas {
+		args = append(args, kind, delta)
+	}
+
+	return ackReactionDeltaScript.Run(ctx, s.client.Inst(),
+		[]string{kReactionDeltaPrefix + target, kReactionDirtySet},
+		args...,
+	).Err()
+}
+
+func sParseReactionDeltas(raw map[string]string) (map[string]int64, error) {
+	deltas := make(map[string]int64, len(raw))
+	for kind, str := range raw {
+		delta, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if delta != 0 {
+			deltas[kind] = delta
+		}
+	}
+
+	return deltas, nil
+}
@@ -0,0 +1,112 @@
+package redisimpl
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestAckReactionDeltas(t *testing.T) {
+	s := sNewTestService(t)
+
+	tests := []struct {
+		name  string
+		incrs map[string]int64
+		// beforeAck 模拟合并写库期间新产生的增量
+		beforeAck map[string]int64
+		ack       map[string]int64
+		want      map[string]int64
+		wantDirty bool
+	}{
+		{
+			name:      "全部扣除后移出待合并集合",
+			incrs:     map[string]int64{"like": 2, "love": -1},
+			ack:       map[string]int64{"like": 2, "love": -1},
+			want:      map[string]int64{},
+			wantDirty: false,
+		},
+		{
+			name:      "合并期间的新增量保留",
+			incrs:     map[string]int64{"like": 2},
+			beforeAck: map[string]int64{"like": 1, "wow": 1},
+			ack:       map[string]int64{"like": 2},
+			want:      map[string]int64{"like": 1, "wow": 1},
+			wantDirty: true,
+		},
+		{
+			name:      "增量已相互抵消",
+			incrs:     map[string]int64{"like": 0},
+			ack:       nil,
+			want:      map[string]int64{},
+			wantDirty: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := sTestKey(t)
+			t.Cleanup(func() {
+				ctx := context.Background()
+				s.client.Inst().Del(ctx, kReactionDeltaPrefix+target)
+				s.client.Inst().SRem(ctx, kReactionDirtySet, target)
+			})
+
+			for kind, delta := range tt.incrs {
+				if err := s.IncrReactionDelta(target, kind, delta); err != nil {
+					t.Fatalf("IncrReactionDelta() error = %v", err)
+				}
+			}
+			for kind, delta := range tt.beforeAck {
+				if err := s.IncrReactionDelta(target, kind, delta); err != nil {
+					t.Fatalf("IncrReactionDelta() error = %v", err)
+				}
+			}
+
+			if err := s.AckReactionDeltas(target, tt.ack); err != nil {
+				t.Fatalf("AckReactionDeltas() error = %v", err)
+			}
+
+			deltas, err := s.GetReactionDeltas([]string{target})
+			if err != nil {
+				t.Fatalf("GetReactionDeltas() error = %v", err)
+			}
+			if got := deltas[target]; !maps.Equal(got, tt.want) {
+				t.Errorf("剩余增量 = %v, want %v", got, tt.want)
+			}
+
+			dirty, err := s.client.Inst().SIsMember(context.Background(), kReactionDirtySet, target).Result()
+			if err != nil {
+				t.Fatalf("SIsMember() error = %v", err)
+			}
+			if dirty != tt.wantDirty {
+				t.Errorf("待合并 = %v, want %v", dirty, tt.wantDirty)
+			}
+		})
+	}
+}
+
+func TestParseReactionDeltas(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]string
+		want    map[string]int64
+		wantErr bool
+	}{
+		{"正负增量", map[string]string{"like": "3", "sad": "-2"}, map[string]int64{"like": 3, "sad": -2}, false},
+		{"忽略归零的增量", map[string]string{"like": "0", "wow": "1"}, map[string]int64{"wow": 1}, false},
+		{"空", map[string]string{}, map[string]int64{}, false},
+		{"非数字", map[string]string{"like": "x"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sParseReactionDeltas(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sParseReactionDeltas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("sParseReactionDeltas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
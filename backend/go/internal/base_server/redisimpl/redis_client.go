@@ -2,14 +2,14 @@ package redisimpl
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/subtle"
 	"errors"
-	"io"
 	"log/slog"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 	"whuclubsynapse-server/internal/base_server/baseconfig"
+	"whuclubsynapse-server/internal/base_server/grpcimpl"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 	"whuclubsynapse-server/internal/shared/rediscli"
 
@@ -22,9 +22,45 @@ const (
 
 type RedisClientService interface {
 	CheckVrfcodeExisting(email string) bool
-	ValidateRegVrfcode(email, vrfcode string) bool
-	UploadClubInfo(clubInfo *dbstruct.Club) error
-	UploadPostInfo(postInfo *dbstruct.ClubPost) error
+	TagVrfcodePurpose(purpose, email string) error
+	// ValidateVrfcode 验证码用途与purpose不一致时同样校验失败
+	ValidateVrfcode(purpose, email, vrfcode string) bool
+	DeleteVrfcode(email string) error
+	// UploadClubInfo 将社团信息推送至RAG同步流，返回流消息ID
+	UploadClubInfo(clubInfo *dbstruct.Club) (string, error)
+	// UploadPostInfo 将帖子正文推送至RAG同步流，正文由调用方从对象存储读取
+	UploadPostInfo(postInfo *dbstruct.ClubPost, content []byte) (string, error)
+	// RemoveRagSource 通知RAG侧删除来源ID对应的全部内容
+	RemoveRagSource(sourceId string, metadata map[string]any) (string, error)
+	// AddRagMessages 以一次往返批量写入RAG同步流，返回各消息的流消息ID
+	AddRagMessages(msgs []*RagMessage) ([]string, error)
+
+	SaveRefreshToken(token string, userId int, ttl time.Duration) error
+	ConsumeRefreshToken(token string, ttl time.Duration) (int, error)
+	DeleteRefreshToken(token string) error
+	DeleteUserRefreshTokens(userId int) error
+	RevokeAccessToken(tokenId string, ttl time.Duration) error
+	GetTokenVersion(userId int) (int64, error)
+	IncrTokenVersion(userId int) (int64, error)
+	GetTokenState(userId int, tokenId string) (int64, bool, error)
+	SaveStreamTicket(ticket string, userId int, role string, ttl time.Duration) error
+	ConsumeStreamTicket(ticket string) (int, string, error)
+
+	SlidingWindowAllow(key string, limit int, window time.Duration) (bool, time.Duration, error)
+	RecordLoginFailure(identifier string, window time.Duration) (int64, error)
+	ResetLoginFailure(identifier string) error
+	SetLoginLock(identifier string, duration time.Duration) error
+	GetLoginLock(identifier string) (time.Duration, error)
+
+	// day为yyyyMMdd格式的日期，额度为0表示不限
+	AcquireLlmQuota(day string, userId int, maxRequests, maxTokens int64) (bool, *LlmUsage, error)
+	IncrLlmUsage(day string, userId int, delta *LlmUsage) error
+	GetLlmUsage(day string, userId int) (*LlmUsage, error)
+
+	IncrReactionDelta(target, kind string, delta int64) error
+	GetReactionDeltas(targets []string) (map[string]map[string]int64, error)
+	GetDirtyReactionTargets(num int) ([]string, error)
+	AckReactionDeltas(target string, deltas map[string]int64) error
 }
 
 type sRedisClientService struct {
@@ -50,132 +86,102 @@ func NewRedisClientService(
 	}
 }
 
-func (s *sRedisClientService) CheckVrfcodeExisting(email string) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 7*time.Second)
-	defer cancel()
+// 邮件验证服务只按邮箱存储验证码，用途由本服务在发送成功后写入值中，
+// 格式为"<用途>:<验证码>"；未标记用途的验证码视为注册验证码
+var tagVrfcodeScript = redis.NewScript(`
+local code = redis.call('GET', KEYS[1])
+if not code then
+	return 0
+end
+if string.find(code, ':', 1, true) then
+	return 1
+end
+
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1] .. ':' .. code, 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1] .. ':' .. code)
+end
+return 1
+`)
+
+func sVrfcodeKey(email string) string {
+	return kVrfCodePrefix + email
+}
 
-	_, err := s.client.Inst().Get(ctx, kVrfCodePrefix+email).Result()
-	if err == redis.Nil {
-		return false
+// sParseVrfcode 拆分存储值中的用途与验证码
+func sParseVrfcode(value string) (string, string) {
+	if purpose, code, ok := strings.Cut(value, ":"); ok {
+		return purpose, code
 	}
-	if err != nil {
-		s.logger.Error("Redis Get操作异常", "error", err)
-		return false
-	}
-
-	return true
+	return grpcimpl.MAILVRF_PURPOSE_REGISTER, value
 }
 
-func (s *sRedisClientService) ValidateRegVrfcode(email, vrfcode string) bool {
+// CheckVrfcodeExisting 同一邮箱同时只有一个有效验证码，不区分用途
+func (s *sRedisClientService) CheckVrfcodeExisting(email string) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 7*time.Second)
 	defer cancel()
 
-	redisVrfcode, err := s.client.Inst().Get(ctx, kVrfCodePrefix+email).Result()
+	_, err := s.client.Inst().Get(ctx, sVrfcodeKey(email)).Result()
 	if err == redis.Nil {
 		return false
 	}
 	if err != nil {
-		s.logger.Error("Redis操作异常", "error", err)
-		return false
-	}
-
-	if redisVrfcode != vrfcode {
+		s.logger.Error("Redis Get操作异常", "error", err)
 		return false
 	}
 
 	return true
 }
 
-func (s *sRedisClientService) UploadClubInfo(clubInfo *dbstruct.Club) error {
-	s.logger.Debug("上传社团信息", "club", clubInfo)
-
+// TagVrfcodePurpose 在邮件验证服务写入验证码后标记其用途，注册验证码无需标记
+func (s *sRedisClientService) TagVrfcodePurpose(purpose, email string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	strClubId := strconv.Itoa(int(clubInfo.ClubId))
-	if strClubId == "" {
-		return errors.New("无法转化club_id为字符串")
-	}
-
-	metadataJson, err := json.Marshal(map[string]any{
-		"name":         clubInfo.Name,
-		"description":  clubInfo.Description,
-		"requirements": clubInfo.Requirements,
-		"tags":         clubInfo.Tags,
-		"category":     clubInfo.CategoryId,
-	})
+	tagged, err := tagVrfcodeScript.Run(ctx, s.client.Inst(),
+		[]string{sVrfcodeKey(email)}, purpose,
+	).Int()
 	if err != nil {
 		return err
 	}
-
-	cmd := s.client.Inst().XAdd(ctx, &redis.XAddArgs{
-		Stream: "rag_sync_stream",
-		Values: map[string]any{
-			"source_id": "club_id::" + strClubId,
-			"content":   clubInfo.Description,
-			"metadata":  string(metadataJson),
-		},
-	})
-
-	msgId, err := cmd.Result()
-	if err != nil {
-		s.logger.Error("Redis操作异常", "error", err)
-		return err
+	if tagged == 0 {
+		return errors.New("验证码不存在或已过期")
 	}
 
-	s.logger.Info("上传社团信息成功", "msg_id", msgId)
-
 	return nil
 }
 
-func (s *sRedisClientService) UploadPostInfo(postInfo *dbstruct.ClubPost) error {
-	s.logger.Debug("上传帖子信息", "post", postInfo)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *sRedisClientService) ValidateVrfcode(purpose, email, vrfcode string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 7*time.Second)
 	defer cancel()
 
-	strPostId := strconv.Itoa(int(postInfo.PostId))
-	if strPostId == "" {
-		return errors.New("无法转化post_id为字符串")
+	value, err := s.client.Inst().Get(ctx, sVrfcodeKey(email)).Result()
+	if err == redis.Nil {
+		return false
 	}
-
-	postFile, err := os.Open(postInfo.ContentUrl)
 	if err != nil {
-		return err
+		s.logger.Error("Redis操作异常", "error", err)
+		return false
 	}
-	defer postFile.Close()
 
-	content, err := io.ReadAll(postFile)
-	if err != nil {
-		return err
+	codePurpose, code := sParseVrfcode(value)
+	if codePurpose != purpose {
+		return false
 	}
 
-	metadataJson, err := json.Marshal(map[string]any{
-		"title":     postInfo.Title,
-		"club_id":   postInfo.ClubId,
-		"author_id": postInfo.UserId,
-		"is_pinned": postInfo.IsPinned,
-	})
-	if err != nil {
-		return err
+	if subtle.ConstantTimeCompare([]byte(code), []byte(vrfcode)) != 1 {
+		return false
 	}
 
-	cmd := s.client.Inst().XAdd(ctx, &redis.XAddArgs{
-		Stream: "rag_sync_stream",
-		Values: map[string]any{
-			"source_id": "post_id::" + strPostId,
-			"content":   string(content),
-			"metadata":  string(metadataJson),
-		},
-	})
-
-	msgId, err := cmd.Result()
-	if err != nil {
-		s.logger.Error("Redis操作异常", "error", err)
-		return err
-	}
+	return true
+}
 
-	s.logger.Info("上传帖子信息成功", "msg_id", msgId)
+// DeleteVrfcode 验证码使用后立即删除，防止重复使用
+func (s *sRedisClientService) DeleteVrfcode(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	return nil
+	return s.client.Inst().Del(ctx, sVrfcodeKey(email)).Err()
 }
@@ -0,0 +1,57 @@
+package redisimpl
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"whuclubsynapse-server/internal/base_server/grpcimpl"
+	"whuclubsynapse-server/internal/shared/rediscli"
+)
+
+// sNewTestService 依赖真实的Redis执行Lua脚本，未设置TEST_REDIS_ADDR时跳过
+func sNewTestService(t *testing.T) *sRedisClientService {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("未设置TEST_REDIS_ADDR，跳过依赖Redis的测试")
+	}
+
+	return &sRedisClientService{
+		client: rediscli.NewRedisClient(addr, os.Getenv("TEST_REDIS_PASSWORD"), 4, 1, 0),
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// sTestKey 为每个测试生成独立的键，避免重复运行时互相影响
+func sTestKey(t *testing.T) string {
+	return "test_" + t.Name() + "_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+func TestParseVrfcode(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantPurpose string
+		wantCode    string
+	}{
+		{"未标记视为注册", "a1b2", grpcimpl.MAILVRF_PURPOSE_REGISTER, "a1b2"},
+		{"找回密码", "reset_password:a1b2", grpcimpl.MAILVRF_PURPOSE_RESET_PASSWORD, "a1b2"},
+		{"只按第一个冒号拆分", "reset_password:a:b", grpcimpl.MAILVRF_PURPOSE_RESET_PASSWORD, "a:b"},
+		{"空值", "", grpcimpl.MAILVRF_PURPOSE_REGISTER, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purpose, code := sParseVrfcode(tt.value)
+			if purpose != tt.wantPurpose || code != tt.wantCode {
+				t.Errorf("sParseVrfcode(%q) = (%q, %q), want (%q, %q)",
+					tt.value, purpose, code, tt.wantPurpose, tt.wantCode,
+				)
+			}
+		})
+	}
+}
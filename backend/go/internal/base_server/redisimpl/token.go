@@ -0,0 +1,200 @@
+package redisimpl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	kRefreshTokenPrefix     = "refresh_token_"
+	kRefreshUsedPrefix      = "refresh_used_"
+	kUserRefreshTokenPrefix = "refresh_user_"
+	kRevokedTokenPrefix     = "revoked_token_"
+	kTokenVersionPrefix     = "token_version_"
+	kStreamTicketPrefix     = "stream_ticket_"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token不存在或已过期")
+	// ErrRefreshTokenReused 已轮换的refresh token被再次使用，视为泄露
+	ErrRefreshTokenReused   = errors.New("refresh token被重复使用")
+	ErrStreamTicketNotFound = errors.New("stream ticket不存在或已过期")
+)
+
+func (s *sRedisClientService) SaveRefreshToken(token string, userId int, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userKey := kUserRefreshTokenPrefix + strconv.Itoa(userId)
+
+	_, err := s.client.Inst().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, kRefreshTokenPrefix+token, userId, ttl)
+		pipe.SAdd(ctx, userKey, token)
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+
+	return err
+}
+
+// ConsumeRefreshToken 取出并删除refresh token，同一token只能成功使用一次
+func (s *sRedisClientService) ConsumeRefreshToken(token string, ttl time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	strUserId, err := s.client.Inst().GetDel(ctx, kRefreshTokenPrefix+token).Result()
+	if err == redis.Nil {
+		strUserId, err = s.client.Inst().Get(ctx, kRefreshUsedPrefix+token).Result()
+		if err == redis.Nil {
+			return 0, ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		userId, _ := strconv.Atoi(strUserId)
+		return userId, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	userId, err := strconv.Atoi(strUserId)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.client.Inst().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, kRefreshUsedPrefix+token, userId, ttl)
+		pipe.SRem(ctx, kUserRefreshTokenPrefix+strUserId, token)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Redis操作异常", "error", err)
+	}
+
+	return userId, nil
+}
+
+func (s *sRedisClientService) DeleteRefreshToken(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	strUserId, err := s.client.Inst().GetDel(ctx, kRefreshTokenPrefix+token).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.client.Inst().SRem(ctx, kUserRefreshTokenPrefix+strUserId, token).Err()
+}
+
+func (s *sRedisClientService) DeleteUserRefreshTokens(userId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userKey := kUserRefreshTokenPrefix + strconv.Itoa(userId)
+
+	tokens, err := s.client.Inst().SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		keys = append(keys, kRefreshTokenPrefix+token)
+	}
+	keys = append(keys, userKey)
+
+	return s.client.Inst().Del(ctx, keys...).Err()
+}
+
+// RevokeAccessToken 吊销单个access token，ttl不短于其剩余有效期即可
+func (s *sRedisClientService) RevokeAccessToken(tokenId string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.client.Inst().Set(ctx, kRevokedTokenPrefix+tokenId, 1, ttl).Err()
+}
+
+func (s *sRedisClientService) GetTokenVersion(userId int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	version, err := s.client.Inst().Get(ctx, kTokenVersionPrefix+strconv.Itoa(userId)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return version, err
+}
+
+func (s *sRedisClientService) IncrTokenVersion(userId int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.client.Inst().Incr(ctx, kTokenVersionPrefix+strconv.Itoa(userId)).Result()
+}
+
+// GetTokenState 返回用户当前token版本以及tokenId是否已被吊销
+func (s *sRedisClientService) GetTokenState(userId int, tokenId string) (int64, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	values, err := s.client.Inst().MGet(ctx,
+		kTokenVersionPrefix+strconv.Itoa(userId),
+		kRevokedTokenPrefix+tokenId,
+	).Result()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var version int64
+	if str, ok := values[0].(string); ok {
+		version, err = strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	return version, values[1] != nil, nil
+}
+
+// SaveStreamTicket 保存一次性的推送连接凭证，值为"userId:role"
+func (s *sRedisClientService) SaveStreamTicket(ticket string, userId int, role string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.client.Inst().Set(ctx,
+		kStreamTicketPrefix+ticket, strconv.Itoa(userId)+":"+role, ttl,
+	).Err()
+}
+
+// ConsumeStreamTicket 取出并删除推送连接凭证，同一凭证只能成功使用一次
+func (s *sRedisClientService) ConsumeStreamTicket(ticket string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value, err := s.client.Inst().GetDel(ctx, kStreamTicketPrefix+ticket).Result()
+	if err == redis.Nil {
+		return 0, "", ErrStreamTicketNotFound
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	strUserId, role, _ := strings.Cut(value, ":")
+	userId, err := strconv.Atoi(strUserId)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userId, role, nil
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClubEventRepo interface {
+	AddEvent(event *dbstruct.ClubEvent) error
+	GetEventById(eventId int) (*dbstruct.ClubEvent, error)
+	GetEventForUpdate(tx *gorm.DB, eventId int) (*dbstruct.ClubEvent, error)
+	GetEventList(clubId, offset, num int) ([]*dbstruct.ClubEvent, error)
+	GetUpcomingEvents(clubId, num int) ([]*dbstruct.ClubEvent, error)
+	CountHeldEvents(clubId int) (int64, error)
+
+	UpdateEvent(tx *gorm.DB, eventId int, fields map[string]any) error
+	UpdateGoingCount(tx *gorm.DB, eventId int, delta int) error
+	CancelEvent(tx *gorm.DB, eventId int) error
+}
+
+type sClubEventRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateClubEventRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) ClubEventRepo {
+	return &sClubEventRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sClubEventRepo) AddEvent(event *dbstruct.ClubEvent) error {
+	return r.database.Create(event).Error
+}
+
+func (r *sClubEventRepo) GetEventById(eventId int) (*dbstruct.ClubEvent, error) {
+	if eventId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var event dbstruct.ClubEvent
+	err := r.database.
+		Where("event_id = ?", eventId).
+		First(&event).Error
+
+	return &event, err
+}
+
+func (r *sClubEventRepo) GetEventForUpdate(tx *gorm.DB, eventId int) (*dbstruct.ClubEvent, error) {
+	if eventId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	// 报名名额的判断依赖going_count，必须锁住活动行防止超额
+	var event dbstruct.ClubEvent
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("event_id = ?", eventId).
+		First(&event).Error
+
+	return &event, err
+}
+
+func (r *sClubEventRepo) GetEventList(clubId, offset, num int) ([]*dbstruct.ClubEvent, error) {
+	var events []*dbstruct.ClubEvent
+	err := r.database.
+		Where("club_id = ?", clubId).
+		Order("start_at DESC").
+		Offset(offset).
+		Limit(num).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *sClubEventRepo) GetUpcomingEvents(clubId, num int) ([]*dbstruct.ClubEvent, error) {
+	var events []*dbstruct.ClubEvent
+	err := r.database.
+		Where("club_id = ? AND status = ? AND end_at > ?",
+			clubId, dbstruct.EVENT_STATUS_SCHEDULED, time.Now(),
+		).
+		Order("start_at ASC").
+		Limit(num).
+		Find(&events).Error
+	return events, err
+}
+
+// CountHeldEvents 统计已开始且未取消的活动数，作为出勤率的分母
+func (r *sClubEventRepo) CountHeldEvents(clubId int) (int64, error) {
+	var count int64
+	err := r.database.
+		Model(&dbstruct.ClubEvent{}).
+		Where("club_id = ? AND status = ? AND start_at <= ?",
+			clubId, dbstruct.EVENT_STATUS_SCHEDULED, time.Now(),
+		).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *sClubEventRepo) UpdateEvent(tx *gorm.DB, eventId int, fields map[string]any) error {
+	return tx.
+		Model(&dbstruct.ClubEvent{}).
+		Where("event_id = ?", eventId).
+		Updates(fields).Error
+}
+
+func (r *sClubEventRepo) UpdateGoingCount(tx *gorm.DB, eventId int, delta int) error {
+	return tx.
+		Model(&dbstruct.ClubEvent{}).
+		Where("event_id = ?", eventId).
+		Update("going_count", gorm.Expr("going_count + ?", delta)).Error
+}
+
+func (r *sClubEventRepo) CancelEvent(tx *gorm.DB, eventId int) error {
+	return tx.
+		Model(&dbstruct.ClubEvent{}).
+		Where("event_id = ?", eventId).
+		Update("status", dbstruct.EVENT_STATUS_CANCELLED).Error
+}
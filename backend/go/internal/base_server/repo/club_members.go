@@ -1,10 +1,13 @@
 package repo
 
 import (
+	"errors"
 	"log/slog"
+	"time"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ClubMemberRepo interface {
@@ -14,8 +17,13 @@ type ClubMemberRepo interface {
 	GetClubMemberInfo(id int) (*dbstruct.ClubMember, error)
 	GetMemberListByClubId(clubId int) ([]*dbstruct.ClubMember, error)
 	GetClubListByUserId(userId int) ([]*dbstruct.Club, error)
+	GetMemberInClub(userId, clubId int) (*dbstruct.ClubMember, error)
+	// GetMemberForUpdate 在事务中读取并锁定成员记录，角色变更与负责人移交据此串行执行
+	GetMemberForUpdate(tx *gorm.DB, userId, clubId int) (*dbstruct.ClubMember, error)
+	UpdateMemberRole(tx *gorm.DB, userId, clubId int, role string) error
+	UpdateMemberLastActive(tx *gorm.DB, userId, clubId int, lastActive time.Time) error
 
-	DeleteMember(userId, clubId int) error
+	DeleteMember(tx *gorm.DB, userId, clubId int) error
 	DeleteClub(tx *gorm.DB, clubId int) error
 }
 
@@ -94,8 +102,49 @@ func (r *sClubMemberRepo) GetClubListByUserId(userId int) ([]*dbstruct.Club, err
 	return clubs, nil
 }
 
-func (r *sClubMemberRepo) DeleteMember(userId, clubId int) error {
-	if err := r.database.
+func (r *sClubMemberRepo) GetMemberInClub(userId, clubId int) (*dbstruct.ClubMember, error) {
+	if userId <= 0 || clubId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var member dbstruct.ClubMember
+	err := r.database.
+		Where("user_id = ? AND club_id = ?", userId, clubId).
+		First(&member).Error
+
+	return &member, err
+}
+
+func (r *sClubMemberRepo) GetMemberForUpdate(tx *gorm.DB, userId, clubId int) (*dbstruct.ClubMember, error) {
+	if userId <= 0 || clubId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var member dbstruct.ClubMember
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND club_id = ?", userId, clubId).
+		First(&member).Error
+
+	return &member, err
+}
+
+func (r *sClubMemberRepo) UpdateMemberRole(tx *gorm.DB, userId, clubId int, role string) error {
+	return tx.
+		Model(&dbstruct.ClubMember{}).
+		Where("user_id = ? AND club_id = ?", userId, clubId).
+		Update("role_in_club", role).Error
+}
+
+func (r *sClubMemberRepo) UpdateMemberLastActive(tx *gorm.DB, userId, clubId int, lastActive time.Time) error {
+	return tx.
+		Model(&dbstruct.ClubMember{}).
+		Where("user_id = ? AND club_id = ?", userId, clubId).
+		Update("last_active", lastActive).Error
+}
+
+func (r *sClubMemberRepo) DeleteMember(tx *gorm.DB, userId, clubId int) error {
+	if err := tx.
 		Where("user_id = ? AND club_id = ?", userId, clubId).
 		Delete(&dbstruct.ClubMember{}).Error; err != nil {
 		r.logger.Error("删除俱乐部成员失败", "user_id", userId, "club_id", clubId, "error", err)
@@ -3,14 +3,24 @@ package repo
 import (
 	"errors"
 	"log/slog"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostCommentRepo interface {
-	CreatePostComment(newComment dbstruct.ClubPostComment) error
-	GetPostComments(postId int) ([]*dbstruct.ClubPostComment, error)
+	CreatePostComment(tx *gorm.DB, newComment *dbstruct.ClubPostComment) error
+	GetCommentById(commentId int) (*dbstruct.ClubPostComment, error)
+	GetCommentForUpdate(tx *gorm.DB, commentId int) (*dbstruct.ClubPostComment, error)
+
+	GetRootComments(postId, offset, num int) ([]*dbstruct.ClubPostComment, error)
+	GetRootCommentsByCursor(postId int, cursor *model.Cursor, num int) (*model.Page[*dbstruct.ClubPostComment], error)
+	GetReplies(rootIds []uint) ([]*dbstruct.ClubPostComment, error)
+
+	UpdateCommentContent(commentId int, content string) error
+	UpdateCommentStatus(tx *gorm.DB, commentId int, status string, removedBy *uint, reason string) error
 }
 
 type sPostCommentRepo struct {
@@ -28,14 +38,46 @@ func CreatePostCommentRepo(
 	}
 }
 
-func (r *sPostCommentRepo) CreatePostComment(newComment dbstruct.ClubPostComment) error {
-	return r.database.Create(&newComment).Error
+func (r *sPostCommentRepo) CreatePostComment(tx *gorm.DB, newComment *dbstruct.ClubPostComment) error {
+	return tx.Create(newComment).Error
+}
+
+func (r *sPostCommentRepo) GetCommentById(commentId int) (*dbstruct.ClubPostComment, error) {
+	if commentId <= 0 {
+		return nil, errors.New("无效的评论ID")
+	}
+
+	var comment dbstruct.ClubPostComment
+	err := r.database.
+		Where("comment_id = ?", commentId).
+		First(&comment).Error
+
+	return &comment, err
+}
+
+func (r *sPostCommentRepo) GetCommentForUpdate(tx *gorm.DB, commentId int) (*dbstruct.ClubPostComment, error) {
+	if commentId <= 0 {
+		return nil, errors.New("无效的评论ID")
+	}
+
+	// 删除时需同步扣减帖子评论数，锁住评论行防止重复扣减
+	var comment dbstruct.ClubPostComment
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("comment_id = ?", commentId).
+		First(&comment).Error
+
+	return &comment, err
 }
 
-func (r *sPostCommentRepo) GetPostComments(postId int) ([]*dbstruct.ClubPostComment, error) {
+func (r *sPostCommentRepo) GetRootComments(postId, offset, num int) ([]*dbstruct.ClubPostComment, error) {
 	var comments []*dbstruct.ClubPostComment
 	err := r.database.
-		Where("post_id = ?", postId).
+		Where("post_id = ? AND parent_comment_id IS NULL", postId).
+		Order("created_at DESC").
+		Order("comment_id DESC").
+		Offset(offset).
+		Limit(num).
 		Find(&comments).Error
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -44,3 +86,58 @@ func (r *sPostCommentRepo) GetPostComments(postId int) ([]*dbstruct.ClubPostComm
 
 	return comments, nil
 }
+
+func (r *sPostCommentRepo) GetRootCommentsByCursor(
+	postId int,
+	cursor *model.Cursor,
+	num int,
+) (*model.Page[*dbstruct.ClubPostComment], error) {
+	return paginateByCursor(
+		r.database.
+			Model(&dbstruct.ClubPostComment{}).
+			Where("post_id = ? AND parent_comment_id IS NULL", postId),
+		cursor, num, "created_at", "comment_id",
+		func(c *dbstruct.ClubPostComment) model.Cursor {
+			return model.Cursor{CreatedAt: c.CreatedAt, Id: c.CommentId}
+		},
+	)
+}
+
+func (r *sPostCommentRepo) GetReplies(rootIds []uint) ([]*dbstruct.ClubPostComment, error) {
+	if len(rootIds) == 0 {
+		return nil, nil
+	}
+
+	var replies []*dbstruct.ClubPostComment
+	err := r.database.
+		Where("root_comment_id IN ?", rootIds).
+		Order("created_at ASC").
+		Order("comment_id ASC").
+		Find(&replies).Error
+
+	return replies, err
+}
+
+func (r *sPostCommentRepo) UpdateCommentContent(commentId int, content string) error {
+	return r.database.
+		Model(&dbstruct.ClubPostComment{}).
+		Where("comment_id = ?", commentId).
+		Update("content", content).Error
+}
+
+func (r *sPostCommentRepo) UpdateCommentStatus(
+	tx *gorm.DB,
+	commentId int,
+	status string,
+	removedBy *uint,
+	reason string,
+) error {
+	return tx.
+		Model(&dbstruct.ClubPostComment{}).
+		Where("comment_id = ?", commentId).
+		Updates(map[string]any{
+			"status":         status,
+			"removed_by":     removedBy,
+			"removed_reason": reason,
+		}).Error
+}
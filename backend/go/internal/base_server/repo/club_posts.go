@@ -3,22 +3,47 @@ package repo
 import (
 	"errors"
 	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ClubPostRepo interface {
 	AddPost(post *dbstruct.ClubPost) error
-	ChangePostVisibility(postId, visibility int) error
+	AppendPost(tx *gorm.DB, post *dbstruct.ClubPost) error
+	GetPostById(postId int) (*dbstruct.ClubPost, error)
+	GetPostForUpdate(tx *gorm.DB, postId int) (*dbstruct.ClubPost, error)
+	ChangePostVisibility(tx *gorm.DB, postId, visibility int) error
 
 	GetClubPostList(clubId, offset, num, visibility int) ([]*dbstruct.ClubPost, error)
+	GetClubPostListByCursor(clubId int, cursor *model.Cursor, num, visibility int) (*model.Page[*dbstruct.ClubPost], error)
 	GetPinnedPost(clubId int) (*dbstruct.ClubPost, error)
 	PinPost(postId int) error
 
 	GetPostsByUserId(userId int) ([]*dbstruct.ClubPost, error)
 
 	UpdatePostUrl(postId int, url string) error
+	UpdatePostContent(tx *gorm.DB, postId int, title, url string) error
+	UpdateCommentCount(tx *gorm.DB, postId int, delta int) error
+	DeletePost(tx *gorm.DB, postId int) error
+
+	GetDraftsByUserId(userId int) ([]*dbstruct.ClubPost, error)
+	UpdateDraft(postId int, fields map[string]any) error
+	// GetDuePosts 取出发布时间已到的定时帖子，按发布时间先后排序
+	GetDuePosts(now time.Time, num int) ([]*dbstruct.ClubPost, error)
+	PublishPost(tx *gorm.DB, postId int, url string, publishedAt time.Time) error
+	UpdatePostStatus(tx *gorm.DB, postId int, status string) error
+
+	// ScanSearchablePosts 按帖子ID升序遍历已发布的公开帖子，用于重建搜索索引
+	ScanSearchablePosts(afterId uint, num int) ([]*dbstruct.ClubPost, error)
+	// GetSearchablePosts 按ID取已发布的公开帖子，不满足条件的ID直接忽略
+	GetSearchablePosts(ids []uint) ([]*dbstruct.ClubPost, error)
+	// GetPublishedPostIds 社团全部已发布帖子的ID，解散社团时用于通知下游移除
+	GetPublishedPostIds(tx *gorm.DB, clubId int) ([]uint, error)
 }
 
 type sClubPostRepo struct {
@@ -40,11 +65,44 @@ func (r *sClubPostRepo) AddPost(post *dbstruct.ClubPost) error {
 	return r.database.Create(post).Error
 }
 
+func (r *sClubPostRepo) AppendPost(tx *gorm.DB, post *dbstruct.ClubPost) error {
+	return tx.Create(post).Error
+}
+
+func (r *sClubPostRepo) GetPostById(postId int) (*dbstruct.ClubPost, error) {
+	if postId <= 0 {
+		return nil, errors.New("无效的帖子ID")
+	}
+
+	var post dbstruct.ClubPost
+	err := r.database.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ?", postId).
+		First(&post).Error
+
+	return &post, err
+}
+
+func (r *sClubPostRepo) GetPostForUpdate(tx *gorm.DB, postId int) (*dbstruct.ClubPost, error) {
+	if postId <= 0 {
+		return nil, errors.New("无效的帖子ID")
+	}
+
+	// 版本号由已有历史版本数推出，并发编辑时需锁住帖子行
+	var post dbstruct.ClubPost
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("post_id = ?", postId).
+		First(&post).Error
+
+	return &post, err
+}
+
 func (r *sClubPostRepo) GetClubPostList(clubId, offset, num, visibility int) ([]*dbstruct.ClubPost, error) {
 	var posts []*dbstruct.ClubPost
 	err := r.database.
 		Model(&dbstruct.ClubPost{}).
-		Where("club_id = ? AND visibility <= ?", clubId, visibility).
+		Where("club_id = ? AND visibility <= ? AND status = ?", clubId, visibility, dbstruct.POST_STATUS_PUBLISHED).
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(num).
@@ -57,8 +115,24 @@ func (r *sClubPostRepo) GetClubPostList(clubId, offset, num, visibility int) ([]
 	return posts, nil
 }
 
-func (r *sClubPostRepo) ChangePostVisibility(postId, visibility int) error {
-	return r.database.
+func (r *sClubPostRepo) GetClubPostListByCursor(
+	clubId int,
+	cursor *model.Cursor,
+	num, visibility int,
+) (*model.Page[*dbstruct.ClubPost], error) {
+	return paginateByCursor(
+		r.database.
+			Model(&dbstruct.ClubPost{}).
+			Where("club_id = ? AND visibility <= ? AND status = ?", clubId, visibility, dbstruct.POST_STATUS_PUBLISHED),
+		cursor, num, "created_at", "post_id",
+		func(p *dbstruct.ClubPost) model.Cursor {
+			return model.Cursor{CreatedAt: p.CreatedAt, Id: p.PostId}
+		},
+	)
+}
+
+func (r *sClubPostRepo) ChangePostVisibility(tx *gorm.DB, postId, visibility int) error {
+	return tx.
 		Model(&dbstruct.ClubPost{}).
 		Where("post_id = ?", postId).
 		Update("visibility", visibility).Error
@@ -68,7 +142,7 @@ func (r *sClubPostRepo) GetPinnedPost(clubId int) (*dbstruct.ClubPost, error) {
 	var post dbstruct.ClubPost
 	err := r.database.
 		Model(&dbstruct.ClubPost{}).
-		Where("club_id = ? AND is_pinned = ?", clubId, true).
+		Where("club_id = ? AND is_pinned = ? AND status = ?", clubId, true, dbstruct.POST_STATUS_PUBLISHED).
 		First(&post).Error
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -89,7 +163,7 @@ func (r *sClubPostRepo) PinPost(postId int) error {
 
 		if err := ctx.
 			Model(&dbstruct.ClubPost{}).
-			Where("post_id = ?", postId).
+			Where("post_id = ? AND status = ?", postId, dbstruct.POST_STATUS_PUBLISHED).
 			Update("is_pinned", true).Error; err != nil {
 			return err
 		}
@@ -101,7 +175,7 @@ func (r *sClubPostRepo) PinPost(postId int) error {
 func (r *sClubPostRepo) GetPostsByUserId(userId int) ([]*dbstruct.ClubPost, error) {
 	var posts []*dbstruct.ClubPost
 	err := r.database.
-		Where("user_id = ?", userId).
+		Where("user_id = ? AND status = ?", userId, dbstruct.POST_STATUS_PUBLISHED).
 		Find(&posts).Error
 	return posts, err
 }
@@ -121,3 +195,138 @@ func (r *sClubPostRepo) UpdatePostUrl(postId int, url string) error {
 
 	return nil
 }
+
+func (r *sClubPostRepo) UpdatePostContent(tx *gorm.DB, postId int, title, url string) error {
+	return tx.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ?", postId).
+		Updates(map[string]any{
+			"title":       title,
+			"content_url": url,
+		}).Error
+}
+
+// UpdateCommentCount 使用UpdateColumn不改动updated_at，该字段记录帖子内容的发布时间
+func (r *sClubPostRepo) UpdateCommentCount(tx *gorm.DB, postId int, delta int) error {
+	return tx.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ?", postId).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta)).Error
+}
+
+func (r *sClubPostRepo) DeletePost(tx *gorm.DB, postId int) error {
+	result := tx.
+		Where("post_id = ?", postId).
+		Delete(&dbstruct.ClubPost{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetDraftsByUserId 取出用户尚未发布的帖子，包括等待审核的帖子
+func (r *sClubPostRepo) GetDraftsByUserId(userId int) ([]*dbstruct.ClubPost, error) {
+	var posts []*dbstruct.ClubPost
+	err := r.database.
+		Where("user_id = ? AND status IN ?", userId,
+			[]string{dbstruct.POST_STATUS_DRAFT, dbstruct.POST_STATUS_SCHEDULED, dbstruct.POST_STATUS_PENDING}).
+		Order("updated_at DESC").
+		Find(&posts).Error
+
+	return posts, err
+}
+
+// UpdateDraft 仅更新草稿与定时状态的帖子，帖子已被发布或提交审核时返回apperr.ErrPostNotDraft，
+// 避免与定时发布并发时把已发布的帖子改回草稿
+func (r *sClubPostRepo) UpdateDraft(postId int, fields map[string]any) error {
+	res := r.database.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ? AND status IN ?", postId,
+			[]string{dbstruct.POST_STATUS_DRAFT, dbstruct.POST_STATUS_SCHEDULED}).
+		Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return apperr.ErrPostNotDraft
+	}
+
+	return nil
+}
+
+func (r *sClubPostRepo) GetDuePosts(now time.Time, num int) ([]*dbstruct.ClubPost, error) {
+	var posts []*dbstruct.ClubPost
+	err := r.database.
+		Where("status = ? AND publish_at <= ?", dbstruct.POST_STATUS_SCHEDULED, now).
+		Order("publish_at ASC").
+		Limit(num).
+		Find(&posts).Error
+
+	return posts, err
+}
+
+// PublishPost 发布时间作为帖子的created_at，使定时帖子按实际发布时间参与排序
+func (r *sClubPostRepo) PublishPost(tx *gorm.DB, postId int, url string, publishedAt time.Time) error {
+	return tx.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ?", postId).
+		Updates(map[string]any{
+			"status":        dbstruct.POST_STATUS_PUBLISHED,
+			"content_url":   url,
+			"draft_content": "",
+			"publish_at":    nil,
+			"created_at":    publishedAt,
+			"updated_at":    publishedAt,
+		}).Error
+}
+
+func (r *sClubPostRepo) UpdatePostStatus(tx *gorm.DB, postId int, status string) error {
+	return tx.
+		Model(&dbstruct.ClubPost{}).
+		Where("post_id = ?", postId).
+		Updates(map[string]any{
+			"status":     status,
+			"publish_at": nil,
+		}).Error
+}
+
+func (r *sClubPostRepo) ScanSearchablePosts(afterId uint, num int) ([]*dbstruct.ClubPost, error) {
+	var posts []*dbstruct.ClubPost
+	err := r.database.
+		Where("post_id > ? AND status = ? AND visibility = 0", afterId, dbstruct.POST_STATUS_PUBLISHED).
+		Order("post_id ASC").
+		Limit(num).
+		Find(&posts).Error
+
+	return posts, err
+}
+
+func (r *sClubPostRepo) GetSearchablePosts(ids []uint) ([]*dbstruct.ClubPost, error) {
+	var posts []*dbstruct.ClubPost
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	err := r.database.
+		Where("post_id IN ? AND status = ? AND visibility = 0", ids, dbstruct.POST_STATUS_PUBLISHED).
+		Order("post_id ASC").
+		Find(&posts).Error
+
+	return posts, err
+}
+
+func (r *sClubPostRepo) GetPublishedPostIds(tx *gorm.DB, clubId int) ([]uint, error) {
+	var ids []uint
+	err := tx.
+		Model(&dbstruct.ClubPost{}).
+		Where("club_id = ? AND status = ?", clubId, dbstruct.POST_STATUS_PUBLISHED).
+		Pluck("post_id", &ids).Error
+
+	return ids, err
+}
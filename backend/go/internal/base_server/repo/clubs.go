@@ -3,21 +3,31 @@ package repo
 import (
 	"errors"
 	"log/slog"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ClubRepo interface {
+	// ScanClubs 按社团ID升序遍历全部社团，用于重建搜索索引
+	ScanClubs(afterId uint, num int) ([]*dbstruct.Club, error)
 	AddClub(club *dbstruct.Club) error
 	AppendClub(tx *gorm.DB, club *dbstruct.Club) error
 	GetClubList(offset, num int) ([]*dbstruct.Club, error)
+	GetClubListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.Club], error)
 	GetClubInfo(id int) (*dbstruct.Club, error)
+	GetClubForUpdate(tx *gorm.DB, id int) (*dbstruct.Club, error)
 	GetClubsByCategory(catId int) ([]*dbstruct.Club, error)
 	GetLatestClubs() ([]*dbstruct.Club, error)
 	GetClubNum() (int64, error)
 	UpdateClubInfo(tx *gorm.DB, newInfo dbstruct.Club) error
-	UpdateClubLogo(clubId int, logoUrl string) error
+	UpdateClubLogo(tx *gorm.DB, clubId int, logoUrl string, variants datatypes.JSON) error
+	UpdatePostReviewPolicy(clubId int, requireReview bool) error
+	UpdateClubLeader(tx *gorm.DB, clubId, leaderId int) error
+	CountClubsLedBy(tx *gorm.DB, leaderId int) (int64, error)
 
 	DeleteClub(tx *gorm.DB, clubId int) error
 }
@@ -55,6 +65,26 @@ func (r *sClubRepo) GetClubList(offset, num int) ([]*dbstruct.Club, error) {
 	return clubs, err
 }
 
+func (r *sClubRepo) ScanClubs(afterId uint, num int) ([]*dbstruct.Club, error) {
+	var clubs []*dbstruct.Club
+	err := r.database.
+		Where("club_id > ?", afterId).
+		Order("club_id ASC").
+		Limit(num).
+		Find(&clubs).Error
+	return clubs, err
+}
+
+func (r *sClubRepo) GetClubListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.Club], error) {
+	return paginateByCursor(
+		r.database.Model(&dbstruct.Club{}),
+		cursor, num, "created_at", "club_id",
+		func(c *dbstruct.Club) model.Cursor {
+			return model.Cursor{CreatedAt: c.CreatedAt, Id: c.ClubId}
+		},
+	)
+}
+
 func (r *sClubRepo) GetClubInfo(id int) (*dbstruct.Club, error) {
 	if id <= 0 {
 		return nil, errors.New("无效的社团ID")
@@ -69,6 +99,20 @@ func (r *sClubRepo) GetClubInfo(id int) (*dbstruct.Club, error) {
 	return &club, err
 }
 
+func (r *sClubRepo) GetClubForUpdate(tx *gorm.DB, id int) (*dbstruct.Club, error) {
+	if id <= 0 {
+		return nil, errors.New("无效的社团ID")
+	}
+
+	var club dbstruct.Club
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("club_id = ?", id).
+		First(&club).Error
+
+	return &club, err
+}
+
 func (r *sClubRepo) GetClubsByCategory(catId int) ([]*dbstruct.Club, error) {
 	if catId <= 0 {
 		return nil, errors.New("无效的分类ID")
@@ -108,11 +152,37 @@ func (r *sClubRepo) UpdateClubInfo(tx *gorm.DB, newInfo dbstruct.Club) error {
 		Updates(newInfo).Error
 }
 
-func (r *sClubRepo) UpdateClubLogo(clubId int, logoUrl string) error {
+func (r *sClubRepo) UpdateClubLogo(tx *gorm.DB, clubId int, logoUrl string, variants datatypes.JSON) error {
+	return tx.
+		Model(&dbstruct.Club{}).
+		Where("club_id = ?", clubId).
+		Updates(map[string]any{
+			"logo_url":      logoUrl,
+			"logo_variants": variants,
+		}).Error
+}
+
+func (r *sClubRepo) UpdatePostReviewPolicy(clubId int, requireReview bool) error {
 	return r.database.
 		Model(&dbstruct.Club{}).
 		Where("club_id = ?", clubId).
-		Update("logo_url", logoUrl).Error
+		Update("require_post_review", requireReview).Error
+}
+
+func (r *sClubRepo) UpdateClubLeader(tx *gorm.DB, clubId, leaderId int) error {
+	return tx.
+		Model(&dbstruct.Club{}).
+		Where("club_id = ?", clubId).
+		Update("leader_id", leaderId).Error
+}
+
+func (r *sClubRepo) CountClubsLedBy(tx *gorm.DB, leaderId int) (int64, error) {
+	var count int64
+	err := tx.
+		Model(&dbstruct.Club{}).
+		Where("leader_id = ?", leaderId).
+		Count(&count).Error
+	return count, err
 }
 
 func (r *sClubRepo) DeleteClub(tx *gorm.DB, clubId int) error {
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
@@ -13,7 +15,9 @@ type CreateClubAppliRepo interface {
 	AddCreateClubAppli(appli *dbstruct.CreateClubAppli) error
 	GetCreateClubAppliList(userId int) ([]*dbstruct.CreateClubAppli, error)
 	GetCreateList(offset, num int) ([]*dbstruct.CreateClubAppli, error)
+	GetCreateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.CreateClubAppli], error)
 	GetApplisByUserId(userId int) ([]*dbstruct.CreateClubAppli, error)
+	GetAppliById(appliId int) (*dbstruct.CreateClubAppli, error)
 	GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.CreateClubAppli, error)
 	ApproveAppli(tx *gorm.DB, appliId int) error
 	RejectAppli(appliId int, reason string) error
@@ -47,7 +51,9 @@ func (r *sCreateClubAppliRepo) AddCreateClubAppli(appli *dbstruct.CreateClubAppl
 		}
 
 		if existing.CreateAppliId != 0 {
-			return fmt.Errorf("用户（user_id: %d） 有正在处理的申请", appli.UserId)
+			return apperr.ErrAlreadyApplied.Wrap(
+				fmt.Errorf("用户（user_id: %d） 有正在处理的申请", appli.UserId),
+			)
 		}
 
 		return tx.Create(appli).Error
@@ -77,6 +83,19 @@ func (r *sCreateClubAppliRepo) GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbs
 	return &appli, err
 }
 
+func (r *sCreateClubAppliRepo) GetAppliById(appliId int) (*dbstruct.CreateClubAppli, error) {
+	if appliId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var appli dbstruct.CreateClubAppli
+	err := r.database.
+		Where("create_appli_id = ?", appliId).
+		First(&appli).Error
+
+	return &appli, err
+}
+
 func (r *sCreateClubAppliRepo) GetApplisByUserId(userId int) ([]*dbstruct.CreateClubAppli, error) {
 	var list []*dbstruct.CreateClubAppli
 	err := r.database.
@@ -119,3 +138,13 @@ func (r *sCreateClubAppliRepo) GetCreateList(offset, num int) ([]*dbstruct.Creat
 		Find(&applis).Error
 	return applis, err
 }
+
+func (r *sCreateClubAppliRepo) GetCreateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.CreateClubAppli], error) {
+	return paginateByCursor(
+		r.database.Model(&dbstruct.CreateClubAppli{}),
+		cursor, num, "applied_at", "create_appli_id",
+		func(a *dbstruct.CreateClubAppli) model.Cursor {
+			return model.Cursor{CreatedAt: a.AppliedAt, Id: a.CreateAppliId}
+		},
+	)
+}
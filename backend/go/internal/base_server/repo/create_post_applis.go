@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CreatePostAppliRepo interface {
+	AddCreatePostAppli(tx *gorm.DB, appli *dbstruct.CreatePostAppli) error
+	GetAppliById(appliId int) (*dbstruct.CreatePostAppli, error)
+	GetPendingApplis(clubId int) ([]*dbstruct.CreatePostAppli, error)
+	GetApplisByUserId(userId int) ([]*dbstruct.CreatePostAppli, error)
+	GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.CreatePostAppli, error)
+	// HasPendingEdit 帖子是否已有待审核的改帖申请
+	HasPendingEdit(tx *gorm.DB, postId int) (bool, error)
+	ApproveAppli(tx *gorm.DB, appliId, reviewerId int) error
+	RejectAppli(tx *gorm.DB, appliId, reviewerId int, reason string) error
+}
+
+type sCreatePostAppliRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateCreatePostAppliRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) CreatePostAppliRepo {
+	return &sCreatePostAppliRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sCreatePostAppliRepo) AddCreatePostAppli(tx *gorm.DB, appli *dbstruct.CreatePostAppli) error {
+	return tx.Create(appli).Error
+}
+
+func (r *sCreatePostAppliRepo) GetAppliById(appliId int) (*dbstruct.CreatePostAppli, error) {
+	if appliId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var appli dbstruct.CreatePostAppli
+	err := r.database.
+		Where("post_appli_id = ?", appliId).
+		First(&appli).Error
+
+	return &appli, err
+}
+
+// GetPendingApplis 连同帖子一并取出，帖子已被作者删除的申请不再返回
+func (r *sCreatePostAppliRepo) GetPendingApplis(clubId int) ([]*dbstruct.CreatePostAppli, error) {
+	var applis []*dbstruct.CreatePostAppli
+	err := r.database.
+		InnerJoins("Post").
+		Where("create_post_applications.club_id = ? AND create_post_applications.status = ?", clubId, "pending").
+		Order("applied_at ASC").
+		Find(&applis).Error
+	return applis, err
+}
+
+func (r *sCreatePostAppliRepo) GetApplisByUserId(userId int) ([]*dbstruct.CreatePostAppli, error) {
+	var applis []*dbstruct.CreatePostAppli
+	err := r.database.
+		Preload("Post").
+		Where("applicant_id = ?", userId).
+		Order("applied_at DESC").
+		Find(&applis).Error
+	return applis, err
+}
+
+func (r *sCreatePostAppliRepo) GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.CreatePostAppli, error) {
+	if appliId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var appli dbstruct.CreatePostAppli
+	err := tx.
+		Model(&dbstruct.CreatePostAppli{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("post_appli_id = ?", appliId).
+		First(&appli).Error
+
+	return &appli, err
+}
+
+func (r *sCreatePostAppliRepo) HasPendingEdit(tx *gorm.DB, postId int) (bool, error) {
+	var count int64
+	err := tx.
+		Model(&dbstruct.CreatePostAppli{}).
+		Where("post_id = ? AND kind = ? AND status = ?", postId, dbstruct.POST_APPLI_EDIT, "pending").
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *sCreatePostAppliRepo) ApproveAppli(tx *gorm.DB, appliId, reviewerId int) error {
+	if appliId <= 0 {
+		return errors.New("无效参数")
+	}
+
+	return tx.
+		Model(&dbstruct.CreatePostAppli{}).
+		Where("post_appli_id = ?", appliId).
+		Updates(map[string]any{
+			"status":      "approved",
+			"reviewer_id": reviewerId,
+			"reviewed_at": time.Now(),
+		}).Error
+}
+
+func (r *sCreatePostAppliRepo) RejectAppli(tx *gorm.DB, appliId, reviewerId int, reason string) error {
+	if appliId <= 0 {
+		return errors.New("无效参数")
+	}
+
+	return tx.
+		Model(&dbstruct.CreatePostAppli{}).
+		Where("post_appli_id = ?", appliId).
+		Updates(map[string]any{
+			"status":          "rejected",
+			"reviewer_id":     reviewerId,
+			"reviewed_at":     time.Now(),
+			"rejected_reason": reason,
+		}).Error
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+// AttendanceStat 社团成员的签到统计，未签到过的成员LastCheckedIn为nil
+type AttendanceStat struct {
+	UserId        uint
+	RoleInClub    string
+	AttendedNum   int
+	LastCheckedIn *time.Time
+}
+
+type EventAttendanceRepo interface {
+	AddAttendance(tx *gorm.DB, attendance *dbstruct.EventAttendance) error
+	HasAttended(tx *gorm.DB, eventId, userId int) (bool, error)
+	GetAttendancesOfEvent(eventId int) ([]*dbstruct.EventAttendance, error)
+	GetAttendanceStats(clubId int) ([]*AttendanceStat, error)
+}
+
+type sEventAttendanceRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateEventAttendanceRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) EventAttendanceRepo {
+	return &sEventAttendanceRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sEventAttendanceRepo) AddAttendance(tx *gorm.DB, attendance *dbstruct.EventAttendance) error {
+	return tx.Create(attendance).Error
+}
+
+func (r *sEventAttendanceRepo) HasAttended(tx *gorm.DB, eventId, userId int) (bool, error) {
+	var count int64
+	err := tx.
+		Model(&dbstruct.EventAttendance{}).
+		Where("event_id = ? AND user_id = ?", eventId, userId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *sEventAttendanceRepo) GetAttendancesOfEvent(eventId int) ([]*dbstruct.EventAttendance, error) {
+	var attendances []*dbstruct.EventAttendance
+	err := r.database.
+		Where("event_id = ?", eventId).
+		Order("checked_in_at ASC").
+		Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *sEventAttendanceRepo) GetAttendanceStats(clubId int) ([]*AttendanceStat, error) {
+	var stats []*AttendanceStat
+	err := r.database.
+		Table("club_members AS cm").
+		Select(`cm.user_id, cm.role_in_club,
+			COUNT(a.attendance_id) AS attended_num,
+			MAX(a.checked_in_at) AS last_checked_in`).
+		Joins("LEFT JOIN club_event_attendances AS a ON a.user_id = cm.user_id AND a.club_id = cm.club_id").
+		Where("cm.club_id = ?", clubId).
+		Group("cm.user_id, cm.role_in_club").
+		Order("attended_num DESC, cm.user_id ASC").
+		Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"errors"
+	"log/slog"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+type EventRsvpRepo interface {
+	AddRsvp(tx *gorm.DB, rsvp *dbstruct.EventRsvp) error
+	GetRsvp(tx *gorm.DB, eventId, userId int) (*dbstruct.EventRsvp, error)
+	GetRsvpsOfEvent(eventId int) ([]*dbstruct.EventRsvp, error)
+	GetActiveRsvpsOfEvent(tx *gorm.DB, eventId int) ([]*dbstruct.EventRsvp, error)
+	GetFirstWaitlisted(tx *gorm.DB, eventId int) (*dbstruct.EventRsvp, error)
+
+	UpdateRsvpStatus(tx *gorm.DB, rsvpId uint, status string) error
+}
+
+type sEventRsvpRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateEventRsvpRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) EventRsvpRepo {
+	return &sEventRsvpRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sEventRsvpRepo) AddRsvp(tx *gorm.DB, rsvp *dbstruct.EventRsvp) error {
+	return tx.Create(rsvp).Error
+}
+
+func (r *sEventRsvpRepo) GetRsvp(tx *gorm.DB, eventId, userId int) (*dbstruct.EventRsvp, error) {
+	if eventId <= 0 || userId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var rsvp dbstruct.EventRsvp
+	err := tx.
+		Where("event_id = ? AND user_id = ?", eventId, userId).
+		First(&rsvp).Error
+
+	return &rsvp, err
+}
+
+func (r *sEventRsvpRepo) GetRsvpsOfEvent(eventId int) ([]*dbstruct.EventRsvp, error) {
+	var rsvps []*dbstruct.EventRsvp
+	err := r.database.
+		Where("event_id = ? AND status <> ?", eventId, dbstruct.RSVP_STATUS_CANCELLED).
+		Order("updated_at ASC").
+		Find(&rsvps).Error
+	return rsvps, err
+}
+
+func (r *sEventRsvpRepo) GetActiveRsvpsOfEvent(tx *gorm.DB, eventId int) ([]*dbstruct.EventRsvp, error) {
+	var rsvps []*dbstruct.EventRsvp
+	err := tx.
+		Where("event_id = ? AND status <> ?", eventId, dbstruct.RSVP_STATUS_CANCELLED).
+		Find(&rsvps).Error
+	return rsvps, err
+}
+
+func (r *sEventRsvpRepo) GetFirstWaitlisted(tx *gorm.DB, eventId int) (*dbstruct.EventRsvp, error) {
+	var rsvp dbstruct.EventRsvp
+	err := tx.
+		Where("event_id = ? AND status = ?", eventId, dbstruct.RSVP_STATUS_WAITLIST).
+		Order("updated_at ASC").
+		First(&rsvp).Error
+
+	return &rsvp, err
+}
+
+func (r *sEventRsvpRepo) UpdateRsvpStatus(tx *gorm.DB, rsvpId uint, status string) error {
+	return tx.
+		Model(&dbstruct.EventRsvp{}).
+		Where("rsvp_id = ?", rsvpId).
+		Update("status", status).Error
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"whuclubsynapse-server/internal/base_server/apperr"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
@@ -13,6 +14,7 @@ type JoinClubAppliRepo interface {
 	AddJoinClubAppli(appli *dbstruct.JoinClubAppli) error
 	GetJoinClubAppliList(clubId int) ([]*dbstruct.JoinClubAppli, error)
 	GetApplisByUserId(userId int) ([]*dbstruct.JoinClubAppli, error)
+	GetAppliById(appliId int) (*dbstruct.JoinClubAppli, error)
 	GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.JoinClubAppli, error)
 	ApproveAppli(tx *gorm.DB, appliId int) error
 	RejectAppli(appliId int, reason string) error
@@ -38,7 +40,7 @@ func (r *sJoinClubAppliRepo) AddJoinClubAppli(appli *dbstruct.JoinClubAppli) err
 		var existing dbstruct.JoinClubAppli
 		err := tx.
 			//Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("user_id = ? AND club_id = ? AND (status = 'pending' OR status = 'approved')",
+			Where("user_id = ? AND club_id = ? AND status = 'pending'",
 				appli.UserId, appli.ClubId).
 			First(&existing).Error
 
@@ -47,8 +49,10 @@ func (r *sJoinClubAppliRepo) AddJoinClubAppli(appli *dbstruct.JoinClubAppli) err
 		}
 
 		if existing.JoinAppliId != 0 {
-			return fmt.Errorf("用户（user_id: %d） 有未被拒绝的请求（club_id: %d）",
-				appli.UserId, appli.ClubId)
+			return apperr.ErrAlreadyApplied.Wrap(
+				fmt.Errorf("用户（user_id: %d） 有未被处理的请求（club_id: %d）",
+					appli.UserId, appli.ClubId),
+			)
 		}
 
 		return tx.Create(appli).Error
@@ -77,6 +81,19 @@ func (s *sJoinClubAppliRepo) GetApplisByUserId(userId int) ([]*dbstruct.JoinClub
 	return applis, err
 }
 
+func (r *sJoinClubAppliRepo) GetAppliById(appliId int) (*dbstruct.JoinClubAppli, error) {
+	if appliId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var appli dbstruct.JoinClubAppli
+	err := r.database.
+		Where("join_appli_id = ?", appliId).
+		First(&appli).Error
+
+	return &appli, err
+}
+
 func (r *sJoinClubAppliRepo) GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.JoinClubAppli, error) {
 	if appliId <= 0 {
 		return nil, errors.New("无效参数")
@@ -85,9 +102,8 @@ func (r *sJoinClubAppliRepo) GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstr
 	var appli dbstruct.JoinClubAppli
 	err := tx.
 		//Clauses(clause.Locking{Strength: "UPDATE"}).
-		Model(&dbstruct.JoinClubAppli{}).
 		Where("join_appli_id = ?", appliId).
-		Find(&appli).Error
+		First(&appli).Error
 
 	return &appli, err
 }
@@ -97,7 +113,7 @@ func (r *sJoinClubAppliRepo) ApproveAppli(tx *gorm.DB, appliId int) error {
 		return errors.New("无效参数")
 	}
 
-	return r.database.
+	return tx.
 		Model(&dbstruct.JoinClubAppli{}).
 		Where("join_appli_id = ?", appliId).
 		Update("status", "approved").Error
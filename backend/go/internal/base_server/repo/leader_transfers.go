@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LeaderTransferRepo interface {
+	// AddLeaderTransfer 同一社团只能有一个待处理的移交，并发提交时由部分唯一索引兜底
+	AddLeaderTransfer(transfer *dbstruct.LeaderTransfer) error
+	GetPendingTransfersForUser(userId int) ([]*dbstruct.LeaderTransfer, error)
+	GetTransferForUpdate(tx *gorm.DB, transferId int) (*dbstruct.LeaderTransfer, error)
+	AcceptTransfer(tx *gorm.DB, transferId int) error
+	RejectTransfer(tx *gorm.DB, transferId int) error
+	CancelPendingTransfers(tx *gorm.DB, clubId int) error
+}
+
+type sLeaderTransferRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateLeaderTransferRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) LeaderTransferRepo {
+	return &sLeaderTransferRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sLeaderTransferRepo) AddLeaderTransfer(transfer *dbstruct.LeaderTransfer) error {
+	return r.database.Transaction(func(tx *gorm.DB) error {
+		var existing dbstruct.LeaderTransfer
+		err := tx.
+			Where("club_id = ? AND status = 'pending'", transfer.ClubId).
+			First(&existing).Error
+
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if existing.TransferId != 0 {
+			return apperr.ErrTransferPending.Wrap(
+				fmt.Errorf("社团（club_id: %d） 有正在处理的负责人移交", transfer.ClubId),
+			)
+		}
+
+		err = tx.Create(transfer).Error
+		if IsUniqueViolation(err) {
+			return apperr.ErrTransferPending.Wrap(err)
+		}
+
+		return err
+	})
+}
+
+func (r *sLeaderTransferRepo) GetPendingTransfersForUser(userId int) ([]*dbstruct.LeaderTransfer, error) {
+	var transfers []*dbstruct.LeaderTransfer
+	err := r.database.
+		Where("to_user_id = ? AND status = 'pending'", userId).
+		Find(&transfers).Error
+	return transfers, err
+}
+
+func (r *sLeaderTransferRepo) GetTransferForUpdate(tx *gorm.DB, transferId int) (*dbstruct.LeaderTransfer, error) {
+	if transferId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var transfer dbstruct.LeaderTransfer
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("transfer_id = ?", transferId).
+		First(&transfer).Error
+
+	return &transfer, err
+}
+
+func (r *sLeaderTransferRepo) AcceptTransfer(tx *gorm.DB, transferId int) error {
+	return tx.
+		Model(&dbstruct.LeaderTransfer{}).
+		Where("transfer_id = ?", transferId).
+		Updates(map[string]any{
+			"status":      "accepted",
+			"reviewed_at": time.Now(),
+		}).Error
+}
+
+func (r *sLeaderTransferRepo) RejectTransfer(tx *gorm.DB, transferId int) error {
+	return tx.
+		Model(&dbstruct.LeaderTransfer{}).
+		Where("transfer_id = ?", transferId).
+		Updates(map[string]any{
+			"status":      "rejected",
+			"reviewed_at": time.Now(),
+		}).Error
+}
+
+func (r *sLeaderTransferRepo) CancelPendingTransfers(tx *gorm.DB, clubId int) error {
+	return tx.
+		Model(&dbstruct.LeaderTransfer{}).
+		Where("club_id = ? AND status = 'pending'", clubId).
+		Update("status", "cancelled").Error
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+// 汇总各项用量，按outcome分别计数
+const kLlmUsageSummaryColumns = `
+	COUNT(*) FILTER (WHERE outcome = @ok) AS requests,
+	COUNT(*) FILTER (WHERE outcome = @rejected) AS rejected,
+	COUNT(*) FILTER (WHERE outcome = @failed) AS failed,
+	COALESCE(SUM(response_bytes), 0) AS response_bytes,
+	COALESCE(SUM(tokens), 0) AS tokens`
+
+type LlmUsageLogRepo interface {
+	AddLog(log *dbstruct.LlmUsageLog) error
+
+	// GetTotal 汇总[from, to)内全部用户的用量
+	GetTotal(from, to time.Time) (*model.LlmUsageSummary, error)
+	// GetUserSummaries 按用户汇总[from, to)内的用量，按token数与调用次数倒序
+	GetUserSummaries(from, to time.Time, offset, num int) ([]*model.LlmUsageSummary, error)
+}
+
+type sLlmUsageLogRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateLlmUsageLogRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) LlmUsageLogRepo {
+	return &sLlmUsageLogRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sLlmUsageLogRepo) AddLog(log *dbstruct.LlmUsageLog) error {
+	return r.database.Create(log).Error
+}
+
+func sLlmUsageOutcomes() map[string]any {
+	return map[string]any{
+		"ok":       dbstruct.LLM_USAGE_OK,
+		"rejected": dbstruct.LLM_USAGE_REJECTED,
+		"failed":   dbstruct.LLM_USAGE_FAILED,
+	}
+}
+
+func (r *sLlmUsageLogRepo) GetTotal(from, to time.Time) (*model.LlmUsageSummary, error) {
+	var total model.LlmUsageSummary
+	err := r.database.
+		Model(&dbstruct.LlmUsageLog{}).
+		Select("COUNT(DISTINCT user_id) AS users,"+kLlmUsageSummaryColumns, sLlmUsageOutcomes()).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Scan(&total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &total, nil
+}
+
+func (r *sLlmUsageLogRepo) GetUserSummaries(
+	from, to time.Time,
+	offset, num int,
+) ([]*model.LlmUsageSummary, error) {
+	var summaries []*model.LlmUsageSummary
+	err := r.database.
+		Model(&dbstruct.LlmUsageLog{}).
+		Select("user_id,"+kLlmUsageSummaryColumns, sLlmUsageOutcomes()).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("user_id").
+		Order("tokens DESC, requests DESC, user_id ASC").
+		Offset(offset).
+		Limit(num).
+		Scan(&summaries).Error
+
+	return summaries, err
+}
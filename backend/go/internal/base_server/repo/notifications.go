@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"errors"
+	"log/slog"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepo interface {
+	AddNotification(notification *dbstruct.Notification) error
+	GetNotifications(userId, offset, num int, unreadOnly bool) ([]*dbstruct.Notification, error)
+	GetUnreadNum(userId int) (int64, error)
+	MarkRead(userId, notificationId int) error
+	MarkAllRead(userId int) error
+}
+
+type sNotificationRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateNotificationRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) NotificationRepo {
+	return &sNotificationRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sNotificationRepo) AddNotification(notification *dbstruct.Notification) error {
+	return r.database.Create(notification).Error
+}
+
+func (r *sNotificationRepo) GetNotifications(userId, offset, num int, unreadOnly bool) ([]*dbstruct.Notification, error) {
+	if offset < 0 || num <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	query := r.database.
+		Model(&dbstruct.Notification{}).
+		Where("user_id = ?", userId)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+
+	var notifications []*dbstruct.Notification
+	err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(num).
+		Find(&notifications).Error
+
+	return notifications, err
+}
+
+func (r *sNotificationRepo) GetUnreadNum(userId int) (int64, error) {
+	var count int64
+	err := r.database.
+		Model(&dbstruct.Notification{}).
+		Where("user_id = ? AND is_read = ?", userId, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *sNotificationRepo) MarkRead(userId, notificationId int) error {
+	result := r.database.
+		Model(&dbstruct.Notification{}).
+		Where("notification_id = ? AND user_id = ?", notificationId, userId).
+		Update("is_read", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *sNotificationRepo) MarkAllRead(userId int) error {
+	return r.database.
+		Model(&dbstruct.Notification{}).
+		Where("user_id = ? AND is_read = ?", userId, false).
+		Update("is_read", true).Error
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"whuclubsynapse-server/internal/base_server/model"
+
+	"gorm.io/gorm"
+)
+
+// paginateByCursor 以 (timeCol, idCol) 倒序做键集分页，多取一条用于判断是否还有下一页
+func paginateByCursor[T any](
+	query *gorm.DB,
+	cursor *model.Cursor,
+	num int,
+	timeCol, idCol string,
+	keyOf func(T) model.Cursor,
+) (*model.Page[T], error) {
+	if num <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	if cursor != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s, %s) < (?, ?)", timeCol, idCol),
+			cursor.CreatedAt, cursor.Id,
+		)
+	}
+
+	var items []T
+	err := query.
+		Order(timeCol + " DESC").
+		Order(idCol + " DESC").
+		Limit(num + 1).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.Page[T]{Items: items}
+	if len(items) > num {
+		page.Items = items[:num]
+		page.HasMore = true
+		page.NextCursor = model.EncodeCursor(keyOf(items[num-1]))
+	}
+
+	return page, nil
+}
@@ -0,0 +1,17 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	kPgUniqueViolation = "23505"
+)
+
+// IsUniqueViolation 判断错误是否由唯一约束冲突引起，并发写入时据此识别已被他方抢先插入的记录
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == kPgUniqueViolation
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"log/slog"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+type PostAttachmentRepo interface {
+	AddAttachment(tx *gorm.DB, attachment *dbstruct.PostAttachment) error
+	CountAttachments(tx *gorm.DB, postId int) (int64, error)
+	GetAttachmentById(attachmentId int) (*dbstruct.PostAttachment, error)
+	GetAttachmentsByPostId(postId int) ([]*dbstruct.PostAttachment, error)
+	DeleteAttachment(attachmentId int) error
+	// CountAttachmentsByUrl 附件按内容去重存储，删除对象前需确认没有其他附件引用
+	CountAttachmentsByUrl(fileUrl string) (int64, error)
+}
+
+type sPostAttachmentRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreatePostAttachmentRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) PostAttachmentRepo {
+	return &sPostAttachmentRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sPostAttachmentRepo) AddAttachment(tx *gorm.DB, attachment *dbstruct.PostAttachment) error {
+	return tx.Create(attachment).Error
+}
+
+func (r *sPostAttachmentRepo) CountAttachments(tx *gorm.DB, postId int) (int64, error) {
+	var count int64
+	err := tx.
+		Model(&dbstruct.PostAttachment{}).
+		Where("post_id = ?", postId).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *sPostAttachmentRepo) GetAttachmentById(attachmentId int) (*dbstruct.PostAttachment, error) {
+	var attachment dbstruct.PostAttachment
+	err := r.database.
+		Where("attachment_id = ?", attachmentId).
+		First(&attachment).Error
+
+	return &attachment, err
+}
+
+func (r *sPostAttachmentRepo) GetAttachmentsByPostId(postId int) ([]*dbstruct.PostAttachment, error) {
+	var attachments []*dbstruct.PostAttachment
+	err := r.database.
+		Where("post_id = ?", postId).
+		Order("attachment_id ASC").
+		Find(&attachments).Error
+
+	return attachments, err
+}
+
+func (r *sPostAttachmentRepo) DeleteAttachment(attachmentId int) error {
+	res := r.database.
+		Where("attachment_id = ?", attachmentId).
+		Delete(&dbstruct.PostAttachment{})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *sPostAttachmentRepo) CountAttachmentsByUrl(fileUrl string) (int64, error) {
+	var count int64
+	err := r.database.
+		Model(&dbstruct.PostAttachment{}).
+		Where("file_url = ?", fileUrl).
+		Count(&count).Error
+
+	return count, err
+}
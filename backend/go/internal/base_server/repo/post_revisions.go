@@ -0,0 +1,63 @@
+package repo
+
+import (
+	"log/slog"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+type PostRevisionRepo interface {
+	AddRevision(tx *gorm.DB, revision *dbstruct.PostRevision) error
+	CountRevisions(tx *gorm.DB, postId int) (int64, error)
+	GetRevisions(postId int) ([]*dbstruct.PostRevision, error)
+	GetRevision(postId, version int) (*dbstruct.PostRevision, error)
+}
+
+type sPostRevisionRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreatePostRevisionRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) PostRevisionRepo {
+	return &sPostRevisionRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sPostRevisionRepo) AddRevision(tx *gorm.DB, revision *dbstruct.PostRevision) error {
+	return tx.Create(revision).Error
+}
+
+func (r *sPostRevisionRepo) CountRevisions(tx *gorm.DB, postId int) (int64, error) {
+	var count int64
+	err := tx.
+		Model(&dbstruct.PostRevision{}).
+		Where("post_id = ?", postId).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *sPostRevisionRepo) GetRevisions(postId int) ([]*dbstruct.PostRevision, error) {
+	var revisions []*dbstruct.PostRevision
+	err := r.database.
+		Where("post_id = ?", postId).
+		Order("version ASC").
+		Find(&revisions).Error
+
+	return revisions, err
+}
+
+func (r *sPostRevisionRepo) GetRevision(postId, version int) (*dbstruct.PostRevision, error) {
+	var revision dbstruct.PostRevision
+	err := r.database.
+		Where("post_id = ? AND version = ?", postId, version).
+		First(&revision).Error
+
+	return &revision, err
+}
@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RagOutboxRepo interface {
+	AddEvent(tx *gorm.DB, event *dbstruct.RagOutbox) error
+	// ClaimDueEvents 取出到期的待处理事件并将其下次处理时间推迟lease，
+	// 多个实例同时处理时同一事件只会被一方取得
+	ClaimDueEvents(tx *gorm.DB, now time.Time, lease time.Duration, num int) ([]*dbstruct.RagOutbox, error)
+	MarkDone(outboxId uint, messageId string, now time.Time) error
+	MarkRetry(outboxId uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(outboxId uint, attempts int, lastError string) error
+	// PurgeDone 删除before之前已处理完成的事件
+	PurgeDone(before time.Time) (int64, error)
+
+	// GetEvents 按事件ID倒序列出指定状态的事件，status为空时不限状态
+	GetEvents(status string, offset, num int) ([]*dbstruct.RagOutbox, error)
+	CountByStatus() (map[string]int64, error)
+	// Requeue 将失败的事件重新置为待处理，事件不存在或未失败时返回gorm.ErrRecordNotFound
+	Requeue(outboxId uint) error
+}
+
+type sRagOutboxRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateRagOutboxRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) RagOutboxRepo {
+	return &sRagOutboxRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sRagOutboxRepo) AddEvent(tx *gorm.DB, event *dbstruct.RagOutbox) error {
+	return tx.Create(event).Error
+}
+
+func (r *sRagOutboxRepo) ClaimDueEvents(
+	tx *gorm.DB,
+	now time.Time,
+	lease time.Duration,
+	num int,
+) ([]*dbstruct.RagOutbox, error) {
+	var events []*dbstruct.RagOutbox
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", dbstruct.OUTBOX_STATUS_PENDING, now).
+		Order("outbox_id ASC").
+		Limit(num).
+		Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	ids := make([]uint, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.OutboxId)
+	}
+
+	err = tx.
+		Model(&dbstruct.RagOutbox{}).
+		Where("outbox_id IN ?", ids).
+		Update("next_attempt_at", now.Add(lease)).Error
+
+	return events, err
+}
+
+func (r *sRagOutboxRepo) MarkDone(outboxId uint, messageId string, now time.Time) error {
+	return r.database.
+		Model(&dbstruct.RagOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"status":       dbstruct.OUTBOX_STATUS_DONE,
+			"message_id":   messageId,
+			"processed_at": now,
+			"last_error":   "",
+		}).Error
+}
+
+func (r *sRagOutboxRepo) MarkRetry(outboxId uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.database.
+		Model(&dbstruct.RagOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (r *sRagOutboxRepo) MarkFailed(outboxId uint, attempts int, lastError string) error {
+	return r.database.
+		Model(&dbstruct.RagOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"status":     dbstruct.OUTBOX_STATUS_FAILED,
+			"attempts":   attempts,
+			"last_error": lastError,
+		}).Error
+}
+
+func (r *sRagOutboxRepo) PurgeDone(before time.Time) (int64, error) {
+	result := r.database.
+		Where("status = ? AND processed_at < ?", dbstruct.OUTBOX_STATUS_DONE, before).
+		Delete(&dbstruct.RagOutbox{})
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *sRagOutboxRepo) GetEvents(status string, offset, num int) ([]*dbstruct.RagOutbox, error) {
+	query := r.database.Model(&dbstruct.RagOutbox{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var events []*dbstruct.RagOutbox
+	err := query.
+		Order("outbox_id DESC").
+		Offset(offset).
+		Limit(num).
+		Find(&events).Error
+
+	return events, err
+}
+
+func (r *sRagOutboxRepo) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.database.
+		Model(&dbstruct.RagOutbox{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+func (r *sRagOutboxRepo) Requeue(outboxId uint) error {
+	result := r.database.
+		Model(&dbstruct.RagOutbox{}).
+		Where("outbox_id = ? AND status = ?", outboxId, dbstruct.OUTBOX_STATUS_FAILED).
+		Updates(map[string]any{
+			"status":          dbstruct.OUTBOX_STATUS_PENDING,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
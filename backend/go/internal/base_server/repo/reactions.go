@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReactionRepo interface {
+	GetReactionForUpdate(tx *gorm.DB, targetType string, targetId, userId int) (*dbstruct.Reaction, error)
+	// AddOrLockReaction 插入回应，用户已回应过该对象时不插入，锁定并返回已有的回应；
+	// 新插入时返回nil。并发的首次回应在唯一索引上排队，不会重复插入
+	AddOrLockReaction(tx *gorm.DB, reaction *dbstruct.Reaction) (*dbstruct.Reaction, error)
+	UpdateReactionKind(tx *gorm.DB, reactionId uint, kind string) error
+	DeleteReaction(tx *gorm.DB, reactionId uint) error
+
+	GetUserReactions(userId int, targetType string, targetIds []uint) ([]*dbstruct.Reaction, error)
+	GetReactionCounts(targetType string, targetIds []uint) ([]*dbstruct.ReactionCount, error)
+	// ApplyCountDeltas 将增量累加到计数表，不存在的计数行按增量插入
+	ApplyCountDeltas(targetType string, targetId uint, deltas map[string]int64) error
+
+	// ScanReactionTargets 按ID升序遍历有回应或有计数的对象
+	ScanReactionTargets(targetType string, afterId uint, num int) ([]uint, error)
+	// RecountTargets 按回应表重新统计对象的计数，修正合并过程中产生的偏差
+	RecountTargets(targetType string, targetIds []uint) error
+}
+
+type sReactionRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateReactionRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) ReactionRepo {
+	return &sReactionRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sReactionRepo) GetReactionForUpdate(
+	tx *gorm.DB,
+	targetType string,
+	targetId, userId int,
+) (*dbstruct.Reaction, error) {
+	var reaction dbstruct.Reaction
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("target_type = ? AND target_id = ? AND user_id = ?", targetType, targetId, userId).
+		First(&reaction).Error
+
+	return &reaction, err
+}
+
+// AddOrLockReaction 冲突时以不改变内容的UPDATE锁定已有行，xmax为0说明是新插入的行
+func (r *sReactionRepo) AddOrLockReaction(tx *gorm.DB, reaction *dbstruct.Reaction) (*dbstruct.Reaction, error) {
+	var res struct {
+		dbstruct.Reaction
+		Inserted bool
+	}
+
+	err := tx.Raw(`
+INSERT INTO reactions (target_type, target_id, user_id, kind)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (target_type, target_id, user_id) DO UPDATE SET kind = reactions.kind
+RETURNING *, (xmax = 0) AS inserted`,
+		reaction.TargetType, reaction.TargetId, reaction.UserId, reaction.Kind,
+	).Scan(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Inserted {
+		return nil, nil
+	}
+
+	return &res.Reaction, nil
+}
+
+func (r *sReactionRepo) UpdateReactionKind(tx *gorm.DB, reactionId uint, kind string) error {
+	return tx.
+		Model(&dbstruct.Reaction{}).
+		Where("reaction_id = ?", reactionId).
+		Update("kind", kind).Error
+}
+
+func (r *sReactionRepo) DeleteReaction(tx *gorm.DB, reactionId uint) error {
+	return tx.
+		Where("reaction_id = ?", reactionId).
+		Delete(&dbstruct.Reaction{}).Error
+}
+
+func (r *sReactionRepo) GetUserReactions(
+	userId int,
+	targetType string,
+	targetIds []uint,
+) ([]*dbstruct.Reaction, error) {
+	if len(targetIds) == 0 {
+		return nil, nil
+	}
+
+	var reactions []*dbstruct.Reaction
+	err := r.database.
+		Where("user_id = ? AND target_type = ? AND target_id IN ?", userId, targetType, targetIds).
+		Find(&reactions).Error
+
+	return reactions, err
+}
+
+func (r *sReactionRepo) GetReactionCounts(targetType string, targetIds []uint) ([]*dbstruct.ReactionCount, error) {
+	if len(targetIds) == 0 {
+		return nil, nil
+	}
+
+	var counts []*dbstruct.ReactionCount
+	err := r.database.
+		Where("target_type = ? AND target_id IN ?", targetType, targetIds).
+		Find(&counts).Error
+
+	return counts, err
+}
+
+func (r *sReactionRepo) ApplyCountDeltas(targetType string, targetId uint, deltas map[string]int64) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]*dbstruct.ReactionCount, 0, len(deltas))
+	for kind, delta := range deltas {
+		rows = append(rows, &dbstruct.ReactionCount{
+			TargetType: targetType,
+			TargetId:   targetId,
+			Kind:       kind,
+			Count:      delta,
+			UpdatedAt:  now,
+		})
+	}
+
+	return r.database.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "target_type"}, {Name: "target_id"}, {Name: "kind"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"count":      gorm.Expr("reaction_counts.count + EXCLUDED.count"),
+				"updated_at": now,
+			}),
+		}).
+		Create(&rows).Error
+}
+
+func (r *sReactionRepo) ScanReactionTargets(targetType string, afterId uint, num int) ([]uint, error) {
+	var targetIds []uint
+	err := r.database.Raw(`
+SELECT target_id FROM (
+  SELECT target_id FROM reactions WHERE target_type = @type AND target_id > @after
+  UNION
+  SELECT target_id FROM reaction_counts WHERE target_type = @type AND target_id > @after
+) t
+ORDER BY target_id
+LIMIT @num`,
+		map[string]any{"type": targetType, "after": afterId, "num": num},
+	).Scan(&targetIds).Error
+
+	return targetIds, err
+}
+
+func (r *sReactionRepo) RecountTargets(targetType string, targetIds []uint) error {
+	if len(targetIds) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	return r.database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Model(&dbstruct.ReactionCount{}).
+			Where("target_type = ? AND target_id IN ?", targetType, targetIds).
+			Updates(map[string]any{"count": 0, "updated_at": now}).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+INSERT INTO reaction_counts (target_type, target_id, kind, count, updated_at)
+SELECT target_type, target_id, kind, COUNT(*), ?
+FROM reactions
+WHERE target_type = ? AND target_id IN ?
+GROUP BY target_type, target_id, kind
+ON CONFLICT (target_type, target_id, kind)
+DO UPDATE SET count = EXCLUDED.count, updated_at = EXCLUDED.updated_at`,
+			now, targetType, targetIds,
+		).Error
+	})
+}
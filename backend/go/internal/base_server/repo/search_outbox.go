@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SearchOutboxRepo interface {
+	AddEvent(tx *gorm.DB, event *dbstruct.SearchOutbox) error
+	// ClaimDueEvents 取出到期的待处理事件并将其下次处理时间推迟lease，
+	// 多个实例同时处理时同一事件只会被一方取得
+	ClaimDueEvents(tx *gorm.DB, now time.Time, lease time.Duration, num int) ([]*dbstruct.SearchOutbox, error)
+	MarkDone(outboxId uint, now time.Time) error
+	MarkRetry(outboxId uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(outboxId uint, attempts int, lastError string) error
+	// PurgeDone 删除before之前已处理完成的事件
+	PurgeDone(before time.Time) (int64, error)
+	// RequeueSince 将since之后产生的事件重新置为待处理，重建索引后用于补上期间的变更
+	RequeueSince(since time.Time) (int64, error)
+}
+
+type sSearchOutboxRepo struct {
+	database *gorm.DB
+	logger   *slog.Logger
+}
+
+func CreateSearchOutboxRepo(
+	database *gorm.DB,
+	logger *slog.Logger,
+) SearchOutboxRepo {
+	return &sSearchOutboxRepo{
+		database: database,
+		logger:   logger,
+	}
+}
+
+func (r *sSearchOutboxRepo) AddEvent(tx *gorm.DB, event *dbstruct.SearchOutbox) error {
+	return tx.Create(event).Error
+}
+
+func (r *sSearchOutboxRepo) ClaimDueEvents(
+	tx *gorm.DB,
+	now time.Time,
+	lease time.Duration,
+	num int,
+) ([]*dbstruct.SearchOutbox, error) {
+	var events []*dbstruct.SearchOutbox
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", dbstruct.OUTBOX_STATUS_PENDING, now).
+		Order("outbox_id ASC").
+		Limit(num).
+		Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	ids := make([]uint, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.OutboxId)
+	}
+
+	err = tx.
+		Model(&dbstruct.SearchOutbox{}).
+		Where("outbox_id IN ?", ids).
+		Update("next_attempt_at", now.Add(lease)).Error
+
+	return events, err
+}
+
+func (r *sSearchOutboxRepo) MarkDone(outboxId uint, now time.Time) error {
+	return r.database.
+		Model(&dbstruct.SearchOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"status":       dbstruct.OUTBOX_STATUS_DONE,
+			"processed_at": now,
+			"last_error":   "",
+		}).Error
+}
+
+func (r *sSearchOutboxRepo) MarkRetry(outboxId uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.database.
+		Model(&dbstruct.SearchOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"attempts":        attempts,
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+func (r *sSearchOutboxRepo) MarkFailed(outboxId uint, attempts int, lastError string) error {
+	return r.database.
+		Model(&dbstruct.SearchOutbox{}).
+		Where("outbox_id = ?", outboxId).
+		Updates(map[string]any{
+			"status":     dbstruct.OUTBOX_STATUS_FAILED,
+			"attempts":   attempts,
+			"last_error": lastError,
+		}).Error
+}
+
+func (r *sSearchOutboxRepo) PurgeDone(before time.Time) (int64, error) {
+	result := r.database.
+		Where("status = ? AND processed_at < ?", dbstruct.OUTBOX_STATUS_DONE, before).
+		Delete(&dbstruct.SearchOutbox{})
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *sSearchOutboxRepo) RequeueSince(since time.Time) (int64, error) {
+	result := r.database.
+		Model(&dbstruct.SearchOutbox{}).
+		Where("created_at >= ?", since).
+		Updates(map[string]any{
+			"status":          dbstruct.OUTBOX_STATUS_PENDING,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"processed_at":    nil,
+		})
+
+	return result.RowsAffected, result.Error
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"gorm.io/gorm"
@@ -12,7 +13,9 @@ import (
 type UpdateClubInfoAppliRepo interface {
 	AddUpdateClubInfoAppli(appli *dbstruct.UpdateClubInfoAppli) error
 	GetUpdateList(offset, num int) ([]*dbstruct.UpdateClubInfoAppli, error)
+	GetUpdateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.UpdateClubInfoAppli], error)
 	GetApplisByUserId(userId int) ([]*dbstruct.UpdateClubInfoAppli, error)
+	GetAppliById(appliId int) (*dbstruct.UpdateClubInfoAppli, error)
 	GetAppliForUpdate(tx *gorm.DB, appliId int) (*dbstruct.UpdateClubInfoAppli, error)
 	ApproveAppli(tx *gorm.DB, appliId int) error
 	RejectAppli(appliId int, reason string) error
@@ -102,6 +105,29 @@ func (r *sUpdateClubInfoAppliRepo) GetUpdateList(offset, num int) ([]*dbstruct.U
 	return applis, err
 }
 
+func (r *sUpdateClubInfoAppliRepo) GetUpdateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.UpdateClubInfoAppli], error) {
+	return paginateByCursor(
+		r.database.Model(&dbstruct.UpdateClubInfoAppli{}),
+		cursor, num, "applied_at", "update_appli_id",
+		func(a *dbstruct.UpdateClubInfoAppli) model.Cursor {
+			return model.Cursor{CreatedAt: a.AppliedAt, Id: a.UpdateAppliId}
+		},
+	)
+}
+
+func (r *sUpdateClubInfoAppliRepo) GetAppliById(appliId int) (*dbstruct.UpdateClubInfoAppli, error) {
+	if appliId <= 0 {
+		return nil, errors.New("无效参数")
+	}
+
+	var appli dbstruct.UpdateClubInfoAppli
+	err := r.database.
+		Where("update_appli_id = ?", appliId).
+		First(&appli).Error
+
+	return &appli, err
+}
+
 func (r *sUpdateClubInfoAppliRepo) GetApplisByUserId(userId int) ([]*dbstruct.UpdateClubInfoAppli, error) {
 	var applis []*dbstruct.UpdateClubInfoAppli
 	err := r.database.
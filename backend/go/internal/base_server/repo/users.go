@@ -4,8 +4,10 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -13,10 +15,15 @@ type UserRepo interface {
 	AddUser(user *dbstruct.User) error
 	GetUserById(id int) (*dbstruct.User, error)
 	GetUserByUsername(username string) (*dbstruct.User, error)
+	GetUserByEmail(email string) (*dbstruct.User, error)
 	GetUserList(offset int, num int) ([]*dbstruct.User, error)
+	GetUserListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.User], error)
 	UpdateUserLastActive(id int) error
-	UpdateUserRole(tx *gorm.DB, id int, role string) error
-	UpdateAvatar(id int, avatarUrl string) error
+	// UpdateUserRole 与 DemoteUserRole 返回角色是否实际发生变化，调用方据此吊销旧token
+	UpdateUserRole(tx *gorm.DB, id int, role string) (bool, error)
+	DemoteUserRole(tx *gorm.DB, id int) (bool, error)
+	UpdateAvatar(id int, avatarUrl string, variants datatypes.JSON) error
+	UpdatePasswordHash(id int, passwordHash string) error
 	UpdateUser(newUser *dbstruct.User) error
 }
 
@@ -63,6 +70,19 @@ func (r *sUserRepo) GetUserByUsername(username string) (*dbstruct.User, error) {
 	return &user, err
 }
 
+func (r *sUserRepo) GetUserByEmail(email string) (*dbstruct.User, error) {
+	if email == "" {
+		return nil, errors.New("无效邮箱")
+	}
+
+	var user dbstruct.User
+	err := r.database.
+		Where("email = ?", email).
+		First(&user).Error
+
+	return &user, err
+}
+
 func (r *sUserRepo) GetUserList(offset int, num int) ([]*dbstruct.User, error) {
 	if offset < 0 || num <= 0 {
 		return nil, errors.New("无效参数")
@@ -78,6 +98,16 @@ func (r *sUserRepo) GetUserList(offset int, num int) ([]*dbstruct.User, error) {
 	return users, err
 }
 
+func (r *sUserRepo) GetUserListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.User], error) {
+	return paginateByCursor(
+		r.database.Model(&dbstruct.User{}),
+		cursor, num, "created_at", "user_id",
+		func(u *dbstruct.User) model.Cursor {
+			return model.Cursor{CreatedAt: u.CreatedAt, Id: u.UserId}
+		},
+	)
+}
+
 func (r *sUserRepo) UpdateUserLastActive(id int) error {
 	return r.database.
 		Model(&dbstruct.User{}).
@@ -85,18 +115,39 @@ func (r *sUserRepo) UpdateUserLastActive(id int) error {
 		Update("last_active", time.Now()).Error
 }
 
-func (r *sUserRepo) UpdateUserRole(tx *gorm.DB, id int, role string) error {
-	return r.database.
+func (r *sUserRepo) UpdateUserRole(tx *gorm.DB, id int, role string) (bool, error) {
+	result := tx.
 		Model(&dbstruct.User{}).
 		Where("user_id = ? AND role = 'user'", id).
-		Update("role", role).Error
+		Update("role", role)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *sUserRepo) DemoteUserRole(tx *gorm.DB, id int) (bool, error) {
+	result := tx.
+		Model(&dbstruct.User{}).
+		Where("user_id = ? AND role = ?", id, dbstruct.ROLE_PUBLISHER).
+		Update("role", dbstruct.ROLE_USER)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *sUserRepo) UpdateAvatar(id int, avatarUrl string, variants datatypes.JSON) error {
+	return r.database.
+		Model(&dbstruct.User{}).
+		Where("user_id = ?", id).
+		Updates(map[string]any{
+			"avatar_url":      avatarUrl,
+			"avatar_variants": variants,
+		}).Error
 }
 
-func (r *sUserRepo) UpdateAvatar(id int, avatarUrl string) error {
+func (r *sUserRepo) UpdatePasswordHash(id int, passwordHash string) error {
 	return r.database.
 		Model(&dbstruct.User{}).
 		Where("user_id = ?", id).
-		Update("avatar_url", avatarUrl).Error
+		Update("password_hash", passwordHash).Error
 }
 
 func (r *sUserRepo) UpdateUser(newUser *dbstruct.User) error {
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+	"whuclubsynapse-server/internal/shared/imgutil"
+
+	"gorm.io/gorm"
+)
+
+const (
+	POST_ATTACHMENT_PREFIX = "post_attachments"
+
+	kAttachmentMaxSize   = 10 << 20
+	kAttachmentMaxNum    = 20
+	kAttachmentThumbEdge = 320
+	// 正文中以 attachment:<附件ID> 引用附件
+	kAttachmentLinkScheme = "attachment:"
+)
+
+// 允许上传的附件类型及保存时使用的扩展名，类型以文件内容嗅探结果为准
+var kAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// AttachmentPolicy 附件限制，字段未配置时使用默认值
+type AttachmentPolicy struct {
+	MaxSize int64 // 单个附件的字节数上限
+	MaxNum  int   // 单个帖子的附件数上限
+}
+
+type AttachmentService interface {
+	// UploadAttachment 保存帖子附件，仅帖子作者可上传；图片附件同时生成缩略图
+	UploadAttachment(postId, uploaderId int, fileName string, file io.Reader) (*dbstruct.PostAttachment, error)
+	// GetAttachments 未发布帖子的附件仅作者可见
+	GetAttachments(postId, userId int) ([]*dbstruct.PostAttachment, error)
+	DeleteAttachment(attachmentId, userId int) error
+
+	// RewriteLinks 将正文中以原始文件名或attachment:<附件ID>引用附件的链接改写为附件地址
+	RewriteLinks(postId uint, content string) (string, error)
+}
+
+type sAttachmentService struct {
+	postAttachmentRepo repo.PostAttachmentRepo
+	clubPostRepo       repo.ClubPostRepo
+
+	blobStore storage.BlobStore
+
+	txCoordinator repo.TransactionCoordinator
+
+	policy AttachmentPolicy
+
+	logger *slog.Logger
+}
+
+func NewAttachmentService(
+	postAttachmentRepo repo.PostAttachmentRepo,
+	clubPostRepo repo.ClubPostRepo,
+
+	blobStore storage.BlobStore,
+
+	txCoordinator repo.TransactionCoordinator,
+
+	policy AttachmentPolicy,
+
+	logger *slog.Logger,
+) AttachmentService {
+	if policy.MaxSize <= 0 {
+		policy.MaxSize = kAttachmentMaxSize
+	}
+	if policy.MaxNum <= 0 {
+		policy.MaxNum = kAttachmentMaxNum
+	}
+
+	return &sAttachmentService{
+		postAttachmentRepo: postAttachmentRepo,
+		clubPostRepo:       clubPostRepo,
+
+		blobStore: blobStore,
+
+		txCoordinator: txCoordinator,
+
+		policy: policy,
+
+		logger: logger,
+	}
+}
+
+func (s *sAttachmentService) UploadAttachment(
+	postId, uploaderId int,
+	fileName string,
+	file io.Reader,
+) (*dbstruct.PostAttachment, error) {
+	post, err := s.sGetPost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(post.UserId) != uploaderId {
+		return nil, apperr.ErrPostNotAuthor
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.policy.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > s.policy.MaxSize {
+		return nil, apperr.ErrAttachmentTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := kAttachmentTypes[contentType]
+	if !ok {
+		return nil, apperr.ErrAttachmentType.WithMessage("不支持的附件类型：" + contentType)
+	}
+
+	ctxPut, cancelPut := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelPut()
+
+	key, err := s.blobStore.Put(ctxPut, POST_ATTACHMENT_PREFIX, ext, data, contentType)
+	if err != nil {
+		return nil, errors.New("保存附件失败，需重试：" + err.Error())
+	}
+
+	attachment := &dbstruct.PostAttachment{
+		PostId:      post.PostId,
+		UploaderId:  uint(uploaderId),
+		FileName:    sAttachmentName(fileName, ext),
+		FileUrl:     s.blobStore.URL(key),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		attachment.ThumbUrl = s.sMakeThumbnail(ctxPut, data)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		// 锁定帖子行，保证并发上传时附件数不超过上限
+		if _, err := s.clubPostRepo.GetPostForUpdate(tx, postId); err != nil {
+			return err
+		}
+
+		count, err := s.postAttachmentRepo.CountAttachments(tx, postId)
+		if err != nil {
+			return err
+		}
+
+		if count >= int64(s.policy.MaxNum) {
+			return apperr.ErrAttachmentLimit
+		}
+
+		return s.postAttachmentRepo.AddAttachment(tx, attachment)
+	})
+	if err != nil {
+		s.sRemoveObjects(attachment)
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrPostNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *sAttachmentService) GetAttachments(postId, userId int) ([]*dbstruct.PostAttachment, error) {
+	post, err := s.sGetPost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.Status != dbstruct.POST_STATUS_PUBLISHED && int(post.UserId) != userId {
+		return nil, apperr.ErrPostNotFound
+	}
+
+	return s.postAttachmentRepo.GetAttachmentsByPostId(postId)
+}
+
+func (s *sAttachmentService) DeleteAttachment(attachmentId, userId int) error {
+	attachment, err := s.postAttachmentRepo.GetAttachmentById(attachmentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.ErrAttachmentNotFound.Wrap(err)
+		}
+		return err
+	}
+
+	if int(attachment.UploaderId) != userId {
+		post, err := s.sGetPost(int(attachment.PostId))
+		if err != nil {
+			return err
+		}
+
+		if int(post.UserId) != userId {
+			return apperr.ErrPostNotAuthor
+		}
+	}
+
+	if err := s.postAttachmentRepo.DeleteAttachment(attachmentId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.ErrAttachmentNotFound.Wrap(err)
+		}
+		return err
+	}
+
+	s.sRemoveObjects(attachment)
+
+	return nil
+}
+
+func (s *sAttachmentService) RewriteLinks(postId uint, content string) (string, error) {
+	attachments, err := s.postAttachmentRepo.GetAttachmentsByPostId(int(postId))
+	if err != nil {
+		return "", err
+	}
+
+	if len(attachments) == 0 {
+		return content, nil
+	}
+
+	// 同名附件以最后上传的为准
+	targets := make(map[string]string, len(attachments)*2)
+	for _, attachment := range attachments {
+		targets[attachment.FileName] = attachment.FileUrl
+		targets[kAttachmentLinkScheme+strconv.FormatUint(uint64(attachment.AttachmentId), 10)] = attachment.FileUrl
+	}
+
+	return model.RewriteMarkdownLinks(content, func(target string) (string, bool) {
+		target = strings.TrimPrefix(target, "./")
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+
+		fileUrl, ok := targets[target]
+		return fileUrl, ok
+	}), nil
+}
+
+func (s *sAttachmentService) sGetPost(postId int) (*dbstruct.ClubPost, error) {
+	post, err := s.clubPostRepo.GetPostById(postId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrPostNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// sMakeThumbnail 缩略图生成失败不影响附件上传，返回空地址
+func (s *sAttachmentService) sMakeThumbnail(ctx context.Context, data []byte) string {
+	img, err := imgutil.Decode(data)
+	if err != nil {
+		// webp等标准库无法解码的格式直接跳过
+		s.logger.Debug("无法解码图片附件，跳过缩略图", "error", err)
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := imgutil.EncodeJPEG(&buf, imgutil.Fit(img, kAttachmentThumbEdge), 80); err != nil {
+		s.logger.Error("生成缩略图失败", "error", err)
+		return ""
+	}
+
+	key, err := s.blobStore.Put(ctx, POST_ATTACHMENT_PREFIX, "_thumb.jpg", buf.Bytes(), "image/jpeg")
+	if err != nil {
+		s.logger.Error("保存缩略图失败", "error", err)
+		return ""
+	}
+
+	return s.blobStore.URL(key)
+}
+
+// sRemoveObjects 相同内容的附件共用存储对象，仍有附件引用时保留
+func (s *sAttachmentService) sRemoveObjects(attachment *dbstruct.PostAttachment) {
+	count, err := s.postAttachmentRepo.CountAttachmentsByUrl(attachment.FileUrl)
+	if err != nil {
+		s.logger.Error("查询附件引用失败", "error", err, "url", attachment.FileUrl)
+		return
+	}
+
+	if count > 0 {
+		return
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, objectUrl := range []string{attachment.FileUrl, attachment.ThumbUrl} {
+		key, ok := s.blobStore.KeyOf(objectUrl)
+		if !ok {
+			continue
+		}
+
+		if err := s.blobStore.Delete(ctxTmt, key); err != nil {
+			s.logger.Error("删除附件对象失败", "error", err, "key", key)
+		}
+	}
+}
+
+// sAttachmentName 只保留原始文件名的最后一段，缺省时以扩展名命名
+func sAttachmentName(fileName, ext string) string {
+	name := path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	name = strings.TrimSpace(strings.ToValidUTF8(name, ""))
+	if name == "" || name == "." || name == "/" {
+		return "attachment" + ext
+	}
+
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+	"whuclubsynapse-server/internal/shared/jwtutil"
+
+	"gorm.io/gorm"
+)
+
+type AuthService interface {
+	IssueTokens(userId int, role, uuid string) (*model.TokenPair, error)
+	// RotateRefreshToken 消费refresh token并返回其所属用户，调用方随后重新签发凭证
+	RotateRefreshToken(refreshToken string) (*dbstruct.User, error)
+	VerifyAccessToken(signed string) (*model.UserClaims, error)
+	Logout(accessToken, refreshToken string) error
+
+	// IssueStreamTicket 签发短时有效的一次性凭证，供无法设置请求头的EventSource建立推送连接
+	IssueStreamTicket(userId int, role string) (string, error)
+	// ConsumeStreamTicket 校验并作废推送连接凭证，返回其所属用户
+	ConsumeStreamTicket(ticket string) (*model.UserClaims, error)
+
+	// RevokeUserTokens 使用户已签发的access token全部失效，refresh token保留，
+	// 刷新时按数据库中的最新角色重新签发
+	RevokeUserTokens(userId int) error
+	// RevokeAllSessions 在RevokeUserTokens基础上删除全部refresh token，用于密码变更等场景
+	RevokeAllSessions(userId int) error
+
+	// CheckLoginLock 返回账号在该客户端IP上的剩余锁定时长，未锁定时为0。
+	// 失败次数按用户名与IP分别累计，其他来源的失败不会锁定正常用户
+	CheckLoginLock(username, clientIp string) (time.Duration, error)
+	// RecordLoginFailure 记录登录失败，达到阈值时锁定并返回锁定时长
+	RecordLoginFailure(username, clientIp string) (time.Duration, error)
+	ResetLoginFailure(username, clientIp string) error
+}
+
+// LoginLockoutPolicy 登录失败锁定策略，失败次数在FailWindow内累计
+type LoginLockoutPolicy struct {
+	FailWindow time.Duration
+	Threshold  int64
+	LockBase   time.Duration
+	LockMax    time.Duration
+}
+
+// LockFor 返回累计失败failNum次时的锁定时长：达到阈值时锁定LockBase，
+// 此后每多失败一次翻倍，不超过LockMax；未达到阈值时为0
+func (p LoginLockoutPolicy) LockFor(failNum int64) time.Duration {
+	if p.Threshold <= 0 || failNum < p.Threshold {
+		return 0
+	}
+
+	lock := p.LockBase
+	for i := p.Threshold; i < failNum && lock < p.LockMax; i++ {
+		lock *= 2
+	}
+
+	return min(lock, p.LockMax)
+}
+
+const (
+	// 凭证仅用于随后立即发起的连接请求
+	kStreamTicketTTL = 30 * time.Second
+)
+
+type sAuthService struct {
+	jwtFactory        *jwtutil.CliamsFactory[model.UserClaims]
+	refreshExpiration time.Duration
+
+	lockout LoginLockoutPolicy
+
+	redisService redisimpl.RedisClientService
+	userRepo     repo.UserRepo
+
+	logger *slog.Logger
+}
+
+func NewAuthService(
+	jwtFactory *jwtutil.CliamsFactory[model.UserClaims],
+	refreshExpiration time.Duration,
+	lockout LoginLockoutPolicy,
+	redisService redisimpl.RedisClientService,
+	userRepo repo.UserRepo,
+	logger *slog.Logger,
+) AuthService {
+	return &sAuthService{
+		jwtFactory:        jwtFactory,
+		refreshExpiration: refreshExpiration,
+		lockout:           lockout,
+
+		redisService: redisService,
+		userRepo:     userRepo,
+
+		logger: logger,
+	}
+}
+
+func (s *sAuthService) IssueTokens(userId int, role, uuid string) (*model.TokenPair, error) {
+	version, err := s.redisService.GetTokenVersion(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenId, err := sRandomToken(16, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.jwtFactory.GenToken(model.UserClaims{
+		UserId:       userId,
+		Role:         role,
+		Uuid:         uuid,
+		TokenId:      tokenId,
+		TokenVersion: version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := sRandomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.redisService.SaveRefreshToken(
+		refreshToken, userId, s.refreshExpiration,
+	); err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.jwtFactory.ExpirationTime / time.Second),
+	}, nil
+}
+
+func (s *sAuthService) RotateRefreshToken(refreshToken string) (*dbstruct.User, error) {
+	if refreshToken == "" {
+		return nil, apperr.ErrRefreshInvalid
+	}
+
+	userId, err := s.redisService.ConsumeRefreshToken(refreshToken, s.refreshExpiration)
+	switch {
+	case errors.Is(err, redisimpl.ErrRefreshTokenNotFound):
+		return nil, apperr.ErrRefreshInvalid.Wrap(err)
+
+	case errors.Is(err, redisimpl.ErrRefreshTokenReused):
+		s.logger.Warn("refresh token被重复使用，吊销该用户全部登录状态", "user_id", userId)
+
+		if err := s.RevokeAllSessions(userId); err != nil {
+			s.logger.Error("吊销用户登录状态失败", "error", err, "user_id", userId)
+		}
+
+		return nil, apperr.ErrRefreshInvalid.Wrap(err)
+
+	case err != nil:
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrRefreshInvalid.Wrap(err)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *sAuthService) VerifyAccessToken(signed string) (*model.UserClaims, error) {
+	claims, err := s.jwtFactory.ParseToken(signed)
+	if err != nil {
+		return nil, apperr.ErrUnauthorized.
+			WithMessage("无效的Authorization请求头").
+			Wrap(err)
+	}
+
+	version, revoked, err := s.redisService.GetTokenState(claims.UserId, claims.TokenId)
+	if err != nil {
+		return nil, apperr.ErrServiceUnavailable.Wrap(err)
+	}
+
+	if revoked || version != claims.TokenVersion {
+		return nil, apperr.ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (s *sAuthService) Logout(accessToken, refreshToken string) error {
+	if accessToken != "" {
+		// access token已过期时无需吊销，仍继续删除refresh token
+		if claims, err := s.jwtFactory.ParseToken(accessToken); err == nil {
+			if err := s.redisService.RevokeAccessToken(
+				claims.TokenId, s.jwtFactory.ExpirationTime,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	if refreshToken != "" {
+		return s.redisService.DeleteRefreshToken(refreshToken)
+	}
+
+	return nil
+}
+
+func (s *sAuthService) IssueStreamTicket(userId int, role string) (string, error) {
+	ticket, err := sRandomToken(24, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.redisService.SaveStreamTicket(ticket, userId, role, kStreamTicketTTL); err != nil {
+		return "", apperr.ErrServiceUnavailable.Wrap(err)
+	}
+
+	return ticket, nil
+}
+
+func (s *sAuthService) ConsumeStreamTicket(ticket string) (*model.UserClaims, error) {
+	userId, role, err := s.redisService.ConsumeStreamTicket(ticket)
+	if errors.Is(err, redisimpl.ErrStreamTicketNotFound) {
+		return nil, apperr.ErrUnauthorized.WithMessage("推送连接凭证无效或已过期")
+	}
+	if err != nil {
+		return nil, apperr.ErrServiceUnavailable.Wrap(err)
+	}
+
+	return &model.UserClaims{UserId: userId, Role: role}, nil
+}
+
+func (s *sAuthService) RevokeUserTokens(userId int) error {
+	_, err := s.redisService.IncrTokenVersion(userId)
+	return err
+}
+
+func (s *sAuthService) RevokeAllSessions(userId int) error {
+	if err := s.RevokeUserTokens(userId); err != nil {
+		return err
+	}
+
+	return s.redisService.DeleteUserRefreshTokens(userId)
+}
+
+// sLoginIdentifier 用户名的规范化方式与登录接口按用户名限流时一致
+func sLoginIdentifier(username, clientIp string) string {
+	return strings.ToLower(strings.TrimSpace(username)) + "@" + clientIp
+}
+
+func (s *sAuthService) CheckLoginLock(username, clientIp string) (time.Duration, error) {
+	return s.redisService.GetLoginLock(sLoginIdentifier(username, clientIp))
+}
+
+func (s *sAuthService) RecordLoginFailure(username, clientIp string) (time.Duration, error) {
+	identifier := sLoginIdentifier(username, clientIp)
+
+	failNum, err := s.redisService.RecordLoginFailure(identifier, s.lockout.FailWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	lock := s.lockout.LockFor(failNum)
+	if lock <= 0 {
+		return 0, nil
+	}
+
+	if err := s.redisService.SetLoginLock(identifier, lock); err != nil {
+		return 0, err
+	}
+
+	s.logger.Warn("登录失败次数过多，锁定账号",
+		"username", username, "client_ip", clientIp, "fail_num", failNum, "lock", lock,
+	)
+
+	return lock, nil
+}
+
+func (s *sAuthService) ResetLoginFailure(username, clientIp string) error {
+	return s.redisService.ResetLoginFailure(sLoginIdentifier(username, clientIp))
+}
+
+func sRandomToken(size int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return encode(buf), nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLockoutPolicyLockFor(t *testing.T) {
+	policy := LoginLockoutPolicy{
+		FailWindow: 15 * time.Minute,
+		Threshold:  5,
+		LockBase:   time.Minute,
+		LockMax:    time.Hour,
+	}
+
+	tests := []struct {
+		name    string
+		policy  LoginLockoutPolicy
+		failNum int64
+		want    time.Duration
+	}{
+		{"未失败", policy, 0, 0},
+		{"低于阈值", policy, 4, 0},
+		{"达到阈值", policy, 5, time.Minute},
+		{"超出一次翻倍", policy, 6, 2 * time.Minute},
+		{"超出三次", policy, 8, 8 * time.Minute},
+		{"翻倍后超过上限时封顶", policy, 11, time.Hour},
+		{"远超阈值仍封顶", policy, 1000, time.Hour},
+		{"阈值为0不锁定", LoginLockoutPolicy{LockBase: time.Minute, LockMax: time.Hour}, 100, 0},
+		{"基础时长超过上限", LoginLockoutPolicy{Threshold: 1, LockBase: 2 * time.Hour, LockMax: time.Hour}, 1, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.LockFor(tt.failNum); got != tt.want {
+				t.Errorf("LockFor(%d) = %v, want %v", tt.failNum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		clientIp string
+		want     string
+	}{
+		{"原样", "alice", "10.0.0.1", "alice@10.0.0.1"},
+		{"大小写不敏感", "Alice", "10.0.0.1", "alice@10.0.0.1"},
+		{"忽略首尾空白", "  ALICE ", "10.0.0.1", "alice@10.0.0.1"},
+		{"不同IP分别计数", "alice", "10.0.0.2", "alice@10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sLoginIdentifier(tt.username, tt.clientIp); got != tt.want {
+				t.Errorf("sLoginIdentifier(%q, %q) = %q, want %q",
+					tt.username, tt.clientIp, got, tt.want,
+				)
+			}
+		})
+	}
+}
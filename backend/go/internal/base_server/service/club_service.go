@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/repo"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 	"whuclubsynapse-server/internal/shared/jsonbutil"
@@ -16,6 +19,7 @@ import (
 
 type ClubService interface {
 	GetClubList(offset, num int) ([]*dbstruct.Club, error)
+	GetClubListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.Club], error)
 	GetClubInfo(clubId int) (*dbstruct.Club, error)
 	GetClubsByCategory(catId int) ([]*dbstruct.Club, error)
 	GetLatestClubs() ([]*dbstruct.Club, error)
@@ -23,6 +27,7 @@ type ClubService interface {
 	GetClubCategories() ([]*dbstruct.Category, error)
 
 	GetJoinApplisForClub(clubId int) ([]*dbstruct.JoinClubAppli, error)
+	GetJoinAppli(appliId int) (*dbstruct.JoinClubAppli, error)
 	GetCreateApplisForUser(userId int) ([]*dbstruct.CreateClubAppli, error)
 
 	GetUserCreateApplis(userId int) ([]*dbstruct.CreateClubAppli, error)
@@ -30,6 +35,8 @@ type ClubService interface {
 
 	GetMemberList(clubId int) ([]*dbstruct.ClubMember, error)
 	GetClubListByUserId(userId int) ([]*dbstruct.Club, error)
+	GetMemberInClub(clubId, userId int) (*dbstruct.ClubMember, error)
+	ChangeMemberRole(clubId, operatorId, targetId int, newRole string) error
 
 	ApplyForCreateClub(newClub dbstruct.Club) error
 	ApplyForJoinClub(userId, expectedClubId uint, reason string) error
@@ -48,15 +55,24 @@ type ClubService interface {
 	UnfavouriteClub(userId, clubId int) error
 	GetFavoriteClubs(userId int) ([]*dbstruct.Club, error)
 
-	UpdateClubLogo(clubId int, logoUrl string) error
+	UpdateClubLogo(clubId int, variants model.ImageVariants) error
+	// SetPostReviewPolicy 设置社团普通成员发帖是否需经审核
+	SetPostReviewPolicy(clubId int, requireReview bool) error
 
 	QuitClub(clubId, userId int) error
 	DissambleClub(clubId int) error
 
+	NominateLeader(clubId, nomineeId int) error
+	GetLeaderTransfersForUser(userId int) ([]*dbstruct.LeaderTransfer, error)
+	AcceptLeaderTransfer(transferId, userId int) error
+	RejectLeaderTransfer(transferId, userId int) error
+
 	GetUpdateApplisForUser(userId int) ([]*dbstruct.UpdateClubInfoAppli, error)
 	GetUpdateList(offset, num int) ([]*dbstruct.UpdateClubInfoAppli, error)
+	GetUpdateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.UpdateClubInfoAppli], error)
 
 	GetCreateList(offset, num int) ([]*dbstruct.CreateClubAppli, error)
+	GetCreateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.CreateClubAppli], error)
 }
 
 type sClubService struct {
@@ -69,6 +85,12 @@ type sClubService struct {
 	categoryRepo            repo.CatogoryRepo
 	updateClubInfoAppliRepo repo.UpdateClubInfoAppliRepo
 	clubFavoriteRepo        repo.ClubFavouriteRepo
+	leaderTransferRepo      repo.LeaderTransferRepo
+
+	notificationService NotificationService
+	authService         AuthService
+	searchService       SearchService
+	ragSyncService      RagSyncService
 
 	txCoordinator repo.TransactionCoordinator
 
@@ -85,6 +107,12 @@ func NewClubService(
 	categoryRepo repo.CatogoryRepo,
 	updateClubInfoAppliRepo repo.UpdateClubInfoAppliRepo,
 	clubFavoriteRepo repo.ClubFavouriteRepo,
+	leaderTransferRepo repo.LeaderTransferRepo,
+
+	notificationService NotificationService,
+	authService AuthService,
+	searchService SearchService,
+	ragSyncService RagSyncService,
 
 	txCoordinator repo.TransactionCoordinator,
 
@@ -100,6 +128,12 @@ func NewClubService(
 		categoryRepo:            categoryRepo,
 		updateClubInfoAppliRepo: updateClubInfoAppliRepo,
 		clubFavoriteRepo:        clubFavoriteRepo,
+		leaderTransferRepo:      leaderTransferRepo,
+
+		notificationService: notificationService,
+		authService:         authService,
+		searchService:       searchService,
+		ragSyncService:      ragSyncService,
 
 		txCoordinator: txCoordinator,
 
@@ -111,8 +145,17 @@ func (s *sClubService) GetClubList(offset, num int) ([]*dbstruct.Club, error) {
 	return s.clubRepo.GetClubList(offset, num)
 }
 
+func (s *sClubService) GetClubListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.Club], error) {
+	return s.clubRepo.GetClubListByCursor(cursor, num)
+}
+
 func (s *sClubService) GetClubInfo(clubId int) (*dbstruct.Club, error) {
-	return s.clubRepo.GetClubInfo(clubId)
+	club, err := s.clubRepo.GetClubInfo(clubId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.ErrClubNotFound.Wrap(err)
+	}
+
+	return club, err
 }
 
 func (s *sClubService) GetClubsByCategory(catId int) ([]*dbstruct.Club, error) {
@@ -147,6 +190,43 @@ func (s *sClubService) GetClubListByUserId(userId int) ([]*dbstruct.Club, error)
 	return s.clubMemberRepo.GetClubListByUserId(userId)
 }
 
+func (s *sClubService) GetMemberInClub(clubId, userId int) (*dbstruct.ClubMember, error) {
+	return s.clubMemberRepo.GetMemberInClub(userId, clubId)
+}
+
+func (s *sClubService) ChangeMemberRole(clubId, operatorId, targetId int, newRole string) error {
+	if !model.IsValidClubRole(newRole) {
+		return apperr.ErrInvalidClubRole.Wrap(errors.New(newRole))
+	}
+
+	if newRole == dbstruct.ROLE_CLUB_LEADER {
+		return apperr.ErrLeaderRoleLocked
+	}
+
+	if operatorId == targetId {
+		return apperr.ErrForbidden.WithMessage("无法修改自己的社团角色")
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		target, err := s.clubMemberRepo.GetMemberForUpdate(tx, targetId, clubId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrNotClubMember.Wrap(err)
+			}
+			return err
+		}
+
+		if target.RoleInClub == dbstruct.ROLE_CLUB_LEADER {
+			return apperr.ErrLeaderRoleLocked
+		}
+
+		return s.clubMemberRepo.UpdateMemberRole(tx, targetId, clubId, newRole)
+	})
+}
+
 func (s *sClubService) ApplyForCreateClub(newClub dbstruct.Club) error {
 	jsonbClub, err := jsonbutil.ToJsonb(newClub)
 	if err != nil {
@@ -163,6 +243,19 @@ func (s *sClubService) ApplyForCreateClub(newClub dbstruct.Club) error {
 
 func (s *sClubService) ApplyForJoinClub(userId, expectedClubId uint, reason string) error {
 	s.logger.Info("申请加入社团", "user_id", userId, "club_id", expectedClubId)
+
+	if _, err := s.GetClubInfo(int(expectedClubId)); err != nil {
+		return err
+	}
+
+	_, err := s.clubMemberRepo.GetMemberInClub(int(userId), int(expectedClubId))
+	if err == nil {
+		return apperr.ErrAlreadyMember
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
 	return s.joinClubAppliRepo.AddJoinClubAppli(&dbstruct.JoinClubAppli{
 		UserId:      userId,
 		ClubId:      expectedClubId,
@@ -190,15 +283,18 @@ func (s *sClubService) ApproveAppliForCreateClub(appliId int) (uint, error) {
 	defer cancel()
 
 	var newClubId uint
+	var applicantId uint
+	var clubName string
+	var roleChanged bool
 
 	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
 		appli, err := s.createClubAppliRepo.GetAppliForUpdate(tx, appliId)
 		if err != nil {
-			return err
+			return sAppliError(err)
 		}
 
 		if appli.Status != "pending" {
-			return errors.New("申请无法处理")
+			return apperr.ErrAppliNotPending
 		}
 
 		if err := s.createClubAppliRepo.ApproveAppli(tx, appliId); err != nil {
@@ -214,8 +310,9 @@ func (s *sClubService) ApproveAppliForCreateClub(appliId int) (uint, error) {
 			return err
 		}
 
-		if err := s.userRepo.UpdateUserRole(
-			tx, int(appli.UserId), dbstruct.ROLE_PUBLISHER); err != nil {
+		roleChanged, err = s.userRepo.UpdateUserRole(
+			tx, int(appli.UserId), dbstruct.ROLE_PUBLISHER)
+		if err != nil {
 			return err
 		}
 
@@ -228,32 +325,81 @@ func (s *sClubService) ApproveAppliForCreateClub(appliId int) (uint, error) {
 		}
 
 		newClubId = newClub.ClubId
+		applicantId = appli.UserId
+		clubName = newClub.Name
 
-		return nil
+		if err := s.searchService.IndexClub(tx, newClub.ClubId); err != nil {
+			return err
+		}
+
+		return s.ragSyncService.SyncClub(tx, newClub.ClubId)
 	})
 	if err != nil {
 		return 0, err
 	}
 
+	if roleChanged {
+		s.sRevokeTokens(int(applicantId))
+	}
+
+	s.sNotify(applicantId, dbstruct.NOTIFY_CREATE_CLUB_APPLI,
+		"社团创建申请已通过",
+		fmt.Sprintf("你申请创建的社团「%s」已通过审核", clubName),
+		uint(appliId),
+	)
+
 	return newClubId, nil
 }
 
 func (s *sClubService) RejectAppliForCreateClub(appliId int, reason string) error {
-	return s.createClubAppliRepo.RejectAppli(appliId, reason)
+	appli, err := s.createClubAppliRepo.GetAppliById(appliId)
+	if err != nil {
+		return sAppliError(err)
+	}
+
+	if appli.Status != "pending" {
+		return apperr.ErrAppliNotPending
+	}
+
+	if err := s.createClubAppliRepo.RejectAppli(appliId, reason); err != nil {
+		return err
+	}
+
+	s.sNotify(appli.UserId, dbstruct.NOTIFY_CREATE_CLUB_APPLI,
+		"社团创建申请被拒绝",
+		"拒绝理由："+reason,
+		uint(appliId),
+	)
+
+	return nil
 }
 
 func (s *sClubService) ApproveAppliForJoinClub(appliId int) error {
 	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+	var userId, clubId uint
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
 		appli, err := s.joinClubAppliRepo.GetAppliForUpdate(tx, appliId)
 		if err != nil {
-			return err
+			return sAppliError(err)
 		}
 
-		userId := appli.UserId
-		clubId := appli.ClubId
+		if appli.Status != "pending" {
+			return apperr.ErrAppliNotPending
+		}
+
+		userId = appli.UserId
+		clubId = appli.ClubId
+
+		_, err = s.clubMemberRepo.GetMemberInClub(int(userId), int(clubId))
+		if err == nil {
+			return apperr.ErrAlreadyMember
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
 		if err := s.clubMemberRepo.AppendClubMember(tx, &dbstruct.ClubMember{
 			ClubId: clubId,
@@ -262,22 +408,63 @@ func (s *sClubService) ApproveAppliForJoinClub(appliId int) error {
 			return err
 		}
 
-		return s.joinClubAppliRepo.ApproveAppli(tx, appliId)
+		if err := s.joinClubAppliRepo.ApproveAppli(tx, appliId); err != nil {
+			return err
+		}
+
+		// 成员数变化后更新索引，保证按成员数筛选的结果准确
+		return s.searchService.IndexClub(tx, clubId)
 	})
+	if err != nil {
+		return err
+	}
+
+	s.sNotify(userId, dbstruct.NOTIFY_JOIN_CLUB_APPLI,
+		"社团加入申请已通过",
+		fmt.Sprintf("你已成为社团（club_id: %d）的成员", clubId),
+		uint(appliId),
+	)
+
+	return nil
 }
 
 func (s *sClubService) RejectAppliForJoinClub(appliId int, reason string) error {
-	return s.joinClubAppliRepo.RejectAppli(appliId, reason)
+	appli, err := s.joinClubAppliRepo.GetAppliById(appliId)
+	if err != nil {
+		return sAppliError(err)
+	}
+
+	if appli.Status != "pending" {
+		return apperr.ErrAppliNotPending
+	}
+
+	if err := s.joinClubAppliRepo.RejectAppli(appliId, reason); err != nil {
+		return err
+	}
+
+	s.sNotify(appli.UserId, dbstruct.NOTIFY_JOIN_CLUB_APPLI,
+		"社团加入申请被拒绝",
+		"拒绝理由："+reason,
+		uint(appliId),
+	)
+
+	return nil
 }
 
 func (s *sClubService) ApproveAppliForUpdateClub(appliId int) error {
 	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+	var applicantId uint
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
 		appli, err := s.updateClubInfoAppliRepo.GetAppliForUpdate(tx, appliId)
 		if err != nil {
-			return err
+			return sAppliError(err)
+		}
+
+		if appli.Status != "pending" {
+			return apperr.ErrAppliNotPending
 		}
 
 		if err := s.updateClubInfoAppliRepo.ApproveAppli(tx, appliId); err != nil {
@@ -291,12 +478,79 @@ func (s *sClubService) ApproveAppliForUpdateClub(appliId int) error {
 			return err
 		}
 
-		return s.clubRepo.UpdateClubInfo(tx, newClub)
+		applicantId = appli.ApplicantId
+
+		if err := s.clubRepo.UpdateClubInfo(tx, newClub); err != nil {
+			return err
+		}
+
+		if err := s.searchService.IndexClub(tx, newClub.ClubId); err != nil {
+			return err
+		}
+
+		return s.ragSyncService.SyncClub(tx, newClub.ClubId)
 	})
+	if err != nil {
+		return err
+	}
+
+	s.sNotify(applicantId, dbstruct.NOTIFY_UPDATE_CLUB_APPLI,
+		"社团信息更新申请已通过",
+		"社团信息已更新",
+		uint(appliId),
+	)
+
+	return nil
 }
 
 func (s *sClubService) RejectAppliForUpdateClub(appliId int, reason string) error {
-	return s.updateClubInfoAppliRepo.RejectAppli(appliId, reason)
+	appli, err := s.updateClubInfoAppliRepo.GetAppliById(appliId)
+	if err != nil {
+		return sAppliError(err)
+	}
+
+	if appli.Status != "pending" {
+		return apperr.ErrAppliNotPending
+	}
+
+	if err := s.updateClubInfoAppliRepo.RejectAppli(appliId, reason); err != nil {
+		return err
+	}
+
+	s.sNotify(appli.ApplicantId, dbstruct.NOTIFY_UPDATE_CLUB_APPLI,
+		"社团信息更新申请被拒绝",
+		"拒绝理由："+reason,
+		uint(appliId),
+	)
+
+	return nil
+}
+
+func sAppliError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrAppliNotFound.Wrap(err)
+	}
+	return err
+}
+
+// sNotify 通知发送失败不影响申请处理结果，仅记录日志
+func (s *sClubService) sNotify(userId uint, notifyType, title, content string, refId uint) {
+	if err := s.notificationService.Notify(
+		userId, notifyType, title, content, refId,
+	); err != nil {
+		s.logger.Error("发送通知失败",
+			"error", err, "user_id", userId, "type", notifyType, "ref_id", refId,
+		)
+	}
+}
+
+// sRevokeTokens 用户角色变更后吊销其旧token，使新角色在下次刷新时生效；失败仅记录日志
+func (s *sClubService) sRevokeTokens(userIds ...int) {
+	for _, userId := range userIds {
+		if err := s.authService.RevokeUserTokens(userId); err != nil {
+			s.logger.Error("吊销用户token失败", "error", err, "user_id", userId)
+		}
+	}
 }
 
 func (s *sClubService) FavouriteClub(userId, clubId int) error {
@@ -329,16 +583,64 @@ func (s *sClubService) GetJoinApplisForClub(clubId int) ([]*dbstruct.JoinClubApp
 	return s.joinClubAppliRepo.GetJoinClubAppliList(clubId)
 }
 
+func (s *sClubService) GetJoinAppli(appliId int) (*dbstruct.JoinClubAppli, error) {
+	appli, err := s.joinClubAppliRepo.GetAppliById(appliId)
+	if err != nil {
+		return nil, sAppliError(err)
+	}
+
+	return appli, nil
+}
+
 func (s *sClubService) GetCreateApplisForUser(userId int) ([]*dbstruct.CreateClubAppli, error) {
 	return s.createClubAppliRepo.GetCreateClubAppliList(userId)
 }
 
-func (s *sClubService) UpdateClubLogo(clubId int, logoUrl string) error {
-	return s.clubRepo.UpdateClubLogo(clubId, logoUrl)
+func (s *sClubService) UpdateClubLogo(clubId int, variants model.ImageVariants) error {
+	variantsJson, err := variants.ToJsonb()
+	if err != nil {
+		return err
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubRepo.UpdateClubLogo(tx, clubId, variants.Default(), variantsJson); err != nil {
+			return err
+		}
+
+		return s.searchService.IndexClub(tx, uint(clubId))
+	})
+}
+
+func (s *sClubService) SetPostReviewPolicy(clubId int, requireReview bool) error {
+	return s.clubRepo.UpdatePostReviewPolicy(clubId, requireReview)
 }
 
 func (s *sClubService) QuitClub(clubId, userId int) error {
-	return s.clubMemberRepo.DeleteMember(userId, clubId)
+	member, err := s.clubMemberRepo.GetMemberInClub(userId, clubId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.ErrNotClubMember.Wrap(err)
+		}
+		return err
+	}
+
+	if member.RoleInClub == dbstruct.ROLE_CLUB_LEADER {
+		return apperr.ErrLeaderCannotQuit
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubMemberRepo.DeleteMember(tx, userId, clubId); err != nil {
+			return err
+		}
+
+		return s.searchService.IndexClub(tx, uint(clubId))
+	})
 }
 
 func (s *sClubService) DissambleClub(clubId int) error {
@@ -346,6 +648,14 @@ func (s *sClubService) DissambleClub(clubId int) error {
 	defer cancel()
 
 	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.leaderTransferRepo.CancelPendingTransfers(tx, clubId); err != nil {
+			return err
+		}
+
+		if err := s.ragSyncService.RemoveClub(tx, uint(clubId)); err != nil {
+			return err
+		}
+
 		if err := s.clubMemberRepo.DeleteClub(tx, clubId); err != nil {
 			return err
 		}
@@ -354,8 +664,152 @@ func (s *sClubService) DissambleClub(clubId int) error {
 			return err
 		}
 
+		return s.searchService.RemoveClub(tx, uint(clubId))
+	})
+}
+
+func (s *sClubService) NominateLeader(clubId, nomineeId int) error {
+	club, err := s.GetClubInfo(clubId)
+	if err != nil {
+		return err
+	}
+
+	if int(club.LeaderId) == nomineeId {
+		return apperr.ErrConflict.WithMessage("被提名者已是社团负责人")
+	}
+
+	if _, err := s.clubMemberRepo.GetMemberInClub(nomineeId, clubId); err != nil {
+		return apperr.ErrNotClubMember.Wrap(err)
+	}
+
+	return s.leaderTransferRepo.AddLeaderTransfer(&dbstruct.LeaderTransfer{
+		ClubId:     club.ClubId,
+		FromUserId: club.LeaderId,
+		ToUserId:   uint(nomineeId),
+	})
+}
+
+func (s *sClubService) GetLeaderTransfersForUser(userId int) ([]*dbstruct.LeaderTransfer, error) {
+	return s.leaderTransferRepo.GetPendingTransfersForUser(userId)
+}
+
+func (s *sClubService) AcceptLeaderTransfer(transferId, userId int) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var roleChangedIds []int
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		transfer, err := s.sGetPendingTransferForUpdate(tx, transferId, userId)
+		if err != nil {
+			return err
+		}
+
+		clubId := int(transfer.ClubId)
+		oldLeaderId := int(transfer.FromUserId)
+
+		club, err := s.clubRepo.GetClubForUpdate(tx, clubId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrClubNotFound.Wrap(err)
+			}
+			return err
+		}
+
+		if int(club.LeaderId) != oldLeaderId {
+			return apperr.ErrTransferStale
+		}
+
+		if _, err := s.clubMemberRepo.GetMemberForUpdate(tx, userId, clubId); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrNotClubMember.Wrap(err)
+			}
+			return err
+		}
+
+		if err := s.leaderTransferRepo.AcceptTransfer(tx, transferId); err != nil {
+			return err
+		}
+
+		if err := s.clubRepo.UpdateClubLeader(tx, clubId, userId); err != nil {
+			return err
+		}
+
+		if err := s.clubMemberRepo.UpdateMemberRole(
+			tx, userId, clubId, dbstruct.ROLE_CLUB_LEADER); err != nil {
+			return err
+		}
+
+		if err := s.clubMemberRepo.UpdateMemberRole(
+			tx, oldLeaderId, clubId, dbstruct.ROLE_CLUB_MEMBER); err != nil {
+			return err
+		}
+
+		promoted, err := s.userRepo.UpdateUserRole(tx, userId, dbstruct.ROLE_PUBLISHER)
+		if err != nil {
+			return err
+		}
+		if promoted {
+			roleChangedIds = append(roleChangedIds, userId)
+		}
+
+		ledNum, err := s.clubRepo.CountClubsLedBy(tx, oldLeaderId)
+		if err != nil {
+			return err
+		}
+
+		if ledNum == 0 {
+			demoted, err := s.userRepo.DemoteUserRole(tx, oldLeaderId)
+			if err != nil {
+				return err
+			}
+			if demoted {
+				roleChangedIds = append(roleChangedIds, oldLeaderId)
+			}
+		}
+
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.sRevokeTokens(roleChangedIds...)
+
+	return nil
+}
+
+func (s *sClubService) RejectLeaderTransfer(transferId, userId int) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if _, err := s.sGetPendingTransferForUpdate(tx, transferId, userId); err != nil {
+			return err
+		}
+
+		return s.leaderTransferRepo.RejectTransfer(tx, transferId)
+	})
+}
+
+func (s *sClubService) sGetPendingTransferForUpdate(tx *gorm.DB, transferId, userId int) (*dbstruct.LeaderTransfer, error) {
+	transfer, err := s.leaderTransferRepo.GetTransferForUpdate(tx, transferId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrTransferNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+
+	if transfer.Status != "pending" {
+		return nil, apperr.ErrTransferNotPending
+	}
+
+	if int(transfer.ToUserId) != userId {
+		return nil, apperr.ErrTransferNotTarget
+	}
+
+	return transfer, nil
 }
 
 func (s *sClubService) GetUpdateApplisForUser(userId int) ([]*dbstruct.UpdateClubInfoAppli, error) {
@@ -366,6 +820,14 @@ func (s *sClubService) GetUpdateList(offset, num int) ([]*dbstruct.UpdateClubInf
 	return s.updateClubInfoAppliRepo.GetUpdateList(offset, num)
 }
 
+func (s *sClubService) GetUpdateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.UpdateClubInfoAppli], error) {
+	return s.updateClubInfoAppliRepo.GetUpdateListByCursor(cursor, num)
+}
+
 func (s *sClubService) GetCreateList(offset, num int) ([]*dbstruct.CreateClubAppli, error) {
 	return s.createClubAppliRepo.GetCreateList(offset, num)
 }
+
+func (s *sClubService) GetCreateListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.CreateClubAppli], error) {
+	return s.createClubAppliRepo.GetCreateListByCursor(cursor, num)
+}
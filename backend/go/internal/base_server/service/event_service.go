@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+type EventService interface {
+	CreateEvent(event *dbstruct.ClubEvent) error
+	UpdateEvent(clubId, eventId int, newInfo dbstruct.ClubEvent) error
+	CancelEvent(clubId, eventId int) error
+
+	GetEvent(clubId, eventId int) (*dbstruct.ClubEvent, error)
+	GetEventList(clubId, offset, num int) ([]*dbstruct.ClubEvent, error)
+	GetUpcomingEvents(clubId, num int) ([]*dbstruct.ClubEvent, error)
+
+	// RsvpEvent 报名活动，名额已满时进入候补，返回最终的报名状态
+	RsvpEvent(clubId, eventId, userId int) (string, error)
+	CancelRsvp(clubId, eventId, userId int) error
+	GetEventRsvps(clubId, eventId int) ([]*dbstruct.EventRsvp, error)
+
+	// CheckCheckinOpen 校验活动当前是否处于可签到时段，用于签发签到凭证前
+	CheckCheckinOpen(clubId, eventId int) (*dbstruct.ClubEvent, error)
+	CheckIn(clubId, eventId, userId int) error
+	GetEventAttendances(clubId, eventId int) ([]*dbstruct.EventAttendance, error)
+	// GetAttendanceStats 返回社团成员签到统计及已举办活动数
+	GetAttendanceStats(clubId int) ([]*repo.AttendanceStat, int64, error)
+}
+
+const (
+	kCheckinOpenAdvance = 30 * time.Minute
+)
+
+type sEventService struct {
+	clubEventRepo       repo.ClubEventRepo
+	eventRsvpRepo       repo.EventRsvpRepo
+	eventAttendanceRepo repo.EventAttendanceRepo
+	clubMemberRepo      repo.ClubMemberRepo
+
+	notificationService NotificationService
+
+	txCoordinator repo.TransactionCoordinator
+
+	logger *slog.Logger
+}
+
+func NewEventService(
+	clubEventRepo repo.ClubEventRepo,
+	eventRsvpRepo repo.EventRsvpRepo,
+	eventAttendanceRepo repo.EventAttendanceRepo,
+	clubMemberRepo repo.ClubMemberRepo,
+
+	notificationService NotificationService,
+
+	txCoordinator repo.TransactionCoordinator,
+
+	logger *slog.Logger,
+) EventService {
+	return &sEventService{
+		clubEventRepo:       clubEventRepo,
+		eventRsvpRepo:       eventRsvpRepo,
+		eventAttendanceRepo: eventAttendanceRepo,
+		clubMemberRepo:      clubMemberRepo,
+
+		notificationService: notificationService,
+
+		txCoordinator: txCoordinator,
+
+		logger: logger,
+	}
+}
+
+func (s *sEventService) sCheckEventInfo(event *dbstruct.ClubEvent) error {
+	if event.Title == "" || event.Location == "" {
+		return apperr.ErrEventInvalid.WithMessage("活动标题和地点不能为空")
+	}
+
+	if !event.EndAt.After(event.StartAt) {
+		return apperr.ErrEventInvalid.WithMessage("活动结束时间必须晚于开始时间")
+	}
+
+	if event.Capacity < 0 {
+		return apperr.ErrEventInvalid.WithMessage("活动人数上限无效")
+	}
+
+	return nil
+}
+
+func (s *sEventService) CreateEvent(event *dbstruct.ClubEvent) error {
+	if err := s.sCheckEventInfo(event); err != nil {
+		return err
+	}
+
+	if !event.StartAt.After(time.Now()) {
+		return apperr.ErrEventInvalid.WithMessage("活动开始时间必须晚于当前时间")
+	}
+
+	event.Status = dbstruct.EVENT_STATUS_SCHEDULED
+	event.GoingCount = 0
+
+	return s.clubEventRepo.AddEvent(event)
+}
+
+func (s *sEventService) UpdateEvent(clubId, eventId int, newInfo dbstruct.ClubEvent) error {
+	if err := s.sCheckEventInfo(&newInfo); err != nil {
+		return err
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var promoted []*dbstruct.EventRsvp
+	var eventTitle string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		event, err := s.sGetScheduledEventForUpdate(tx, clubId, eventId)
+		if err != nil {
+			return err
+		}
+
+		if newInfo.Capacity > 0 && newInfo.Capacity < event.GoingCount {
+			return apperr.ErrEventCapacityLow.WithMessage(
+				fmt.Sprintf("已有%d人报名，人数上限不能低于报名人数", event.GoingCount),
+			)
+		}
+
+		if err := s.clubEventRepo.UpdateEvent(tx, eventId, map[string]any{
+			"title":       newInfo.Title,
+			"description": newInfo.Description,
+			"location":    newInfo.Location,
+			"start_at":    newInfo.StartAt,
+			"end_at":      newInfo.EndAt,
+			"capacity":    newInfo.Capacity,
+		}); err != nil {
+			return err
+		}
+
+		event.Capacity = newInfo.Capacity
+		eventTitle = newInfo.Title
+
+		// 扩容后依次递补候补成员
+		for s.sHasVacancy(event) {
+			rsvp, err := s.sPromoteWaitlisted(tx, event)
+			if err != nil {
+				return err
+			}
+			if rsvp == nil {
+				break
+			}
+
+			promoted = append(promoted, rsvp)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rsvp := range promoted {
+		s.sNotifyPromoted(rsvp, eventTitle)
+	}
+
+	return nil
+}
+
+func (s *sEventService) CancelEvent(clubId, eventId int) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rsvps []*dbstruct.EventRsvp
+	var eventTitle string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		event, err := s.sGetScheduledEventForUpdate(tx, clubId, eventId)
+		if err != nil {
+			return err
+		}
+
+		rsvps, err = s.eventRsvpRepo.GetActiveRsvpsOfEvent(tx, eventId)
+		if err != nil {
+			return err
+		}
+
+		eventTitle = event.Title
+
+		return s.clubEventRepo.CancelEvent(tx, eventId)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rsvp := range rsvps {
+		if err := s.notificationService.Notify(
+			rsvp.UserId, dbstruct.NOTIFY_EVENT_CANCELLED,
+			"活动已取消",
+			fmt.Sprintf("你报名的活动「%s」已被取消", eventTitle),
+			uint(eventId),
+		); err != nil {
+			s.logger.Error("发送活动取消通知失败",
+				"error", err, "user_id", rsvp.UserId, "event_id", eventId,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (s *sEventService) GetEvent(clubId, eventId int) (*dbstruct.ClubEvent, error) {
+	event, err := s.clubEventRepo.GetEventById(eventId)
+	if err != nil {
+		return nil, sEventError(err)
+	}
+
+	if event.ClubId != uint(clubId) {
+		return nil, apperr.ErrEventNotFound
+	}
+
+	return event, nil
+}
+
+func (s *sEventService) GetEventList(clubId, offset, num int) ([]*dbstruct.ClubEvent, error) {
+	return s.clubEventRepo.GetEventList(clubId, offset, num)
+}
+
+func (s *sEventService) GetUpcomingEvents(clubId, num int) ([]*dbstruct.ClubEvent, error) {
+	return s.clubEventRepo.GetUpcomingEvents(clubId, num)
+}
+
+func (s *sEventService) RsvpEvent(clubId, eventId, userId int) (string, error) {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var status string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		event, err := s.sGetScheduledEventForUpdate(tx, clubId, eventId)
+		if err != nil {
+			return err
+		}
+
+		if !event.StartAt.After(time.Now()) {
+			return apperr.ErrEventStarted
+		}
+
+		status = dbstruct.RSVP_STATUS_WAITLIST
+		if s.sHasVacancy(event) {
+			status = dbstruct.RSVP_STATUS_GOING
+		}
+
+		rsvp, err := s.eventRsvpRepo.GetRsvp(tx, eventId, userId)
+		switch {
+		case err == nil:
+			if rsvp.Status != dbstruct.RSVP_STATUS_CANCELLED {
+				return apperr.ErrAlreadyRsvped
+			}
+
+			// 取消后重新报名，候补顺序按本次报名时间计算
+			if err := s.eventRsvpRepo.UpdateRsvpStatus(tx, rsvp.RsvpId, status); err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.eventRsvpRepo.AddRsvp(tx, &dbstruct.EventRsvp{
+				EventId: uint(eventId),
+				UserId:  uint(userId),
+				Status:  status,
+			}); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if status == dbstruct.RSVP_STATUS_GOING {
+			return s.clubEventRepo.UpdateGoingCount(tx, eventId, 1)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+func (s *sEventService) CancelRsvp(clubId, eventId, userId int) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var promoted *dbstruct.EventRsvp
+	var eventTitle string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		event, err := s.sGetScheduledEventForUpdate(tx, clubId, eventId)
+		if err != nil {
+			return err
+		}
+
+		rsvp, err := s.eventRsvpRepo.GetRsvp(tx, eventId, userId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrNotRsvped.Wrap(err)
+			}
+			return err
+		}
+
+		if rsvp.Status == dbstruct.RSVP_STATUS_CANCELLED {
+			return apperr.ErrNotRsvped
+		}
+
+		if err := s.eventRsvpRepo.
+			UpdateRsvpStatus(tx, rsvp.RsvpId, dbstruct.RSVP_STATUS_CANCELLED); err != nil {
+			return err
+		}
+
+		if rsvp.Status != dbstruct.RSVP_STATUS_GOING {
+			return nil
+		}
+
+		if err := s.clubEventRepo.UpdateGoingCount(tx, eventId, -1); err != nil {
+			return err
+		}
+		event.GoingCount--
+		eventTitle = event.Title
+
+		promoted, err = s.sPromoteWaitlisted(tx, event)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if promoted != nil {
+		s.sNotifyPromoted(promoted, eventTitle)
+	}
+
+	return nil
+}
+
+func (s *sEventService) GetEventRsvps(clubId, eventId int) ([]*dbstruct.EventRsvp, error) {
+	if _, err := s.GetEvent(clubId, eventId); err != nil {
+		return nil, err
+	}
+
+	return s.eventRsvpRepo.GetRsvpsOfEvent(eventId)
+}
+
+func (s *sEventService) CheckCheckinOpen(clubId, eventId int) (*dbstruct.ClubEvent, error) {
+	event, err := s.GetEvent(clubId, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.Status != dbstruct.EVENT_STATUS_SCHEDULED {
+		return nil, apperr.ErrEventCancelled
+	}
+
+	now := time.Now()
+	if now.Before(event.StartAt.Add(-kCheckinOpenAdvance)) {
+		return nil, apperr.ErrCheckinNotOpen
+	}
+
+	if now.After(event.EndAt) {
+		return nil, apperr.ErrCheckinNotOpen.WithMessage("活动已结束")
+	}
+
+	return event, nil
+}
+
+func (s *sEventService) CheckIn(clubId, eventId, userId int) error {
+	if _, err := s.CheckCheckinOpen(clubId, eventId); err != nil {
+		return err
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		attended, err := s.eventAttendanceRepo.HasAttended(tx, eventId, userId)
+		if err != nil {
+			return err
+		}
+
+		if attended {
+			return apperr.ErrAlreadyCheckedIn
+		}
+
+		now := time.Now()
+
+		if err := s.eventAttendanceRepo.AddAttendance(tx, &dbstruct.EventAttendance{
+			EventId:     uint(eventId),
+			ClubId:      uint(clubId),
+			UserId:      uint(userId),
+			CheckedInAt: now,
+		}); err != nil {
+			return err
+		}
+
+		return s.clubMemberRepo.UpdateMemberLastActive(tx, userId, clubId, now)
+	})
+}
+
+func (s *sEventService) GetEventAttendances(clubId, eventId int) ([]*dbstruct.EventAttendance, error) {
+	if _, err := s.GetEvent(clubId, eventId); err != nil {
+		return nil, err
+	}
+
+	return s.eventAttendanceRepo.GetAttendancesOfEvent(eventId)
+}
+
+func (s *sEventService) GetAttendanceStats(clubId int) ([]*repo.AttendanceStat, int64, error) {
+	heldNum, err := s.clubEventRepo.CountHeldEvents(clubId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stats, err := s.eventAttendanceRepo.GetAttendanceStats(clubId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stats, heldNum, nil
+}
+
+func (s *sEventService) sGetScheduledEventForUpdate(tx *gorm.DB, clubId, eventId int) (*dbstruct.ClubEvent, error) {
+	event, err := s.clubEventRepo.GetEventForUpdate(tx, eventId)
+	if err != nil {
+		return nil, sEventError(err)
+	}
+
+	if event.ClubId != uint(clubId) {
+		return nil, apperr.ErrEventNotFound
+	}
+
+	if event.Status != dbstruct.EVENT_STATUS_SCHEDULED {
+		return nil, apperr.ErrEventCancelled
+	}
+
+	return event, nil
+}
+
+func sEventError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrEventNotFound.Wrap(err)
+	}
+	return err
+}
+
+func (s *sEventService) sHasVacancy(event *dbstruct.ClubEvent) bool {
+	return event.Capacity == 0 || event.GoingCount < event.Capacity
+}
+
+// sPromoteWaitlisted 将最早的候补成员转为正式报名，没有候补时返回nil
+func (s *sEventService) sPromoteWaitlisted(tx *gorm.DB, event *dbstruct.ClubEvent) (*dbstruct.EventRsvp, error) {
+	rsvp, err := s.eventRsvpRepo.GetFirstWaitlisted(tx, int(event.EventId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := s.eventRsvpRepo.
+		UpdateRsvpStatus(tx, rsvp.RsvpId, dbstruct.RSVP_STATUS_GOING); err != nil {
+		return nil, err
+	}
+
+	if err := s.clubEventRepo.UpdateGoingCount(tx, int(event.EventId), 1); err != nil {
+		return nil, err
+	}
+	event.GoingCount++
+
+	return rsvp, nil
+}
+
+func (s *sEventService) sNotifyPromoted(rsvp *dbstruct.EventRsvp, eventTitle string) {
+	if err := s.notificationService.Notify(
+		rsvp.UserId, dbstruct.NOTIFY_EVENT_PROMOTED,
+		"活动候补成功",
+		fmt.Sprintf("你已从候补转为活动「%s」的正式报名", eventTitle),
+		rsvp.EventId,
+	); err != nil {
+		s.logger.Error("发送候补递补通知失败",
+			"error", err, "user_id", rsvp.UserId, "event_id", rsvp.EventId,
+		)
+	}
+}
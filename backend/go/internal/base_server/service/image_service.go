@@ -0,0 +1,102 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/imgutil"
+)
+
+const (
+	kImageMaxSize     = 10 << 20
+	kImageJpegQuality = 85
+)
+
+// 允许上传的头像与logo格式，以文件内容嗅探结果为准
+var kImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type ImageService interface {
+	// StoreSquareVariants 校验并解码上传的图片，居中裁剪为正方形后按各标准尺寸重新编码为jpeg保存；
+	// 重新编码会丢弃EXIF等元数据
+	StoreSquareVariants(ctx context.Context, prefix string, file io.Reader) (model.ImageVariants, error)
+}
+
+type sImageService struct {
+	blobStore storage.BlobStore
+
+	logger *slog.Logger
+}
+
+func NewImageService(
+	blobStore storage.BlobStore,
+
+	logger *slog.Logger,
+) ImageService {
+	return &sImageService{
+		blobStore: blobStore,
+
+		logger: logger,
+	}
+}
+
+func (s *sImageService) StoreSquareVariants(
+	ctx context.Context,
+	prefix string,
+	file io.Reader,
+) (model.ImageVariants, error) {
+	data, err := io.ReadAll(io.LimitReader(file, kImageMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > kImageMaxSize {
+		return nil, apperr.ErrImageTooLarge
+	}
+
+	if contentType := http.DetectContentType(data); !kImageTypes[contentType] {
+		return nil, apperr.ErrInvalidImage.WithMessage("不支持的图片格式：" + contentType)
+	}
+
+	img, err := imgutil.Decode(data)
+	if err != nil {
+		if errors.Is(err, imgutil.ErrImageTooLarge) {
+			return nil, apperr.ErrImageTooLarge.WithMessage("图片分辨率过大")
+		}
+		return nil, apperr.ErrInvalidImage.Wrap(err)
+	}
+
+	var current image.Image = imgutil.CropSquare(img)
+	variants := make(model.ImageVariants, len(model.IMAGE_VARIANT_SIZES))
+
+	for _, size := range model.IMAGE_VARIANT_SIZES {
+		// 在上一尺寸的基础上继续缩放，减少大图的重复计算；原图不足时放大，保证各尺寸边长一致
+		if current.Bounds().Dx() != size {
+			current = imgutil.Resize(current, size, size)
+		}
+
+		var buf bytes.Buffer
+		if err := imgutil.EncodeJPEG(&buf, current, kImageJpegQuality); err != nil {
+			return nil, err
+		}
+
+		key, err := s.blobStore.Put(ctx, prefix, "_"+model.VariantKey(size)+".jpg", buf.Bytes(), "image/jpeg")
+		if err != nil {
+			return nil, errors.New("保存图片失败，需重试：" + err.Error())
+		}
+
+		variants[model.VariantKey(size)] = s.blobStore.URL(key)
+	}
+
+	return variants, nil
+}
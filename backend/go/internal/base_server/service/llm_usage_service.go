@@ -0,0 +1,149 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+)
+
+const (
+	kLlmUsageDayLayout = "20060102"
+)
+
+// LlmQuota 每日LLM调用额度，Tokens为响应的token数；0表示不限
+type LlmQuota struct {
+	DailyRequests int64
+	DailyTokens   int64
+}
+
+type LlmUsageService interface {
+	// Acquire 检查用户now当天的额度并计入一次调用，超出额度时返回apperr.ErrLlmQuotaExceeded；
+	// Redis不可用时放行并记录日志，避免计量组件故障导致LLM功能整体不可用
+	Acquire(userId int, role string, now time.Time) error
+	// Record 调用结束后累计用量并写入审计记录，用量计入log.CreatedAt当天；
+	// 失败的调用退还Acquire计入的次数
+	Record(log *dbstruct.LlmUsageLog)
+
+	// GetUsage 返回用户当天的用量与所属角色的额度
+	GetUsage(userId int, role string) (*redisimpl.LlmUsage, LlmQuota, error)
+	// GetReport 汇总[from, to)内的用量，同时返回按用户汇总的一页
+	GetReport(from, to time.Time, offset, num int) (*model.LlmUsageSummary, []*model.LlmUsageSummary, error)
+}
+
+type sLlmUsageService struct {
+	llmUsageLogRepo repo.LlmUsageLogRepo
+
+	redisService redisimpl.RedisClientService
+
+	quotas map[string]LlmQuota
+
+	logger *slog.Logger
+}
+
+// NewLlmUsageService quotas按用户角色配置，未配置的角色使用普通用户的额度
+func NewLlmUsageService(
+	llmUsageLogRepo repo.LlmUsageLogRepo,
+
+	redisService redisimpl.RedisClientService,
+
+	quotas map[string]LlmQuota,
+
+	logger *slog.Logger,
+) LlmUsageService {
+	return &sLlmUsageService{
+		llmUsageLogRepo: llmUsageLogRepo,
+
+		redisService: redisService,
+
+		quotas: quotas,
+
+		logger: logger,
+	}
+}
+
+func (s *sLlmUsageService) sQuotaOf(role string) LlmQuota {
+	if quota, ok := s.quotas[role]; ok {
+		return quota
+	}
+
+	return s.quotas[dbstruct.ROLE_USER]
+}
+
+func (s *sLlmUsageService) Acquire(userId int, role string, now time.Time) error {
+	quota := s.sQuotaOf(role)
+
+	allowed, usage, err := s.redisService.AcquireLlmQuota(
+		now.Format(kLlmUsageDayLayout), userId,
+		quota.DailyRequests, quota.DailyTokens,
+	)
+	if err != nil {
+		s.logger.Error("LLM额度检查失败", "error", err, "user_id", userId)
+		return nil
+	}
+
+	if !allowed {
+		s.logger.Info("LLM调用超出当日额度",
+			"user_id", userId, "role", role,
+			"requests", usage.Requests, "tokens", usage.Tokens,
+		)
+		return apperr.ErrLlmQuotaExceeded
+	}
+
+	return nil
+}
+
+func (s *sLlmUsageService) Record(log *dbstruct.LlmUsageLog) {
+	day := log.CreatedAt.Format(kLlmUsageDayLayout)
+
+	var delta *redisimpl.LlmUsage
+	switch log.Outcome {
+	case dbstruct.LLM_USAGE_OK:
+		delta = &redisimpl.LlmUsage{Bytes: log.ResponseBytes, Tokens: log.Tokens}
+	case dbstruct.LLM_USAGE_FAILED:
+		delta = &redisimpl.LlmUsage{Requests: -1}
+	}
+
+	if delta != nil {
+		if err := s.redisService.IncrLlmUsage(day, int(log.UserId), delta); err != nil {
+			s.logger.Error("累计LLM用量失败", "error", err, "user_id", log.UserId)
+		}
+	}
+
+	if err := s.llmUsageLogRepo.AddLog(log); err != nil {
+		s.logger.Error("写入LLM调用记录失败",
+			"error", err, "user_id", log.UserId, "route", log.Route, "outcome", log.Outcome,
+		)
+	}
+}
+
+func (s *sLlmUsageService) GetUsage(userId int, role string) (*redisimpl.LlmUsage, LlmQuota, error) {
+	quota := s.sQuotaOf(role)
+
+	usage, err := s.redisService.GetLlmUsage(time.Now().Format(kLlmUsageDayLayout), userId)
+	if err != nil {
+		return nil, quota, apperr.ErrServiceUnavailable.Wrap(err)
+	}
+
+	return usage, quota, nil
+}
+
+func (s *sLlmUsageService) GetReport(
+	from, to time.Time,
+	offset, num int,
+) (*model.LlmUsageSummary, []*model.LlmUsageSummary, error) {
+	total, err := s.llmUsageLogRepo.GetTotal(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summaries, err := s.llmUsageLogRepo.GetUserSummaries(from, to, offset, num)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return total, summaries, nil
+}
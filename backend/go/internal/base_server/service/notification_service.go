@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+const (
+	kNotificationChanSize = 16
+)
+
+type NotificationService interface {
+	Notify(userId uint, notifyType, title, content string, refId uint) error
+
+	GetNotifications(userId, offset, num int, unreadOnly bool) ([]*dbstruct.Notification, error)
+	GetUnreadNum(userId int) (int64, error)
+	MarkRead(userId, notificationId int) error
+	MarkAllRead(userId int) error
+
+	// Subscribe 订阅用户的新通知，返回的cancel必须在连接结束时调用
+	Subscribe(userId uint) (<-chan *dbstruct.Notification, func())
+}
+
+type sNotificationService struct {
+	notificationRepo repo.NotificationRepo
+
+	mtx         sync.RWMutex
+	subscribers map[uint]map[chan *dbstruct.Notification]struct{}
+
+	logger *slog.Logger
+}
+
+func NewNotificationService(
+	notificationRepo repo.NotificationRepo,
+	logger *slog.Logger,
+) NotificationService {
+	return &sNotificationService{
+		notificationRepo: notificationRepo,
+		subscribers:      make(map[uint]map[chan *dbstruct.Notification]struct{}),
+		logger:           logger,
+	}
+}
+
+func (s *sNotificationService) Notify(
+	userId uint,
+	notifyType, title, content string,
+	refId uint,
+) error {
+	notification := dbstruct.Notification{
+		UserId:  userId,
+		Type:    notifyType,
+		Title:   title,
+		Content: content,
+		RefId:   refId,
+	}
+
+	if err := s.notificationRepo.AddNotification(&notification); err != nil {
+		return err
+	}
+
+	s.publish(&notification)
+
+	return nil
+}
+
+func (s *sNotificationService) GetNotifications(userId, offset, num int, unreadOnly bool) ([]*dbstruct.Notification, error) {
+	return s.notificationRepo.GetNotifications(userId, offset, num, unreadOnly)
+}
+
+func (s *sNotificationService) GetUnreadNum(userId int) (int64, error) {
+	return s.notificationRepo.GetUnreadNum(userId)
+}
+
+func (s *sNotificationService) MarkRead(userId, notificationId int) error {
+	err := s.notificationRepo.MarkRead(userId, notificationId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrNotificationNotFound.Wrap(err)
+	}
+
+	return err
+}
+
+func (s *sNotificationService) MarkAllRead(userId int) error {
+	return s.notificationRepo.MarkAllRead(userId)
+}
+
+func (s *sNotificationService) Subscribe(userId uint) (<-chan *dbstruct.Notification, func()) {
+	ch := make(chan *dbstruct.Notification, kNotificationChanSize)
+
+	s.mtx.Lock()
+	if s.subscribers[userId] == nil {
+		s.subscribers[userId] = make(map[chan *dbstruct.Notification]struct{})
+	}
+	s.subscribers[userId][ch] = struct{}{}
+	s.mtx.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.mtx.Lock()
+			delete(s.subscribers[userId], ch)
+			if len(s.subscribers[userId]) == 0 {
+				delete(s.subscribers, userId)
+			}
+			s.mtx.Unlock()
+
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+func (s *sNotificationService) publish(notification *dbstruct.Notification) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for ch := range s.subscribers[notification.UserId] {
+		select {
+		case ch <- notification:
+		default:
+			// 客户端消费过慢时丢弃推送，通知已落库，可通过列表接口补齐
+			s.logger.Warn("通知推送队列已满，丢弃实时推送",
+				"user_id", notification.UserId,
+				"notification_id", notification.NotificationId,
+			)
+		}
+	}
+}
@@ -1,41 +1,101 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
-	"os"
-	"strconv"
+	"slices"
 	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
 	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
 )
 
 const (
-	POST_FILE_DIR     = "pub/post_files/"
-	POST_TMP_FILE_DIR = "pub/post_tmp_files/"
+	// 帖子正文在对象存储中的键前缀
+	POST_FILE_PREFIX = "post_files"
+
+	kPostDiffMaxCells = 4_000_000
+
+	kPostScheduleBatch    = 50
+	kPostScheduleInterval = 30 * time.Second
 )
 
 type PostService interface {
+	GetPostById(postId int) (*dbstruct.ClubPost, error)
 	GetLatestPosts(clubId, num, visibility int) ([]*dbstruct.ClubPost, error)
 	GetPostList(clubId, offset, num, visibility int) ([]*dbstruct.ClubPost, error)
+	GetPostListByCursor(clubId int, cursor *model.Cursor, num, visibility int) (*model.Page[*dbstruct.ClubPost], error)
 	GetPinnedPost(clubId int) (*dbstruct.ClubPost, error)
 	GetPostsByUserId(userId int) ([]*dbstruct.ClubPost, error)
+	// GetPostContent 从对象存储读取帖子或历史版本的正文
+	GetPostContent(contentUrl string) ([]byte, error)
 
+	// CreatePost 发布帖子；社团开启发帖审核且作者为普通成员时，帖子以pending状态提交审核，
+	// 调用方可据newPost.Status判断
 	CreatePost(newPost *dbstruct.ClubPost,
 		sender func(writer *io.PipeWriter) error) error
 
-	CreatePostComment(newComment dbstruct.ClubPostComment) error
-	GetPostComments(postId int) ([]*dbstruct.ClubPostComment, error)
+	// CreatePostComment 发表评论并累加帖子评论数，ParentCommentId非空时为楼中回复
+	CreatePostComment(newComment *dbstruct.ClubPostComment) error
+	GetCommentById(commentId int) (*dbstruct.ClubPostComment, error)
+	// GetPostComments 按楼层分页，返回本页的顶层评论及其下全部回复
+	GetPostComments(postId, offset, num int) ([]*dbstruct.ClubPostComment, []*dbstruct.ClubPostComment, error)
+	GetPostCommentsByCursor(postId int, cursor *model.Cursor, num int) (*model.Page[*dbstruct.ClubPostComment], []*dbstruct.ClubPostComment, error)
+	EditComment(commentId, userId int, content string) error
+	DeleteComment(commentId, userId int) error
+	// RemoveComment 社团负责人或管理员删除评论，并将理由通知评论作者
+	RemoveComment(commentId, moderatorId int, reason string) error
 
 	BanPost(role string, postId int) error
 	PinPost(postId int) error
+
+	// EditPost 将帖子当前标题与内容存为历史版本后写入新内容，title为空时保留原标题；
+	// 正文中引用附件的链接改写为附件地址。作者需经审核时新版本作为改帖申请提交，
+	// 帖子保持原内容，此时返回该申请
+	EditPost(postId, editorId int, title, content string) (*dbstruct.ClubPost, *dbstruct.CreatePostAppli, error)
+	DeletePost(postId int) error
+	GetPostRevisions(postId int) ([]*dbstruct.PostRevision, error)
+	// DiffPostRevisions 比较帖子两个版本，to为0表示当前版本，from为0表示to的上一版本
+	DiffPostRevisions(postId, from, to int) (*model.PostDiff, error)
+
+	// SaveDraft 保存草稿，PublishAt非空时作为定时发布
+	SaveDraft(draft *dbstruct.ClubPost) error
+	UpdateDraft(postId, userId int, title, content string, publishAt *time.Time) (*dbstruct.ClubPost, error)
+	GetDrafts(userId int) ([]*dbstruct.ClubPost, error)
+	// PublishDraft 立即发布草稿或定时帖子，需审核时转为pending状态
+	PublishDraft(postId, userId int) (*dbstruct.ClubPost, error)
+	// RunScheduler 按interval周期发布到期的定时帖子，直到ctx结束；interval未配置时使用默认周期
+	RunScheduler(ctx context.Context, interval time.Duration)
+
+	GetCreatePostAppli(appliId int) (*dbstruct.CreatePostAppli, error)
+	GetPendingPostApplis(clubId int) ([]*dbstruct.CreatePostAppli, error)
+	GetPostApplisByUserId(userId int) ([]*dbstruct.CreatePostAppli, error)
+	// ApproveAppliForCreatePost 通过发帖申请并发布帖子
+	ApproveAppliForCreatePost(appliId, reviewerId int) (*dbstruct.ClubPost, error)
+	// RejectAppliForCreatePost 拒绝发帖申请，帖子退回作者的草稿
+	RejectAppliForCreatePost(appliId, reviewerId int, reason string) error
 }
 
 type sPostService struct {
-	clubPostRepo repo.ClubPostRepo
-	//createPostAppliRepo repo.CreatePostAppliRepo
-	postCommentRepo repo.PostCommentRepo
+	clubPostRepo        repo.ClubPostRepo
+	createPostAppliRepo repo.CreatePostAppliRepo
+	postCommentRepo     repo.PostCommentRepo
+	postRevisionRepo    repo.PostRevisionRepo
+	clubRepo            repo.ClubRepo
+	clubMemberRepo      repo.ClubMemberRepo
+
+	blobStore           storage.BlobStore
+	notificationService NotificationService
+	attachmentService   AttachmentService
+	searchService       SearchService
+	ragSyncService      RagSyncService
 
 	txCoordinator repo.TransactionCoordinator
 
@@ -44,22 +104,51 @@ type sPostService struct {
 
 func CreatePostService(
 	clubPostRepo repo.ClubPostRepo,
-	//createPostAppliRepo repo.CreatePostAppliRepo,
+	createPostAppliRepo repo.CreatePostAppliRepo,
 	postCommentRepo repo.PostCommentRepo,
+	postRevisionRepo repo.PostRevisionRepo,
+	clubRepo repo.ClubRepo,
+	clubMemberRepo repo.ClubMemberRepo,
+
+	blobStore storage.BlobStore,
+	notificationService NotificationService,
+	attachmentService AttachmentService,
+	searchService SearchService,
+	ragSyncService RagSyncService,
 
 	txCoordinator repo.TransactionCoordinator,
 
 	logger *slog.Logger,
 ) PostService {
 	return &sPostService{
-		clubPostRepo: clubPostRepo,
-		//createPostAppliRepo: createPostAppliRepo,
-		postCommentRepo: postCommentRepo,
+		clubPostRepo:        clubPostRepo,
+		createPostAppliRepo: createPostAppliRepo,
+		postCommentRepo:     postCommentRepo,
+		postRevisionRepo:    postRevisionRepo,
+		clubRepo:            clubRepo,
+		clubMemberRepo:      clubMemberRepo,
+
+		blobStore:           blobStore,
+		notificationService: notificationService,
+		attachmentService:   attachmentService,
+		searchService:       searchService,
+		ragSyncService:      ragSyncService,
+
+		txCoordinator: txCoordinator,
 
 		logger: logger,
 	}
 }
 
+func (s *sPostService) GetPostById(postId int) (*dbstruct.ClubPost, error) {
+	post, err := s.clubPostRepo.GetPostById(postId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperr.ErrPostNotFound.Wrap(err)
+	}
+
+	return post, err
+}
+
 func (s *sPostService) GetLatestPosts(clubId, num, visibility int) ([]*dbstruct.ClubPost, error) {
 	return s.clubPostRepo.GetClubPostList(clubId, 0, num, visibility)
 }
@@ -68,8 +157,30 @@ func (s *sPostService) GetPostList(clubId, offset, num, visibility int) ([]*dbst
 	return s.clubPostRepo.GetClubPostList(clubId, offset, num, visibility)
 }
 
+func (s *sPostService) GetPostListByCursor(
+	clubId int,
+	cursor *model.Cursor,
+	num, visibility int,
+) (*model.Page[*dbstruct.ClubPost], error) {
+	return s.clubPostRepo.GetClubPostListByCursor(clubId, cursor, num, visibility)
+}
+
 func (s *sPostService) ChangePostVisibility(postId, visibility int) error {
-	return s.clubPostRepo.ChangePostVisibility(postId, visibility)
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubPostRepo.ChangePostVisibility(tx, postId, visibility); err != nil {
+			return err
+		}
+
+		if err := s.searchService.IndexPost(tx, uint(postId)); err != nil {
+			return err
+		}
+
+		// 非公开的帖子由RAG侧移除
+		return s.ragSyncService.SyncPost(tx, uint(postId))
+	})
 }
 
 func (s *sPostService) GetPinnedPost(clubId int) (*dbstruct.ClubPost, error) {
@@ -84,66 +195,940 @@ func (s *sPostService) CreatePost(
 	newPost *dbstruct.ClubPost,
 	sender func(writer *io.PipeWriter) error,
 ) error {
-	if err := s.clubPostRepo.AddPost(newPost); err != nil {
+	review, err := s.sRequiresReview(newPost.ClubId, newPost.UserId)
+	if err != nil {
 		return err
 	}
 
-	newFilePath := POST_FILE_DIR +
-		time.Now().Format("2006-01-02") + "_" +
-		strconv.FormatInt(int64(newPost.PostId), 10) + ".md"
+	reader, writer := io.Pipe()
+	defer reader.Close()
+
+	go sender(writer)
 
-	newFile, err := os.Create(newFilePath)
+	content, err := io.ReadAll(reader)
 	if err != nil {
-		return errors.New("创建对应post文件失败，需重试：" + err.Error() +
-			"（path: " + newFilePath + "）")
+		return errors.New("读取帖子内容失败，需重试：" + err.Error())
 	}
 
-	defer newFile.Close()
+	if review {
+		return s.sCreatePendingPost(newPost, string(content))
+	}
 
-	reader, writer := io.Pipe()
-	defer reader.Close()
+	contentUrl, err := s.sPutContent(content)
+	if err != nil {
+		return err
+	}
 
-	go sender(writer)
+	newPost.Status = dbstruct.POST_STATUS_PUBLISHED
+	newPost.ContentUrl = contentUrl
 
-	if _, err := io.CopyBuffer(
-		newFile, reader,
-		nil); err != nil {
-		if err != io.EOF {
-			return errors.New("写入文件失败，需重试：" + err.Error())
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubPostRepo.AppendPost(tx, newPost); err != nil {
+			return err
 		}
-		return nil
+
+		if err := s.searchService.IndexPost(tx, newPost.PostId); err != nil {
+			return err
+		}
+
+		return s.ragSyncService.SyncPost(tx, newPost.PostId)
+	})
+}
+
+func (s *sPostService) CreatePostComment(newComment *dbstruct.ClubPostComment) error {
+	post, err := s.GetPostById(int(newComment.PostId))
+	if err != nil {
+		return err
+	}
+
+	if post.Status != dbstruct.POST_STATUS_PUBLISHED {
+		return apperr.ErrPostNotFound
+	}
+
+	if newComment.ParentCommentId != nil {
+		parent, err := s.GetCommentById(int(*newComment.ParentCommentId))
+		if err != nil {
+			return err
+		}
+
+		if parent.PostId != newComment.PostId {
+			return apperr.ErrCommentNotFound
+		}
+
+		if parent.Status != dbstruct.COMMENT_STATUS_NORMAL {
+			return apperr.ErrCommentDeleted
+		}
+
+		rootId := parent.CommentId
+		if parent.RootCommentId != nil {
+			rootId = *parent.RootCommentId
+		}
+		newComment.RootCommentId = &rootId
+	}
+
+	newComment.Status = dbstruct.COMMENT_STATUS_NORMAL
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.postCommentRepo.CreatePostComment(tx, newComment); err != nil {
+			return err
+		}
+
+		// 评论数只统计正常状态的评论，由应用维护，数据库不再使用触发器
+		return s.clubPostRepo.UpdateCommentCount(tx, int(newComment.PostId), 1)
+	})
+}
+
+func (s *sPostService) GetCommentById(commentId int) (*dbstruct.ClubPostComment, error) {
+	comment, err := s.postCommentRepo.GetCommentById(commentId)
+	if err != nil {
+		return nil, sCommentError(err)
+	}
+
+	return comment, nil
+}
+
+func (s *sPostService) GetPostComments(
+	postId, offset, num int,
+) ([]*dbstruct.ClubPostComment, []*dbstruct.ClubPostComment, error) {
+	roots, err := s.postCommentRepo.GetRootComments(postId, offset, num)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.clubPostRepo.UpdatePostUrl(int(newPost.PostId), newFilePath); err != nil {
-		return errors.New("更新post URI失败，需重试：" + err.Error())
+	replies, err := s.postCommentRepo.GetReplies(sCommentIds(roots))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	newPost.ContentUrl = newFilePath
+	return roots, replies, nil
+}
+
+func (s *sPostService) GetPostCommentsByCursor(
+	postId int,
+	cursor *model.Cursor,
+	num int,
+) (*model.Page[*dbstruct.ClubPostComment], []*dbstruct.ClubPostComment, error) {
+	page, err := s.postCommentRepo.GetRootCommentsByCursor(postId, cursor, num)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replies, err := s.postCommentRepo.GetReplies(sCommentIds(page.Items))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return page, replies, nil
+}
+
+func (s *sPostService) EditComment(commentId, userId int, content string) error {
+	comment, err := s.GetCommentById(commentId)
+	if err != nil {
+		return err
+	}
+
+	if int(comment.UserId) != userId {
+		return apperr.ErrCommentNotAuthor
+	}
+
+	if comment.Status != dbstruct.COMMENT_STATUS_NORMAL {
+		return apperr.ErrCommentDeleted
+	}
+
+	return s.postCommentRepo.UpdateCommentContent(commentId, content)
+}
+
+func (s *sPostService) DeleteComment(commentId, userId int) error {
+	_, err := s.sCloseComment(commentId, func(comment *dbstruct.ClubPostComment) error {
+		if int(comment.UserId) != userId {
+			return apperr.ErrCommentNotAuthor
+		}
+		return nil
+	}, dbstruct.COMMENT_STATUS_DELETED, nil, "")
+
+	return err
+}
+
+func (s *sPostService) RemoveComment(commentId, moderatorId int, reason string) error {
+	moderator := uint(moderatorId)
+	comment, err := s.sCloseComment(commentId, nil,
+		dbstruct.COMMENT_STATUS_REMOVED, &moderator, reason,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.sNotify(comment.UserId, dbstruct.NOTIFY_COMMENT_REMOVED,
+		"评论已被删除",
+		"你的评论因违反社团规定被删除，理由："+reason,
+		comment.CommentId,
+	)
 
 	return nil
 }
 
-func (s *sPostService) CreatePostComment(newComment dbstruct.ClubPostComment) error {
-	return s.postCommentRepo.CreatePostComment(newComment)
+// sCloseComment 在事务内将正常状态的评论置为status并扣减帖子评论数，check可在变更前追加校验
+func (s *sPostService) sCloseComment(
+	commentId int,
+	check func(comment *dbstruct.ClubPostComment) error,
+	status string,
+	removedBy *uint,
+	reason string,
+) (*dbstruct.ClubPostComment, error) {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var closed *dbstruct.ClubPostComment
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		comment, err := s.postCommentRepo.GetCommentForUpdate(tx, commentId)
+		if err != nil {
+			return err
+		}
+
+		if check != nil {
+			if err := check(comment); err != nil {
+				return err
+			}
+		}
+
+		if comment.Status != dbstruct.COMMENT_STATUS_NORMAL {
+			return apperr.ErrCommentDeleted
+		}
+
+		if err := s.postCommentRepo.UpdateCommentStatus(
+			tx, commentId, status, removedBy, reason,
+		); err != nil {
+			return err
+		}
+
+		if err := s.clubPostRepo.UpdateCommentCount(tx, int(comment.PostId), -1); err != nil {
+			return err
+		}
+
+		closed = comment
+		return nil
+	})
+	if err != nil {
+		return nil, sCommentError(err)
+	}
+
+	return closed, nil
+}
+
+func sCommentError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrCommentNotFound.Wrap(err)
+	}
+	return err
 }
 
-func (s *sPostService) GetPostComments(postId int) ([]*dbstruct.ClubPostComment, error) {
-	return s.postCommentRepo.GetPostComments(postId)
+func sCommentIds(comments []*dbstruct.ClubPostComment) []uint {
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.CommentId)
+	}
+	return ids
 }
 
 func (s *sPostService) BanPost(role string, postId int) error {
 	switch role {
 	case dbstruct.ROLE_ADMIN:
-		return s.clubPostRepo.ChangePostVisibility(postId, 2)
+		return s.ChangePostVisibility(postId, 2)
 
-	case dbstruct.ROLE_CLUB_LEADER:
-		return s.clubPostRepo.ChangePostVisibility(postId, 1)
+	case dbstruct.ROLE_CLUB_LEADER, dbstruct.ROLE_CLUB_VICE_LEADER:
+		return s.ChangePostVisibility(postId, 1)
 
 	default:
-		return errors.New("未知权限：" + role)
+		return apperr.ErrForbidden.WithMessage("未知权限：" + role)
 	}
 }
 
 func (s *sPostService) PinPost(postId int) error {
 	return s.clubPostRepo.PinPost(postId)
 }
+
+func (s *sPostService) EditPost(postId, editorId int, title, content string) (*dbstruct.ClubPost, *dbstruct.CreatePostAppli, error) {
+	post, err := s.GetPostById(postId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if int(post.UserId) != editorId {
+		return nil, nil, apperr.ErrPostNotAuthor
+	}
+
+	if post.Status != dbstruct.POST_STATUS_PUBLISHED {
+		return nil, nil, apperr.ErrPostNotPublished
+	}
+
+	review, err := s.sRequiresReview(post.ClubId, post.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if title == "" {
+		title = post.Title
+	}
+
+	content, err = s.attachmentService.RewriteLinks(post.PostId, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if title == post.Title {
+		if oldContent, err := s.GetPostContent(post.ContentUrl); err == nil && string(oldContent) == content {
+			return nil, nil, apperr.ErrPostUnchanged
+		}
+	}
+
+	// 正文按内容寻址保存，历史版本引用的旧对象不受影响
+	newContentUrl, err := s.sPutContent([]byte(content))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var appli *dbstruct.CreatePostAppli
+
+	err = s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		locked, err := s.clubPostRepo.GetPostForUpdate(tx, postId)
+		if err != nil {
+			return err
+		}
+
+		if !review {
+			return s.sApplyEditInTx(tx, locked, title, newContentUrl)
+		}
+
+		// 同一帖子同时只保留一个待审核的修改，避免审核顺序不同导致版本错乱
+		pending, err := s.createPostAppliRepo.HasPendingEdit(tx, postId)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return apperr.ErrPostPendingReview
+		}
+
+		appli = &dbstruct.CreatePostAppli{
+			PostId:      locked.PostId,
+			ClubId:      locked.ClubId,
+			ApplicantId: locked.UserId,
+			Kind:        dbstruct.POST_APPLI_EDIT,
+			Title:       title,
+			ContentUrl:  newContentUrl,
+		}
+		return s.createPostAppliRepo.AddCreatePostAppli(tx, appli)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apperr.ErrPostNotFound.Wrap(err)
+		}
+		return nil, nil, err
+	}
+
+	if appli != nil {
+		return post, appli, nil
+	}
+
+	post.Title = title
+	post.ContentUrl = newContentUrl
+	post.UpdatedAt = time.Now()
+
+	return post, nil, nil
+}
+
+// sApplyEditInTx 将帖子当前内容存为历史版本后写入新内容，post须已加锁
+func (s *sPostService) sApplyEditInTx(tx *gorm.DB, post *dbstruct.ClubPost, title, contentUrl string) error {
+	count, err := s.postRevisionRepo.CountRevisions(tx, int(post.PostId))
+	if err != nil {
+		return err
+	}
+
+	if err := s.postRevisionRepo.AddRevision(tx, &dbstruct.PostRevision{
+		PostId:      post.PostId,
+		Version:     int(count) + 1,
+		Title:       post.Title,
+		ContentUrl:  post.ContentUrl,
+		PublishedAt: post.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.clubPostRepo.UpdatePostContent(tx, int(post.PostId), title, contentUrl); err != nil {
+		return err
+	}
+
+	post.Title = title
+	post.ContentUrl = contentUrl
+	post.UpdatedAt = time.Now()
+
+	if err := s.searchService.IndexPost(tx, post.PostId); err != nil {
+		return err
+	}
+
+	return s.ragSyncService.SyncPost(tx, post.PostId)
+}
+
+func (s *sPostService) DeletePost(postId int) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubPostRepo.DeletePost(tx, postId); err != nil {
+			return err
+		}
+
+		if err := s.searchService.RemovePost(tx, uint(postId)); err != nil {
+			return err
+		}
+
+		return s.ragSyncService.RemovePost(tx, uint(postId))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrPostNotFound.Wrap(err)
+	}
+
+	return err
+}
+
+func (s *sPostService) GetPostRevisions(postId int) ([]*dbstruct.PostRevision, error) {
+	if _, err := s.GetPostById(postId); err != nil {
+		return nil, err
+	}
+
+	return s.postRevisionRepo.GetRevisions(postId)
+}
+
+func (s *sPostService) DiffPostRevisions(postId, from, to int) (*model.PostDiff, error) {
+	post, err := s.GetPostById(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.postRevisionRepo.GetRevisions(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := len(revisions) + 1
+	if to == 0 {
+		to = current
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	if from < 1 || to < 1 || from > current || to > current {
+		return nil, apperr.ErrRevisionNotFound
+	}
+
+	fromTitle, fromContent, err := s.sPostVersion(post, revisions, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTitle, toContent, err := s.sPostVersion(post, revisions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := model.DiffLines(fromContent, toContent, kPostDiffMaxCells)
+	if err != nil {
+		if errors.Is(err, model.ErrDiffTooLarge) {
+			return nil, apperr.ErrPostDiffTooLarge.Wrap(err)
+		}
+		return nil, err
+	}
+
+	return &model.PostDiff{
+		PostId:      post.PostId,
+		FromVersion: from,
+		ToVersion:   to,
+		FromTitle:   fromTitle,
+		ToTitle:     toTitle,
+		Lines:       lines,
+	}, nil
+}
+
+func (s *sPostService) SaveDraft(draft *dbstruct.ClubPost) error {
+	status, err := sDraftStatus(draft.PublishAt)
+	if err != nil {
+		return err
+	}
+
+	draft.Status = status
+	draft.ContentUrl = ""
+
+	return s.clubPostRepo.AddPost(draft)
+}
+
+func (s *sPostService) UpdateDraft(
+	postId, userId int,
+	title, content string,
+	publishAt *time.Time,
+) (*dbstruct.ClubPost, error) {
+	draft, err := s.sGetOwnDraft(postId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := sDraftStatus(publishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.clubPostRepo.UpdateDraft(postId, map[string]any{
+		"title":         title,
+		"draft_content": content,
+		"status":        status,
+		"publish_at":    publishAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	draft.Title = title
+	draft.DraftContent = content
+	draft.Status = status
+	draft.PublishAt = publishAt
+
+	return draft, nil
+}
+
+func (s *sPostService) GetDrafts(userId int) ([]*dbstruct.ClubPost, error) {
+	return s.clubPostRepo.GetDraftsByUserId(userId)
+}
+
+func (s *sPostService) PublishDraft(postId, userId int) (*dbstruct.ClubPost, error) {
+	if _, err := s.sGetOwnDraft(postId, userId); err != nil {
+		return nil, err
+	}
+
+	return s.sPublish(postId)
+}
+
+func (s *sPostService) RunScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = kPostScheduleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			posts, err := s.clubPostRepo.GetDuePosts(time.Now(), kPostScheduleBatch)
+			if err != nil {
+				s.logger.Error("查询到期定时帖子失败", "error", err)
+				continue
+			}
+
+			for _, post := range posts {
+				if _, err := s.sPublish(int(post.PostId)); err != nil &&
+					!errors.Is(err, apperr.ErrPostNotDraft) {
+					s.logger.Error("定时发布帖子失败", "error", err, "post_id", post.PostId)
+				}
+			}
+		}
+	}
+}
+
+func (s *sPostService) sGetOwnDraft(postId, userId int) (*dbstruct.ClubPost, error) {
+	draft, err := s.GetPostById(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	if int(draft.UserId) != userId {
+		return nil, apperr.ErrPostNotAuthor
+	}
+
+	switch draft.Status {
+	case dbstruct.POST_STATUS_PUBLISHED:
+		return nil, apperr.ErrPostNotDraft
+	case dbstruct.POST_STATUS_PENDING:
+		return nil, apperr.ErrPostPendingReview
+	}
+
+	return draft, nil
+}
+
+// sPublish 发布草稿，作者发帖需审核时改为提交审核；搜索索引与RAG同步事件随发布在同一事务中写入。
+// 帖子行加锁并校验状态，调度与手动发布并发时只有一方生效
+func (s *sPostService) sPublish(postId int) (*dbstruct.ClubPost, error) {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var post *dbstruct.ClubPost
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		var err error
+		post, err = s.clubPostRepo.GetPostForUpdate(tx, postId)
+		if err != nil {
+			return err
+		}
+
+		if post.Status != dbstruct.POST_STATUS_DRAFT && post.Status != dbstruct.POST_STATUS_SCHEDULED {
+			return apperr.ErrPostNotDraft
+		}
+
+		review, err := s.sRequiresReview(post.ClubId, post.UserId)
+		if err != nil {
+			return err
+		}
+
+		if review {
+			return s.sSubmitForReview(tx, post)
+		}
+
+		return s.sPublishInTx(tx, post)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrPostNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+
+	if post.Status == dbstruct.POST_STATUS_PENDING {
+		s.logger.Info("帖子已提交审核", "post_id", post.PostId)
+		return post, nil
+	}
+
+	s.logger.Info("帖子已发布", "post_id", post.PostId)
+
+	return post, nil
+}
+
+// sPublishInTx 改写附件链接后保存正文并将帖子置为已发布，同时写入索引同步事件
+func (s *sPostService) sPublishInTx(tx *gorm.DB, post *dbstruct.ClubPost) error {
+	content, err := s.attachmentService.RewriteLinks(post.PostId, post.DraftContent)
+	if err != nil {
+		return err
+	}
+
+	contentUrl, err := s.sPutContent([]byte(content))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.clubPostRepo.PublishPost(tx, int(post.PostId), contentUrl, now); err != nil {
+		return err
+	}
+
+	post.Status = dbstruct.POST_STATUS_PUBLISHED
+	post.ContentUrl = contentUrl
+	post.DraftContent = ""
+	post.PublishAt = nil
+	post.CreatedAt = now
+	post.UpdatedAt = now
+
+	if err := s.searchService.IndexPost(tx, post.PostId); err != nil {
+		return err
+	}
+
+	return s.ragSyncService.SyncPost(tx, post.PostId)
+}
+
+func (s *sPostService) sSubmitForReview(tx *gorm.DB, post *dbstruct.ClubPost) error {
+	if err := s.clubPostRepo.UpdatePostStatus(tx, int(post.PostId), dbstruct.POST_STATUS_PENDING); err != nil {
+		return err
+	}
+
+	if err := s.createPostAppliRepo.AddCreatePostAppli(tx, &dbstruct.CreatePostAppli{
+		PostId:      post.PostId,
+		ClubId:      post.ClubId,
+		ApplicantId: post.UserId,
+	}); err != nil {
+		return err
+	}
+
+	post.Status = dbstruct.POST_STATUS_PENDING
+	post.PublishAt = nil
+
+	return nil
+}
+
+// sCreatePendingPost 需审核的帖子内容暂存为草稿内容，审核通过后才写入内容文件
+func (s *sPostService) sCreatePendingPost(
+	newPost *dbstruct.ClubPost,
+	content string,
+) error {
+	newPost.Status = dbstruct.POST_STATUS_PENDING
+	newPost.DraftContent = content
+	newPost.ContentUrl = ""
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		if err := s.clubPostRepo.AppendPost(tx, newPost); err != nil {
+			return err
+		}
+
+		return s.createPostAppliRepo.AddCreatePostAppli(tx, &dbstruct.CreatePostAppli{
+			PostId:      newPost.PostId,
+			ClubId:      newPost.ClubId,
+			ApplicantId: newPost.UserId,
+		})
+	})
+}
+
+// sRequiresReview 社团开启发帖审核时，干事及以上角色的帖子免审，其余用户均需审核
+func (s *sPostService) sRequiresReview(clubId, userId uint) (bool, error) {
+	club, err := s.clubRepo.GetClubInfo(int(clubId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, apperr.ErrClubNotFound.Wrap(err)
+		}
+		return false, err
+	}
+
+	if !club.RequirePostReview {
+		return false, nil
+	}
+
+	member, err := s.clubMemberRepo.GetMemberInClub(int(userId), int(clubId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return !model.HasClubPermission(member.RoleInClub, dbstruct.ROLE_CLUB_OFFICER), nil
+}
+
+// sPutContent 保存帖子正文，返回其访问地址
+func (s *sPostService) sPutContent(content []byte) (string, error) {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := s.blobStore.Put(ctxTmt, POST_FILE_PREFIX, ".md", content, "text/markdown; charset=utf-8")
+	if err != nil {
+		return "", errors.New("保存帖子内容失败，需重试：" + err.Error())
+	}
+
+	return s.blobStore.URL(key), nil
+}
+
+func (s *sPostService) GetPostContent(contentUrl string) ([]byte, error) {
+	key, ok := s.blobStore.KeyOf(contentUrl)
+	if !ok {
+		return nil, errors.New("无效的帖子内容地址：" + contentUrl)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.blobStore.Get(ctxTmt, key)
+}
+
+func (s *sPostService) GetCreatePostAppli(appliId int) (*dbstruct.CreatePostAppli, error) {
+	appli, err := s.createPostAppliRepo.GetAppliById(appliId)
+	if err != nil {
+		return nil, sAppliError(err)
+	}
+
+	return appli, nil
+}
+
+func (s *sPostService) GetPendingPostApplis(clubId int) ([]*dbstruct.CreatePostAppli, error) {
+	return s.createPostAppliRepo.GetPendingApplis(clubId)
+}
+
+func (s *sPostService) GetPostApplisByUserId(userId int) ([]*dbstruct.CreatePostAppli, error) {
+	return s.createPostAppliRepo.GetApplisByUserId(userId)
+}
+
+func (s *sPostService) ApproveAppliForCreatePost(appliId, reviewerId int) (*dbstruct.ClubPost, error) {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var post *dbstruct.ClubPost
+	var postAppliId uint
+	var kind string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		appli, err := s.createPostAppliRepo.GetAppliForUpdate(tx, appliId)
+		if err != nil {
+			return sAppliError(err)
+		}
+
+		if appli.Status != "pending" {
+			return apperr.ErrAppliNotPending
+		}
+
+		post, err = s.clubPostRepo.GetPostForUpdate(tx, int(appli.PostId))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrPostNotFound.Wrap(err)
+			}
+			return err
+		}
+
+		kind = appli.Kind
+		postAppliId = appli.PostAppliId
+
+		if kind == dbstruct.POST_APPLI_EDIT {
+			if post.Status != dbstruct.POST_STATUS_PUBLISHED {
+				return apperr.ErrPostNotPublished
+			}
+
+			if err := s.createPostAppliRepo.ApproveAppli(tx, appliId, reviewerId); err != nil {
+				return err
+			}
+
+			return s.sApplyEditInTx(tx, post, appli.Title, appli.ContentUrl)
+		}
+
+		if post.Status != dbstruct.POST_STATUS_PENDING {
+			return apperr.ErrAppliNotPending
+		}
+
+		if err := s.createPostAppliRepo.ApproveAppli(tx, appliId, reviewerId); err != nil {
+			return err
+		}
+
+		return s.sPublishInTx(tx, post)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == dbstruct.POST_APPLI_EDIT {
+		s.logger.Info("帖子修改已生效", "post_id", post.PostId)
+
+		s.sNotify(post.UserId, dbstruct.NOTIFY_CREATE_POST_APPLI,
+			"帖子修改审核已通过",
+			fmt.Sprintf("你对帖子「%s」的修改已通过审核", post.Title),
+			postAppliId,
+		)
+
+		return post, nil
+	}
+
+	s.logger.Info("帖子已发布", "post_id", post.PostId)
+
+	s.sNotify(post.UserId, dbstruct.NOTIFY_CREATE_POST_APPLI,
+		"帖子审核已通过",
+		fmt.Sprintf("你的帖子「%s」已通过审核并发布", post.Title),
+		postAppliId,
+	)
+
+	return post, nil
+}
+
+func (s *sPostService) RejectAppliForCreatePost(appliId, reviewerId int, reason string) error {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var appli *dbstruct.CreatePostAppli
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		var err error
+		appli, err = s.createPostAppliRepo.GetAppliForUpdate(tx, appliId)
+		if err != nil {
+			return sAppliError(err)
+		}
+
+		if appli.Status != "pending" {
+			return apperr.ErrAppliNotPending
+		}
+
+		if err := s.createPostAppliRepo.RejectAppli(tx, appliId, reviewerId, reason); err != nil {
+			return err
+		}
+
+		// 改帖申请被拒绝时帖子保持原内容，仍处于发布状态
+		if appli.Kind == dbstruct.POST_APPLI_EDIT {
+			return nil
+		}
+
+		return s.clubPostRepo.UpdatePostStatus(tx, int(appli.PostId), dbstruct.POST_STATUS_DRAFT)
+	})
+	if err != nil {
+		return err
+	}
+
+	if appli.Kind == dbstruct.POST_APPLI_EDIT {
+		s.sNotify(appli.ApplicantId, dbstruct.NOTIFY_CREATE_POST_APPLI,
+			"帖子修改审核未通过",
+			"帖子保持修改前的内容，拒绝理由："+reason,
+			appli.PostAppliId,
+		)
+
+		return nil
+	}
+
+	s.sNotify(appli.ApplicantId, dbstruct.NOTIFY_CREATE_POST_APPLI,
+		"帖子审核未通过",
+		"帖子已退回草稿箱，拒绝理由："+reason,
+		appli.PostAppliId,
+	)
+
+	return nil
+}
+
+func (s *sPostService) sNotify(userId uint, notifyType, title, content string, refId uint) {
+	if err := s.notificationService.Notify(
+		userId, notifyType, title, content, refId,
+	); err != nil {
+		s.logger.Error("发送通知失败",
+			"error", err, "user_id", userId, "type", notifyType, "ref_id", refId,
+		)
+	}
+}
+
+// sDraftStatus 根据定时发布时间确定草稿状态，定时时间须晚于当前时间
+func sDraftStatus(publishAt *time.Time) (string, error) {
+	if publishAt == nil {
+		return dbstruct.POST_STATUS_DRAFT, nil
+	}
+
+	if !publishAt.After(time.Now()) {
+		return "", apperr.ErrInvalidPublishTime
+	}
+
+	return dbstruct.POST_STATUS_SCHEDULED, nil
+}
+
+// sPostVersion 读取指定版本的标题与内容，版本号超出历史版本数时为帖子当前内容
+func (s *sPostService) sPostVersion(
+	post *dbstruct.ClubPost,
+	revisions []*dbstruct.PostRevision,
+	version int,
+) (string, string, error) {
+	title, contentUrl := post.Title, post.ContentUrl
+	if version <= len(revisions) {
+		idx := slices.IndexFunc(revisions, func(r *dbstruct.PostRevision) bool {
+			return r.Version == version
+		})
+		if idx < 0 {
+			return "", "", apperr.ErrRevisionNotFound
+		}
+
+		title, contentUrl = revisions[idx].Title, revisions[idx].ContentUrl
+	}
+
+	content, err := s.GetPostContent(contentUrl)
+	if err != nil {
+		return "", "", errors.New("读取帖子内容失败：" + err.Error() +
+			"（path: " + contentUrl + "）")
+	}
+
+	return title, string(content), nil
+}
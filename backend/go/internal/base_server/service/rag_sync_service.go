@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+const (
+	kRagRelayInterval    = 5 * time.Second
+	kRagRelayBatch       = 50
+	kRagRelayLease       = time.Minute // 取出的事件在此期间内不会被其他实例重复处理
+	kRagRelayMaxAttempts = 10
+	kRagRelayBackoffBase = 5 * time.Second
+	kRagRelayBackoffMax  = 10 * time.Minute
+	kRagOutboxRetention  = 7 * 24 * time.Hour
+)
+
+type RagSyncService interface {
+	// 以下方法在调用方的事务中写入RAG同步事件，事务提交后由RunRelay推送至rag_sync_stream
+	SyncClub(tx *gorm.DB, clubId uint) error
+	// RemoveClub 同时移除社团全部已发布的帖子，需在删除帖子之前调用
+	RemoveClub(tx *gorm.DB, clubId uint) error
+	// SyncPost 推送时帖子未发布或非公开则通知RAG侧移除
+	SyncPost(tx *gorm.DB, postId uint) error
+	RemovePost(tx *gorm.DB, postId uint) error
+
+	// RunRelay 按interval周期推送到期的同步事件，直到ctx结束；interval未配置时使用默认周期
+	RunRelay(ctx context.Context, interval time.Duration)
+
+	// GetOutbox 按事件ID倒序列出同步事件，status为空时不限状态；同时返回各状态的事件数
+	GetOutbox(status string, offset, num int) ([]*dbstruct.RagOutbox, map[string]int64, error)
+	// RetryEvent 将失败的事件重新置为待处理
+	RetryEvent(outboxId uint) error
+}
+
+type sRagSyncService struct {
+	clubRepo      repo.ClubRepo
+	clubPostRepo  repo.ClubPostRepo
+	ragOutboxRepo repo.RagOutboxRepo
+
+	redisService redisimpl.RedisClientService
+	blobStore    storage.BlobStore
+
+	txCoordinator repo.TransactionCoordinator
+
+	logger *slog.Logger
+}
+
+func NewRagSyncService(
+	clubRepo repo.ClubRepo,
+	clubPostRepo repo.ClubPostRepo,
+	ragOutboxRepo repo.RagOutboxRepo,
+
+	redisService redisimpl.RedisClientService,
+	blobStore storage.BlobStore,
+
+	txCoordinator repo.TransactionCoordinator,
+
+	logger *slog.Logger,
+) RagSyncService {
+	return &sRagSyncService{
+		clubRepo:      clubRepo,
+		clubPostRepo:  clubPostRepo,
+		ragOutboxRepo: ragOutboxRepo,
+
+		redisService: redisService,
+		blobStore:    blobStore,
+
+		txCoordinator: txCoordinator,
+
+		logger: logger,
+	}
+}
+
+func (s *sRagSyncService) SyncClub(tx *gorm.DB, clubId uint) error {
+	return s.sEnqueue(tx, dbstruct.RAG_ENTITY_CLUB, clubId, dbstruct.RAG_OP_UPSERT)
+}
+
+func (s *sRagSyncService) RemoveClub(tx *gorm.DB, clubId uint) error {
+	postIds, err := s.clubPostRepo.GetPublishedPostIds(tx, int(clubId))
+	if err != nil {
+		return err
+	}
+
+	for _, postId := range postIds {
+		if err := s.RemovePost(tx, postId); err != nil {
+			return err
+		}
+	}
+
+	return s.sEnqueue(tx, dbstruct.RAG_ENTITY_CLUB, clubId, dbstruct.RAG_OP_DELETE)
+}
+
+func (s *sRagSyncService) SyncPost(tx *gorm.DB, postId uint) error {
+	return s.sEnqueue(tx, dbstruct.RAG_ENTITY_POST, postId, dbstruct.RAG_OP_UPSERT)
+}
+
+func (s *sRagSyncService) RemovePost(tx *gorm.DB, postId uint) error {
+	return s.sEnqueue(tx, dbstruct.RAG_ENTITY_POST, postId, dbstruct.RAG_OP_DELETE)
+}
+
+func (s *sRagSyncService) sEnqueue(tx *gorm.DB, entity string, entityId uint, op string) error {
+	return s.ragOutboxRepo.AddEvent(tx, &dbstruct.RagOutbox{
+		Entity:        entity,
+		EntityId:      entityId,
+		Op:            op,
+		Status:        dbstruct.OUTBOX_STATUS_PENDING,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+func (s *sRagSyncService) RunRelay(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = kRagRelayInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			s.sRelayOnce()
+
+			if _, err := s.ragOutboxRepo.PurgeDone(time.Now().Add(-kRagOutboxRetention)); err != nil {
+				s.logger.Error("清理已完成的RAG同步事件失败", "error", err)
+			}
+		}
+	}
+}
+
+func (s *sRagSyncService) sRelayOnce() {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var events []*dbstruct.RagOutbox
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		var err error
+		events, err = s.ragOutboxRepo.ClaimDueEvents(tx, time.Now(), kRagRelayLease, kRagRelayBatch)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("读取RAG同步事件失败", "error", err)
+		return
+	}
+
+	for _, event := range events {
+		s.sHandleEvent(event)
+	}
+}
+
+// sHandleEvent 失败时按指数退避重试，次数耗尽后标记为失败，可由管理员重新提交
+func (s *sRagSyncService) sHandleEvent(event *dbstruct.RagOutbox) {
+	now := time.Now()
+
+	msgId, err := s.sPublish(event)
+	if err == nil {
+		if err := s.ragOutboxRepo.MarkDone(event.OutboxId, msgId, now); err != nil {
+			s.logger.Error("标记RAG同步事件完成失败", "error", err, "outbox_id", event.OutboxId)
+		}
+		return
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= kRagRelayMaxAttempts {
+		s.logger.Error("RAG同步事件重试次数耗尽",
+			"error", err, "outbox_id", event.OutboxId,
+			"entity", event.Entity, "entity_id", event.EntityId,
+		)
+
+		if err := s.ragOutboxRepo.MarkFailed(event.OutboxId, attempts, err.Error()); err != nil {
+			s.logger.Error("标记RAG同步事件失败状态失败", "error", err, "outbox_id", event.OutboxId)
+		}
+		return
+	}
+
+	backoff := min(kRagRelayBackoffBase<<(attempts-1), kRagRelayBackoffMax)
+	s.logger.Warn("RAG同步失败，稍后重试",
+		"error", err, "outbox_id", event.OutboxId, "attempts", attempts, "backoff", backoff,
+	)
+
+	if err := s.ragOutboxRepo.MarkRetry(event.OutboxId, attempts, now.Add(backoff), err.Error()); err != nil {
+		s.logger.Error("更新RAG同步事件失败", "error", err, "outbox_id", event.OutboxId)
+	}
+}
+
+// sPublish 推送数据库中的最新内容，返回流消息ID。对象已不存在、帖子未发布或非公开时改为通知RAG侧移除，
+// 重复移除不影响结果
+func (s *sRagSyncService) sPublish(event *dbstruct.RagOutbox) (string, error) {
+	switch event.Entity {
+	case dbstruct.RAG_ENTITY_CLUB:
+		sourceId := redisimpl.RagClubSourceId(event.EntityId)
+		if event.Op == dbstruct.RAG_OP_DELETE {
+			return s.redisService.RemoveRagSource(sourceId, nil)
+		}
+
+		club, err := s.clubRepo.GetClubInfo(int(event.EntityId))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.redisService.RemoveRagSource(sourceId, nil)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return s.redisService.UploadClubInfo(club)
+
+	case dbstruct.RAG_ENTITY_POST:
+		sourceId := redisimpl.RagPostSourceId(event.EntityId)
+		if event.Op == dbstruct.RAG_OP_DELETE {
+			return s.redisService.RemoveRagSource(sourceId, nil)
+		}
+
+		post, err := s.clubPostRepo.GetPostById(int(event.EntityId))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.redisService.RemoveRagSource(sourceId, nil)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if post.Status != dbstruct.POST_STATUS_PUBLISHED || post.Visibility != 0 {
+			return s.redisService.RemoveRagSource(sourceId, map[string]any{
+				"club_id":    post.ClubId,
+				"visibility": redisimpl.RagVisibility(post.Visibility),
+			})
+		}
+
+		content, err := s.sGetContent(post.ContentUrl)
+		if err != nil {
+			return "", err
+		}
+
+		return s.redisService.UploadPostInfo(post, content)
+
+	default:
+		return "", errors.New("未知的RAG同步对象：" + event.Entity)
+	}
+}
+
+func (s *sRagSyncService) sGetContent(contentUrl string) ([]byte, error) {
+	key, ok := s.blobStore.KeyOf(contentUrl)
+	if !ok {
+		return nil, errors.New("无效的帖子内容地址：" + contentUrl)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.blobStore.Get(ctxTmt, key)
+}
+
+func (s *sRagSyncService) GetOutbox(status string, offset, num int) ([]*dbstruct.RagOutbox, map[string]int64, error) {
+	events, err := s.ragOutboxRepo.GetEvents(status, offset, num)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts, err := s.ragOutboxRepo.CountByStatus()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return events, counts, nil
+}
+
+func (s *sRagSyncService) RetryEvent(outboxId uint) error {
+	err := s.ragOutboxRepo.Requeue(outboxId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.ErrNotFound.WithMessage("同步事件不存在或未处于失败状态").Wrap(err)
+	}
+
+	return err
+}
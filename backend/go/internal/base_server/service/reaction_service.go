@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+const (
+	kReactionFlushBatch    = 200
+	kReactionFlushInterval = 10 * time.Second
+
+	kReactionRecountBatch    = 500
+	kReactionRecountInterval = time.Hour
+)
+
+var kReactionKinds = []string{
+	dbstruct.REACTION_LIKE,
+	dbstruct.REACTION_LOVE,
+	dbstruct.REACTION_LAUGH,
+	dbstruct.REACTION_WOW,
+	dbstruct.REACTION_SAD,
+}
+
+type ReactionService interface {
+	// React 添加回应，已有其他回应时替换为kind
+	React(userId int, targetType string, targetId int, kind string) error
+	Unreact(userId int, targetType string, targetId int) error
+
+	// GetSummaries 汇总已落库的计数与Redis中尚未合并的增量，userId为0时不查询调用者的回应
+	GetSummaries(userId int, targetType string, targetIds []uint) (map[uint]*model.ReactionSummary, error)
+
+	// FlushCounts 将Redis中累积的计数增量合并写入数据库，返回本次处理的对象数
+	FlushCounts() (int, error)
+	// RecountCounts 按回应表重新统计已落库的计数，修正增量写入失败或重复合并造成的偏差；
+	// 仍有未合并增量的对象跳过，留待下次统计。返回重新统计的对象数
+	RecountCounts() (int, error)
+	// RunFlusher 按interval周期合并计数并定期重新统计，直到ctx结束；interval未配置时使用默认周期
+	RunFlusher(ctx context.Context, interval time.Duration)
+}
+
+type sReactionService struct {
+	reactionRepo    repo.ReactionRepo
+	clubPostRepo    repo.ClubPostRepo
+	postCommentRepo repo.PostCommentRepo
+
+	redisService redisimpl.RedisClientService
+
+	txCoordinator repo.TransactionCoordinator
+
+	logger *slog.Logger
+}
+
+func NewReactionService(
+	reactionRepo repo.ReactionRepo,
+	clubPostRepo repo.ClubPostRepo,
+	postCommentRepo repo.PostCommentRepo,
+
+	redisService redisimpl.RedisClientService,
+
+	txCoordinator repo.TransactionCoordinator,
+
+	logger *slog.Logger,
+) ReactionService {
+	return &sReactionService{
+		reactionRepo:    reactionRepo,
+		clubPostRepo:    clubPostRepo,
+		postCommentRepo: postCommentRepo,
+
+		redisService: redisService,
+
+		txCoordinator: txCoordinator,
+
+		logger: logger,
+	}
+}
+
+func (s *sReactionService) React(userId int, targetType string, targetId int, kind string) error {
+	if !slices.Contains(kReactionKinds, kind) {
+		return apperr.ErrInvalidReaction
+	}
+
+	if err := s.sCheckTarget(targetType, targetId); err != nil {
+		return err
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var oldKind string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		reaction, err := s.reactionRepo.AddOrLockReaction(tx, &dbstruct.Reaction{
+			TargetType: targetType,
+			TargetId:   uint(targetId),
+			UserId:     uint(userId),
+			Kind:       kind,
+		})
+		if err != nil || reaction == nil {
+			return err
+		}
+
+		if reaction.Kind == kind {
+			oldKind = kind
+			return nil
+		}
+
+		oldKind = reaction.Kind
+		return s.reactionRepo.UpdateReactionKind(tx, reaction.ReactionId, kind)
+	})
+	if err != nil {
+		return err
+	}
+
+	if oldKind == kind {
+		return nil
+	}
+
+	target := sReactionTarget(targetType, uint(targetId))
+	if oldKind != "" {
+		s.sIncrDelta(target, oldKind, -1)
+	}
+	s.sIncrDelta(target, kind, 1)
+
+	return nil
+}
+
+func (s *sReactionService) Unreact(userId int, targetType string, targetId int) error {
+	if targetType != dbstruct.REACTION_TARGET_POST && targetType != dbstruct.REACTION_TARGET_COMMENT {
+		return apperr.ErrInvalidReaction
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var oldKind string
+
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		reaction, err := s.reactionRepo.GetReactionForUpdate(tx, targetType, targetId, userId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		oldKind = reaction.Kind
+		return s.reactionRepo.DeleteReaction(tx, reaction.ReactionId)
+	})
+	if err != nil {
+		return err
+	}
+
+	if oldKind != "" {
+		s.sIncrDelta(sReactionTarget(targetType, uint(targetId)), oldKind, -1)
+	}
+
+	return nil
+}
+
+func (s *sReactionService) GetSummaries(
+	userId int,
+	targetType string,
+	targetIds []uint,
+) (map[uint]*model.ReactionSummary, error) {
+	summaries := make(map[uint]*model.ReactionSummary, len(targetIds))
+	if len(targetIds) == 0 {
+		return summaries, nil
+	}
+
+	for _, id := range targetIds {
+		summaries[id] = &model.ReactionSummary{Counts: map[string]int64{}}
+	}
+
+	counts, err := s.reactionRepo.GetReactionCounts(targetType, targetIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, count := range counts {
+		summaries[count.TargetId].Counts[count.Kind] += count.Count
+	}
+
+	targets := make([]string, 0, len(targetIds))
+	for _, id := range targetIds {
+		targets = append(targets, sReactionTarget(targetType, id))
+	}
+
+	// 增量读取失败时退回已落库的计数，只影响展示的实时性
+	pending, err := s.redisService.GetReactionDeltas(targets)
+	if err != nil {
+		s.logger.Error("读取回应计数增量失败", "error", err, "target_type", targetType)
+	}
+
+	for i, id := range targetIds {
+		for kind, delta := range pending[targets[i]] {
+			summaries[id].Counts[kind] += delta
+		}
+	}
+
+	for _, summary := range summaries {
+		for kind, count := range summary.Counts {
+			if count <= 0 {
+				delete(summary.Counts, kind)
+			}
+		}
+	}
+
+	if userId <= 0 {
+		return summaries, nil
+	}
+
+	reactions, err := s.reactionRepo.GetUserReactions(userId, targetType, targetIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reaction := range reactions {
+		summaries[reaction.TargetId].MyReaction = reaction.Kind
+	}
+
+	return summaries, nil
+}
+
+// FlushCounts 先写库再从Redis扣除已合并的增量，写库失败时增量保留；
+// 写库成功而扣除失败时增量会被重复合并，由RecountCounts修正
+func (s *sReactionService) FlushCounts() (int, error) {
+	targets, err := s.redisService.GetDirtyReactionTargets(kReactionFlushBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	pending, err := s.redisService.GetReactionDeltas(targets)
+	if err != nil {
+		return 0, err
+	}
+
+	flushed := 0
+	for _, target := range targets {
+		targetType, targetId, ok := sParseReactionTarget(target)
+		if !ok {
+			s.logger.Error("无法解析回应对象", "target", target)
+			continue
+		}
+
+		deltas := pending[target]
+		if err := s.reactionRepo.ApplyCountDeltas(targetType, targetId, deltas); err != nil {
+			s.logger.Error("合并回应计数失败", "error", err, "target", target)
+			continue
+		}
+
+		if err := s.redisService.AckReactionDeltas(target, deltas); err != nil {
+			s.logger.Error("扣除已合并的回应计数增量失败，等待重新统计修正",
+				"error", err, "target", target, "deltas", deltas,
+			)
+			continue
+		}
+
+		flushed++
+	}
+
+	return flushed, nil
+}
+
+func (s *sReactionService) RecountCounts() (int, error) {
+	recounted := 0
+	for _, targetType := range []string{dbstruct.REACTION_TARGET_POST, dbstruct.REACTION_TARGET_COMMENT} {
+		var afterId uint
+		for {
+			targetIds, err := s.reactionRepo.ScanReactionTargets(targetType, afterId, kReactionRecountBatch)
+			if err != nil {
+				return recounted, err
+			}
+			if len(targetIds) == 0 {
+				break
+			}
+			afterId = targetIds[len(targetIds)-1]
+
+			targets := make([]string, 0, len(targetIds))
+			for _, id := range targetIds {
+				targets = append(targets, sReactionTarget(targetType, id))
+			}
+
+			// 未合并的增量在重新统计后仍会被累加，这些对象本轮跳过
+			pending, err := s.redisService.GetReactionDeltas(targets)
+			if err != nil {
+				return recounted, err
+			}
+
+			settled := make([]uint, 0, len(targetIds))
+			for i, id := range targetIds {
+				if _, ok := pending[targets[i]]; !ok {
+					settled = append(settled, id)
+				}
+			}
+
+			if err := s.reactionRepo.RecountTargets(targetType, settled); err != nil {
+				return recounted, err
+			}
+			recounted += len(settled)
+
+			if len(targetIds) < kReactionRecountBatch {
+				break
+			}
+		}
+	}
+
+	return recounted, nil
+}
+
+func (s *sReactionService) RunFlusher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = kReactionFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	recountTicker := time.NewTicker(kReactionRecountInterval)
+	defer recountTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			flushed, err := s.FlushCounts()
+			if err != nil {
+				s.logger.Error("合并回应计数中断", "error", err)
+			}
+			if flushed > 0 {
+				s.logger.Debug("合并回应计数", "target_num", flushed)
+			}
+
+		case <-recountTicker.C:
+			recounted, err := s.RecountCounts()
+			if err != nil {
+				s.logger.Error("重新统计回应计数中断", "error", err)
+			}
+			s.logger.Debug("重新统计回应计数", "target_num", recounted)
+		}
+	}
+}
+
+func (s *sReactionService) sCheckTarget(targetType string, targetId int) error {
+	switch targetType {
+	case dbstruct.REACTION_TARGET_POST:
+		post, err := s.clubPostRepo.GetPostById(targetId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.ErrPostNotFound.Wrap(err)
+			}
+			return err
+		}
+
+		if post.Status != dbstruct.POST_STATUS_PUBLISHED {
+			return apperr.ErrPostNotFound
+		}
+
+	case dbstruct.REACTION_TARGET_COMMENT:
+		comment, err := s.postCommentRepo.GetCommentById(targetId)
+		if err != nil {
+			return sCommentError(err)
+		}
+
+		if comment.Status != dbstruct.COMMENT_STATUS_NORMAL {
+			return apperr.ErrCommentDeleted
+		}
+
+	default:
+		return apperr.ErrInvalidReaction
+	}
+
+	return nil
+}
+
+// sIncrDelta 回应已落库，计数增量写入失败只记录日志，不回滚用户操作，
+// 偏差由定期的RecountCounts修正
+func (s *sReactionService) sIncrDelta(target, kind string, delta int64) {
+	if err := s.redisService.IncrReactionDelta(target, kind, delta); err != nil {
+		s.logger.Error("累加回应计数失败",
+			"error", err, "target", target, "kind", kind, "delta", delta,
+		)
+	}
+}
+
+func sReactionTarget(targetType string, targetId uint) string {
+	return targetType + ":" + strconv.FormatUint(uint64(targetId), 10)
+}
+
+func sParseReactionTarget(target string) (string, uint, bool) {
+	targetType, strId, ok := strings.Cut(target, ":")
+	if !ok {
+		return "", 0, false
+	}
+
+	targetId, err := strconv.ParseUint(strId, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return targetType, uint(targetId), true
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"whuclubsynapse-server/internal/base_server/redisimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+)
+
+// sFakeReactionRepo 以内存保存已落库的计数，只实现合并与统计用到的方法
+type sFakeReactionRepo struct {
+	repo.ReactionRepo
+
+	counts map[string]map[string]int64
+	// reactions 回应表中各对象按kind统计的实际回应数
+	reactions map[string]map[string]int64
+
+	failTargets map[string]bool
+	onApply     func(target string)
+	recounted   []string
+}
+
+func (r *sFakeReactionRepo) ApplyCountDeltas(targetType string, targetId uint, deltas map[string]int64) error {
+	target := sReactionTarget(targetType, targetId)
+	if r.failTargets[target] {
+		return errors.New("写库失败")
+	}
+
+	if r.counts[target] == nil {
+		r.counts[target] = map[string]int64{}
+	}
+	for kind, delta := range deltas {
+		r.counts[target][kind] += delta
+	}
+
+	if r.onApply != nil {
+		r.onApply(target)
+	}
+	return nil
+}
+
+func (r *sFakeReactionRepo) GetReactionCounts(targetType string, targetIds []uint) ([]*dbstruct.ReactionCount, error) {
+	var res []*dbstruct.ReactionCount
+	for _, id := range targetIds {
+		for kind, count := range r.counts[sReactionTarget(targetType, id)] {
+			res = append(res, &dbstruct.ReactionCount{
+				TargetType: targetType, TargetId: id, Kind: kind, Count: count,
+			})
+		}
+	}
+	return res, nil
+}
+
+func (r *sFakeReactionRepo) ScanReactionTargets(targetType string, afterId uint, num int) ([]uint, error) {
+	var ids []uint
+	for target := range r.reactions {
+		if t, id, _ := sParseReactionTarget(target); t == targetType && id > afterId {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids[:min(num, len(ids))], nil
+}
+
+func (r *sFakeReactionRepo) RecountTargets(targetType string, targetIds []uint) error {
+	for _, id := range targetIds {
+		target := sReactionTarget(targetType, id)
+		r.counts[target] = maps.Clone(r.reactions[target])
+		r.recounted = append(r.recounted, target)
+	}
+	return nil
+}
+
+// sFakeRedis 按Redis脚本的语义在内存中保存计数增量
+type sFakeRedis struct {
+	redisimpl.RedisClientService
+
+	deltas  map[string]map[string]int64
+	failAck bool
+}
+
+func (r *sFakeRedis) IncrReactionDelta(target, kind string, delta int64) error {
+	if r.deltas[target] == nil {
+		r.deltas[target] = map[string]int64{}
+	}
+	r.deltas[target][kind] += delta
+	return nil
+}
+
+func (r *sFakeRedis) GetDirtyReactionTargets(num int) ([]string, error) {
+	targets := slices.Sorted(maps.Keys(r.deltas))
+	return targets[:min(num, len(targets))], nil
+}
+
+func (r *sFakeRedis) GetReactionDeltas(targets []string) (map[string]map[string]int64, error) {
+	res := make(map[string]map[string]int64)
+	for _, target := range targets {
+		deltas := map[string]int64{}
+		for kind, delta := range r.deltas[target] {
+			if delta != 0 {
+				deltas[kind] = delta
+			}
+		}
+		if len(deltas) > 0 {
+			res[target] = deltas
+		}
+	}
+	return res, nil
+}
+
+func (r *sFakeRedis) AckReactionDeltas(target string, deltas map[string]int64) error {
+	if r.failAck {
+		return errors.New("Redis不可用")
+	}
+
+	for kind, delta := range deltas {
+		r.deltas[target][kind] -= delta
+	}
+	for _, delta := range r.deltas[target] {
+		if delta != 0 {
+			return nil
+		}
+	}
+	delete(r.deltas, target)
+	return nil
+}
+
+func sNewTestReactionService(reactionRepo repo.ReactionRepo, redis redisimpl.RedisClientService) *sReactionService {
+	return NewReactionService(
+		reactionRepo, nil, nil, redis, nil,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	).(*sReactionService)
+}
+
+func TestFlushCounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		counts      map[string]map[string]int64
+		deltas      map[string]map[string]int64
+		failTargets map[string]bool
+		failAck     bool
+		// onApply 模拟写库期间有新的回应
+		onApply func(r *sFakeRedis, target string)
+
+		wantFlushed int
+		wantCounts  map[string]map[string]int64
+		wantDeltas  map[string]map[string]int64
+	}{
+		{
+			name:        "增量累加到已有计数",
+			counts:      map[string]map[string]int64{"post:1": {"like": 3}},
+			deltas:      map[string]map[string]int64{"post:1": {"like": 2, "love": 1}, "comment:2": {"wow": -1}},
+			wantFlushed: 2,
+			wantCounts:  map[string]map[string]int64{"post:1": {"like": 5, "love": 1}, "comment:2": {"wow": -1}},
+			wantDeltas:  map[string]map[string]int64{},
+		},
+		{
+			name:        "增量相互抵消时只清理待合并标记",
+			counts:      map[string]map[string]int64{},
+			deltas:      map[string]map[string]int64{"post:1": {"like": 0}},
+			wantFlushed: 1,
+			wantCounts:  map[string]map[string]int64{"post:1": {}},
+			wantDeltas:  map[string]map[string]int64{},
+		},
+		{
+			name:        "写库失败的增量保留",
+			counts:      map[string]map[string]int64{},
+			deltas:      map[string]map[string]int64{"post:1": {"like": 2}, "post:2": {"sad": 1}},
+			failTargets: map[string]bool{"post:1": true},
+			wantFlushed: 1,
+			wantCounts:  map[string]map[string]int64{"post:2": {"sad": 1}},
+			wantDeltas:  map[string]map[string]int64{"post:1": {"like": 2}},
+		},
+		{
+			name:    "写库期间的新增量留到下次合并",
+			counts:  map[string]map[string]int64{},
+			deltas:  map[string]map[string]int64{"post:1": {"like": 2}},
+			onApply: func(r *sFakeRedis, target string) { r.IncrReactionDelta(target, "like", 1) },
+
+			wantFlushed: 1,
+			wantCounts:  map[string]map[string]int64{"post:1": {"like": 2}},
+			wantDeltas:  map[string]map[string]int64{"post:1": {"like": 1}},
+		},
+		{
+			name:        "扣除增量失败时保留增量",
+			counts:      map[string]map[string]int64{},
+			deltas:      map[string]map[string]int64{"post:1": {"like": 2}},
+			failAck:     true,
+			wantFlushed: 0,
+			wantCounts:  map[string]map[string]int64{"post:1": {"like": 2}},
+			wantDeltas:  map[string]map[string]int64{"post:1": {"like": 2}},
+		},
+		{
+			name:        "无法解析的对象跳过",
+			counts:      map[string]map[string]int64{},
+			deltas:      map[string]map[string]int64{"bad": {"like": 1}},
+			wantFlushed: 0,
+			wantCounts:  map[string]map[string]int64{},
+			wantDeltas:  map[string]map[string]int64{"bad": {"like": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := &sFakeRedis{deltas: tt.deltas, failAck: tt.failAck}
+			reactionRepo := &sFakeReactionRepo{counts: tt.counts, failTargets: tt.failTargets}
+			if tt.onApply != nil {
+				reactionRepo.onApply = func(target string) { tt.onApply(redis, target) }
+			}
+
+			flushed, err := sNewTestReactionService(reactionRepo, redis).FlushCounts()
+			if err != nil {
+				t.Fatalf("FlushCounts() error = %v", err)
+			}
+
+			if flushed != tt.wantFlushed {
+				t.Errorf("FlushCounts() = %d, want %d", flushed, tt.wantFlushed)
+			}
+			if !sEqualCounts(reactionRepo.counts, tt.wantCounts) {
+				t.Errorf("落库计数 = %v, want %v", reactionRepo.counts, tt.wantCounts)
+			}
+			if !sEqualCounts(redis.deltas, tt.wantDeltas) {
+				t.Errorf("剩余增量 = %v, want %v", redis.deltas, tt.wantDeltas)
+			}
+		})
+	}
+}
+
+func TestGetSummariesMergesPendingDeltas(t *testing.T) {
+	reactionRepo := &sFakeReactionRepo{counts: map[string]map[string]int64{
+		"post:1": {"like": 3, "sad": 1},
+		"post:2": {"love": 1},
+	}}
+	redis := &sFakeRedis{deltas: map[string]map[string]int64{
+		"post:1": {"like": 2, "sad": -1},
+		"post:3": {"wow": 1},
+	}}
+
+	summaries, err := sNewTestReactionService(reactionRepo, redis).
+		GetSummaries(0, dbstruct.REACTION_TARGET_POST, []uint{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("GetSummaries() error = %v", err)
+	}
+
+	want := map[uint]map[string]int64{
+		1: {"like": 5},
+		2: {"love": 1},
+		3: {"wow": 1},
+		4: {},
+	}
+	for id, counts := range want {
+		if got := summaries[id].Counts; !maps.Equal(got, counts) {
+			t.Errorf("summaries[%d].Counts = %v, want %v", id, got, counts)
+		}
+	}
+}
+
+func TestRecountCountsSkipsPendingTargets(t *testing.T) {
+	reactionRepo := &sFakeReactionRepo{
+		counts: map[string]map[string]int64{
+			"post:1":    {"like": 7},
+			"post:2":    {"like": 1},
+			"comment:1": {"wow": 2},
+		},
+		reactions: map[string]map[string]int64{
+			"post:1":    {"like": 5},
+			"post:2":    {"like": 2},
+			"comment:1": {"wow": 1},
+		},
+	}
+	redis := &sFakeRedis{deltas: map[string]map[string]int64{
+		"post:2": {"like": 1},
+	}}
+
+	recounted, err := sNewTestReactionService(reactionRepo, redis).RecountCounts()
+	if err != nil {
+		t.Fatalf("RecountCounts() error = %v", err)
+	}
+
+	if recounted != 2 {
+		t.Errorf("RecountCounts() = %d, want 2", recounted)
+	}
+
+	wantCounts := map[string]map[string]int64{
+		"post:1":    {"like": 5},
+		"post:2":    {"like": 1},
+		"comment:1": {"wow": 1},
+	}
+	if !sEqualCounts(reactionRepo.counts, wantCounts) {
+		t.Errorf("落库计数 = %v, want %v", reactionRepo.counts, wantCounts)
+	}
+}
+
+func TestParseReactionTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantType string
+		wantId   uint
+		wantOk   bool
+	}{
+		{"post:12", dbstruct.REACTION_TARGET_POST, 12, true},
+		{"comment:3", dbstruct.REACTION_TARGET_COMMENT, 3, true},
+		{sReactionTarget(dbstruct.REACTION_TARGET_POST, 42), dbstruct.REACTION_TARGET_POST, 42, true},
+		{"post", "", 0, false},
+		{"post:", "", 0, false},
+		{"post:-1", "", 0, false},
+		{"post:abc", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			targetType, targetId, ok := sParseReactionTarget(tt.target)
+			if targetType != tt.wantType || targetId != tt.wantId || ok != tt.wantOk {
+				t.Errorf("sParseReactionTarget(%q) = (%q, %d, %v), want (%q, %d, %v)",
+					tt.target, targetType, targetId, ok, tt.wantType, tt.wantId, tt.wantOk,
+				)
+			}
+		})
+	}
+}
+
+func sEqualCounts(got, want map[string]map[string]int64) bool {
+	return maps.EqualFunc(got, want, func(a, b map[string]int64) bool {
+		return maps.Equal(a, b)
+	})
+}
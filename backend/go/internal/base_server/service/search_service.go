@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/esimpl"
+	"whuclubsynapse-server/internal/base_server/repo"
+	"whuclubsynapse-server/internal/base_server/storage"
+	"whuclubsynapse-server/internal/shared/dbstruct"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ES默认只允许翻到前10000条结果
+	kSearchMaxWindow = 10000
+
+	kSearchSyncInterval    = 5 * time.Second
+	kSearchSyncBatch       = 100
+	kSearchSyncLease       = time.Minute // 取出的事件在此期间内不会被其他实例重复处理
+	kSearchSyncMaxAttempts = 10
+	kSearchSyncBackoffBase = 5 * time.Second
+	kSearchSyncBackoffMax  = 10 * time.Minute
+	// 已完成的事件保留时长，重建索引期间产生的变更据此重放
+	kSearchOutboxRetention = 24 * time.Hour
+)
+
+type SearchService interface {
+	SearchClubs(query *esimpl.ClubQuery) (*esimpl.SearchResult[esimpl.ClubDoc], error)
+	SearchPosts(query *esimpl.PostQuery) (*esimpl.SearchResult[esimpl.PostDoc], error)
+
+	// 以下方法在调用方的事务中写入索引同步事件，事务提交后由RunSyncWorker应用到ES
+	IndexClub(tx *gorm.DB, clubId uint) error
+	// RemoveClub 同时移除社团的全部帖子
+	RemoveClub(tx *gorm.DB, clubId uint) error
+	// IndexPost 帖子未发布、非公开或已删除时从索引中移除
+	IndexPost(tx *gorm.DB, postId uint) error
+	RemovePost(tx *gorm.DB, postId uint) error
+
+	// RunSyncWorker 按interval周期处理到期的同步事件，直到ctx结束；interval未配置时使用默认周期
+	RunSyncWorker(ctx context.Context, interval time.Duration)
+}
+
+type sSearchService struct {
+	clubRepo         repo.ClubRepo
+	clubPostRepo     repo.ClubPostRepo
+	searchOutboxRepo repo.SearchOutboxRepo
+
+	searchClient esimpl.SearchClientService
+	blobStore    storage.BlobStore
+
+	txCoordinator repo.TransactionCoordinator
+
+	logger *slog.Logger
+}
+
+func NewSearchService(
+	clubRepo repo.ClubRepo,
+	clubPostRepo repo.ClubPostRepo,
+	searchOutboxRepo repo.SearchOutboxRepo,
+
+	searchClient esimpl.SearchClientService,
+	blobStore storage.BlobStore,
+
+	txCoordinator repo.TransactionCoordinator,
+
+	logger *slog.Logger,
+) SearchService {
+	return &sSearchService{
+		clubRepo:         clubRepo,
+		clubPostRepo:     clubPostRepo,
+		searchOutboxRepo: searchOutboxRepo,
+
+		searchClient: searchClient,
+		blobStore:    blobStore,
+
+		txCoordinator: txCoordinator,
+
+		logger: logger,
+	}
+}
+
+func (s *sSearchService) SearchClubs(query *esimpl.ClubQuery) (*esimpl.SearchResult[esimpl.ClubDoc], error) {
+	if err := sCheckSearchWindow(query.From, query.Size); err != nil {
+		return nil, err
+	}
+
+	result, err := s.searchClient.SearchClubs(query)
+	if err != nil {
+		return nil, apperr.ErrServiceUnavailable.WithMessage("搜索服务暂不可用").Wrap(err)
+	}
+
+	return result, nil
+}
+
+func (s *sSearchService) SearchPosts(query *esimpl.PostQuery) (*esimpl.SearchResult[esimpl.PostDoc], error) {
+	if err := sCheckSearchWindow(query.From, query.Size); err != nil {
+		return nil, err
+	}
+
+	result, err := s.searchClient.SearchPosts(query)
+	if err != nil {
+		return nil, apperr.ErrServiceUnavailable.WithMessage("搜索服务暂不可用").Wrap(err)
+	}
+
+	return result, nil
+}
+
+func sCheckSearchWindow(from, size int) error {
+	if from < 0 || size <= 0 || from+size > kSearchMaxWindow {
+		return apperr.ErrBadRequest.WithMessage("搜索结果最多翻到第10000条")
+	}
+	return nil
+}
+
+func (s *sSearchService) IndexClub(tx *gorm.DB, clubId uint) error {
+	return s.sEnqueue(tx, dbstruct.SEARCH_ENTITY_CLUB, clubId, dbstruct.SEARCH_OP_UPSERT)
+}
+
+func (s *sSearchService) RemoveClub(tx *gorm.DB, clubId uint) error {
+	return s.sEnqueue(tx, dbstruct.SEARCH_ENTITY_CLUB, clubId, dbstruct.SEARCH_OP_DELETE)
+}
+
+func (s *sSearchService) IndexPost(tx *gorm.DB, postId uint) error {
+	return s.sEnqueue(tx, dbstruct.SEARCH_ENTITY_POST, postId, dbstruct.SEARCH_OP_UPSERT)
+}
+
+func (s *sSearchService) RemovePost(tx *gorm.DB, postId uint) error {
+	return s.sEnqueue(tx, dbstruct.SEARCH_ENTITY_POST, postId, dbstruct.SEARCH_OP_DELETE)
+}
+
+func (s *sSearchService) sEnqueue(tx *gorm.DB, entity string, entityId uint, op string) error {
+	return s.searchOutboxRepo.AddEvent(tx, &dbstruct.SearchOutbox{
+		Entity:        entity,
+		EntityId:      entityId,
+		Op:            op,
+		Status:        dbstruct.OUTBOX_STATUS_PENDING,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+func (s *sSearchService) RunSyncWorker(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = kSearchSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			s.sSyncOnce()
+
+			if _, err := s.searchOutboxRepo.PurgeDone(time.Now().Add(-kSearchOutboxRetention)); err != nil {
+				s.logger.Error("清理已完成的索引同步事件失败", "error", err)
+			}
+		}
+	}
+}
+
+func (s *sSearchService) sSyncOnce() {
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var events []*dbstruct.SearchOutbox
+	err := s.txCoordinator.RunInTransaction(ctxTmt, func(tx *gorm.DB) error {
+		var err error
+		events, err = s.searchOutboxRepo.ClaimDueEvents(tx, time.Now(), kSearchSyncLease, kSearchSyncBatch)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("读取索引同步事件失败", "error", err)
+		return
+	}
+
+	for _, event := range events {
+		s.sHandleEvent(event)
+	}
+}
+
+// sHandleEvent 失败时按指数退避重试，次数耗尽后标记为失败，等待重建索引时修正
+func (s *sSearchService) sHandleEvent(event *dbstruct.SearchOutbox) {
+	now := time.Now()
+
+	err := s.sApply(event)
+	if err == nil {
+		if err := s.searchOutboxRepo.MarkDone(event.OutboxId, now); err != nil {
+			s.logger.Error("标记索引同步事件完成失败", "error", err, "outbox_id", event.OutboxId)
+		}
+		return
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= kSearchSyncMaxAttempts {
+		s.logger.Error("索引同步事件重试次数耗尽",
+			"error", err, "outbox_id", event.OutboxId,
+			"entity", event.Entity, "entity_id", event.EntityId,
+		)
+
+		if err := s.searchOutboxRepo.MarkFailed(event.OutboxId, attempts, err.Error()); err != nil {
+			s.logger.Error("标记索引同步事件失败状态失败", "error", err, "outbox_id", event.OutboxId)
+		}
+		return
+	}
+
+	backoff := min(kSearchSyncBackoffBase<<(attempts-1), kSearchSyncBackoffMax)
+	s.logger.Warn("索引同步失败，稍后重试",
+		"error", err, "outbox_id", event.OutboxId, "attempts", attempts, "backoff", backoff,
+	)
+
+	if err := s.searchOutboxRepo.MarkRetry(event.OutboxId, attempts, now.Add(backoff), err.Error()); err != nil {
+		s.logger.Error("更新索引同步事件失败", "error", err, "outbox_id", event.OutboxId)
+	}
+}
+
+// sApply 写入时总是读取数据库中的最新状态，因此同一对象的多个事件以任意顺序应用结果一致
+func (s *sSearchService) sApply(event *dbstruct.SearchOutbox) error {
+	switch event.Entity {
+	case dbstruct.SEARCH_ENTITY_CLUB:
+		if event.Op == dbstruct.SEARCH_OP_DELETE {
+			return s.searchClient.DeleteClub(event.EntityId)
+		}
+
+		club, err := s.clubRepo.GetClubInfo(int(event.EntityId))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.searchClient.DeleteClub(event.EntityId)
+		}
+		if err != nil {
+			return err
+		}
+
+		return s.searchClient.IndexClub(esimpl.NewClubDoc(club))
+
+	case dbstruct.SEARCH_ENTITY_POST:
+		if event.Op == dbstruct.SEARCH_OP_DELETE {
+			return s.searchClient.DeletePost(event.EntityId)
+		}
+
+		post, err := s.clubPostRepo.GetPostById(int(event.EntityId))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.searchClient.DeletePost(event.EntityId)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !esimpl.IsPostSearchable(post) {
+			return s.searchClient.DeletePost(event.EntityId)
+		}
+
+		content, err := s.sGetContent(post.ContentUrl)
+		if err != nil {
+			return err
+		}
+
+		return s.searchClient.IndexPost(esimpl.NewPostDoc(post, string(content)))
+
+	default:
+		return errors.New("未知的索引同步对象：" + event.Entity)
+	}
+}
+
+func (s *sSearchService) sGetContent(contentUrl string) ([]byte, error) {
+	key, ok := s.blobStore.KeyOf(contentUrl)
+	if !ok {
+		return nil, errors.New("无效的帖子内容地址：" + contentUrl)
+	}
+
+	ctxTmt, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.blobStore.Get(ctxTmt, key)
+}
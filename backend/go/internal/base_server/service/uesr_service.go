@@ -2,10 +2,13 @@ package service
 
 import (
 	"errors"
+	"whuclubsynapse-server/internal/base_server/apperr"
+	"whuclubsynapse-server/internal/base_server/model"
 	"whuclubsynapse-server/internal/base_server/repo"
 	"whuclubsynapse-server/internal/shared/dbstruct"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserService interface {
@@ -13,9 +16,15 @@ type UserService interface {
 	Register(username, email, passwordHash string) (*dbstruct.User, error)
 	GetUserById(id int) (*dbstruct.User, error)
 	GetUserList(offset int, num int) ([]*dbstruct.User, error)
+	GetUserListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.User], error)
 	KeepUserActive(id int, role string) error
-	UpdateAvatar(id int, avatarUrl string) error
+	UpdateAvatar(id int, variants model.ImageVariants) error
 	UpdateUser(newUser *dbstruct.User) error
+
+	GetUserByEmail(email string) (*dbstruct.User, error)
+	// ResetPassword 调用方需先校验找回密码验证码
+	ResetPassword(email, newPassword string) (*dbstruct.User, error)
+	ChangePassword(id int, oldPassword, newPassword string) error
 }
 
 type sUserService struct {
@@ -63,6 +72,9 @@ func (s *sUserService) Register(username, email, passwordHash string) (*dbstruct
 func (s *sUserService) GetUserById(id int) (*dbstruct.User, error) {
 	userModel, err := s.UserRepo.GetUserById(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrUserNotFound.Wrap(err)
+		}
 		return nil, err
 	}
 
@@ -78,6 +90,10 @@ func (s *sUserService) GetUserList(offset int, num int) ([]*dbstruct.User, error
 	return userModelList, nil
 }
 
+func (s *sUserService) GetUserListByCursor(cursor *model.Cursor, num int) (*model.Page[*dbstruct.User], error) {
+	return s.UserRepo.GetUserListByCursor(cursor, num)
+}
+
 func (s *sUserService) KeepUserActive(id int, role string) error {
 	userModel, err := s.UserRepo.GetUserById(id)
 	if err != nil {
@@ -85,7 +101,7 @@ func (s *sUserService) KeepUserActive(id int, role string) error {
 	}
 
 	if userModel.Role != role {
-		return errors.New("用户身份错误")
+		return apperr.ErrUnauthorized.WithMessage("用户身份错误")
 	}
 
 	err = s.UserRepo.UpdateUserLastActive(id)
@@ -96,14 +112,76 @@ func (s *sUserService) KeepUserActive(id int, role string) error {
 	return nil
 }
 
-func (s *sUserService) UpdateAvatar(id int, avatarUrl string) error {
-	return s.UserRepo.UpdateAvatar(id, avatarUrl)
+func (s *sUserService) UpdateAvatar(id int, variants model.ImageVariants) error {
+	variantsJson, err := variants.ToJsonb()
+	if err != nil {
+		return err
+	}
+
+	return s.UserRepo.UpdateAvatar(id, variants.Default(), variantsJson)
 }
 
 func (s *sUserService) UpdateUser(newUser *dbstruct.User) error {
 	if newUser.UserId <= 0 {
-		return errors.New("无效用户ID")
+		return apperr.ErrBadRequest.WithMessage("无效用户ID")
 	}
 
 	return s.UserRepo.UpdateUser(newUser)
 }
+
+func (s *sUserService) GetUserByEmail(email string) (*dbstruct.User, error) {
+	userModel, err := s.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.ErrUserNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+
+	return userModel, nil
+}
+
+func (s *sUserService) ResetPassword(email, newPassword string) (*dbstruct.User, error) {
+	userModel, err := s.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sUpdatePassword(int(userModel.UserId), newPassword); err != nil {
+		return nil, err
+	}
+
+	return userModel, nil
+}
+
+func (s *sUserService) ChangePassword(id int, oldPassword, newPassword string) error {
+	userModel, err := s.GetUserById(id)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(
+		[]byte(userModel.PasswordHash),
+		[]byte(oldPassword),
+	); err != nil {
+		return apperr.ErrWrongCredentials.WithMessage("原密码错误")
+	}
+
+	return s.sUpdatePassword(id, newPassword)
+}
+
+func (s *sUserService) sUpdatePassword(id int, newPassword string) error {
+	if newPassword == "" {
+		return apperr.ErrBadRequest.WithMessage("新密码不能为空")
+	}
+
+	hashedBytes, err := bcrypt.GenerateFromPassword(
+		[]byte(newPassword),
+		bcrypt.DefaultCost,
+	)
+	if err != nil {
+		return apperr.ErrBadRequest.WithMessage("密码无效").Wrap(err)
+	}
+
+	return s.UserRepo.UpdatePasswordHash(id, string(hashedBytes))
+}
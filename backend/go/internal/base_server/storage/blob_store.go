@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("对象不存在")
+	ErrInvalidKey = errors.New("无效的对象键")
+)
+
+// BlobStore 上传文件的对象存储。对象键形如 prefix/<内容SHA-256><ext>，
+// 内容相同的对象只保存一份，因此对象一经写入不会被覆盖
+type BlobStore interface {
+	// Put 保存内容并返回对象键，对象已存在时直接返回
+	Put(ctx context.Context, prefix, ext string, data []byte, contentType string) (string, error)
+	// Get 读取对象内容，对象不存在时返回ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+
+	// URL 对象的公开访问地址
+	URL(key string) string
+	// KeyOf 由公开访问地址反解出对象键，地址不属于该存储时返回false
+	KeyOf(url string) (string, bool)
+}
+
+// ContentKey 按内容计算对象键
+func ContentKey(prefix, ext string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return path.Join(prefix, hex.EncodeToString(sum[:])+ext)
+}
+
+// sCheckKey 拒绝绝对路径及跳出存储根目录的键
+func sCheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return ErrInvalidKey
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package storage
+
+import "whuclubsynapse-server/internal/base_server/baseconfig"
+
+// NewBlobStore 按配置创建存储后端。未配置后端时使用本地目录，
+// 与此前直接写入pub/目录的文件布局一致；S3模式下迁移前上传的文件仍从本地目录读取与删除
+func NewBlobStore(cfg *baseconfig.Config) (BlobStore, error) {
+	if cfg.BlobLocalRoot == "" {
+		cfg.BlobLocalRoot = "pub/"
+	}
+
+	if cfg.BlobBackend == "s3" {
+		store, err := NewS3Store(S3Config{
+			Endpoint:   cfg.BlobS3Endpoint,
+			Region:     cfg.BlobS3Region,
+			Bucket:     cfg.BlobS3Bucket,
+			AccessKey:  cfg.BlobS3AccessKey,
+			SecretKey:  cfg.BlobS3SecretKey,
+			PathStyle:  cfg.BlobS3PathStyle,
+			PublicBase: cfg.BlobPublicBase,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// 迁移前的文件地址为/pub/...或pub/...，由静态文件路由对外提供
+		return NewMigratingStore(store, NewLocalStore(cfg.BlobLocalRoot, "/pub/")), nil
+	}
+
+	// 与静态文件路由一致，地址不依赖页面所在路径
+	if cfg.BlobPublicBase == "" {
+		cfg.BlobPublicBase = "/pub/"
+	}
+
+	return NewLocalStore(cfg.BlobLocalRoot, cfg.BlobPublicBase), nil
+}
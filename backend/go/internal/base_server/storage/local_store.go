@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type sLocalStore struct {
+	root       string
+	publicBase string
+}
+
+// NewLocalStore 对象保存在root目录下，由本服务以静态文件形式对外提供；
+// publicBase为对象键之前的访问路径前缀
+func NewLocalStore(root, publicBase string) BlobStore {
+	return &sLocalStore{
+		root:       root,
+		publicBase: publicBase,
+	}
+}
+
+func (s *sLocalStore) Put(_ context.Context, prefix, ext string, data []byte, _ string) (string, error) {
+	key := ContentKey(prefix, ext, data)
+
+	filePath := filepath.Join(s.root, filepath.FromSlash(key))
+	if _, err := os.Stat(filePath); err == nil {
+		return key, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	// 先写临时文件再改名，避免读取到写了一半的对象
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (s *sLocalStore) Get(_ context.Context, key string) ([]byte, error) {
+	if err := sCheckKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (s *sLocalStore) Delete(_ context.Context, key string) error {
+	if err := sCheckKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(s.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *sLocalStore) URL(key string) string {
+	return s.publicBase + key
+}
+
+// KeyOf 早期保存的地址为相对路径pub/...，与/pub/...视为同一对象
+func (s *sLocalStore) KeyOf(url string) (string, bool) {
+	key, ok := strings.CutPrefix(strings.TrimPrefix(url, "/"), strings.TrimPrefix(s.publicBase, "/"))
+	if !ok || sCheckKey(key) != nil {
+		return "", false
+	}
+
+	return key, true
+}
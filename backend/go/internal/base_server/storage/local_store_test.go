@@ -0,0 +1,35 @@
+package storage
+
+import "testing"
+
+func TestLocalStoreKeyOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		publicBase string
+		url        string
+		wantKey    string
+		wantOk     bool
+	}{
+		{"当前地址", "/pub/", "/pub/post_files/a.md", "post_files/a.md", true},
+		{"早期相对地址", "/pub/", "pub/post_files/a.md", "post_files/a.md", true},
+		{"前缀为相对路径", "pub/", "/pub/user_avatars/b.png", "user_avatars/b.png", true},
+		{"其他路径", "/pub/", "/static/a.md", "", false},
+		{"外部地址", "/pub/", "https://cdn.example.com/pub/a.md", "", false},
+		{"跳出存储目录", "/pub/", "/pub/../config.json", "", false},
+		{"只有前缀", "/pub/", "/pub/", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := NewLocalStore(t.TempDir(), tt.publicBase).KeyOf(tt.url)
+			if key != tt.wantKey || ok != tt.wantOk {
+				t.Errorf("KeyOf(%q) = (%q, %v), want (%q, %v)", tt.url, key, ok, tt.wantKey, tt.wantOk)
+			}
+		})
+	}
+
+	store := NewLocalStore(t.TempDir(), "/pub/")
+	if key, ok := store.KeyOf(store.URL("club_logos/c.png")); key != "club_logos/c.png" || !ok {
+		t.Errorf("KeyOf(URL(key)) = (%q, %v), want (%q, true)", key, ok, "club_logos/c.png")
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"strings"
+)
+
+// kLegacyKeyPrefix 迁移前对象的键前缀，Put生成的键以内容类别开头，不会与之冲突
+const kLegacyKeyPrefix = "_legacy/"
+
+type sMigratingStore struct {
+	BlobStore
+	legacy BlobStore
+}
+
+// NewMigratingStore 新对象写入store；切换存储后端前保存的对象仍由legacy读取与删除，
+// 其地址不属于store，由KeyOf返回带kLegacyKeyPrefix前缀的键加以区分
+func NewMigratingStore(store, legacy BlobStore) BlobStore {
+	return &sMigratingStore{
+		BlobStore: store,
+		legacy:    legacy,
+	}
+}
+
+func (s *sMigratingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if legacyKey, ok := strings.CutPrefix(key, kLegacyKeyPrefix); ok {
+		return s.legacy.Get(ctx, legacyKey)
+	}
+
+	return s.BlobStore.Get(ctx, key)
+}
+
+func (s *sMigratingStore) Delete(ctx context.Context, key string) error {
+	if legacyKey, ok := strings.CutPrefix(key, kLegacyKeyPrefix); ok {
+		return s.legacy.Delete(ctx, legacyKey)
+	}
+
+	return s.BlobStore.Delete(ctx, key)
+}
+
+func (s *sMigratingStore) URL(key string) string {
+	if legacyKey, ok := strings.CutPrefix(key, kLegacyKeyPrefix); ok {
+		return s.legacy.URL(legacyKey)
+	}
+
+	return s.BlobStore.URL(key)
+}
+
+func (s *sMigratingStore) KeyOf(url string) (string, bool) {
+	if key, ok := s.BlobStore.KeyOf(url); ok {
+		return key, true
+	}
+
+	if key, ok := s.legacy.KeyOf(url); ok {
+		return kLegacyKeyPrefix + key, true
+	}
+
+	return "", false
+}
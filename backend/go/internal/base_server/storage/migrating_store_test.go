@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigratingStore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "post_files"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "post_files", "old.md"), []byte("旧帖子"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s3, _ := sNewTestS3Store(t, "https://cdn.example.com")
+	store := NewMigratingStore(s3, NewLocalStore(root, "/pub/"))
+	ctx := context.Background()
+
+	newKey, err := store.Put(ctx, "post_files", ".md", []byte("新帖子"), "text/markdown")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		wantKey  string
+		wantData string
+	}{
+		{"新对象", "https://cdn.example.com/" + newKey, newKey, "新帖子"},
+		{"旧相对地址", "pub/post_files/old.md", kLegacyKeyPrefix + "post_files/old.md", "旧帖子"},
+		{"旧绝对地址", "/pub/post_files/old.md", kLegacyKeyPrefix + "post_files/old.md", "旧帖子"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := store.KeyOf(tt.url)
+			if !ok || key != tt.wantKey {
+				t.Fatalf("KeyOf(%q) = (%q, %v), want (%q, true)", tt.url, key, ok, tt.wantKey)
+			}
+
+			data, err := store.Get(ctx, key)
+			if err != nil || string(data) != tt.wantData {
+				t.Errorf("Get(%q) = (%q, %v), want (%q, nil)", key, data, err, tt.wantData)
+			}
+		})
+	}
+
+	if got := store.URL(kLegacyKeyPrefix + "post_files/old.md"); got != "/pub/post_files/old.md" {
+		t.Errorf("旧对象URL = %q, want %q", got, "/pub/post_files/old.md")
+	}
+
+	if _, ok := store.KeyOf("https://other.example.com/a.md"); ok {
+		t.Errorf("其他地址不应属于该存储")
+	}
+
+	if err := store.Delete(ctx, kLegacyKeyPrefix+"post_files/old.md"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "post_files", "old.md")); !os.IsNotExist(err) {
+		t.Errorf("旧对象未从本地目录删除")
+	}
+}
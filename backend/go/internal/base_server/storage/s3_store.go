@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	kS3Service   = "s3"
+	kS3Algorithm = "AWS4-HMAC-SHA256"
+	// 不含正文的请求使用空内容的哈希
+	kS3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Config 兼容S3协议的对象存储，MinIO等自建服务需开启PathStyle
+type S3Config struct {
+	Endpoint  string // 如 https://s3.amazonaws.com 或 http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	// 对象公开访问地址的前缀，为空时使用存储服务地址
+	PublicBase string
+}
+
+type sS3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Store(cfg S3Config) (BlobStore, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3地址：%s", cfg.Endpoint)
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	store := &sS3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if store.cfg.PublicBase == "" {
+		store.cfg.PublicBase = store.sObjectURL("").String()
+	}
+
+	return store, nil
+}
+
+func (s *sS3Store) Put(ctx context.Context, prefix, ext string, data []byte, contentType string) (string, error) {
+	key := ContentKey(prefix, ext, data)
+
+	resp, err := s.sDo(ctx, http.MethodHead, key, nil, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return key, nil
+	}
+
+	resp, err = s.sDo(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", sS3Error(resp)
+	}
+
+	return key, nil
+}
+
+func (s *sS3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := sCheckKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.sDo(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, sS3Error(resp)
+	}
+}
+
+func (s *sS3Store) Delete(ctx context.Context, key string) error {
+	if err := sCheckKey(key); err != nil {
+		return err
+	}
+
+	resp, err := s.sDo(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return sS3Error(resp)
+	}
+}
+
+func (s *sS3Store) URL(key string) string {
+	return strings.TrimSuffix(s.cfg.PublicBase, "/") + "/" + key
+}
+
+func (s *sS3Store) KeyOf(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, strings.TrimSuffix(s.cfg.PublicBase, "/")+"/")
+	if !ok || sCheckKey(key) != nil {
+		return "", false
+	}
+
+	return key, true
+}
+
+// sObjectURL 路径风格为 endpoint/bucket/key，否则为 bucket.endpoint/key
+func (s *sS3Store) sObjectURL(key string) *url.URL {
+	objectURL := *s.endpoint
+	basePath := strings.TrimSuffix(objectURL.Path, "/")
+
+	if s.cfg.PathStyle {
+		objectURL.Path = basePath + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		objectURL.Host = s.cfg.Bucket + "." + objectURL.Host
+		objectURL.Path = basePath + "/" + key
+	}
+
+	return &objectURL
+}
+
+func (s *sS3Store) sDo(
+	ctx context.Context,
+	method, key string,
+	body []byte,
+	contentType string,
+) (*http.Response, error) {
+	objectURL := s.sObjectURL(key)
+
+	var reader io.Reader
+	payloadHash := kS3EmptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	s.sSign(req, objectURL, payloadHash, time.Now().UTC())
+
+	return s.client.Do(req)
+}
+
+// sSign 按AWS Signature Version 4为请求签名，只签名host与x-amz-*头
+func (s *sS3Store) sSign(req *http.Request, objectURL *url.URL, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		objectURL.EscapedPath(),
+		"",
+		"host:" + objectURL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/" + kS3Service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := kS3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := sHmac([]byte("AWS4"+s.cfg.SecretKey), date)
+	signingKey = sHmac(signingKey, s.cfg.Region)
+	signingKey = sHmac(signingKey, kS3Service)
+	signingKey = sHmac(signingKey, "aws4_request")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		kS3Algorithm, s.cfg.AccessKey, scope, signedHeaders,
+		hex.EncodeToString(sHmac(signingKey, stringToSign)),
+	))
+}
+
+func sHmac(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sS3Error(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3请求失败：%s %s", resp.Status, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	kTestS3AccessKey = "AKIDEXAMPLE"
+	kTestS3SecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	kTestS3Bucket    = "whuclubsynapse"
+)
+
+// sFakeS3 在内存中保存对象，校验每个请求的SigV4签名
+type sFakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	methods []string
+}
+
+func (f *sFakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := sVerifySigV4(r, body); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+kTestS3Bucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.methods = append(f.methods, r.Method)
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		f.objects[key] = body
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// sVerifySigV4 按服务端收到的请求独立计算签名
+func sVerifySigV4(r *http.Request, body []byte) error {
+	sum := sha256.Sum256(body)
+	if got := r.Header.Get("x-amz-content-sha256"); got != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("x-amz-content-sha256 = %s", got)
+	}
+
+	amzDate := r.Header.Get("x-amz-date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return fmt.Errorf("x-amz-date = %s", amzDate)
+	}
+	scope := amzDate[:8] + "/us-east-1/s3/aws4_request"
+
+	canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + r.Header.Get("x-amz-content-sha256") + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		r.Header.Get("x-amz-content-sha256")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	key := []byte("AWS4" + kTestS3SecretKey)
+	for _, part := range []string{amzDate[:8], "us-east-1", "s3", "aws4_request"} {
+		key = mac(key, part)
+	}
+	signature := hex.EncodeToString(mac(key,
+		"AWS4-HMAC-SHA256\n"+amzDate+"\n"+scope+"\n"+hex.EncodeToString(canonicalHash[:]),
+	))
+
+	want := "AWS4-HMAC-SHA256 Credential=" + kTestS3AccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + signature
+	if got := r.Header.Get("Authorization"); got != want {
+		return fmt.Errorf("Authorization = %s, want %s", got, want)
+	}
+
+	return nil
+}
+
+func sNewTestS3Store(t *testing.T, publicBase string) (BlobStore, *sFakeS3) {
+	t.Helper()
+
+	fake := &sFakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:   server.URL,
+		Bucket:     kTestS3Bucket,
+		AccessKey:  kTestS3AccessKey,
+		SecretKey:  kTestS3SecretKey,
+		PathStyle:  true,
+		PublicBase: publicBase,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store() error = %v", err)
+	}
+
+	return store, fake
+}
+
+func TestS3SignMatchesReferenceVector(t *testing.T) {
+	store, err := NewS3Store(S3Config{
+		Endpoint:  "http://localhost:9000",
+		Bucket:    kTestS3Bucket,
+		AccessKey: kTestS3AccessKey,
+		SecretKey: kTestS3SecretKey,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store() error = %v", err)
+	}
+	s3 := store.(*sS3Store)
+
+	objectURL := s3.sObjectURL("post_files/ab.md")
+	req, _ := http.NewRequest(http.MethodPut, objectURL.String(), strings.NewReader("hello"))
+	s3.sSign(req, objectURL,
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+	)
+
+	// 参考签名由独立的SigV4实现按相同参数算出
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20250601/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=c70e97d5461b1ec638ca353a6b5e0b8747479196222f6a012662f168a6da1fde"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestS3StoreRequests(t *testing.T) {
+	store, fake := sNewTestS3Store(t, "")
+	ctx := context.Background()
+
+	key, err := store.Put(ctx, "post_files", ".md", []byte("hello"), "text/markdown")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if want := ContentKey("post_files", ".md", []byte("hello")); key != want {
+		t.Errorf("Put() = %q, want %q", key, want)
+	}
+
+	// 内容相同的对象已存在，不再上传
+	if _, err := store.Put(ctx, "post_files", ".md", []byte("hello"), "text/markdown"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	data, err := store.Get(ctx, key)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Get() = (%q, %v), want (%q, nil)", data, err, "hello")
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后Get() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("删除不存在的对象 error = %v", err)
+	}
+
+	wantMethods := []string{"HEAD", "PUT", "HEAD", "GET", "DELETE", "GET", "DELETE"}
+	if got := strings.Join(fake.methods, ","); got != strings.Join(wantMethods, ",") {
+		t.Errorf("请求序列 = %s, want %s", got, strings.Join(wantMethods, ","))
+	}
+
+	if _, err := store.Get(ctx, "../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get(../secret) error = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestS3StoreKeyOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		publicBase string
+		url        string
+		wantKey    string
+		wantOk     bool
+	}{
+		{"CDN地址", "https://cdn.example.com/files", "https://cdn.example.com/files/post_files/a.md", "post_files/a.md", true},
+		{"CDN地址带斜杠", "https://cdn.example.com/files/", "https://cdn.example.com/files/post_files/a.md", "post_files/a.md", true},
+		{"其他域名", "https://cdn.example.com/files", "https://other.example.com/files/post_files/a.md", "", false},
+		{"本地旧地址", "https://cdn.example.com/files", "pub/post_files/a.md", "", false},
+		{"跳出前缀", "https://cdn.example.com/files", "https://cdn.example.com/files/../a.md", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := sNewTestS3Store(t, tt.publicBase)
+
+			key, ok := store.KeyOf(tt.url)
+			if key != tt.wantKey || ok != tt.wantOk {
+				t.Errorf("KeyOf(%q) = (%q, %v), want (%q, %v)", tt.url, key, ok, tt.wantKey, tt.wantOk)
+			}
+		})
+	}
+
+	// 未配置公开地址时使用存储服务的对象地址
+	store, _ := sNewTestS3Store(t, "")
+	if key, ok := store.KeyOf(store.URL("club_logos/c.png")); key != "club_logos/c.png" || !ok {
+		t.Errorf("KeyOf(URL(key)) = (%q, %v), want (%q, true)", key, ok, "club_logos/c.png")
+	}
+}
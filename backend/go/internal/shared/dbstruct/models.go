@@ -8,16 +8,18 @@ import (
 )
 
 type User struct {
-	UserId       uint      `gorm:"primaryKey;column:user_id"`
-	Username     string    `gorm:"size:50;unique;not null"`
-	PasswordHash string    `gorm:"type:char(60);not null"`
-	Email        string    `gorm:"size:100;unique;not null"`
-	AvatarUrl    string    `gorm:"size:255"`
-	Role         string    `gorm:"size:20;default:'user';not null"`
-	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
-	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
-	LastActive   time.Time
-	Extension    string `gorm:"type:text"`
+	UserId       uint   `gorm:"primaryKey;column:user_id"`
+	Username     string `gorm:"size:50;unique;not null"`
+	PasswordHash string `gorm:"type:char(60);not null"`
+	Email        string `gorm:"size:100;unique;not null"`
+	AvatarUrl    string `gorm:"size:255"`
+	// AvatarVariants 各尺寸头像地址，AvatarUrl保留默认尺寸
+	AvatarVariants datatypes.JSON `gorm:"type:jsonb"`
+	Role           string         `gorm:"size:20;default:'user';not null"`
+	CreatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt      time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	LastActive     time.Time
+	Extension      string `gorm:"type:text"`
 }
 
 const (
@@ -36,17 +38,19 @@ type Category struct {
 func (Category) TableName() string { return "categories" }
 
 type Club struct {
-	ClubId       uint           `gorm:"primaryKey;column:club_id" json:"club_id"`
-	Name         string         `gorm:"size:100;not null" json:"name"`
-	LeaderId     uint           `gorm:"not null" json:"leader_id"`
-	CategoryId   uint           `gorm:"not null" json:"category_id"`
-	Description  string         `gorm:"type:text;not null" json:"description"`
-	LogoUrl      string         `gorm:"size:255" json:"logo_url"`
-	MemberCount  int            `gorm:"default:0;not null" json:"member_count"`
-	Requirements string         `gorm:"type:text" json:"requirements"`
-	CreatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"created_at"`
-	UpdatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"updated_at"`
-	Tags         datatypes.JSON `json:"type:jsonb"`
+	ClubId            uint           `gorm:"primaryKey;column:club_id" json:"club_id"`
+	Name              string         `gorm:"size:100;not null" json:"name"`
+	LeaderId          uint           `gorm:"not null" json:"leader_id"`
+	CategoryId        uint           `gorm:"not null" json:"category_id"`
+	Description       string         `gorm:"type:text;not null" json:"description"`
+	LogoUrl           string         `gorm:"size:255" json:"logo_url"`
+	LogoVariants      datatypes.JSON `gorm:"type:jsonb" json:"logo_variants"` // 各尺寸logo地址，LogoUrl保留默认尺寸
+	MemberCount       int            `gorm:"default:0;not null" json:"member_count"`
+	Requirements      string         `gorm:"type:text" json:"requirements"`
+	RequirePostReview bool           `gorm:"default:false;not null" json:"require_post_review"` // 开启后普通成员发帖需经审核
+	CreatedAt         time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"updated_at"`
+	Tags              datatypes.JSON `json:"type:jsonb"`
 
 	Leader   User     `gorm:"foreignKey:LeaderId" json:"-"`
 	Category Category `gorm:"foreignKey:CategoryId" json:"-"`
@@ -67,8 +71,10 @@ type ClubMember struct {
 }
 
 const (
-	ROLE_CLUB_MEMBER = "member"
-	ROLE_CLUB_LEADER = "leader"
+	ROLE_CLUB_MEMBER      = "member"
+	ROLE_CLUB_OFFICER     = "officer"
+	ROLE_CLUB_VICE_LEADER = "vice_leader"
+	ROLE_CLUB_LEADER      = "leader"
 )
 
 func (ClubMember) TableName() string { return "club_members" }
@@ -117,36 +123,128 @@ type ClubFavorite struct {
 func (ClubFavorite) TableName() string { return "club_favorites" }
 
 type ClubPost struct {
-	PostId       uint      `gorm:"primaryKey;column:post_id" json:"post_id"`
-	ClubId       uint      `gorm:"not null" json:"club_id"`
-	UserId       uint      `gorm:"not null" json:"user_id"`
-	Title        string    `gorm:"size:120;not null" json:"title"`
-	ContentUrl   string    `gorm:"type:text;not null" json:"content_url"`
-	Visibility   int16     `gorm:"default:0;not null" json:"visibility"` // 0=公开, 1=社团成员, 2=管理员
-	IsPinned     bool      `gorm:"default:false;not null" json:"is_pinned"`
-	CommentCount int       `gorm:"default:0;not null" json:"comment_count"`
-	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;not null" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;not null" json:"updated_at"`
+	PostId       uint           `gorm:"primaryKey;column:post_id" json:"post_id"`
+	ClubId       uint           `gorm:"not null" json:"club_id"`
+	UserId       uint           `gorm:"not null" json:"user_id"`
+	Title        string         `gorm:"size:120;not null" json:"title"`
+	ContentUrl   string         `gorm:"type:text;not null" json:"content_url"`
+	Visibility   int16          `gorm:"default:0;not null" json:"visibility"` // 0=公开, 1=社团成员, 2=管理员
+	IsPinned     bool           `gorm:"default:false;not null" json:"is_pinned"`
+	CommentCount int            `gorm:"default:0;not null" json:"comment_count"`
+	Status       string         `gorm:"size:20;default:'published';not null;index" json:"status"`
+	DraftContent string         `gorm:"type:text" json:"-"`      // 草稿内容，发布时写入内容文件后清空
+	PublishAt    *time.Time     `gorm:"index" json:"publish_at"` // 定时发布时间，仅scheduled状态有效
+	CreatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Club   Club `gorm:"foreignKey:ClubId" json:"-"`
 	Author User `gorm:"foreignKey:UserId" json:"-"`
 }
 
+const (
+	POST_STATUS_DRAFT     = "draft"
+	POST_STATUS_SCHEDULED = "scheduled"
+	POST_STATUS_PENDING   = "pending" // 等待社团负责人审核
+	POST_STATUS_PUBLISHED = "published"
+)
+
 func (ClubPost) TableName() string { return "club_posts" }
 
+// PostRevision 帖子被编辑前的历史版本，Version从1开始递增，帖子当前内容为最新版本
+type PostRevision struct {
+	RevisionId  uint      `gorm:"primaryKey;column:revision_id"`
+	PostId      uint      `gorm:"not null;uniqueIndex:idx_post_revision_version"`
+	Version     int       `gorm:"not null;uniqueIndex:idx_post_revision_version"`
+	Title       string    `gorm:"size:120;not null"`
+	ContentUrl  string    `gorm:"type:text;not null"`
+	PublishedAt time.Time `gorm:"not null"` // 该版本的发布时间
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	Post ClubPost `gorm:"foreignKey:PostId"`
+}
+
+func (PostRevision) TableName() string { return "post_revisions" }
+
+// PostAttachment 帖子附件，图片附件额外生成缩略图
+type PostAttachment struct {
+	AttachmentId uint      `gorm:"primaryKey;column:attachment_id"`
+	PostId       uint      `gorm:"not null;index"`
+	UploaderId   uint      `gorm:"not null"`
+	FileName     string    `gorm:"size:255;not null"` // 上传时的原始文件名，正文中以此引用附件
+	FileUrl      string    `gorm:"type:text;not null;index"`
+	ThumbUrl     string    `gorm:"type:text"`
+	ContentType  string    `gorm:"size:100;not null"`
+	Size         int64     `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	Post ClubPost `gorm:"foreignKey:PostId"`
+}
+
+func (PostAttachment) TableName() string { return "post_attachments" }
+
 type ClubPostComment struct {
-	CommentId uint      `gorm:"primaryKey;column:comment_id"`
-	PostId    uint      `gorm:"not null"`
-	UserId    uint      `gorm:"not null"`
-	Content   string    `gorm:"type:text;not null"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	CommentId       uint      `gorm:"primaryKey;column:comment_id"`
+	PostId          uint      `gorm:"not null;index"`
+	UserId          uint      `gorm:"not null"`
+	ParentCommentId *uint     `gorm:"index"` // 为空表示直接评论帖子
+	RootCommentId   *uint     `gorm:"index"` // 所在楼层的顶层评论，分页时按楼层取回全部回复
+	Content         string    `gorm:"type:text;not null"`
+	Status          string    `gorm:"size:20;default:'normal';not null"`
+	RemovedBy       *uint     // 管理删除的操作者
+	RemovedReason   string    `gorm:"size:255"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
 
 	Post   ClubPost `gorm:"foreignKey:PostId"`
 	Author User     `gorm:"foreignKey:UserId"`
 }
 
+// 评论被删除后保留记录以维持楼层结构，返回时隐藏内容
+const (
+	COMMENT_STATUS_NORMAL  = "normal"
+	COMMENT_STATUS_DELETED = "deleted" // 作者删除
+	COMMENT_STATUS_REMOVED = "removed" // 社团负责人或管理员删除
+)
+
 func (ClubPostComment) TableName() string { return "club_post_comments" }
 
+// Reaction 用户对帖子或评论的表情回应，每个用户对同一对象仅保留一种
+type Reaction struct {
+	ReactionId uint      `gorm:"primaryKey;column:reaction_id"`
+	TargetType string    `gorm:"size:20;not null;uniqueIndex:idx_reaction_user_target"`
+	TargetId   uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target"`
+	UserId     uint      `gorm:"not null;uniqueIndex:idx_reaction_user_target"`
+	Kind       string    `gorm:"size:20;not null"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	User User `gorm:"foreignKey:UserId"`
+}
+
+const (
+	REACTION_TARGET_POST    = "post"
+	REACTION_TARGET_COMMENT = "comment"
+
+	REACTION_LIKE  = "like"
+	REACTION_LOVE  = "love"
+	REACTION_LAUGH = "laugh"
+	REACTION_WOW   = "wow"
+	REACTION_SAD   = "sad"
+)
+
+func (Reaction) TableName() string { return "reactions" }
+
+// ReactionCount 回应计数，由Redis中累积的增量定期合并写入，避免热门帖子的行锁争用
+type ReactionCount struct {
+	TargetType string    `gorm:"primaryKey;size:20"`
+	TargetId   uint      `gorm:"primaryKey"`
+	Kind       string    `gorm:"primaryKey;size:20"`
+	Count      int64     `gorm:"default:0;not null"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+}
+
+func (ReactionCount) TableName() string { return "reaction_counts" }
+
 type UpdateClubInfoAppli struct {
 	UpdateAppliId  uint           `gorm:"primaryKey;column:update_appli_id"`
 	ClubId         uint           `gorm:"not null"`
@@ -164,19 +262,209 @@ type UpdateClubInfoAppli struct {
 
 func (UpdateClubInfoAppli) TableName() string { return "update_club_info_applications" }
 
-// type CreatePostAppli struct {
-// 	PostAppliId     uint           `gorm:"primaryKey;column:post_appli_id"`
-// 	ClubId          uint           `gorm:"not null"`
-// 	ApplicantId     uint           `gorm:"not null"`
-// 	Proposal        datatypes.JSON `gorm:"type:jsonb;not null"`
-// 	DraftContentUrl string         `gorm:"size:255;not null"`
-// 	AppliedAt       time.Time      `gorm:"default:CURRENT_TIMESTAMP;not null"`
-// 	Status          string         `gorm:"size:20;default:'pending';not null"`
-// 	ReviewedAt      time.Time
-// 	RejectedReason  string `gorm:"size:255"`
-
-// 	Club      Club `gorm:"foreignKey:ClubId"`
-// 	Applicant User `gorm:"foreignKey:ApplicantId"`
-// }
-
-// func (CreatePostAppli) TableName() string { return "create_post_applications" }
+type LeaderTransfer struct {
+	TransferId uint      `gorm:"primaryKey;column:transfer_id"`
+	ClubId     uint      `gorm:"not null"`
+	FromUserId uint      `gorm:"not null"`
+	ToUserId   uint      `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	Status     string    `gorm:"size:20;default:'pending';not null"`
+	ReviewedAt time.Time
+
+	Club     Club `gorm:"foreignKey:ClubId"`
+	FromUser User `gorm:"foreignKey:FromUserId"`
+	ToUser   User `gorm:"foreignKey:ToUserId"`
+}
+
+func (LeaderTransfer) TableName() string { return "club_leader_transfers" }
+
+type Notification struct {
+	NotificationId uint      `gorm:"primaryKey;column:notification_id"`
+	UserId         uint      `gorm:"not null;index"`
+	Type           string    `gorm:"size:30;not null"`
+	Title          string    `gorm:"size:120;not null"`
+	Content        string    `gorm:"type:text"`
+	RefId          uint      `gorm:"default:0;not null"` // 关联的申请ID等
+	IsRead         bool      `gorm:"default:false;not null"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	User User `gorm:"foreignKey:UserId"`
+}
+
+const (
+	NOTIFY_CREATE_CLUB_APPLI = "create_club_appli"
+	NOTIFY_UPDATE_CLUB_APPLI = "update_club_appli"
+	NOTIFY_JOIN_CLUB_APPLI   = "join_club_appli"
+	NOTIFY_EVENT_CANCELLED   = "event_cancelled"
+	NOTIFY_EVENT_PROMOTED    = "event_promoted"
+	NOTIFY_COMMENT_REMOVED   = "comment_removed"
+	NOTIFY_CREATE_POST_APPLI = "create_post_appli"
+)
+
+func (Notification) TableName() string { return "notifications" }
+
+type ClubEvent struct {
+	EventId     uint      `gorm:"primaryKey;column:event_id"`
+	ClubId      uint      `gorm:"not null;index"`
+	CreatorId   uint      `gorm:"not null"`
+	Title       string    `gorm:"size:120;not null"`
+	Description string    `gorm:"type:text"`
+	Location    string    `gorm:"size:255;not null"`
+	StartAt     time.Time `gorm:"not null"`
+	EndAt       time.Time `gorm:"not null"`
+	Capacity    int       `gorm:"default:0;not null"` // 0=不限人数
+	GoingCount  int       `gorm:"default:0;not null"`
+	Status      string    `gorm:"size:20;default:'scheduled';not null"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	Club    Club `gorm:"foreignKey:ClubId"`
+	Creator User `gorm:"foreignKey:CreatorId"`
+}
+
+const (
+	EVENT_STATUS_SCHEDULED = "scheduled"
+	EVENT_STATUS_CANCELLED = "cancelled"
+)
+
+func (ClubEvent) TableName() string { return "club_events" }
+
+type EventRsvp struct {
+	RsvpId    uint      `gorm:"primaryKey;column:rsvp_id"`
+	EventId   uint      `gorm:"not null;uniqueIndex:idx_event_rsvp_user"`
+	UserId    uint      `gorm:"not null;uniqueIndex:idx_event_rsvp_user"`
+	Status    string    `gorm:"size:20;not null"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"` // 候补队列按此排序
+
+	Event ClubEvent `gorm:"foreignKey:EventId"`
+	User  User      `gorm:"foreignKey:UserId"`
+}
+
+const (
+	RSVP_STATUS_GOING     = "going"
+	RSVP_STATUS_WAITLIST  = "waitlist"
+	RSVP_STATUS_CANCELLED = "cancelled"
+)
+
+func (EventRsvp) TableName() string { return "club_event_rsvps" }
+
+type EventAttendance struct {
+	AttendanceId uint      `gorm:"primaryKey;column:attendance_id"`
+	EventId      uint      `gorm:"not null;uniqueIndex:idx_event_attendance_user"`
+	ClubId       uint      `gorm:"not null;index"`
+	UserId       uint      `gorm:"not null;uniqueIndex:idx_event_attendance_user"`
+	CheckedInAt  time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+
+	Event ClubEvent `gorm:"foreignKey:EventId"`
+	User  User      `gorm:"foreignKey:UserId"`
+}
+
+func (EventAttendance) TableName() string { return "club_event_attendances" }
+
+const (
+	POST_APPLI_CREATE = "create" // 新帖发布
+	POST_APPLI_EDIT   = "edit"   // 已发布帖子的修改
+)
+
+// CreatePostAppli 开启发帖审核的社团中普通成员的发帖与改帖申请。发帖申请审核期间帖子处于pending状态；
+// 改帖申请的新版本保存在Title与ContentUrl中，审核期间帖子仍展示原内容
+type CreatePostAppli struct {
+	PostAppliId    uint      `gorm:"primaryKey;column:post_appli_id"`
+	PostId         uint      `gorm:"not null;index"`
+	ClubId         uint      `gorm:"not null;index"`
+	ApplicantId    uint      `gorm:"not null"`
+	Kind           string    `gorm:"size:20;default:'create';not null"`
+	Title          string    `gorm:"size:120"`
+	ContentUrl     string    `gorm:"type:text"`
+	AppliedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP;not null"`
+	Status         string    `gorm:"size:20;default:'pending';not null"`
+	ReviewerId     *uint
+	ReviewedAt     time.Time
+	RejectedReason string `gorm:"size:255"`
+
+	Post      ClubPost `gorm:"foreignKey:PostId"`
+	Club      Club     `gorm:"foreignKey:ClubId"`
+	Applicant User     `gorm:"foreignKey:ApplicantId"`
+}
+
+func (CreatePostAppli) TableName() string { return "create_post_applications" }
+
+// SearchOutbox 搜索索引同步事件，与业务数据在同一事务中写入，由同步任务应用到ES；
+// 处理完成的事件保留一段时间，供重建索引后重放
+type SearchOutbox struct {
+	OutboxId      uint      `gorm:"primaryKey;column:outbox_id"`
+	Entity        string    `gorm:"size:20;not null"` // club或post
+	EntityId      uint      `gorm:"not null"`
+	Op            string    `gorm:"size:20;not null"`
+	Status        string    `gorm:"size:20;default:'pending';not null;index:idx_search_outbox_due,priority:1"`
+	Attempts      int       `gorm:"default:0;not null"`
+	NextAttemptAt time.Time `gorm:"default:CURRENT_TIMESTAMP;not null;index:idx_search_outbox_due,priority:2"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP;not null;index"`
+	ProcessedAt   *time.Time
+}
+
+const (
+	SEARCH_ENTITY_CLUB = "club"
+	SEARCH_ENTITY_POST = "post"
+
+	SEARCH_OP_UPSERT = "upsert"
+	SEARCH_OP_DELETE = "delete"
+
+	OUTBOX_STATUS_PENDING = "pending"
+	OUTBOX_STATUS_DONE    = "done"
+	OUTBOX_STATUS_FAILED  = "failed" // 重试次数耗尽，需人工处理或重建索引
+)
+
+func (SearchOutbox) TableName() string { return "search_outbox" }
+
+// RagOutbox RAG同步事件，与业务数据在同一事务中写入，由转发任务推送至rag_sync_stream；
+// 推送成功后记录流消息ID作为确认
+type RagOutbox struct {
+	OutboxId      uint      `gorm:"primaryKey;column:outbox_id"`
+	Entity        string    `gorm:"size:20;not null"` // club或post
+	EntityId      uint      `gorm:"not null"`
+	Op            string    `gorm:"size:20;default:'upsert';not null"` // upsert或delete
+	Status        string    `gorm:"size:20;default:'pending';not null;index:idx_rag_outbox_due,priority:1"`
+	Attempts      int       `gorm:"default:0;not null"`
+	NextAttemptAt time.Time `gorm:"default:CURRENT_TIMESTAMP;not null;index:idx_rag_outbox_due,priority:2"`
+	LastError     string    `gorm:"type:text"`
+	MessageId     string    `gorm:"size:40"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP;not null;index"`
+	ProcessedAt   *time.Time
+}
+
+const (
+	RAG_ENTITY_CLUB = "club"
+	RAG_ENTITY_POST = "post"
+
+	// 取值与RAG服务消息的op字段一致
+	RAG_OP_UPSERT = "upsert"
+	RAG_OP_DELETE = "delete"
+)
+
+func (RagOutbox) TableName() string { return "rag_outbox" }
+
+// LlmUsageLog LLM调用审计记录，经由转发的每次LLM调用一条，包括因额度被拒绝的调用
+type LlmUsageLog struct {
+	LogId         uint      `gorm:"primaryKey;column:log_id"`
+	UserId        uint      `gorm:"not null;index:idx_llm_usage_user_time,priority:1"`
+	Role          string    `gorm:"size:20;not null"`
+	Route         string    `gorm:"size:100;not null"`
+	Outcome       string    `gorm:"size:20;not null"`
+	StatusCode    int       `gorm:"default:0;not null"`
+	RequestBytes  int64     `gorm:"default:0;not null"`
+	ResponseBytes int64     `gorm:"default:0;not null"`
+	Tokens        int64     `gorm:"default:0;not null"`
+	DurationMs    int64     `gorm:"default:0;not null"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP;not null;index;index:idx_llm_usage_user_time,priority:2"`
+}
+
+const (
+	LLM_USAGE_OK       = "ok"       // 上游已响应，不论响应状态码
+	LLM_USAGE_REJECTED = "rejected" // 超出当日额度
+	LLM_USAGE_FAILED   = "failed"   // 未到达上游或上游无响应，不计入额度
+)
+
+func (LlmUsageLog) TableName() string { return "llm_usage_logs" }
@@ -0,0 +1,156 @@
+package imgutil
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// sExifSegment 构造只含一个方向标记条目的APP1段
+func sExifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], kExifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(2+len(payload)))
+	return append(seg, payload...)
+}
+
+func sJpeg(segments ...[]byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	for _, seg := range segments {
+		data = append(data, seg...)
+	}
+	return append(data, 0xFF, 0xD9)
+}
+
+func TestJpegOrientation(t *testing.T) {
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x06, 'J', 'F', 'I', 'F'}
+	sos := []byte{0xFF, 0xDA, 0x00, 0x02}
+
+	exif := sExifSegment(binary.BigEndian, 6)
+	truncated := sJpeg(exif)[:len(exif)-4]
+
+	badOrder := sExifSegment(binary.LittleEndian, 6)
+	copy(badOrder[10:], "XX")
+
+	badIfd := sExifSegment(binary.BigEndian, 6)
+	binary.BigEndian.PutUint32(badIfd[14:], 1000)
+
+	manyEntries := sExifSegment(binary.LittleEndian, 6)
+	binary.LittleEndian.PutUint16(manyEntries[18:], 5)
+
+	otherTag := sExifSegment(binary.BigEndian, 6)
+	binary.BigEndian.PutUint16(otherTag[20:], 0x0100)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"小端序", sJpeg(sExifSegment(binary.LittleEndian, 6)), 6},
+		{"大端序", sJpeg(sExifSegment(binary.BigEndian, 8)), 8},
+		{"APP0之后", sJpeg(app0, sExifSegment(binary.LittleEndian, 3)), 3},
+		{"段间填充", sJpeg([]byte{0xFF}, sExifSegment(binary.BigEndian, 5)), 5},
+		{"无EXIF", sJpeg(app0), 1},
+		{"图像数据之后的EXIF", sJpeg(sos, sExifSegment(binary.BigEndian, 6)), 1},
+		{"非JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"空数据", nil, 1},
+		{"段被截断", truncated, 1},
+		{"段长度小于2", sJpeg([]byte{0xFF, 0xE1, 0x00, 0x01}), 1},
+		{"缺少段标记", append([]byte{0xFF, 0xD8, 0x00}, exif...), 1},
+		{"未知字节序", sJpeg(badOrder), 1},
+		{"IFD偏移越界", sJpeg(badIfd), 1},
+		{"条目数偏大但方向条目在前", sJpeg(manyEntries), 6},
+		{"无方向条目", sJpeg(otherTag), 1},
+		{"方向值越界", sJpeg(sExifSegment(binary.BigEndian, 9)), 1},
+		{"方向值为0", sJpeg(sExifSegment(binary.LittleEndian, 0)), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := JpegOrientation(tt.data); got != tt.want {
+				t.Errorf("JpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// sGrid 以灰度值区分像素，rows中每个字符对应一个像素
+func sGrid(rows ...string) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x := range row {
+			img.SetGray(x, y, color.Gray{Y: row[x]})
+		}
+	}
+	return img
+}
+
+func sRows(img image.Image) []string {
+	bounds := img.Bounds()
+	rows := make([]string, 0, bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := make([]byte, 0, bounds.Dx())
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			row = append(row, color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	// ABC
+	// DEF
+	src := sGrid("ABC", "DEF")
+
+	tests := []struct {
+		name        string
+		orientation int
+		want        []string
+	}{
+		{"正常", 1, []string{"ABC", "DEF"}},
+		{"水平翻转", 2, []string{"CBA", "FED"}},
+		{"旋转180°", 3, []string{"FED", "CBA"}},
+		{"垂直翻转", 4, []string{"DEF", "ABC"}},
+		{"沿主对角线翻转", 5, []string{"AD", "BE", "CF"}},
+		{"顺时针旋转90°", 6, []string{"DA", "EB", "FC"}},
+		{"沿副对角线翻转", 7, []string{"FC", "EB", "DA"}},
+		{"逆时针旋转90°", 8, []string{"CF", "BE", "AD"}},
+		{"非法方向", 9, []string{"ABC", "DEF"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sRows(Orient(src, tt.orientation))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Orient() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Orient() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// 子图的坐标不从原点开始
+	sub := sGrid("xxxx", "xABC", "xDEF").SubImage(image.Rect(1, 1, 4, 3))
+	if got := sRows(Orient(sub, 6)); len(got) != 3 || got[0] != "DA" || got[2] != "FC" {
+		t.Errorf("子图Orient() = %q, want %q", got, []string{"DA", "EB", "FC"})
+	}
+}
@@ -0,0 +1,135 @@
+package imgutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// 超过该像素数的图片不解码，避免恶意构造的小文件解码后占满内存
+const kMaxDecodePixels = 40_000_000
+
+var ErrImageTooLarge = errors.New("图片尺寸过大")
+
+// Decode 解码jpeg/png/gif图片，解码前先检查尺寸；jpeg按EXIF方向标记摆正
+func Decode(data []byte) (image.Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width*cfg.Height > kMaxDecodePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "jpeg" {
+		img = Orient(img, JpegOrientation(data))
+	}
+
+	return img, nil
+}
+
+// Fit 将图片等比缩小到长边不超过maxEdge，已满足时原样返回
+func Fit(src image.Image, maxEdge int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxEdge && srcH <= maxEdge {
+		return src
+	}
+
+	if srcW >= srcH {
+		return Resize(src, maxEdge, max(1, srcH*maxEdge/srcW))
+	}
+	return Resize(src, max(1, srcW*maxEdge/srcH), maxEdge)
+}
+
+// CropSquare 以中心为准裁剪出最大的正方形
+func CropSquare(src image.Image) image.Image {
+	bounds := src.Bounds()
+	edge := min(bounds.Dx(), bounds.Dy())
+
+	x0 := bounds.Min.X + (bounds.Dx()-edge)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-edge)/2
+	rect := image.Rect(x0, y0, x0+edge, y0+edge)
+
+	if sub, ok := src.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(dst, dst.Bounds(), src, rect.Min, draw.Src)
+	return dst
+}
+
+// Resize 缩放到指定尺寸；缩小采用区域平均，放大退化为最近邻，头像与缩略图场景下效果足够
+func Resize(src image.Image, dstW, dstH int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+// EncodeJPEG 透明区域按白色背景输出
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// 预乘alpha的颜色叠加白色背景
+			bg := 0xffff - a
+			flat.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + bg) >> 8),
+				G: uint8((g + bg) >> 8),
+				B: uint8((b + bg) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+}
@@ -0,0 +1,110 @@
+package imgutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// sPngHeader 只构造PNG签名与IHDR块，足以让DecodeConfig读出尺寸
+func sPngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 4+13)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12] = 8 // 位深
+	ihdr[13] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, image.NewRGBA(image.Rect(0, 0, 4, 2)))
+
+	var jpegBuf bytes.Buffer
+	jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil)
+	rotated := append([]byte{0xFF, 0xD8}, sExifSegment(binary.LittleEndian, 6)...)
+	rotated = append(rotated, jpegBuf.Bytes()[2:]...)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantErr  error
+		wantSize image.Point
+	}{
+		{"普通PNG", pngBuf.Bytes(), nil, image.Pt(4, 2)},
+		{"无方向JPEG", jpegBuf.Bytes(), nil, image.Pt(4, 2)},
+		{"旋转JPEG", rotated, nil, image.Pt(2, 4)},
+		{"超过像素上限", sPngHeader(8000, 5001), ErrImageTooLarge, image.Point{}},
+		{"单边极长", sPngHeader(1<<30, 1), ErrImageTooLarge, image.Point{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && img.Bounds().Size() != tt.wantSize {
+				t.Errorf("Decode() size = %v, want %v", img.Bounds().Size(), tt.wantSize)
+			}
+		})
+	}
+
+	// 恰好等于上限时应通过尺寸检查，随后因缺少图像数据解码失败
+	if _, err := Decode(sPngHeader(8000, 5000)); err == nil || errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Decode(上限尺寸) error = %v, want 非尺寸错误", err)
+	}
+
+	if _, err := Decode([]byte("not an image")); err == nil {
+		t.Errorf("Decode(非图片) 应返回错误")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name    string
+		w, h    int
+		maxEdge int
+		want    image.Point
+	}{
+		{"无需缩小", 100, 50, 200, image.Pt(100, 50)},
+		{"横图", 400, 100, 200, image.Pt(200, 50)},
+		{"竖图", 100, 400, 200, image.Pt(50, 200)},
+		{"极窄", 1000, 1, 100, image.Pt(100, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fit(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.maxEdge).Bounds().Size()
+			if got != tt.want {
+				t.Errorf("Fit() size = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResizeAveragesArea(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 2, 2))
+	src.SetGray(0, 0, color.Gray{Y: 0})
+	src.SetGray(1, 0, color.Gray{Y: 200})
+	src.SetGray(0, 1, color.Gray{Y: 100})
+	src.SetGray(1, 1, color.Gray{Y: 100})
+
+	got := Resize(src, 1, 1).RGBAAt(0, 0)
+	if got.R < 99 || got.R > 100 || got.A != 0xff {
+		t.Errorf("Resize() = %v, want 约(100,100,100,255)", got)
+	}
+}
@@ -0,0 +1,16 @@
+-- 社团负责人移交
+CREATE TABLE IF NOT EXISTS club_leader_transfers (
+  transfer_id SERIAL PRIMARY KEY,
+  club_id INT NOT NULL REFERENCES clubs(club_id) ON DELETE CASCADE,
+  from_user_id INT NOT NULL REFERENCES users(user_id),
+  to_user_id INT NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  reviewed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_leader_transfer_to_user ON club_leader_transfers (to_user_id, status);
+
+-- 同一社团同时只能有一个待处理的移交
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leader_transfer_pending
+  ON club_leader_transfers (club_id) WHERE status = 'pending';
@@ -0,0 +1,29 @@
+-- 社团活动与报名
+CREATE TABLE IF NOT EXISTS club_events (
+  event_id SERIAL PRIMARY KEY,
+  club_id INT NOT NULL REFERENCES clubs(club_id) ON DELETE CASCADE,
+  creator_id INT NOT NULL REFERENCES users(user_id),
+  title VARCHAR(120) NOT NULL,
+  description TEXT,
+  location VARCHAR(255) NOT NULL,
+  start_at TIMESTAMP NOT NULL,
+  end_at TIMESTAMP NOT NULL,
+  capacity INT NOT NULL DEFAULT 0,
+  going_count INT NOT NULL DEFAULT 0,
+  status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_club_events_club_id ON club_events (club_id);
+
+CREATE TABLE IF NOT EXISTS club_event_rsvps (
+  rsvp_id SERIAL PRIMARY KEY,
+  event_id INT NOT NULL REFERENCES club_events(event_id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_rsvp_user ON club_event_rsvps (event_id, user_id);
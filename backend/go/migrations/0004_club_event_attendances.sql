@@ -0,0 +1,11 @@
+-- 活动签到记录
+CREATE TABLE IF NOT EXISTS club_event_attendances (
+  attendance_id SERIAL PRIMARY KEY,
+  event_id INT NOT NULL REFERENCES club_events(event_id) ON DELETE CASCADE,
+  club_id INT NOT NULL REFERENCES clubs(club_id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  checked_in_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_attendance_user ON club_event_attendances (event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_club_event_attendances_club_id ON club_event_attendances (club_id);
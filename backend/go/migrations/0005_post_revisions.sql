@@ -0,0 +1,18 @@
+-- 帖子软删除与编辑历史
+ALTER TABLE club_posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE club_posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_club_posts_deleted_at ON club_posts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+  revision_id SERIAL PRIMARY KEY,
+  post_id INT NOT NULL REFERENCES club_posts(post_id) ON DELETE CASCADE,
+  version INT NOT NULL,
+  title VARCHAR(120) NOT NULL,
+  content_url TEXT NOT NULL,
+  published_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 编辑时已锁定帖子行，唯一索引兜底保证同一帖子的版本号不重复
+CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revision_version ON post_revisions (post_id, version);
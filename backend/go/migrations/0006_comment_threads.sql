@@ -0,0 +1,21 @@
+-- 评论楼层、软删除与审核字段
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS parent_comment_id INT REFERENCES club_post_comments(comment_id);
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS root_comment_id INT REFERENCES club_post_comments(comment_id);
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'normal';
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS removed_by INT REFERENCES users(user_id);
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS removed_reason VARCHAR(255);
+ALTER TABLE club_post_comments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_club_post_comments_post_id ON club_post_comments (post_id);
+CREATE INDEX IF NOT EXISTS idx_club_post_comments_parent_comment_id ON club_post_comments (parent_comment_id);
+CREATE INDEX IF NOT EXISTS idx_club_post_comments_root_comment_id ON club_post_comments (root_comment_id);
+
+-- 评论删除改为修改状态，触发器无法感知；评论数改由应用在发表与删除评论的事务中维护，
+-- 只统计状态为normal的评论
+DROP TRIGGER IF EXISTS trg_update_comment_count ON club_post_comments;
+DROP FUNCTION IF EXISTS update_post_comment_count();
+
+UPDATE club_posts p SET comment_count = (
+  SELECT COUNT(*) FROM club_post_comments c
+  WHERE c.post_id = p.post_id AND c.status = 'normal'
+);
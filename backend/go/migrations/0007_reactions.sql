@@ -0,0 +1,22 @@
+-- 帖子与评论的表情回应
+CREATE TABLE IF NOT EXISTS reactions (
+  reaction_id SERIAL PRIMARY KEY,
+  target_type VARCHAR(20) NOT NULL,
+  target_id INT NOT NULL,
+  user_id INT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  kind VARCHAR(20) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 每个用户对同一对象只保留一个回应，回应时依赖此索引执行 ON CONFLICT
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reaction_user_target ON reactions (target_type, target_id, user_id);
+
+-- 已合并的回应计数，Redis中的增量定期合并到此表
+CREATE TABLE IF NOT EXISTS reaction_counts (
+  target_type VARCHAR(20) NOT NULL,
+  target_id INT NOT NULL,
+  kind VARCHAR(20) NOT NULL,
+  count BIGINT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (target_type, target_id, kind)
+);
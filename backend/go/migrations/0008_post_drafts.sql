@@ -0,0 +1,7 @@
+-- 帖子草稿与定时发布，已有帖子均视为已发布
+ALTER TABLE club_posts ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published';
+ALTER TABLE club_posts ADD COLUMN IF NOT EXISTS draft_content TEXT;
+ALTER TABLE club_posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_club_posts_status ON club_posts (status);
+CREATE INDEX IF NOT EXISTS idx_club_posts_publish_at ON club_posts (publish_at);
@@ -0,0 +1,20 @@
+-- 发帖审核：社团可要求普通成员的发帖与改帖经负责人审核
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS require_post_review BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS create_post_applications (
+  post_appli_id SERIAL PRIMARY KEY,
+  post_id INT NOT NULL REFERENCES club_posts(post_id) ON DELETE CASCADE,
+  club_id INT NOT NULL REFERENCES clubs(club_id) ON DELETE CASCADE,
+  applicant_id INT NOT NULL REFERENCES users(user_id),
+  kind VARCHAR(20) NOT NULL DEFAULT 'create',
+  title VARCHAR(120),
+  content_url TEXT,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  reviewer_id INT REFERENCES users(user_id),
+  reviewed_at TIMESTAMP,
+  rejected_reason VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_create_post_applications_post_id ON create_post_applications (post_id);
+CREATE INDEX IF NOT EXISTS idx_create_post_applications_club_id ON create_post_applications (club_id);
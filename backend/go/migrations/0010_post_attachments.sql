@@ -0,0 +1,14 @@
+-- 帖子附件，图片附件额外保存缩略图
+CREATE TABLE IF NOT EXISTS post_attachments (
+  attachment_id SERIAL PRIMARY KEY,
+  post_id INT NOT NULL REFERENCES club_posts(post_id) ON DELETE CASCADE,
+  uploader_id INT NOT NULL REFERENCES users(user_id),
+  file_name VARCHAR(255) NOT NULL,
+  file_path TEXT NOT NULL,
+  thumb_path TEXT,
+  content_type VARCHAR(100) NOT NULL,
+  size BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_attachments_post_id ON post_attachments (post_id);
@@ -0,0 +1,15 @@
+-- 附件改为保存对象的公开访问地址
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_name = 'post_attachments' AND column_name = 'file_path') THEN
+    ALTER TABLE post_attachments RENAME COLUMN file_path TO file_url;
+  END IF;
+
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_name = 'post_attachments' AND column_name = 'thumb_path') THEN
+    ALTER TABLE post_attachments RENAME COLUMN thumb_path TO thumb_url;
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_post_attachments_file_url ON post_attachments (file_url);
@@ -0,0 +1,3 @@
+-- 头像与社团logo的多尺寸地址
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_variants JSONB;
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS logo_variants JSONB;
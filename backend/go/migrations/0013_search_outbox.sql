@@ -0,0 +1,16 @@
+-- 搜索索引同步事件，与业务数据在同一事务中写入
+CREATE TABLE IF NOT EXISTS search_outbox (
+  outbox_id SERIAL PRIMARY KEY,
+  entity VARCHAR(20) NOT NULL,
+  entity_id INT NOT NULL,
+  op VARCHAR(20) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  processed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_outbox_due ON search_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_search_outbox_created_at ON search_outbox (created_at);
@@ -0,0 +1,16 @@
+-- RAG同步事件，与业务数据在同一事务中写入，推送至rag_sync_stream后记录流消息ID
+CREATE TABLE IF NOT EXISTS rag_outbox (
+  outbox_id SERIAL PRIMARY KEY,
+  entity VARCHAR(20) NOT NULL,
+  entity_id INT NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  message_id VARCHAR(40),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  processed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rag_outbox_due ON rag_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_rag_outbox_created_at ON rag_outbox (created_at);
@@ -0,0 +1,2 @@
+-- RAG同步事件区分更新与移除，已有事件均按更新处理
+ALTER TABLE rag_outbox ADD COLUMN IF NOT EXISTS op VARCHAR(20) NOT NULL DEFAULT 'upsert';
@@ -0,0 +1,17 @@
+-- LLM调用审计记录，包括因额度被拒绝的调用
+CREATE TABLE IF NOT EXISTS llm_usage_logs (
+  log_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL,
+  role VARCHAR(20) NOT NULL,
+  route VARCHAR(100) NOT NULL,
+  outcome VARCHAR(20) NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  request_bytes BIGINT NOT NULL DEFAULT 0,
+  response_bytes BIGINT NOT NULL DEFAULT 0,
+  tokens BIGINT NOT NULL DEFAULT 0,
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_time ON llm_usage_logs (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_logs_created_at ON llm_usage_logs (created_at);